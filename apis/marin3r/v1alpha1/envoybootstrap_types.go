@@ -25,6 +25,12 @@ type EnvoyBootstrapSpec struct {
 	// DiscoveryService is the name of the DiscoveryService resource the envoy will be a client of
 	// +operator-sdk:csv:customresourcedefinitions:type=spec
 	DiscoveryService string `json:"discoveryService"`
+	// DiscoveryServiceNamespace is the namespace of the DiscoveryService resource the envoy will be
+	// a client of. Defaults to the namespace of the EnvoyBootstrap. A DiscoveryService in another
+	// namespace must select the namespace of the EnvoyBootstrap with its namespaceSelector.
+	// +operator-sdk:csv:customresourcedefinitions:type=spec
+	// +optional
+	DiscoveryServiceNamespace string `json:"discoveryServiceNamespace,omitempty"`
 	// ClientCertificate is a struct containing options for the certificate used to authenticate with the
 	// discovery service
 	// +operator-sdk:csv:customresourcedefinitions:type=spec
@@ -32,6 +38,28 @@ type EnvoyBootstrapSpec struct {
 	// EnvoyStaticConfig is a struct that controls options for the envoy's static config file
 	// +operator-sdk:csv:customresourcedefinitions:type=spec
 	EnvoyStaticConfig *EnvoyStaticConfig `json:"envoyStaticConfig"`
+	// AllowedNodeIDs is the list of nodeIDs whose configuration can be fetched from the
+	// discovery service by the envoy clients that use the certificate issued for this
+	// EnvoyBootstrap. Only nodeIDs in the namespace of the EnvoyBootstrap are allowed
+	// when set. All nodeIDs are allowed when unset.
+	// +operator-sdk:csv:customresourcedefinitions:type=spec
+	// +optional
+	AllowedNodeIDs []string `json:"allowedNodeIDs,omitempty"`
+	// ServiceAccountToken configures the envoy clients to authenticate with the discovery
+	// service using a projected ServiceAccount token instead of the client certificate. The
+	// DiscoveryService must have ServiceAccountToken authentication enabled.
+	// +operator-sdk:csv:customresourcedefinitions:type=spec
+	// +optional
+	ServiceAccountToken *ServiceAccountToken `json:"serviceAccountToken,omitempty"`
+}
+
+// ServiceAccountToken allows specifying options for the ServiceAccount
+// token used to authenticate with the discovery service
+type ServiceAccountToken struct {
+	// Directory defines the directory in the envoy container where
+	// the projected ServiceAccount token is mounted
+	// +operator-sdk:csv:customresourcedefinitions:type=spec
+	Directory string `json:"directory"`
 }
 
 // EnvoyStaticConfig allows specifying envoy static config
@@ -77,6 +105,13 @@ type ClientCertificate struct {
 	// The requested ‘duration’ (i.e. lifetime) of the Certificate
 	// +operator-sdk:csv:customresourcedefinitions:type=spec
 	Duration metav1.Duration `json:"duration"`
+	// PerPod makes each envoy client request its own short-lived certificate from the
+	// discovery service, which must have PodCertificates enabled, instead of using the
+	// certificate stored in SecretName. The certificate is written to Directory, along
+	// with the SDS resource envoy loads it from, and renewed before it expires.
+	// +operator-sdk:csv:customresourcedefinitions:type=spec
+	// +optional
+	PerPod bool `json:"perPod,omitempty"`
 }
 
 // EnvoyBootstrapStatus defines the observed state of EnvoyBootstrap
@@ -95,6 +130,14 @@ type EnvoyBootstrap struct {
 	Status EnvoyBootstrapStatus `json:"status,omitempty"`
 }
 
+// GetDiscoveryServiceNamespace returns the namespace of the DiscoveryService
+func (eb *EnvoyBootstrap) GetDiscoveryServiceNamespace() string {
+	if eb.Spec.DiscoveryServiceNamespace == "" {
+		return eb.GetNamespace()
+	}
+	return eb.Spec.DiscoveryServiceNamespace
+}
+
 // +kubebuilder:object:root=true
 
 // EnvoyBootstrapList contains a list of EnvoyBootstrap
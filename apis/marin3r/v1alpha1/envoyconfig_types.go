@@ -17,12 +17,20 @@ limitations under the License.
 package v1alpha1
 
 import (
+	"fmt"
+	"strings"
+	"time"
+
 	"github.com/3scale/marin3r/pkg/common"
 	"github.com/3scale/marin3r/pkg/envoy"
+	envoy_resources "github.com/3scale/marin3r/pkg/envoy/resources"
 	envoy_serializer "github.com/3scale/marin3r/pkg/envoy/serializer"
 	"github.com/operator-framework/operator-lib/status"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/util/validation/field"
 )
 
 const (
@@ -39,6 +47,14 @@ const (
 	// tainted
 	RollbackFailedCondition status.ConditionType = "RollbackFailed"
 
+	// DanglingReferencesCondition indicates that the resources of the
+	// EnvoyConfig reference other resources that cannot be found
+	DanglingReferencesCondition status.ConditionType = "DanglingReferences"
+
+	// RevisionPinnedCondition indicates that the EnvoyConfig object has
+	// a pinned version that is published instead of the desired version
+	RevisionPinnedCondition status.ConditionType = "RevisionPinned"
+
 	/* State */
 
 	//InSyncState indicates that a EnvoyConfig object has its resources spec
@@ -52,6 +68,16 @@ const (
 	// RollbackFailedState indicates that there is no untainted revision that
 	// can be pusblished in the xds server cache
 	RollbackFailedState string = "RollbackFailed"
+
+	// PinnedState indicates that a EnvoyConfig object has the revision
+	// specified in spec.pinnedVersion published in the xds server cache
+	PinnedState string = "Pinned"
+
+	/* Defaults */
+
+	// DefaultRevisionHistoryLimit is the number of revisions that
+	// are kept if no other limit is specified
+	DefaultRevisionHistoryLimit int = 10
 )
 
 // EnvoyConfigSpec defines the desired state of EnvoyConfig
@@ -72,11 +98,132 @@ type EnvoyConfigSpec struct {
 	// +operator-sdk:csv:customresourcedefinitions:type=spec
 	// +optional
 	EnvoyAPI *string `json:"envoyAPI,omitempty"`
+	// BlockOnDanglingReferences, when set to true, prevents the publication of resources
+	// that reference other resources that cannot be found, like a listener pointing to an
+	// RDS route configuration or a route pointing to a cluster that don't exist. Dangling
+	// references are always reported in the status, even if this is unset.
+	// +operator-sdk:csv:customresourcedefinitions:type=spec
+	// +optional
+	BlockOnDanglingReferences *bool `json:"blockOnDanglingReferences,omitempty"`
+	// PinnedVersion forces the publication of the revision with the given version instead of the
+	// revision for the current resources. The revision must exist and not be tainted for the pin
+	// to take effect. Remove the field to go back to publishing the current resources.
+	// +operator-sdk:csv:customresourcedefinitions:type=spec
+	// +optional
+	PinnedVersion *string `json:"pinnedVersion,omitempty"`
+	// UntaintAfter is the period of time after which tainted revisions are automatically
+	// untainted so they can be published again. This is useful when revisions get tainted
+	// due to transient failures. Only revisions tainted because a gateway rejected them are
+	// untainted automatically, and never if unset.
+	// +operator-sdk:csv:customresourcedefinitions:type=spec
+	// +optional
+	UntaintAfter *metav1.Duration `json:"untaintAfter,omitempty"`
+	// RevisionHistory defines which of the EnvoyConfigRevisions of this EnvoyConfig are kept.
+	// By default the last 10 revisions are kept.
+	// +operator-sdk:csv:customresourcedefinitions:type=spec
+	// +optional
+	RevisionHistory *RevisionHistoryPolicy `json:"revisionHistory,omitempty"`
+	// Rollout enables the progressive rollout of new revisions to the proxies that share the nodeID.
+	// A new revision is first published to a subset of the connected proxies, the canaries, and to
+	// the rest of them once all the canaries have acknowledged it and the bake time has elapsed.
+	// If any of the canaries rejects the revision it gets tainted and the previous revision is
+	// published back. New revisions are published to all the proxies at once if unset, or if the
+	// discovery service runs several replicas.
+	// +operator-sdk:csv:customresourcedefinitions:type=spec
+	// +optional
+	Rollout *RolloutPolicy `json:"rollout,omitempty"`
 	// EnvoyResources holds the different types of resources suported by the envoy discovery service
 	// +operator-sdk:csv:customresourcedefinitions:type=spec
 	EnvoyResources *EnvoyResources `json:"envoyResources"`
 }
 
+// RevisionHistoryPolicy defines which EnvoyConfigRevisions are kept. A revision is deleted
+// when it exceeds any of the limits, but the published revision, the revision for the current
+// resources and the last 'MinUntainted' untainted revisions are never deleted.
+type RevisionHistoryPolicy struct {
+	// Limit is the maximum number of revisions to keep. Defaults to 10.
+	// +kubebuilder:validation:Minimum=1
+	// +operator-sdk:csv:customresourcedefinitions:type=spec
+	// +optional
+	Limit *int32 `json:"limit,omitempty"`
+	// MaxAge is the maximum age of the revisions to keep. The age of a revision is measured
+	// since it was last published, or since it was created if it has never been published.
+	// Revisions are not deleted due to its age if unset.
+	// +operator-sdk:csv:customresourcedefinitions:type=spec
+	// +optional
+	MaxAge *metav1.Duration `json:"maxAge,omitempty"`
+	// MinUntainted is the number of most recent untainted revisions that are always
+	// kept, regardless of the other limits. Defaults to 0.
+	// +kubebuilder:validation:Minimum=0
+	// +operator-sdk:csv:customresourcedefinitions:type=spec
+	// +optional
+	MinUntainted *int32 `json:"minUntainted,omitempty"`
+}
+
+// GetLimit returns the maximum number of revisions to keep
+func (rhp *RevisionHistoryPolicy) GetLimit() int {
+	if rhp == nil || rhp.Limit == nil {
+		return DefaultRevisionHistoryLimit
+	}
+	return int(*rhp.Limit)
+}
+
+// GetMaxAge returns the maximum age of the revisions to keep. A zero
+// value means that revisions are not deleted due to its age.
+func (rhp *RevisionHistoryPolicy) GetMaxAge() time.Duration {
+	if rhp == nil || rhp.MaxAge == nil {
+		return 0
+	}
+	return rhp.MaxAge.Duration
+}
+
+// GetMinUntainted returns the number of most recent untainted revisions
+// that are always kept
+func (rhp *RevisionHistoryPolicy) GetMinUntainted() int {
+	if rhp == nil || rhp.MinUntainted == nil {
+		return 0
+	}
+	return int(*rhp.MinUntainted)
+}
+
+// RolloutPolicy defines how new revisions are rolled out to the proxies that share a nodeID
+type RolloutPolicy struct {
+	// Canaries is the number of connected proxies that receive a new revision first, or
+	// the percentage of them if a percentage is given (e.g. "10%"). Percentages are rounded
+	// up. At least one proxy is always selected. Defaults to 1.
+	// +operator-sdk:csv:customresourcedefinitions:type=spec
+	// +optional
+	Canaries *intstr.IntOrString `json:"canaries,omitempty"`
+	// BakeTime is the period of time that the canaries must run a new revision after
+	// acknowledging it before it is published to the rest of the proxies. Defaults to 0.
+	// +operator-sdk:csv:customresourcedefinitions:type=spec
+	// +optional
+	BakeTime *metav1.Duration `json:"bakeTime,omitempty"`
+}
+
+// GetCanaries returns the number of proxies, out of the given
+// number of connected proxies, that are selected as canaries
+func (rp *RolloutPolicy) GetCanaries(connected int) int {
+	canaries := intstr.FromInt(1)
+	if rp != nil && rp.Canaries != nil {
+		canaries = *rp.Canaries
+	}
+	n, err := intstr.GetValueFromIntOrPercent(&canaries, connected, true)
+	if err != nil || n < 1 {
+		return 1
+	}
+	return n
+}
+
+// GetBakeTime returns the period of time that canaries must run
+// a new revision before it is published to the rest of the proxies
+func (rp *RolloutPolicy) GetBakeTime() time.Duration {
+	if rp == nil || rp.BakeTime == nil {
+		return 0
+	}
+	return rp.BakeTime.Duration
+}
+
 // EnvoyResources holds each envoy api resource type
 type EnvoyResources struct {
 	// Endpoints is a list of the envoy ClusterLoadAssignment resource type.
@@ -109,6 +256,83 @@ type EnvoyResources struct {
 	Secrets []EnvoySecretResource `json:"secrets,omitempty"`
 }
 
+// VersionFor returns the version of the given resource type, calculated from the hash
+// of the resources of that type. Each resource type is versioned independently so
+// envoy only receives the resource types that have changed between config versions.
+// The version of Secret resources is further extended with the hash of the Secret
+// contents when the snapshot is generated, as the contents are not part of the spec.
+func (er *EnvoyResources) VersionFor(rType envoy.Type) string {
+	if er == nil {
+		er = &EnvoyResources{}
+	}
+	switch rType {
+	case envoy.Endpoint:
+		return common.Hash(er.Endpoints)
+	case envoy.Cluster:
+		return common.Hash(er.Clusters)
+	case envoy.Route:
+		return common.Hash(er.Routes)
+	case envoy.Listener:
+		return common.Hash(er.Listeners)
+	case envoy.Runtime:
+		return common.Hash(er.Runtimes)
+	case envoy.Secret:
+		return common.Hash(er.Secrets)
+	}
+	return ""
+}
+
+// MatchesVersion returns true if the given version of a resource type
+// was generated from the resources of that type
+func (er *EnvoyResources) MatchesVersion(rType envoy.Type, version string) bool {
+	if rType == envoy.Secret {
+		return strings.HasPrefix(version, er.VersionFor(envoy.Secret)+"-")
+	}
+	return version == er.VersionFor(rType)
+}
+
+// Validate checks that the resources can be loaded using the given serialization and envoy
+// API version, and that they pass the validation rules of the envoy protos. The given path
+// is used to report the fields that fail the validation.
+func (er *EnvoyResources) Validate(path *field.Path, serialization envoy_serializer.Serialization, envoyAPI envoy.APIVersion) field.ErrorList {
+	if er == nil {
+		return field.ErrorList{field.Required(path, "envoy resources are required")}
+	}
+
+	decoder := envoy_serializer.NewResourceUnmarshaller(serialization, envoyAPI)
+	generator := envoy_resources.NewGenerator(envoyAPI)
+
+	errs := field.ErrorList{}
+	for _, t := range []struct {
+		rType     envoy.Type
+		name      string
+		resources []EnvoyResource
+	}{
+		{envoy.Endpoint, "endpoints", er.Endpoints},
+		{envoy.Cluster, "clusters", er.Clusters},
+		{envoy.Route, "routes", er.Routes},
+		{envoy.Listener, "listeners", er.Listeners},
+		{envoy.Runtime, "runtime", er.Runtimes},
+	} {
+		for idx, resource := range t.resources {
+			res := generator.New(t.rType)
+			if err := decoder.Unmarshal(resource.Value, res); err != nil {
+				errs = append(errs, field.Invalid(path.Child(t.name).Index(idx).Child("value"), resource.Value,
+					fmt.Sprintf("invalid envoy resource value: '%s'", err)))
+				continue
+			}
+			if v, ok := res.(interface{ Validate() error }); ok {
+				if err := v.Validate(); err != nil {
+					errs = append(errs, field.Invalid(path.Child(t.name).Index(idx).Child("value"), resource.Value,
+						fmt.Sprintf("invalid envoy resource: '%s'", err)))
+				}
+			}
+		}
+	}
+
+	return errs
+}
+
 // EnvoyResource holds serialized representation of an envoy
 // resource
 type EnvoyResource struct {
@@ -148,6 +372,11 @@ type EnvoyConfigStatus struct {
 	// the spec of the EnvoyConfig object
 	// +operator-sdk:csv:customresourcedefinitions:type=status
 	DesiredVersion string `json:"desiredVersion,omitempty"`
+	// Proxies summarizes the status of the envoy proxies that receive
+	// the published version from the discovery service
+	// +operator-sdk:csv:customresourcedefinitions:type=status
+	// +optional
+	Proxies *ProxiesStatus `json:"proxies,omitempty"`
 	// Conditions represent the latest available observations of an object's state
 	// +operator-sdk:csv:customresourcedefinitions:type=status
 	Conditions status.Conditions `json:"conditions,omitempty"`
@@ -181,6 +410,9 @@ type ConfigRevisionRef struct {
 // +kubebuilder:printcolumn:JSONPath=".status.desiredVersion",name=Desired Version,type=string
 // +kubebuilder:printcolumn:JSONPath=".status.publishedVersion",name=Published Version,type=string
 // +kubebuilder:printcolumn:JSONPath=".status.cacheState",name=Cache State,type=string
+// +kubebuilder:printcolumn:JSONPath=".spec.pinnedVersion",name=Pinned Version,type=string,priority=1
+// +kubebuilder:printcolumn:JSONPath=".status.proxies.synced",name=Synced Proxies,type=integer,priority=1
+// +kubebuilder:printcolumn:JSONPath=".status.proxies.connected",name=Connected Proxies,type=integer,priority=1
 // +operator-sdk:csv:customresourcedefinitions:displayName="EnvoyConfig"
 // +operator-sdk:csv:customresourcedefinitions:resources={{EnvoyConfigRevision,v1alpha1}}
 type EnvoyConfig struct {
@@ -207,12 +439,45 @@ func (ec *EnvoyConfig) GetSerialization() envoy_serializer.Serialization {
 	return envoy_serializer.Serialization(*ec.Spec.Serialization)
 }
 
+// GetPinnedVersion returns the version that has been pinned for
+// publication, or an empty string if no version is pinned.
+func (ec *EnvoyConfig) GetPinnedVersion() string {
+	if ec.Spec.PinnedVersion == nil {
+		return ""
+	}
+	return *ec.Spec.PinnedVersion
+}
+
+// GetUntaintAfter returns the period of time after which tainted revisions are
+// automatically untainted. A zero value means that revisions are never untainted.
+func (ec *EnvoyConfig) GetUntaintAfter() time.Duration {
+	if ec.Spec.UntaintAfter == nil {
+		return 0
+	}
+	return ec.Spec.UntaintAfter.Duration
+}
+
+// GetBlockOnDanglingReferences returns true if resources with dangling
+// references should not be published.
+func (ec *EnvoyConfig) GetBlockOnDanglingReferences() bool {
+	return ec.Spec.BlockOnDanglingReferences != nil && *ec.Spec.BlockOnDanglingReferences
+}
+
 // GetEnvoyResourcesVersion returns the hash of the resources in the spec which
 // univoquely identifies the version of the resources.
 func (ec *EnvoyConfig) GetEnvoyResourcesVersion() string {
 	return common.Hash(ec.Spec.EnvoyResources)
 }
 
+// Validate checks that the envoy resources of the EnvoyConfig are valid
+func (ec *EnvoyConfig) Validate() error {
+	errs := ec.Spec.EnvoyResources.Validate(field.NewPath("spec", "envoyResources"), ec.GetSerialization(), ec.GetEnvoyAPIVersion())
+	if len(errs) > 0 {
+		return errors.NewInvalid(GroupVersion.WithKind("EnvoyConfig").GroupKind(), ec.GetName(), errs)
+	}
+	return nil
+}
+
 // +kubebuilder:object:root=true
 
 // EnvoyConfigList contains a list of EnvoyConfig
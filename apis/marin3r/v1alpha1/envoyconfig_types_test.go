@@ -18,10 +18,12 @@ package v1alpha1
 
 import (
 	"testing"
+	"time"
 
 	"github.com/3scale/marin3r/pkg/common"
 	"github.com/3scale/marin3r/pkg/envoy"
 	envoy_serializer "github.com/3scale/marin3r/pkg/envoy/serializer"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/utils/pointer"
 )
 
@@ -120,3 +122,88 @@ func TestEnvoyConfig_GetEnvoyResourcesVersion(t *testing.T) {
 		})
 	}
 }
+
+func TestEnvoyResources_VersionFor(t *testing.T) {
+	resources := &EnvoyResources{
+		Clusters: []EnvoyResource{{Name: "cluster", Value: "{\"name\": \"cluster\"}"}},
+	}
+	cases := []struct {
+		testName       string
+		resources      *EnvoyResources
+		rType          envoy.Type
+		expectedResult string
+	}{
+		{"Cluster version", resources, envoy.Cluster, common.Hash(resources.Clusters)},
+		{"Other types are not affected by clusters", resources, envoy.Listener, common.Hash([]EnvoyResource(nil))},
+		{"Nil resources", nil, envoy.Route, common.Hash([]EnvoyResource(nil))},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.testName, func(subT *testing.T) {
+			receivedResult := tc.resources.VersionFor(tc.rType)
+			if receivedResult != tc.expectedResult {
+				subT.Errorf("Expected result differs: Expected: %v, Received: %v", tc.expectedResult, receivedResult)
+			}
+		})
+	}
+}
+
+func TestEnvoyResources_MatchesVersion(t *testing.T) {
+	resources := &EnvoyResources{
+		Clusters: []EnvoyResource{{Name: "cluster", Value: "{\"name\": \"cluster\"}"}},
+	}
+	cases := []struct {
+		testName       string
+		rType          envoy.Type
+		version        string
+		expectedResult bool
+	}{
+		{"Matches", envoy.Cluster, resources.VersionFor(envoy.Cluster), true},
+		{"Does not match", envoy.Cluster, "xxxx", false},
+		{"Matches secrets", envoy.Secret, resources.VersionFor(envoy.Secret) + "-xxxx", true},
+		{"Does not match secrets", envoy.Secret, resources.VersionFor(envoy.Secret), false},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.testName, func(subT *testing.T) {
+			receivedResult := resources.MatchesVersion(tc.rType, tc.version)
+			if receivedResult != tc.expectedResult {
+				subT.Errorf("Expected result differs: Expected: %v, Received: %v", tc.expectedResult, receivedResult)
+			}
+		})
+	}
+}
+
+func TestRevisionHistoryPolicy_Getters(t *testing.T) {
+	cases := []struct {
+		testName             string
+		policy               *RevisionHistoryPolicy
+		expectedLimit        int
+		expectedMaxAge       time.Duration
+		expectedMinUntainted int
+	}{
+		{"Nil policy returns defaults", nil, DefaultRevisionHistoryLimit, 0, 0},
+		{"Empty policy returns defaults", &RevisionHistoryPolicy{}, DefaultRevisionHistoryLimit, 0, 0},
+		{"Returns the values in the policy",
+			&RevisionHistoryPolicy{
+				Limit:        pointer.Int32Ptr(50),
+				MaxAge:       &metav1.Duration{Duration: time.Hour},
+				MinUntainted: pointer.Int32Ptr(3),
+			},
+			50, time.Hour, 3},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.testName, func(subT *testing.T) {
+			if limit := tc.policy.GetLimit(); limit != tc.expectedLimit {
+				subT.Errorf("Expected limit differs: Expected: %v, Received: %v", tc.expectedLimit, limit)
+			}
+			if maxAge := tc.policy.GetMaxAge(); maxAge != tc.expectedMaxAge {
+				subT.Errorf("Expected maxAge differs: Expected: %v, Received: %v", tc.expectedMaxAge, maxAge)
+			}
+			if minUntainted := tc.policy.GetMinUntainted(); minUntainted != tc.expectedMinUntainted {
+				subT.Errorf("Expected minUntainted differs: Expected: %v, Received: %v", tc.expectedMinUntainted, minUntainted)
+			}
+		})
+	}
+}
@@ -21,7 +21,9 @@ import (
 	envoy_serializer "github.com/3scale/marin3r/pkg/envoy/serializer"
 	"github.com/operator-framework/operator-lib/status"
 
+	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
 )
 
 const (
@@ -39,6 +41,17 @@ const (
 	// problems have been observed with this revision and should not be published
 	RevisionTaintedCondition status.ConditionType = "RevisionTainted"
 
+	/* Annotations */
+
+	// RevisionUntaintAnnotation is an annotation that can be added to a tainted
+	// EnvoyConfigRevision to clear its taint so it can be published again. The
+	// annotation is removed once the taint has been cleared.
+	RevisionUntaintAnnotation string = "marin3r.3scale.net/untaint"
+	// RevisionReportedNACKAnnotation is an annotation that discovery service replicas
+	// that are not the leader add to an EnvoyConfigRevision rejected by a gateway. It
+	// holds the error message and the leader removes it once it taints the revision.
+	RevisionReportedNACKAnnotation string = "marin3r.3scale.net/reported-nack"
+
 	/* Finalizers */
 
 	// EnvoyConfigRevisionFinalizer is the finalizer for EnvoyConfig objects
@@ -66,6 +79,19 @@ type EnvoyConfigRevisionSpec struct {
 	// +operator-sdk:csv:customresourcedefinitions:type=spec
 	// +optional
 	Serialization *string `json:"serialization,omitempty"`
+	// BlockOnDanglingReferences, when set to true, prevents the publication of resources
+	// that reference other resources that cannot be found, like a listener pointing to an
+	// RDS route configuration or a route pointing to a cluster that don't exist. Dangling
+	// references are always reported in the status, even if this is unset.
+	// +operator-sdk:csv:customresourcedefinitions:type=spec
+	// +optional
+	BlockOnDanglingReferences *bool `json:"blockOnDanglingReferences,omitempty"`
+	// Rollout enables the progressive rollout of the revision to the proxies that share the
+	// nodeID. The revision is published to all the proxies at once if unset, or if the discovery
+	// service runs several replicas.
+	// +operator-sdk:csv:customresourcedefinitions:type=spec
+	// +optional
+	Rollout *RolloutPolicy `json:"rollout,omitempty"`
 	// EnvoyResources holds the different types of resources suported by the envoy discovery service
 	// +operator-sdk:csv:customresourcedefinitions:type=spec
 	EnvoyResources *EnvoyResources `json:"envoyResources"`
@@ -88,11 +114,105 @@ type EnvoyConfigRevisionStatus struct {
 	// +operator-sdk:csv:customresourcedefinitions:type=status
 	// +optional
 	Tainted *bool `json:"tainted,omitempty"`
+	// Proxies summarizes the status of the envoy proxies that receive
+	// the resources of this revision from the discovery service. Only
+	// populated while the revision is published.
+	// +operator-sdk:csv:customresourcedefinitions:type=status
+	// +optional
+	Proxies *ProxiesStatus `json:"proxies,omitempty"`
+	// Rollout holds the progress of the rollout of the revision to the proxies. Only
+	// populated while the revision is published and spec.rollout is set.
+	// +operator-sdk:csv:customresourcedefinitions:type=status
+	// +optional
+	Rollout *RolloutStatus `json:"rollout,omitempty"`
 	// Conditions represent the latest available observations of an object's state
 	// +operator-sdk:csv:customresourcedefinitions:type=status
 	Conditions status.Conditions `json:"conditions"`
 }
 
+// ProxiesStatus summarizes the status of the envoy proxies
+// connected to the discovery service for a given nodeID
+type ProxiesStatus struct {
+	// Connected is the number of envoy proxies with open streams
+	// against the discovery service
+	// +operator-sdk:csv:customresourcedefinitions:type=status
+	Connected int32 `json:"connected"`
+	// Synced is the number of connected envoy proxies that have
+	// acknowledged the published resources
+	// +operator-sdk:csv:customresourcedefinitions:type=status
+	Synced int32 `json:"synced"`
+	// Failing is the number of connected envoy proxies that have
+	// rejected the published resources
+	// +operator-sdk:csv:customresourcedefinitions:type=status
+	Failing int32 `json:"failing"`
+	// Details holds the status of each of the connected envoy proxies
+	// +operator-sdk:csv:customresourcedefinitions:type=status
+	// +optional
+	Details []ProxyStatus `json:"details,omitempty"`
+}
+
+// ProxyStatus holds the status of a single envoy proxy
+type ProxyStatus struct {
+	// Address is the peer address of the envoy proxy
+	// +operator-sdk:csv:customresourcedefinitions:type=status
+	Address string `json:"address"`
+	// Streams is the number of streams the envoy proxy
+	// has open against the discovery service
+	// +operator-sdk:csv:customresourcedefinitions:type=status
+	Streams int32 `json:"streams"`
+	// Synced is true if the envoy proxy has acknowledged
+	// the published resources
+	// +operator-sdk:csv:customresourcedefinitions:type=status
+	Synced bool `json:"synced"`
+	// AckedVersions is the last version acknowledged by the envoy
+	// proxy for each resource type
+	// +operator-sdk:csv:customresourcedefinitions:type=status
+	// +optional
+	AckedVersions map[string]string `json:"ackedVersions,omitempty"`
+	// LastNackedVersion is the last version rejected by the envoy proxy
+	// +operator-sdk:csv:customresourcedefinitions:type=status
+	// +optional
+	LastNackedVersion string `json:"lastNackedVersion,omitempty"`
+	// LastNackMessage is the error message the envoy proxy
+	// returned when rejecting LastNackedVersion
+	// +operator-sdk:csv:customresourcedefinitions:type=status
+	// +optional
+	LastNackMessage string `json:"lastNackMessage,omitempty"`
+}
+
+// RolloutPhase is the phase of the rollout of a revision
+type RolloutPhase string
+
+const (
+	// RolloutCanaryPhase means that the revision is only published to the canaries
+	RolloutCanaryPhase RolloutPhase = "Canary"
+	// RolloutCompletedPhase means that the revision is published to all the proxies
+	RolloutCompletedPhase RolloutPhase = "Completed"
+)
+
+// RolloutStatus holds the progress of the rollout of a revision
+type RolloutStatus struct {
+	// Phase is the phase of the rollout, either "Canary" or "Completed"
+	// +operator-sdk:csv:customresourcedefinitions:type=status
+	Phase RolloutPhase `json:"phase"`
+	// Canaries is the list of addresses of the proxies selected as canaries
+	// +operator-sdk:csv:customresourcedefinitions:type=status
+	// +optional
+	Canaries []string `json:"canaries,omitempty"`
+	// StartedAt is the time at which the revision was published to the canaries
+	// +operator-sdk:csv:customresourcedefinitions:type=status
+	// +optional
+	StartedAt *metav1.Time `json:"startedAt,omitempty"`
+	// SyncedAt is the time at which all the canaries had acknowledged the revision
+	// +operator-sdk:csv:customresourcedefinitions:type=status
+	// +optional
+	SyncedAt *metav1.Time `json:"syncedAt,omitempty"`
+	// CompletedAt is the time at which the revision was published to all the proxies
+	// +operator-sdk:csv:customresourcedefinitions:type=status
+	// +optional
+	CompletedAt *metav1.Time `json:"completedAt,omitempty"`
+}
+
 // IsPublished returns true if this revision is published, false otherwise
 func (status *EnvoyConfigRevisionStatus) IsPublished() bool {
 	if status.Published == nil {
@@ -123,6 +243,7 @@ func (status *EnvoyConfigRevisionStatus) IsTainted() bool {
 // +kubebuilder:printcolumn:JSONPath=".metadata.creationTimestamp",name="Created At",type=string,format=date-time
 // +kubebuilder:printcolumn:JSONPath=".status.lastPublishedAt",name="Last Published At",type=string,format=date-time
 // +kubebuilder:printcolumn:JSONPath=".status.tainted",name=Tainted,type=boolean
+// +kubebuilder:printcolumn:JSONPath=".status.rollout.phase",name=Rollout,type=string,priority=1
 // +operator-sdk:csv:customresourcedefinitions:displayName="EnvoyConfigRevision"
 type EnvoyConfigRevision struct {
 	metav1.TypeMeta   `json:",inline"`
@@ -148,6 +269,21 @@ func (ecr *EnvoyConfigRevision) GetSerialization() envoy_serializer.Serializatio
 	return envoy_serializer.Serialization(*ecr.Spec.Serialization)
 }
 
+// GetBlockOnDanglingReferences returns true if resources with dangling
+// references should not be published.
+func (ecr *EnvoyConfigRevision) GetBlockOnDanglingReferences() bool {
+	return ecr.Spec.BlockOnDanglingReferences != nil && *ecr.Spec.BlockOnDanglingReferences
+}
+
+// Validate checks that the envoy resources of the EnvoyConfigRevision are valid
+func (ecr *EnvoyConfigRevision) Validate() error {
+	errs := ecr.Spec.EnvoyResources.Validate(field.NewPath("spec", "envoyResources"), ecr.GetSerialization(), ecr.GetEnvoyAPIVersion())
+	if len(errs) > 0 {
+		return errors.NewInvalid(GroupVersion.WithKind("EnvoyConfigRevision").GroupKind(), ecr.GetName(), errs)
+	}
+	return nil
+}
+
 // +kubebuilder:object:root=true
 
 // EnvoyConfigRevisionList contains a list of EnvoyConfigRevision
@@ -22,8 +22,10 @@ import (
 
 	"github.com/3scale/marin3r/pkg/version"
 	"github.com/operator-framework/operator-lib/status"
+	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
 )
 
 const (
@@ -40,9 +42,31 @@ const (
 	// DiscoveryServiceLabelKey is the label key that the mutating webhook uses to determine if
 	// Pod mutation is enabled in a namespace
 	DiscoveryServiceLabelKey string = "marin3r.3scale.net/discovery-service"
-	// DiscoveryServiceCertificateHashLabelKey is the label in the discovery service Deployment that
-	// stores the hash of the current server certificate
-	DiscoveryServiceCertificateHashLabelKey string = "marin3r.3scale.net/server-certificate-hash"
+	// DiscoveryServiceTokenAudience is the audience of the ServiceAccount tokens
+	// that envoy proxies use to authenticate with the discovery service
+	DiscoveryServiceTokenAudience string = "marin3r.3scale.net"
+	// DiscoveryServiceFinalizer is the finalizer that deletes the cluster
+	// scoped resources created for a DiscoveryService
+	DiscoveryServiceFinalizer string = "finalizer.operator.marin3r.3scale.net"
+	// CARotationRequestedAtAnnotation is the annotation of a DiscoveryService that requests
+	// the rotation of its root CA. It holds a RFC3339 timestamp and a rotation starts if it is
+	// later than the NotBefore of the current root CA.
+	CARotationRequestedAtAnnotation string = "operator.marin3r.3scale.net/ca-rotation-requested-at"
+	// TrustBundleSecretKey is the key of the Secret that holds the
+	// bundle of CA certificates trusted by the discovery service
+	TrustBundleSecretKey string = "ca.crt"
+	// CACertificateReadyCondition is a condition that indicates if the
+	// root CA of the discovery service is ready
+	CACertificateReadyCondition status.ConditionType = "CACertificateReady"
+	// ServerCertificateReadyCondition is a condition that indicates if the
+	// server certificate of the discovery service is ready
+	ServerCertificateReadyCondition status.ConditionType = "ServerCertificateReady"
+	// DeploymentAvailableCondition is a condition that indicates if the
+	// Deployment of the discovery service is available
+	DeploymentAvailableCondition status.ConditionType = "DeploymentAvailable"
+	// DiscoveryServiceReadyCondition is a condition that indicates if the
+	// discovery service is ready to serve configurations to envoy proxies
+	DiscoveryServiceReadyCondition status.ConditionType = "Ready"
 
 	/* Default values */
 
@@ -52,6 +76,10 @@ const (
 	DefaultWebhookPort uint32 = 9443
 	// DefaultXdsServerPort is the default port where the discovery service xds server port listens
 	DefaultXdsServerPort uint32 = 18000
+	// DefaultPodCertificatesPort is the default port where the discovery service pod certificate signer listens
+	DefaultPodCertificatesPort uint32 = 18001
+	// DefaultPodCertificateDuration is the default duration of per-pod client certificates
+	DefaultPodCertificateDuration string = "24h"
 	// DefaultRootCertificateDuration is the default root CA certificate duration
 	DefaultRootCertificateDuration string = "26280h" // 3 years
 	// DefaultRootCertificateSecretNamePrefix is the default prefix for the Secret
@@ -76,6 +104,8 @@ const (
 	LoadBalancerType ServiceType = "LoadBalancer"
 	// HeadlessType represents a headless Service
 	HeadlessType ServiceType = "Headless"
+	// NodePortType represents a NodePort Service
+	NodePortType ServiceType = "NodePort"
 )
 
 // DiscoveryServiceSpec defines the desired state of DiscoveryService
@@ -112,6 +142,208 @@ type DiscoveryServiceSpec struct {
 	// +operator-sdk:csv:customresourcedefinitions:type=spec
 	// +optional
 	ServiceConfig *ServiceConfig `json:"ServiceConfig,omitempty"`
+	// DeltaXds enables the incremental (delta) variant of the xDS protocol, so envoy
+	// proxies only receive the resources that change between config versions. The
+	// bootstrap configs generated for this DiscoveryService are configured to use it.
+	// Only supported for envoy API v3. Defaults to false.
+	// +operator-sdk:csv:customresourcedefinitions:type=spec
+	// +optional
+	DeltaXds *bool `json:"deltaXds,omitempty"`
+	// NodeHash configures how envoy nodes are mapped to the nodeID that EnvoyConfigs
+	// are matched against. Defaults to the id of the node.
+	// +operator-sdk:csv:customresourcedefinitions:type=spec
+	// +optional
+	NodeHash *NodeHashConfig `json:"nodeHash,omitempty"`
+	// ServiceAccountTokenAuth allows envoy proxies to authenticate with the xDS server using
+	// a projected ServiceAccount token instead of a client certificate. Tokens are verified
+	// with the TokenReview API, so the operator creates a ClusterRole and ClusterRoleBinding
+	// that allow the discovery service to create TokenReviews, which requires the operator
+	// to run with cluster scope. Defaults to false.
+	// +operator-sdk:csv:customresourcedefinitions:type=spec
+	// +optional
+	ServiceAccountTokenAuth *bool `json:"serviceAccountTokenAuth,omitempty"`
+	// AllowUnrestrictedClients allows envoy proxies whose client certificate was not issued
+	// by an EnvoyBootstrap, or was issued by one without allowedNodeIDs, to fetch the config of
+	// any nodeID. By default those issued by an EnvoyBootstrap without allowedNodeIDs can only
+	// fetch the nodeIDs of its namespace and the rest are denied. Defaults to false.
+	// +operator-sdk:csv:customresourcedefinitions:type=spec
+	// +optional
+	AllowUnrestrictedClients *bool `json:"allowUnrestrictedClients,omitempty"`
+	// PodCertificates enables the issuing of short-lived per-pod client certificates to the
+	// envoy proxies, so each proxy has its own identity instead of sharing the per-namespace
+	// client certificate. Proxies request their certificate authenticating with a ServiceAccount
+	// token, so the operator grants the same cluster scoped permissions than for ServiceAccountTokenAuth.
+	// +operator-sdk:csv:customresourcedefinitions:type=spec
+	// +optional
+	PodCertificates *PodCertificatesConfig `json:"podCertificates,omitempty"`
+	// Replicas is the number of discovery service replicas. All replicas serve envoy proxies
+	// from the published EnvoyConfigRevisions but, when there is more than one, only the
+	// replica elected as leader writes the status of the marin3r resources. Defaults to 1.
+	// +kubebuilder:validation:Minimum=1
+	// +operator-sdk:csv:customresourcedefinitions:type=spec
+	// +optional
+	Replicas *int32 `json:"replicas,omitempty"`
+	// PodDisruptionBudget configures a PodDisruptionBudget for the discovery service
+	// Pods. No PodDisruptionBudget is created when not set.
+	// +operator-sdk:csv:customresourcedefinitions:type=spec
+	// +optional
+	PodDisruptionBudget *PodDisruptionBudgetSpec `json:"podDisruptionBudget,omitempty"`
+	// Affinity holds the scheduling constraints of the discovery service Pods. When
+	// not set and there is more than one replica, it defaults to a preferred pod
+	// anti-affinity that spreads the replicas across nodes.
+	// +operator-sdk:csv:customresourcedefinitions:type=spec
+	// +optional
+	Affinity *corev1.Affinity `json:"affinity,omitempty"`
+	// NamespaceSelector makes the discovery service serve the EnvoyConfigs of all the
+	// namespaces whose labels match the selector, in addition to its own namespace,
+	// instead of only the latter. The operator creates a ClusterRole and ClusterRoleBinding
+	// for the discovery service, so it requires the operator to run with cluster scope.
+	// An empty selector matches all namespaces.
+	// +operator-sdk:csv:customresourcedefinitions:type=spec
+	// +optional
+	NamespaceSelector *metav1.LabelSelector `json:"namespaceSelector,omitempty"`
+	// PodTemplate holds options that are merged into the Pod template of the discovery
+	// service Deployment, like scheduling constraints or security settings.
+	// +operator-sdk:csv:customresourcedefinitions:type=spec
+	// +optional
+	PodTemplate *PodTemplateConfig `json:"podTemplate,omitempty"`
+	// DeploymentStrategy is the strategy used to replace the discovery service Pods.
+	// Defaults to a rolling update with 25% max unavailable and 25% max surge.
+	// +operator-sdk:csv:customresourcedefinitions:type=spec
+	// +optional
+	DeploymentStrategy *appsv1.DeploymentStrategy `json:"deploymentStrategy,omitempty"`
+}
+
+// PodTemplateConfig has options that are merged into the Pod
+// template of the discovery service Deployment
+type PodTemplateConfig struct {
+	// Labels are added to the labels of the discovery service Pods. They
+	// cannot override the labels the operator uses to select the Pods.
+	// +operator-sdk:csv:customresourcedefinitions:type=spec
+	// +optional
+	Labels map[string]string `json:"labels,omitempty"`
+	// Annotations are added to the annotations of the discovery service Pods
+	// +operator-sdk:csv:customresourcedefinitions:type=spec
+	// +optional
+	Annotations map[string]string `json:"annotations,omitempty"`
+	// NodeSelector constrains the nodes the discovery service Pods can run on
+	// +operator-sdk:csv:customresourcedefinitions:type=spec
+	// +optional
+	NodeSelector map[string]string `json:"nodeSelector,omitempty"`
+	// Tolerations allow the discovery service Pods to run on tainted nodes
+	// +operator-sdk:csv:customresourcedefinitions:type=spec
+	// +optional
+	Tolerations []corev1.Toleration `json:"tolerations,omitempty"`
+	// PriorityClassName is the PriorityClass of the discovery service Pods
+	// +operator-sdk:csv:customresourcedefinitions:type=spec
+	// +optional
+	PriorityClassName string `json:"priorityClassName,omitempty"`
+	// SecurityContext is the security context of the discovery service Pods
+	// +operator-sdk:csv:customresourcedefinitions:type=spec
+	// +optional
+	SecurityContext *corev1.PodSecurityContext `json:"securityContext,omitempty"`
+	// ContainerSecurityContext is the security context of the discovery service container
+	// +operator-sdk:csv:customresourcedefinitions:type=spec
+	// +optional
+	ContainerSecurityContext *corev1.SecurityContext `json:"containerSecurityContext,omitempty"`
+	// ImagePullSecrets is the list of Secrets used to pull the discovery service image
+	// +operator-sdk:csv:customresourcedefinitions:type=spec
+	// +optional
+	ImagePullSecrets []corev1.LocalObjectReference `json:"imagePullSecrets,omitempty"`
+}
+
+// PodDisruptionBudgetSpec has options to configure the
+// PodDisruptionBudget of the discovery service Pods
+type PodDisruptionBudgetSpec struct {
+	// MinAvailable is the number of Pods that must remain available, either an absolute
+	// number or a percentage. It cannot be set together with MaxUnavailable.
+	// +operator-sdk:csv:customresourcedefinitions:type=spec
+	// +optional
+	MinAvailable *intstr.IntOrString `json:"minAvailable,omitempty"`
+	// MaxUnavailable is the number of Pods that can be unavailable, either an absolute
+	// number or a percentage. It cannot be set together with MinAvailable. Defaults
+	// to 1 when neither MinAvailable nor MaxUnavailable are set.
+	// +operator-sdk:csv:customresourcedefinitions:type=spec
+	// +optional
+	MaxUnavailable *intstr.IntOrString `json:"maxUnavailable,omitempty"`
+}
+
+// Validate checks that at most one of MinAvailable and MaxUnavailable is set
+func (pdb *PodDisruptionBudgetSpec) Validate() error {
+	if pdb.MinAvailable != nil && pdb.MaxUnavailable != nil {
+		return fmt.Errorf("minAvailable and maxUnavailable cannot be both set")
+	}
+	return nil
+}
+
+// PodCertificatesConfig has options to configure
+// the issuing of per-pod client certificates
+type PodCertificatesConfig struct {
+	// Duration is the lifetime of the per-pod client certificates. They
+	// are renewed after two thirds of their lifetime. Defaults to 24h.
+	// +operator-sdk:csv:customresourcedefinitions:type=spec
+	// +optional
+	Duration *metav1.Duration `json:"duration,omitempty"`
+	// Port is the port where the pod certificate signer listens. Defaults to 18001.
+	// +operator-sdk:csv:customresourcedefinitions:type=spec
+	// +optional
+	Port *uint32 `json:"port,omitempty"`
+}
+
+// NodeHashStrategy is an enum with the available ways of computing the nodeID of an envoy node
+type NodeHashStrategy string
+
+const (
+	// NodeIDHashStrategy uses the id of the node, set with the --service-node flag
+	NodeIDHashStrategy NodeHashStrategy = "NodeID"
+	// NodeClusterHashStrategy uses the cluster of the node, set with the --service-cluster flag
+	NodeClusterHashStrategy NodeHashStrategy = "NodeCluster"
+	// NodeMetadataHashStrategy uses the value of a key of the node metadata
+	NodeMetadataHashStrategy NodeHashStrategy = "NodeMetadata"
+	// CompositeHashStrategy combines the values of other strategies
+	CompositeHashStrategy NodeHashStrategy = "Composite"
+)
+
+// NodeHashConfig defines how the nodeID of an envoy node is computed
+type NodeHashConfig struct {
+	// Strategy is the way the nodeID of an envoy node is computed
+	// +kubebuilder:validation:Enum=NodeID;NodeCluster;NodeMetadata;Composite
+	Strategy NodeHashStrategy `json:"strategy"`
+	// MetadataKey is the key of the node metadata used by the NodeMetadata strategy
+	// +optional
+	MetadataKey string `json:"metadataKey,omitempty"`
+	// Composite is the list of strategies whose values are combined, in order and
+	// separated by "_", to compute the nodeID when the Composite strategy is used
+	// +optional
+	Composite []NodeHashStrategy `json:"composite,omitempty"`
+}
+
+// Strategies returns the list of non composite strategies used to compute the nodeID
+func (nhc *NodeHashConfig) Strategies() []NodeHashStrategy {
+	if nhc.Strategy == CompositeHashStrategy {
+		return nhc.Composite
+	}
+	return []NodeHashStrategy{nhc.Strategy}
+}
+
+// Validate checks that the NodeHashConfig holds all the
+// required configuration for the selected strategies
+func (nhc *NodeHashConfig) Validate() error {
+	if nhc.Strategy == CompositeHashStrategy && len(nhc.Composite) == 0 {
+		return fmt.Errorf("at least one strategy is required for the Composite strategy")
+	}
+	for _, strategy := range nhc.Strategies() {
+		switch strategy {
+		case NodeIDHashStrategy, NodeClusterHashStrategy:
+		case NodeMetadataHashStrategy:
+			if nhc.MetadataKey == "" {
+				return fmt.Errorf("metadataKey is required for the NodeMetadata strategy")
+			}
+		default:
+			return fmt.Errorf("strategy %q is not valid", strategy)
+		}
+	}
+	return nil
 }
 
 // DiscoveryServiceStatus defines the observed state of DiscoveryService
@@ -119,6 +351,84 @@ type DiscoveryServiceStatus struct {
 	// Conditions represent the latest available observations of an object's state
 	// +operator-sdk:csv:customresourcedefinitions:type=status
 	Conditions status.Conditions `json:"conditions"`
+	// CARotation holds the status of the last rotation of the root CA
+	// +operator-sdk:csv:customresourcedefinitions:type=status
+	// +optional
+	CARotation *CARotationStatus `json:"caRotation,omitempty"`
+	// Ready is true when the discovery service is available to serve
+	// configurations to envoy proxies
+	// +operator-sdk:csv:customresourcedefinitions:type=status
+	// +optional
+	Ready *bool `json:"ready,omitempty"`
+	// XdsEndpoint is the address and port envoy proxies use to connect
+	// to the discovery service
+	// +operator-sdk:csv:customresourcedefinitions:type=status
+	// +optional
+	XdsEndpoint *XdsEndpointStatus `json:"xdsEndpoint,omitempty"`
+	// Replicas is the number of discovery service Pods
+	// +operator-sdk:csv:customresourcedefinitions:type=status
+	// +optional
+	Replicas int32 `json:"replicas,omitempty"`
+	// AvailableReplicas is the number of discovery service Pods that are available
+	// +operator-sdk:csv:customresourcedefinitions:type=status
+	// +optional
+	AvailableReplicas int32 `json:"availableReplicas,omitempty"`
+	// ConnectedProxies is the number of envoy proxies connected to the discovery
+	// service, as reported in the status of the EnvoyConfigs it serves
+	// +operator-sdk:csv:customresourcedefinitions:type=status
+	// +optional
+	ConnectedProxies int32 `json:"connectedProxies,omitempty"`
+}
+
+// IsReady returns true if the discovery service is ready
+func (status *DiscoveryServiceStatus) IsReady() bool {
+	if status.Ready == nil {
+		return false
+	}
+	return *status.Ready
+}
+
+// XdsEndpointStatus is the address and port of the xDS server
+type XdsEndpointStatus struct {
+	// Address is the DNS name of the Service of the xDS server
+	// +operator-sdk:csv:customresourcedefinitions:type=status
+	Address string `json:"address"`
+	// Port is the port of the Service of the xDS server
+	// +operator-sdk:csv:customresourcedefinitions:type=status
+	Port uint32 `json:"port"`
+}
+
+// CARotationPhase is a phase of the rotation of the root CA
+type CARotationPhase string
+
+const (
+	// CARotationStagingPhase is the phase where the new root CA is issued
+	CARotationStagingPhase CARotationPhase = "Staging"
+	// CARotationDistributingPhase is the phase where both the current and the new root
+	// CAs are trusted, waiting for the trust bundle to reach all its consumers
+	CARotationDistributingPhase CARotationPhase = "Distributing"
+	// CARotationReissuingPhase is the phase where the new root CA is in use and the
+	// certificates issued by the previous one are being reissued. The previous
+	// root CA is trusted until all certificates have been reissued.
+	CARotationReissuingPhase CARotationPhase = "Reissuing"
+	// CARotationCompletedPhase is the phase where only the new root CA is trusted
+	CARotationCompletedPhase CARotationPhase = "Completed"
+)
+
+// CARotationStatus is the status of the rotation of the root CA
+type CARotationStatus struct {
+	// Phase is the current phase of the rotation
+	// +operator-sdk:csv:customresourcedefinitions:type=status
+	Phase CARotationPhase `json:"phase"`
+	// LastTransitionTime is the time of the last phase change
+	// +operator-sdk:csv:customresourcedefinitions:type=status
+	LastTransitionTime metav1.Time `json:"lastTransitionTime"`
+}
+
+// TrustBundleSecretName returns the name of the Secret that holds the bundle
+// of CA certificates trusted during the rotation of the CA in the given Secret
+func TrustBundleSecretName(caSecretName string) string {
+	return fmt.Sprintf("%s-bundle", caSecretName)
 }
 
 // PKIConfig has configuration for the PKI that marin3r manages for the
@@ -146,6 +456,44 @@ type ServiceConfig struct {
 	Name string `json:"name,omitempty"`
 	// +operator-sdk:csv:customresourcedefinitions:type=spec
 	Type ServiceType `json:"type,omitempty"`
+	// Annotations are added to the Service, for example to
+	// configure the load balancer of a cloud provider
+	// +operator-sdk:csv:customresourcedefinitions:type=spec
+	// +optional
+	Annotations map[string]string `json:"annotations,omitempty"`
+	// LoadBalancerSourceRanges restricts the client IPs that can reach
+	// the Service. Only used by LoadBalancer Services.
+	// +operator-sdk:csv:customresourcedefinitions:type=spec
+	// +optional
+	LoadBalancerSourceRanges []string `json:"loadBalancerSourceRanges,omitempty"`
+	// ExternalTrafficPolicy sets the externalTrafficPolicy of the Service.
+	// Only used by NodePort and LoadBalancer Services.
+	// +operator-sdk:csv:customresourcedefinitions:type=spec
+	// +optional
+	ExternalTrafficPolicy corev1.ServiceExternalTrafficPolicyType `json:"externalTrafficPolicy,omitempty"`
+	// NodePort is the node port of the xDS server. It is allocated by
+	// kubernetes if unset. Only used by NodePort and LoadBalancer Services.
+	// +operator-sdk:csv:customresourcedefinitions:type=spec
+	// +optional
+	NodePort *int32 `json:"nodePort,omitempty"`
+	// HealthPort is a plaintext port where the discovery service serves its
+	// liveness (/healthz) and readiness (/readyz) endpoints. The Deployment gets
+	// probes against it and the Service exposes it. Disabled if unset.
+	// +operator-sdk:csv:customresourcedefinitions:type=spec
+	// +optional
+	HealthPort *uint32 `json:"healthPort,omitempty"`
+	// ExternalAddress is a host name or IP where envoy proxies outside of the cluster
+	// reach the discovery service. When set, it is added to the server certificate and
+	// used by the EnvoyBootstrap configs in place of the address of the Service.
+	// +operator-sdk:csv:customresourcedefinitions:type=spec
+	// +optional
+	ExternalAddress string `json:"externalAddress,omitempty"`
+	// ExternalPort is the port where envoy proxies reach the discovery service at the
+	// ExternalAddress. Defaults to NodePort for NodePort Services and to the xDS server
+	// port otherwise.
+	// +operator-sdk:csv:customresourcedefinitions:type=spec
+	// +optional
+	ExternalPort *uint32 `json:"externalPort,omitempty"`
 }
 
 // +kubebuilder:object:root=true
@@ -154,6 +502,12 @@ type ServiceConfig struct {
 // only one DiscoveryService per cluster is supported.
 // +kubebuilder:subresource:status
 // +kubebuilder:resource:path=discoveryservices,scope=Namespaced
+// +kubebuilder:printcolumn:JSONPath=".status.ready",name="Ready",type=boolean
+// +kubebuilder:printcolumn:JSONPath=".status.xdsEndpoint.address",name=Address,type=string
+// +kubebuilder:printcolumn:JSONPath=".status.xdsEndpoint.port",name=Port,type=integer
+// +kubebuilder:printcolumn:JSONPath=".status.availableReplicas",name=Available,type=integer
+// +kubebuilder:printcolumn:JSONPath=".status.connectedProxies",name=Proxies,type=integer
+// +kubebuilder:printcolumn:JSONPath=".metadata.creationTimestamp",name=Age,type=date
 // +operator-sdk:csv:customresourcedefinitions:displayName="DiscoveryService"
 // +operator-sdk:csv:customresourcedefinitions.resources={{Deployment,v1},{Service,v1},{DiscoveryServiceCertificate,v1alpha1}
 type DiscoveryService struct {
@@ -193,6 +547,178 @@ func (d *DiscoveryService) Debug() bool {
 	return *d.Spec.Debug
 }
 
+// DeltaXds returns a boolean value that indicates if the
+// incremental variant of the xDS protocol is enabled
+func (d *DiscoveryService) DeltaXds() bool {
+	if d.Spec.DeltaXds == nil {
+		return false
+	}
+	return *d.Spec.DeltaXds
+}
+
+// PodCertificatesEnabled returns a boolean value that indicates if
+// per-pod client certificates are issued to envoy proxies
+func (d *DiscoveryService) PodCertificatesEnabled() bool {
+	return d.Spec.PodCertificates != nil
+}
+
+// GetPodCertificatesPort returns the port the pod certificate signer will listen at
+func (d *DiscoveryService) GetPodCertificatesPort() uint32 {
+	if d.Spec.PodCertificates != nil && d.Spec.PodCertificates.Port != nil {
+		return *d.Spec.PodCertificates.Port
+	}
+	return DefaultPodCertificatesPort
+}
+
+// GetPodCertificateDuration returns the lifetime of the per-pod client certificates
+func (d *DiscoveryService) GetPodCertificateDuration() time.Duration {
+	if d.Spec.PodCertificates != nil && d.Spec.PodCertificates.Duration != nil {
+		return d.Spec.PodCertificates.Duration.Duration
+	}
+	duration, _ := time.ParseDuration(DefaultPodCertificateDuration)
+	return duration
+}
+
+// ServiceAccountTokenAuth returns a boolean value that indicates if
+// envoy proxies can authenticate using ServiceAccount tokens
+func (d *DiscoveryService) ServiceAccountTokenAuth() bool {
+	if d.Spec.ServiceAccountTokenAuth == nil {
+		return false
+	}
+	return *d.Spec.ServiceAccountTokenAuth
+}
+
+// NeedsClusterRole returns a boolean value that indicates if the discovery service
+// requires cluster scoped permissions, granted by a ClusterRole and ClusterRoleBinding
+func (d *DiscoveryService) NeedsClusterRole() bool {
+	return d.ClusterScoped() || d.ServiceAccountTokenAuth() || d.PodCertificatesEnabled()
+}
+
+// AllowUnrestrictedClients returns a boolean value that indicates if clients
+// not restricted by an EnvoyBootstrap policy can fetch any nodeID
+func (d *DiscoveryService) AllowUnrestrictedClients() bool {
+	if d.Spec.AllowUnrestrictedClients == nil {
+		return false
+	}
+	return *d.Spec.AllowUnrestrictedClients
+}
+
+// GetNodeHash returns the configuration used to compute the nodeID of envoy nodes
+func (d *DiscoveryService) GetNodeHash() *NodeHashConfig {
+	if d.Spec.NodeHash == nil {
+		return &NodeHashConfig{Strategy: NodeIDHashStrategy}
+	}
+	return d.Spec.NodeHash
+}
+
+// ClusterScoped returns a boolean value that indicates if the discovery
+// service serves other namespaces than its own
+func (d *DiscoveryService) ClusterScoped() bool {
+	return d.Spec.NamespaceSelector != nil
+}
+
+// GetNamespaceSelector returns the namespaceSelector in the format of the label
+// selectors of the API, or an error if the namespaceSelector is not valid
+func (d *DiscoveryService) GetNamespaceSelector() (string, error) {
+	selector, err := metav1.LabelSelectorAsSelector(d.Spec.NamespaceSelector)
+	if err != nil {
+		return "", err
+	}
+	return selector.String(), nil
+}
+
+// GetReplicas returns the number of discovery service replicas
+func (d *DiscoveryService) GetReplicas() int32 {
+	if d.Spec.Replicas == nil {
+		return 1
+	}
+	return *d.Spec.Replicas
+}
+
+// LeaderElection returns a boolean value that indicates if the discovery service
+// replicas need to elect a leader to write the status of the marin3r resources
+func (d *DiscoveryService) LeaderElection() bool {
+	return d.GetReplicas() > 1
+}
+
+// GetAffinity returns the scheduling constraints of the discovery service Pods
+func (d *DiscoveryService) GetAffinity(labels map[string]string) *corev1.Affinity {
+	if d.Spec.Affinity != nil {
+		return d.Spec.Affinity
+	}
+	if d.GetReplicas() > 1 {
+		return d.defaultAffinity(labels)
+	}
+	return nil
+}
+
+func (d *DiscoveryService) defaultAffinity(labels map[string]string) *corev1.Affinity {
+	return &corev1.Affinity{
+		PodAntiAffinity: &corev1.PodAntiAffinity{
+			PreferredDuringSchedulingIgnoredDuringExecution: []corev1.WeightedPodAffinityTerm{{
+				Weight: 100,
+				PodAffinityTerm: corev1.PodAffinityTerm{
+					LabelSelector: &metav1.LabelSelector{MatchLabels: labels},
+					TopologyKey:   corev1.LabelHostname,
+				},
+			}},
+		},
+	}
+}
+
+// GetPodTemplate returns the options to merge into the Pod template of the discovery service Deployment
+func (d *DiscoveryService) GetPodTemplate() *PodTemplateConfig {
+	if d.Spec.PodTemplate == nil {
+		return &PodTemplateConfig{}
+	}
+	return d.Spec.PodTemplate
+}
+
+// GetDeploymentStrategy returns the strategy of the discovery service Deployment. The fields
+// the API server would default are set, so the Deployment does not differ from the stored one.
+func (d *DiscoveryService) GetDeploymentStrategy() appsv1.DeploymentStrategy {
+	if d.Spec.DeploymentStrategy == nil {
+		return d.defaultDeploymentStrategy()
+	}
+	if d.Spec.DeploymentStrategy.Type == appsv1.RecreateDeploymentStrategyType {
+		return appsv1.DeploymentStrategy{Type: appsv1.RecreateDeploymentStrategyType}
+	}
+
+	strategy := d.defaultDeploymentStrategy()
+	if ru := d.Spec.DeploymentStrategy.RollingUpdate; ru != nil {
+		if ru.MaxUnavailable != nil {
+			strategy.RollingUpdate.MaxUnavailable = ru.MaxUnavailable
+		}
+		if ru.MaxSurge != nil {
+			strategy.RollingUpdate.MaxSurge = ru.MaxSurge
+		}
+	}
+	return strategy
+}
+
+func (d *DiscoveryService) defaultDeploymentStrategy() appsv1.DeploymentStrategy {
+	return appsv1.DeploymentStrategy{
+		Type: appsv1.RollingUpdateDeploymentStrategyType,
+		RollingUpdate: &appsv1.RollingUpdateDeployment{
+			MaxUnavailable: &intstr.IntOrString{Type: intstr.String, StrVal: "25%"},
+			MaxSurge:       &intstr.IntOrString{Type: intstr.String, StrVal: "25%"},
+		},
+	}
+}
+
+// GetPodDisruptionBudget returns the PodDisruptionBudget configuration for the discovery
+// service Pods, or nil if no PodDisruptionBudget should be created
+func (d *DiscoveryService) GetPodDisruptionBudget() *PodDisruptionBudgetSpec {
+	if d.Spec.PodDisruptionBudget == nil {
+		return nil
+	}
+	if d.Spec.PodDisruptionBudget.MinAvailable == nil && d.Spec.PodDisruptionBudget.MaxUnavailable == nil {
+		maxUnavailable := intstr.FromInt(1)
+		return &PodDisruptionBudgetSpec{MaxUnavailable: &maxUnavailable}
+	}
+	return d.Spec.PodDisruptionBudget
+}
+
 func (d *DiscoveryService) defaultDeploymentResources() corev1.ResourceRequirements {
 	return corev1.ResourceRequirements{}
 }
@@ -266,6 +792,28 @@ func (d *DiscoveryService) defaultServiceConfig() *ServiceConfig {
 	}
 }
 
+// GetXdsEndpoint returns the host and port envoy proxies use to connect to the
+// discovery service: the external address when set, or the Service otherwise
+func (d *DiscoveryService) GetXdsEndpoint() (string, uint32) {
+	svc := d.GetServiceConfig()
+	if svc.ExternalAddress == "" {
+		return fmt.Sprintf("%s.%s.svc", svc.Name, d.GetNamespace()), d.GetXdsServerPort()
+	}
+	if svc.ExternalPort != nil {
+		return svc.ExternalAddress, *svc.ExternalPort
+	}
+	if svc.Type == NodePortType && svc.NodePort != nil {
+		return svc.ExternalAddress, uint32(*svc.NodePort)
+	}
+	return svc.ExternalAddress, d.GetXdsServerPort()
+}
+
+// ClusterObjectName returns the name of the cluster scoped resources the discoveryservices
+// controller needs to create, which includes the namespace to make it unique
+func (d *DiscoveryService) ClusterObjectName() string {
+	return fmt.Sprintf("%s-%s-%s", "marin3r", d.GetNamespace(), d.GetName())
+}
+
 // OwnedObjectName returns the name of the resources the discoveryservices controller
 // needs to create
 func (d *DiscoveryService) OwnedObjectName() string {
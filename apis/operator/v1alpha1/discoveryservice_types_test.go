@@ -6,10 +6,12 @@ import (
 	"time"
 
 	"github.com/3scale/marin3r/pkg/version"
+	appsv1 "k8s.io/api/apps/v1"
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/equality"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
 	"k8s.io/utils/pointer"
 )
 
@@ -256,6 +258,62 @@ func TestDiscoveryService_GetServiceConfig(t *testing.T) {
 	}
 }
 
+func TestDiscoveryService_GetXdsEndpoint(t *testing.T) {
+	cases := []struct {
+		testName                string
+		discoveryServiceFactory func() *DiscoveryService
+		expectedHost            string
+		expectedPort            uint32
+	}{
+		{"Returns the Service address by default",
+			func() *DiscoveryService {
+				return &DiscoveryService{ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "ns"}}
+			},
+			"marin3r-test.ns.svc", DefaultXdsServerPort,
+		},
+		{"Returns the external address with the xDS server port",
+			func() *DiscoveryService {
+				return &DiscoveryService{
+					ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "ns"},
+					Spec: DiscoveryServiceSpec{
+						ServiceConfig: &ServiceConfig{Name: "svc", Type: LoadBalancerType, ExternalAddress: "xds.example.com"},
+					}}
+			},
+			"xds.example.com", DefaultXdsServerPort,
+		},
+		{"Returns the external address with the node port",
+			func() *DiscoveryService {
+				return &DiscoveryService{
+					ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "ns"},
+					Spec: DiscoveryServiceSpec{
+						ServiceConfig: &ServiceConfig{Name: "svc", Type: NodePortType, NodePort: pointer.Int32Ptr(30000), ExternalAddress: "10.0.0.1"},
+					}}
+			},
+			"10.0.0.1", 30000,
+		},
+		{"Returns the external address with the external port",
+			func() *DiscoveryService {
+				return &DiscoveryService{
+					ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "ns"},
+					Spec: DiscoveryServiceSpec{
+						ServiceConfig: &ServiceConfig{Name: "svc", Type: NodePortType, NodePort: pointer.Int32Ptr(30000),
+							ExternalAddress: "xds.example.com", ExternalPort: func() *uint32 { var u uint32 = 443; return &u }()},
+					}}
+			},
+			"xds.example.com", 443,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.testName, func(subT *testing.T) {
+			host, port := tc.discoveryServiceFactory().GetXdsEndpoint()
+			if tc.expectedHost != host || tc.expectedPort != port {
+				subT.Errorf("Expected result differs: Expected: %v:%v, Received: %v:%v", tc.expectedHost, tc.expectedPort, host, port)
+			}
+		})
+	}
+}
+
 func TestDiscoveryService_GetImage(t *testing.T) {
 	cases := []struct {
 		testName                string
@@ -323,3 +381,585 @@ func TestDiscoveryService_Debug(t *testing.T) {
 		})
 	}
 }
+
+func TestDiscoveryService_DeltaXds(t *testing.T) {
+	cases := []struct {
+		testName                string
+		discoveryServiceFactory func() *DiscoveryService
+		expectedResult          bool
+	}{
+		{"With default",
+			func() *DiscoveryService {
+				return &DiscoveryService{}
+			},
+			false,
+		},
+		{"With explicitly set value",
+			func() *DiscoveryService {
+				return &DiscoveryService{
+					Spec: DiscoveryServiceSpec{
+						DeltaXds: pointer.BoolPtr(true),
+					},
+				}
+			},
+			true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.testName, func(subT *testing.T) {
+			receivedResult := tc.discoveryServiceFactory().DeltaXds()
+			if tc.expectedResult != receivedResult {
+				subT.Errorf("Expected result differs: Expected: %v, Received: %v", tc.expectedResult, receivedResult)
+			}
+		})
+	}
+}
+
+func TestDiscoveryService_ServiceAccountTokenAuth(t *testing.T) {
+	cases := []struct {
+		testName                string
+		discoveryServiceFactory func() *DiscoveryService
+		expectedResult          bool
+	}{
+		{"With default",
+			func() *DiscoveryService {
+				return &DiscoveryService{}
+			},
+			false,
+		},
+		{"With explicitly set value",
+			func() *DiscoveryService {
+				return &DiscoveryService{
+					Spec: DiscoveryServiceSpec{
+						ServiceAccountTokenAuth: pointer.BoolPtr(true),
+					},
+				}
+			},
+			true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.testName, func(subT *testing.T) {
+			receivedResult := tc.discoveryServiceFactory().ServiceAccountTokenAuth()
+			if tc.expectedResult != receivedResult {
+				subT.Errorf("Expected result differs: Expected: %v, Received: %v", tc.expectedResult, receivedResult)
+			}
+		})
+	}
+}
+
+func TestDiscoveryService_NeedsClusterRole(t *testing.T) {
+	cases := []struct {
+		testName                string
+		discoveryServiceFactory func() *DiscoveryService
+		expectedResult          bool
+	}{
+		{"With default",
+			func() *DiscoveryService {
+				return &DiscoveryService{}
+			},
+			false,
+		},
+		{"With ServiceAccount token authentication",
+			func() *DiscoveryService {
+				return &DiscoveryService{
+					Spec: DiscoveryServiceSpec{
+						ServiceAccountTokenAuth: pointer.BoolPtr(true),
+					},
+				}
+			},
+			true,
+		},
+		{"With pod certificates",
+			func() *DiscoveryService {
+				return &DiscoveryService{
+					Spec: DiscoveryServiceSpec{
+						PodCertificates: &PodCertificatesConfig{},
+					},
+				}
+			},
+			true,
+		},
+		{"With namespaceSelector",
+			func() *DiscoveryService {
+				return &DiscoveryService{
+					Spec: DiscoveryServiceSpec{
+						NamespaceSelector: &metav1.LabelSelector{},
+					},
+				}
+			},
+			true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.testName, func(subT *testing.T) {
+			receivedResult := tc.discoveryServiceFactory().NeedsClusterRole()
+			if tc.expectedResult != receivedResult {
+				subT.Errorf("Expected result differs: Expected: %v, Received: %v", tc.expectedResult, receivedResult)
+			}
+		})
+	}
+}
+
+func TestDiscoveryService_GetPodCertificatesPort(t *testing.T) {
+	cases := []struct {
+		testName                string
+		discoveryServiceFactory func() *DiscoveryService
+		expectedResult          uint32
+	}{
+		{"With default",
+			func() *DiscoveryService {
+				return &DiscoveryService{Spec: DiscoveryServiceSpec{PodCertificates: &PodCertificatesConfig{}}}
+			},
+			DefaultPodCertificatesPort,
+		},
+		{"With explicitly set value",
+			func() *DiscoveryService {
+				return &DiscoveryService{
+					Spec: DiscoveryServiceSpec{
+						PodCertificates: &PodCertificatesConfig{Port: func() *uint32 { var u uint32 = 9000; return &u }()},
+					},
+				}
+			},
+			9000,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.testName, func(subT *testing.T) {
+			receivedResult := tc.discoveryServiceFactory().GetPodCertificatesPort()
+			if tc.expectedResult != receivedResult {
+				subT.Errorf("Expected result differs: Expected: %v, Received: %v", tc.expectedResult, receivedResult)
+			}
+		})
+	}
+}
+
+func TestDiscoveryService_GetPodCertificateDuration(t *testing.T) {
+	cases := []struct {
+		testName                string
+		discoveryServiceFactory func() *DiscoveryService
+		expectedResult          time.Duration
+	}{
+		{"With default",
+			func() *DiscoveryService {
+				return &DiscoveryService{}
+			},
+			24 * time.Hour,
+		},
+		{"With explicitly set value",
+			func() *DiscoveryService {
+				return &DiscoveryService{
+					Spec: DiscoveryServiceSpec{
+						PodCertificates: &PodCertificatesConfig{Duration: &metav1.Duration{Duration: time.Hour}},
+					},
+				}
+			},
+			time.Hour,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.testName, func(subT *testing.T) {
+			receivedResult := tc.discoveryServiceFactory().GetPodCertificateDuration()
+			if tc.expectedResult != receivedResult {
+				subT.Errorf("Expected result differs: Expected: %v, Received: %v", tc.expectedResult, receivedResult)
+			}
+		})
+	}
+}
+
+func TestDiscoveryService_GetNodeHash(t *testing.T) {
+	cases := []struct {
+		testName                string
+		discoveryServiceFactory func() *DiscoveryService
+		expectedResult          *NodeHashConfig
+	}{
+		{"With default",
+			func() *DiscoveryService {
+				return &DiscoveryService{}
+			},
+			&NodeHashConfig{Strategy: NodeIDHashStrategy},
+		},
+		{"With explicitly set value",
+			func() *DiscoveryService {
+				return &DiscoveryService{
+					Spec: DiscoveryServiceSpec{
+						NodeHash: &NodeHashConfig{Strategy: NodeClusterHashStrategy},
+					},
+				}
+			},
+			&NodeHashConfig{Strategy: NodeClusterHashStrategy},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.testName, func(subT *testing.T) {
+			receivedResult := tc.discoveryServiceFactory().GetNodeHash()
+			if !equality.Semantic.DeepEqual(tc.expectedResult, receivedResult) {
+				subT.Errorf("Expected result differs: Expected: %v, Received: %v", tc.expectedResult, receivedResult)
+			}
+		})
+	}
+}
+
+func TestDiscoveryService_GetReplicas(t *testing.T) {
+	cases := []struct {
+		testName                string
+		discoveryServiceFactory func() *DiscoveryService
+		expectedResult          int32
+	}{
+		{"With default",
+			func() *DiscoveryService {
+				return &DiscoveryService{}
+			},
+			1,
+		},
+		{"With explicitly set value",
+			func() *DiscoveryService {
+				return &DiscoveryService{
+					Spec: DiscoveryServiceSpec{
+						Replicas: pointer.Int32Ptr(3),
+					},
+				}
+			},
+			3,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.testName, func(subT *testing.T) {
+			receivedResult := tc.discoveryServiceFactory().GetReplicas()
+			if tc.expectedResult != receivedResult {
+				subT.Errorf("Expected result differs: Expected: %v, Received: %v", tc.expectedResult, receivedResult)
+			}
+		})
+	}
+}
+
+func TestDiscoveryService_ClusterScoped(t *testing.T) {
+	cases := []struct {
+		testName                string
+		discoveryServiceFactory func() *DiscoveryService
+		expectedResult          bool
+	}{
+		{"With default",
+			func() *DiscoveryService {
+				return &DiscoveryService{}
+			},
+			false,
+		},
+		{"With an empty namespace selector",
+			func() *DiscoveryService {
+				return &DiscoveryService{
+					Spec: DiscoveryServiceSpec{
+						NamespaceSelector: &metav1.LabelSelector{},
+					},
+				}
+			},
+			true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.testName, func(subT *testing.T) {
+			receivedResult := tc.discoveryServiceFactory().ClusterScoped()
+			if tc.expectedResult != receivedResult {
+				subT.Errorf("Expected result differs: Expected: %v, Received: %v", tc.expectedResult, receivedResult)
+			}
+		})
+	}
+}
+
+func TestDiscoveryService_GetNamespaceSelector(t *testing.T) {
+	cases := []struct {
+		testName                string
+		discoveryServiceFactory func() *DiscoveryService
+		expectedResult          string
+		expectedError           bool
+	}{
+		{"With an empty namespace selector",
+			func() *DiscoveryService {
+				return &DiscoveryService{
+					Spec: DiscoveryServiceSpec{
+						NamespaceSelector: &metav1.LabelSelector{},
+					},
+				}
+			},
+			"", false,
+		},
+		{"With match labels and expressions",
+			func() *DiscoveryService {
+				return &DiscoveryService{
+					Spec: DiscoveryServiceSpec{
+						NamespaceSelector: &metav1.LabelSelector{
+							MatchLabels: map[string]string{"key": "value"},
+							MatchExpressions: []metav1.LabelSelectorRequirement{{
+								Key: "env", Operator: metav1.LabelSelectorOpIn, Values: []string{"dev", "prod"},
+							}},
+						},
+					},
+				}
+			},
+			"env in (dev,prod),key=value", false,
+		},
+		{"With an invalid operator",
+			func() *DiscoveryService {
+				return &DiscoveryService{
+					Spec: DiscoveryServiceSpec{
+						NamespaceSelector: &metav1.LabelSelector{
+							MatchExpressions: []metav1.LabelSelectorRequirement{{Key: "env", Operator: "xx"}},
+						},
+					},
+				}
+			},
+			"", true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.testName, func(subT *testing.T) {
+			receivedResult, err := tc.discoveryServiceFactory().GetNamespaceSelector()
+			if (err != nil) != tc.expectedError {
+				subT.Errorf("Expected error differs: Expected: %v, Received: %v", tc.expectedError, err)
+			}
+			if tc.expectedResult != receivedResult {
+				subT.Errorf("Expected result differs: Expected: %v, Received: %v", tc.expectedResult, receivedResult)
+			}
+		})
+	}
+}
+
+func TestDiscoveryService_GetAffinity(t *testing.T) {
+	labels := map[string]string{"key": "value"}
+	cases := []struct {
+		testName                string
+		discoveryServiceFactory func() *DiscoveryService
+		expectedResult          *v1.Affinity
+	}{
+		{"With default and a single replica",
+			func() *DiscoveryService {
+				return &DiscoveryService{}
+			},
+			nil,
+		},
+		{"With default and several replicas",
+			func() *DiscoveryService {
+				return &DiscoveryService{
+					Spec: DiscoveryServiceSpec{
+						Replicas: pointer.Int32Ptr(2),
+					},
+				}
+			},
+			&v1.Affinity{
+				PodAntiAffinity: &v1.PodAntiAffinity{
+					PreferredDuringSchedulingIgnoredDuringExecution: []v1.WeightedPodAffinityTerm{{
+						Weight: 100,
+						PodAffinityTerm: v1.PodAffinityTerm{
+							LabelSelector: &metav1.LabelSelector{MatchLabels: labels},
+							TopologyKey:   "kubernetes.io/hostname",
+						},
+					}},
+				},
+			},
+		},
+		{"With explicitly set value",
+			func() *DiscoveryService {
+				return &DiscoveryService{
+					Spec: DiscoveryServiceSpec{
+						Replicas: pointer.Int32Ptr(2),
+						Affinity: &v1.Affinity{NodeAffinity: &v1.NodeAffinity{}},
+					},
+				}
+			},
+			&v1.Affinity{NodeAffinity: &v1.NodeAffinity{}},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.testName, func(subT *testing.T) {
+			receivedResult := tc.discoveryServiceFactory().GetAffinity(labels)
+			if !equality.Semantic.DeepEqual(tc.expectedResult, receivedResult) {
+				subT.Errorf("Expected result differs: Expected: %v, Received: %v", tc.expectedResult, receivedResult)
+			}
+		})
+	}
+}
+
+func TestDiscoveryService_GetPodDisruptionBudget(t *testing.T) {
+	cases := []struct {
+		testName                string
+		discoveryServiceFactory func() *DiscoveryService
+		expectedResult          *PodDisruptionBudgetSpec
+	}{
+		{"With default",
+			func() *DiscoveryService {
+				return &DiscoveryService{}
+			},
+			nil,
+		},
+		{"With an empty spec",
+			func() *DiscoveryService {
+				return &DiscoveryService{
+					Spec: DiscoveryServiceSpec{
+						PodDisruptionBudget: &PodDisruptionBudgetSpec{},
+					},
+				}
+			},
+			&PodDisruptionBudgetSpec{MaxUnavailable: intstrPtr(intstr.FromInt(1))},
+		},
+		{"With explicitly set value",
+			func() *DiscoveryService {
+				return &DiscoveryService{
+					Spec: DiscoveryServiceSpec{
+						PodDisruptionBudget: &PodDisruptionBudgetSpec{MinAvailable: intstrPtr(intstr.FromString("50%"))},
+					},
+				}
+			},
+			&PodDisruptionBudgetSpec{MinAvailable: intstrPtr(intstr.FromString("50%"))},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.testName, func(subT *testing.T) {
+			receivedResult := tc.discoveryServiceFactory().GetPodDisruptionBudget()
+			if !equality.Semantic.DeepEqual(tc.expectedResult, receivedResult) {
+				subT.Errorf("Expected result differs: Expected: %v, Received: %v", tc.expectedResult, receivedResult)
+			}
+		})
+	}
+}
+
+func TestDiscoveryService_GetDeploymentStrategy(t *testing.T) {
+	cases := []struct {
+		testName                string
+		discoveryServiceFactory func() *DiscoveryService
+		expectedResult          appsv1.DeploymentStrategy
+	}{
+		{"With default",
+			func() *DiscoveryService {
+				return &DiscoveryService{}
+			},
+			appsv1.DeploymentStrategy{
+				Type: appsv1.RollingUpdateDeploymentStrategyType,
+				RollingUpdate: &appsv1.RollingUpdateDeployment{
+					MaxUnavailable: intstrPtr(intstr.FromString("25%")),
+					MaxSurge:       intstrPtr(intstr.FromString("25%")),
+				},
+			},
+		},
+		{"With recreate strategy",
+			func() *DiscoveryService {
+				return &DiscoveryService{
+					Spec: DiscoveryServiceSpec{
+						DeploymentStrategy: &appsv1.DeploymentStrategy{Type: appsv1.RecreateDeploymentStrategyType},
+					},
+				}
+			},
+			appsv1.DeploymentStrategy{Type: appsv1.RecreateDeploymentStrategyType},
+		},
+		{"With partially set rolling update",
+			func() *DiscoveryService {
+				return &DiscoveryService{
+					Spec: DiscoveryServiceSpec{
+						DeploymentStrategy: &appsv1.DeploymentStrategy{
+							RollingUpdate: &appsv1.RollingUpdateDeployment{MaxUnavailable: intstrPtr(intstr.FromInt(0))},
+						},
+					},
+				}
+			},
+			appsv1.DeploymentStrategy{
+				Type: appsv1.RollingUpdateDeploymentStrategyType,
+				RollingUpdate: &appsv1.RollingUpdateDeployment{
+					MaxUnavailable: intstrPtr(intstr.FromInt(0)),
+					MaxSurge:       intstrPtr(intstr.FromString("25%")),
+				},
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.testName, func(subT *testing.T) {
+			receivedResult := tc.discoveryServiceFactory().GetDeploymentStrategy()
+			if !equality.Semantic.DeepEqual(tc.expectedResult, receivedResult) {
+				subT.Errorf("Expected result differs: Expected: %v, Received: %v", tc.expectedResult, receivedResult)
+			}
+		})
+	}
+}
+
+func TestPodDisruptionBudgetSpec_Validate(t *testing.T) {
+	cases := []struct {
+		testName      string
+		pdb           *PodDisruptionBudgetSpec
+		expectedError bool
+	}{
+		{"Valid minAvailable",
+			&PodDisruptionBudgetSpec{MinAvailable: intstrPtr(intstr.FromInt(1))},
+			false,
+		},
+		{"Valid maxUnavailable",
+			&PodDisruptionBudgetSpec{MaxUnavailable: intstrPtr(intstr.FromString("25%"))},
+			false,
+		},
+		{"Both minAvailable and maxUnavailable",
+			&PodDisruptionBudgetSpec{MinAvailable: intstrPtr(intstr.FromInt(1)), MaxUnavailable: intstrPtr(intstr.FromInt(1))},
+			true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.testName, func(subT *testing.T) {
+			err := tc.pdb.Validate()
+			if (err != nil) != tc.expectedError {
+				subT.Errorf("Expected error differs: Expected: %v, Received: %v", tc.expectedError, err)
+			}
+		})
+	}
+}
+
+func intstrPtr(v intstr.IntOrString) *intstr.IntOrString {
+	return &v
+}
+
+func TestNodeHashConfig_Validate(t *testing.T) {
+	cases := []struct {
+		testName      string
+		nodeHash      *NodeHashConfig
+		expectedError bool
+	}{
+		{"Valid single strategy",
+			&NodeHashConfig{Strategy: NodeClusterHashStrategy},
+			false,
+		},
+		{"Valid composite strategy",
+			&NodeHashConfig{Strategy: CompositeHashStrategy, MetadataKey: "version",
+				Composite: []NodeHashStrategy{NodeClusterHashStrategy, NodeMetadataHashStrategy}},
+			false,
+		},
+		{"NodeMetadata strategy without metadataKey",
+			&NodeHashConfig{Strategy: NodeMetadataHashStrategy},
+			true,
+		},
+		{"Composite strategy without strategies",
+			&NodeHashConfig{Strategy: CompositeHashStrategy},
+			true,
+		},
+		{"Nested composite strategy",
+			&NodeHashConfig{Strategy: CompositeHashStrategy, Composite: []NodeHashStrategy{CompositeHashStrategy}},
+			true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.testName, func(subT *testing.T) {
+			err := tc.nodeHash.Validate()
+			if (err != nil) != tc.expectedError {
+				subT.Errorf("Expected error differs: Expected: %v, Received: %v", tc.expectedError, err)
+			}
+		})
+	}
+}
@@ -17,6 +17,8 @@ limitations under the License.
 package v1alpha1
 
 import (
+	"fmt"
+
 	"github.com/operator-framework/operator-lib/status"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -34,6 +36,12 @@ const (
 	// IssuerCertificateHashLabelKey is the label that stores the hash of the certificate managed
 	// by the DiscoveryServiceCertificate resource
 	IssuerCertificateHashLabelKey string = "issuer-certificate-hash"
+	// DiscoveryServiceCertificateFinalizer is the finalizer that revokes the certificate
+	// of CA signed DiscoveryServiceCertificates when they are deleted
+	DiscoveryServiceCertificateFinalizer string = "finalizer.operator.marin3r.3scale.net"
+	// RevocationListSecretKey is the key of the Secret that holds the
+	// revocation list of the certificates issued by a CA
+	RevocationListSecretKey string = "ca.crl"
 )
 
 // DiscoveryServiceCertificateSpec defines the desired state of DiscoveryServiceCertificate
@@ -60,7 +68,7 @@ type DiscoveryServiceCertificateSpec struct {
 	// +optional
 	Hosts []string `json:"hosts,omitempty"`
 	// Signer specifies  the signer to use to create this certificate. Supported
-	// signers are CertManager and SelfSigned.
+	// signers are SelfSigned, CASigned and CertManager.
 	// +operator-sdk:csv:customresourcedefinitions:type=spec
 	Signer DiscoveryServiceCertificateSigner `json:"signer"`
 	// SecretRef is a reference to the secret that will hold the certificate
@@ -72,8 +80,91 @@ type DiscoveryServiceCertificateSpec struct {
 	// +operator-sdk:csv:customresourcedefinitions:type=spec
 	// +optional
 	CertificateRenewalConfig *CertificateRenewalConfig `json:"certificateRenewal,omitempty"`
+	// PrivateKey configures the algorithm and size of the private key of the certificate.
+	// Defaults to an ECDSA key over the P-256 curve. If set, certificates with a different
+	// key are reissued.
+	// +operator-sdk:csv:customresourcedefinitions:type=spec
+	// +optional
+	PrivateKey *PrivateKeyConfig `json:"privateKey,omitempty"`
+	// Subject holds additional fields of the subject of the certificate. If unset,
+	// the subject has the "marin3r.3scale.net" organization.
+	// +operator-sdk:csv:customresourcedefinitions:type=spec
+	// +optional
+	Subject *X509Subject `json:"subject,omitempty"`
+	// URIs is the list of URI SANs of the certificate
+	// +operator-sdk:csv:customresourcedefinitions:type=spec
+	// +optional
+	URIs []string `json:"uris,omitempty"`
+	// Usages is the list of key usages of the certificate. If no key usage is listed,
+	// they are "digital signature", plus "key encipherment" for RSA keys, or "cert sign"
+	// and "crl sign" for CAs. "server auth" is always added to server certificates.
+	// +operator-sdk:csv:customresourcedefinitions:type=spec
+	// +optional
+	Usages []KeyUsage `json:"usages,omitempty"`
 }
 
+// PrivateKeyAlgorithm is the algorithm of a private key
+// +kubebuilder:validation:Enum=RSA;ECDSA
+type PrivateKeyAlgorithm string
+
+const (
+	// RSAPrivateKeyAlgorithm is the RSA private key algorithm
+	RSAPrivateKeyAlgorithm PrivateKeyAlgorithm = "RSA"
+	// ECDSAPrivateKeyAlgorithm is the ECDSA private key algorithm
+	ECDSAPrivateKeyAlgorithm PrivateKeyAlgorithm = "ECDSA"
+)
+
+// PrivateKeyConfig configures the private key of a certificate
+type PrivateKeyConfig struct {
+	// Algorithm is the algorithm of the private key
+	// +operator-sdk:csv:customresourcedefinitions:type=spec
+	Algorithm PrivateKeyAlgorithm `json:"algorithm"`
+	// Size is the size of the private key in bits: 3072 (default) or 4096
+	// for RSA keys and 256 (default) or 384 for ECDSA keys.
+	// +kubebuilder:validation:Enum=256;384;3072;4096
+	// +operator-sdk:csv:customresourcedefinitions:type=spec
+	// +optional
+	Size int `json:"size,omitempty"`
+}
+
+// X509Subject holds the subject fields of a certificate
+type X509Subject struct {
+	// +operator-sdk:csv:customresourcedefinitions:type=spec
+	// +optional
+	Organizations []string `json:"organizations,omitempty"`
+	// +operator-sdk:csv:customresourcedefinitions:type=spec
+	// +optional
+	OrganizationalUnits []string `json:"organizationalUnits,omitempty"`
+	// +operator-sdk:csv:customresourcedefinitions:type=spec
+	// +optional
+	Countries []string `json:"countries,omitempty"`
+	// +operator-sdk:csv:customresourcedefinitions:type=spec
+	// +optional
+	Provinces []string `json:"provinces,omitempty"`
+	// +operator-sdk:csv:customresourcedefinitions:type=spec
+	// +optional
+	Localities []string `json:"localities,omitempty"`
+}
+
+// KeyUsage is a key usage of a certificate
+// +kubebuilder:validation:Enum="digital signature";"key encipherment";"cert sign";"crl sign";"server auth";"client auth"
+type KeyUsage string
+
+const (
+	// UsageDigitalSignature is the digital signature key usage
+	UsageDigitalSignature KeyUsage = "digital signature"
+	// UsageKeyEncipherment is the key encipherment key usage
+	UsageKeyEncipherment KeyUsage = "key encipherment"
+	// UsageCertSign is the certificate signing key usage
+	UsageCertSign KeyUsage = "cert sign"
+	// UsageCRLSign is the revocation list signing key usage
+	UsageCRLSign KeyUsage = "crl sign"
+	// UsageServerAuth is the server authentication extended key usage
+	UsageServerAuth KeyUsage = "server auth"
+	// UsageClientAuth is the client authentication extended key usage
+	UsageClientAuth KeyUsage = "client auth"
+)
+
 // IsServerCertificate returns true if the certificate is issued for server
 // usage or false if not
 func (d *DiscoveryServiceCertificate) IsServerCertificate() bool {
@@ -113,6 +204,18 @@ func (d *DiscoveryServiceCertificate) defaultCertificateRenewalConfig() Certific
 	return CertificateRenewalConfig{Enabled: true}
 }
 
+// IsRevocable returns true if the certificate is signed by a CA, so it
+// is added to the revocation list of the CA when the resource is deleted
+func (d *DiscoveryServiceCertificate) IsRevocable() bool {
+	return d.Spec.Signer.CASigned != nil && !d.IsCA()
+}
+
+// RevocationListSecretName returns the name of the Secret that holds the
+// revocation list of the certificates issued by the CA in the given Secret
+func RevocationListSecretName(caSecretName string) string {
+	return fmt.Sprintf("%s-crl", caSecretName)
+}
+
 // DiscoveryServiceCertificateSigner specifies the signer to use to provision the certificate
 type DiscoveryServiceCertificateSigner struct {
 	// SelfSigned holds specific configuration for the SelfSigned signer
@@ -125,6 +228,11 @@ type DiscoveryServiceCertificateSigner struct {
 	// +operator-sdk:csv:customresourcedefinitions:type=spec
 	// +optional
 	CASigned *CASignedConfig `json:"caSigned,omitempty"`
+	// CertManager holds specific configuration for the CertManager signer
+	// +kubebuilder:validation:Optional
+	// +operator-sdk:csv:customresourcedefinitions:type=spec
+	// +optional
+	CertManager *CertManagerConfig `json:"certManager,omitempty"`
 }
 
 // CertificateRenewalConfig configures the certificate renewal process.
@@ -144,6 +252,46 @@ type CASignedConfig struct {
 	SecretRef corev1.SecretReference `json:"caSecretRef"`
 }
 
+// CertManagerConfig is used to delegate the issuance of the certificate to
+// cert-manager. A cert-manager Certificate is created for the DiscoveryServiceCertificate
+// and cert-manager stores the certificate in the Secret referred in SecretRef.
+type CertManagerConfig struct {
+	// IssuerRef is a reference to the cert-manager issuer that signs the certificate
+	// +operator-sdk:csv:customresourcedefinitions:type=spec
+	IssuerRef CertManagerIssuerReference `json:"issuerRef"`
+}
+
+// CertManagerIssuerReference is a reference to a cert-manager issuer
+type CertManagerIssuerReference struct {
+	// Name is the name of the issuer
+	// +operator-sdk:csv:customresourcedefinitions:type=spec
+	Name string `json:"name"`
+	// Kind is the kind of the issuer. Defaults to "Issuer".
+	// +operator-sdk:csv:customresourcedefinitions:type=spec
+	// +optional
+	Kind string `json:"kind,omitempty"`
+	// Group is the API group of the issuer. Defaults to "cert-manager.io".
+	// +operator-sdk:csv:customresourcedefinitions:type=spec
+	// +optional
+	Group string `json:"group,omitempty"`
+}
+
+// GetKind returns the kind of the issuer
+func (r *CertManagerIssuerReference) GetKind() string {
+	if r.Kind == "" {
+		return "Issuer"
+	}
+	return r.Kind
+}
+
+// GetGroup returns the API group of the issuer
+func (r *CertManagerIssuerReference) GetGroup() string {
+	if r.Group == "" {
+		return "cert-manager.io"
+	}
+	return r.Group
+}
+
 // DiscoveryServiceCertificateStatus defines the observed state of DiscoveryServiceCertificate
 type DiscoveryServiceCertificateStatus struct {
 	// Ready is a boolean that specifies if the certificate is ready to be used
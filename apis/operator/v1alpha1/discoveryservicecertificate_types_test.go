@@ -57,6 +57,55 @@ func TestDiscoveryServiceCertificate_IsServerCertificate(t *testing.T) {
 	}
 }
 
+func TestDiscoveryServiceCertificate_IsRevocable(t *testing.T) {
+	cases := []struct {
+		testName                           string
+		discoveryServiceCertificateFactory func() *DiscoveryServiceCertificate
+		expectedResult                     bool
+	}{
+		{"With a self-signed certificate",
+			func() *DiscoveryServiceCertificate {
+				return &DiscoveryServiceCertificate{
+					Spec: DiscoveryServiceCertificateSpec{
+						Signer: DiscoveryServiceCertificateSigner{SelfSigned: &SelfSignedConfig{}},
+					},
+				}
+			},
+			false,
+		},
+		{"With a CA signed certificate",
+			func() *DiscoveryServiceCertificate {
+				return &DiscoveryServiceCertificate{
+					Spec: DiscoveryServiceCertificateSpec{
+						Signer: DiscoveryServiceCertificateSigner{CASigned: &CASignedConfig{}},
+					},
+				}
+			},
+			true,
+		},
+		{"With a CA signed CA",
+			func() *DiscoveryServiceCertificate {
+				return &DiscoveryServiceCertificate{
+					Spec: DiscoveryServiceCertificateSpec{
+						IsCA:   pointer.BoolPtr(true),
+						Signer: DiscoveryServiceCertificateSigner{CASigned: &CASignedConfig{}},
+					},
+				}
+			},
+			false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.testName, func(subT *testing.T) {
+			receivedResult := tc.discoveryServiceCertificateFactory().IsRevocable()
+			if !equality.Semantic.DeepEqual(tc.expectedResult, receivedResult) {
+				subT.Errorf("Expected result differs: Expected: %v, Received: %v", tc.expectedResult, receivedResult)
+			}
+		})
+	}
+}
+
 func TestDiscoveryServiceCertificate_IsCA(t *testing.T) {
 	cases := []struct {
 		testName                           string
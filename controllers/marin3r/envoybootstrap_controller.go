@@ -22,8 +22,12 @@ import (
 	"github.com/go-logr/logr"
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 
 	marin3rv1alpha1 "github.com/3scale/marin3r/apis/marin3r/v1alpha1"
 	operatorv1alpha1 "github.com/3scale/marin3r/apis/operator/v1alpha1"
@@ -42,7 +46,9 @@ type EnvoyBootstrapReconciler struct {
 // +kubebuilder:rbac:groups=marin3r.3scale.net,namespace=placeholder,resources=envoybootstraps,verbs=get;list;watch;update;patch
 // +kubebuilder:rbac:groups=marin3r.3scale.net,namespace=placeholder,resources=envoybootstraps/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups=operator.marin3r.3scale.net,namespace=placeholder,resources=discoveryservicecertificates,verbs=get;list;watch;create;update;patch
+// +kubebuilder:rbac:groups=operator.marin3r.3scale.net,namespace=placeholder,resources=discoveryservices,verbs=get;list;watch
 // +kubebuilder:rbac:groups="core",namespace=placeholder,resources=configmaps,verbs=get;list;watch;create;update;patch
+// +kubebuilder:rbac:groups="core",namespace=placeholder,resources=secrets,verbs=get;list;watch
 
 func (r *EnvoyBootstrapReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 	ctx := context.Background()
@@ -62,16 +68,19 @@ func (r *EnvoyBootstrapReconciler) Reconcile(req ctrl.Request) (ctrl.Result, err
 		return ctrl.Result{}, err
 	}
 
-	certificateReconciler := envoybootstrap.NewClientCertificateReconciler(ctx, log, r.Client, r.Scheme, eb)
-	result, err := certificateReconciler.Reconcile()
-	if result.Requeue || err != nil {
-		return result, err
+	// Clients with per-pod certificates don't use the shared client certificate
+	if !eb.Spec.ClientCertificate.PerPod {
+		certificateReconciler := envoybootstrap.NewClientCertificateReconciler(ctx, log, r.Client, r.Scheme, eb)
+		result, err := certificateReconciler.Reconcile()
+		if result.Requeue || err != nil {
+			return result, err
+		}
 	}
 
 	configReconciler := envoybootstrap.NewBootstrapConfigReconciler(ctx, log, r.Client, r.Scheme, eb)
 
 	// Reconcile the v2 config
-	result, err = configReconciler.Reconcile(envoy.APIv2)
+	result, err := configReconciler.Reconcile(envoy.APIv2)
 	if result.Requeue || err != nil {
 		return result, err
 	}
@@ -90,5 +99,27 @@ func (r *EnvoyBootstrapReconciler) SetupWithManager(mgr ctrl.Manager) error {
 		For(&marin3rv1alpha1.EnvoyBootstrap{}).
 		Owns(&operatorv1alpha1.DiscoveryServiceCertificate{}).
 		Owns(&corev1.ConfigMap{}).
+		Watches(&source.Kind{Type: &operatorv1alpha1.DiscoveryService{}},
+			&handler.EnqueueRequestsFromMapFunc{ToRequests: handler.ToRequestsFunc(r.discoveryServiceHandler)}).
 		Complete(r)
 }
+
+// discoveryServiceHandler enqueues the EnvoyBootstrap resources that point to a DiscoveryService
+// whenever it changes, so the bootstrap configs reflect changes in the DiscoveryService spec.
+func (r *EnvoyBootstrapReconciler) discoveryServiceHandler(o handler.MapObject) []reconcile.Request {
+	// EnvoyBootstraps can point to DiscoveryServices of other namespaces
+	list := &marin3rv1alpha1.EnvoyBootstrapList{}
+	if err := r.Client.List(context.Background(), list); err != nil {
+		r.Log.Error(err, "unable to list EnvoyBootstrap resources")
+		return []reconcile.Request{}
+	}
+
+	requests := []reconcile.Request{}
+	for _, eb := range list.Items {
+		if eb.Spec.DiscoveryService == o.Meta.GetName() && eb.GetDiscoveryServiceNamespace() == o.Meta.GetNamespace() {
+			requests = append(requests, reconcile.Request{
+				NamespacedName: types.NamespacedName{Name: eb.GetName(), Namespace: eb.GetNamespace()}})
+		}
+	}
+	return requests
+}
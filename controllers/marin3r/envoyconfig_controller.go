@@ -23,11 +23,13 @@ import (
 	envoyconfig "github.com/3scale/marin3r/pkg/reconcilers/marin3r/envoyconfig"
 
 	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 )
 
 // EnvoyConfigReconciler reconciles a EnvoyConfig object
@@ -35,6 +37,9 @@ type EnvoyConfigReconciler struct {
 	Client client.Client
 	Log    logr.Logger
 	Scheme *runtime.Scheme
+	// ServesNamespace filters the namespaces whose EnvoyConfigs are reconciled
+	// when the discovery service watches several namespaces
+	ServesNamespace ServesNamespaceFunc
 }
 
 // Reconcile progresses EnvoyConfig resources to its desired state
@@ -61,6 +66,12 @@ func (r *EnvoyConfigReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error)
 		return ctrl.Result{}, err
 	}
 
+	// EnvoyConfigs of namespaces this discovery service does not
+	// serve are left to the discovery service serving them
+	if ok, err := r.ServesNamespace.serves(ctx, ec.GetNamespace()); !ok || err != nil {
+		return ctrl.Result{}, err
+	}
+
 	log = log.WithValues("nodeID", ec.Spec.NodeID, "envoyAPI", ec.GetEnvoyAPIVersion())
 
 	if ok := envoyconfig.IsInitialized(ec); !ok {
@@ -90,13 +101,18 @@ func (r *EnvoyConfigReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error)
 		return reconcile.Result{}, nil
 	}
 
-	return ctrl.Result{}, nil
+	// The result can hold a RequeueAfter if the taint of some revision is due to expire
+	return result, nil
 }
 
 // SetupWithManager adds the controller to the manager
 func (r *EnvoyConfigReconciler) SetupWithManager(mgr ctrl.Manager) error {
-	return ctrl.NewControllerManagedBy(mgr).
+	b := ctrl.NewControllerManagedBy(mgr).
 		For(&marin3rv1alpha1.EnvoyConfig{}).
-		Owns(&marin3rv1alpha1.EnvoyConfigRevision{}).
-		Complete(r)
+		Owns(&marin3rv1alpha1.EnvoyConfigRevision{})
+	if r.ServesNamespace != nil {
+		b = b.Watches(&source.Kind{Type: &corev1.Namespace{}},
+			namespaceHandler(r.Client, func() runtime.Object { return &marin3rv1alpha1.EnvoyConfigList{} }, r.Log))
+	}
+	return b.Complete(r)
 }
@@ -729,8 +729,8 @@ var _ = Describe("EnvoyConfig controller", func() {
 
 			BeforeEach(func() {
 				OnErrorFn := rollback.OnError(k8sClient)
-				version := common.Hash(ec.Spec.EnvoyResources)
-				err := OnErrorFn(nodeID, version, "msg", envoy.APIv2)
+				version := ec.Spec.EnvoyResources.VersionFor(envoy.Endpoint)
+				err := OnErrorFn(namespace, nodeID, envoy.Endpoint, version, "msg", envoy.APIv2)
 				Expect(err).ToNot(HaveOccurred())
 			})
 
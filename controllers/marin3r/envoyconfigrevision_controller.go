@@ -19,27 +19,45 @@ package controllers
 import (
 	"context"
 	"fmt"
+	"time"
 
 	marin3rv1alpha1 "github.com/3scale/marin3r/apis/marin3r/v1alpha1"
 	"github.com/3scale/marin3r/pkg/common"
 	xdss "github.com/3scale/marin3r/pkg/discoveryservice/xdss"
+	"github.com/3scale/marin3r/pkg/discoveryservice/xdss/stats"
 	envoy "github.com/3scale/marin3r/pkg/envoy"
 	envoy_resources "github.com/3scale/marin3r/pkg/envoy/resources"
 	envoy_serializer "github.com/3scale/marin3r/pkg/envoy/serializer"
+	rollback "github.com/3scale/marin3r/pkg/reconcilers/marin3r/envoyconfig/rollback"
 	envoyconfigrevision "github.com/3scale/marin3r/pkg/reconcilers/marin3r/envoyconfigrevision"
 
 	"github.com/go-logr/logr"
 	"github.com/operator-framework/operator-lib/status"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/utils/pointer"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/predicate"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+)
+
+const (
+	// proxiesStatusResyncPeriod is the period at which the status of the
+	// proxies is refreshed for published revisions
+	proxiesStatusResyncPeriod = 30 * time.Second
+	// pendingWritesResyncPeriod is the period at which replicas that are not the leader
+	// requeue revisions with pending writes to the API, so they are written if the
+	// replica gets elected
+	pendingWritesResyncPeriod = 30 * time.Second
 )
 
 // EnvoyConfigRevisionReconciler reconciles a EnvoyConfigRevision object
@@ -48,7 +66,19 @@ type EnvoyConfigRevisionReconciler struct {
 	Log        logr.Logger
 	Scheme     *runtime.Scheme
 	XdsCache   xdss.Cache
+	XdsStats   *stats.Stats
 	APIVersion envoy.APIVersion
+	// IsLeader reports if this replica of the discovery service is the leader. When
+	// set, the controller runs in all the replicas, writing the published revisions
+	// to the xDS cache of each one, but only the leader writes to the API.
+	IsLeader func() bool
+	// Replicated is set when several discovery service replicas serve the proxies. Revisions
+	// with a rollout policy are then published to all the proxies at once, as canaries can
+	// only be selected among the proxies connected to the replica that runs the rollout.
+	Replicated bool
+	// ServesNamespace filters the namespaces whose revisions are written to the xDS
+	// cache when the discovery service watches several namespaces
+	ServesNamespace ServesNamespaceFunc
 }
 
 // Reconcile progresses EnvoyConfigRevision resources to its desired state
@@ -73,7 +103,23 @@ func (r *EnvoyConfigRevisionReconciler) Reconcile(req ctrl.Request) (ctrl.Result
 		return ctrl.Result{}, err
 	}
 
+	// Revisions of namespaces this discovery service does not serve are
+	// removed from the xDS cache and left to the discovery service serving them
+	if ok, err := r.ServesNamespace.serves(ctx, ecr.GetNamespace()); !ok || err != nil {
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		envoyconfigrevision.CleanupLogic(ecr, r.XdsCache, log)
+		return ctrl.Result{}, nil
+	}
+
+	leader := r.isLeader()
+
 	if ok := envoyconfigrevision.IsInitialized(ecr); !ok {
+		if !leader {
+			// The leader initializes the revision, which triggers a new reconcile
+			return ctrl.Result{RequeueAfter: pendingWritesResyncPeriod}, nil
+		}
 		if err := r.Client.Update(ctx, ecr); err != nil {
 			log.Error(err, "unable to update EnvoyConfigRevision")
 			return ctrl.Result{}, err
@@ -87,6 +133,9 @@ func (r *EnvoyConfigRevisionReconciler) Reconcile(req ctrl.Request) (ctrl.Result
 			return reconcile.Result{}, nil
 		}
 		envoyconfigrevision.CleanupLogic(ecr, r.XdsCache, log)
+		if !leader {
+			return ctrl.Result{RequeueAfter: pendingWritesResyncPeriod}, nil
+		}
 		controllerutil.RemoveFinalizer(ecr, marin3rv1alpha1.EnvoyConfigRevisionFinalizer)
 		if err = r.Client.Update(ctx, ecr); err != nil {
 			log.Error(err, "unable to update EnvoyConfigRevision")
@@ -96,25 +145,63 @@ func (r *EnvoyConfigRevisionReconciler) Reconcile(req ctrl.Request) (ctrl.Result
 		return reconcile.Result{}, nil
 	}
 
+	// Gateways connected to other replicas report NACKs through an annotation
+	if leader {
+		if reported, err := rollback.TaintReported(r.Client, ecr); reported || err != nil {
+			if err != nil {
+				log.Error(err, "unable to taint EnvoyConfigRevision")
+				return ctrl.Result{}, err
+			}
+			log.Info("Tainted revision with the NACK reported by another replica")
+			return reconcile.Result{}, nil
+		}
+	}
+
+	cacheReconciler := envoyconfigrevision.NewCacheReconciler(
+		ctx, r.Log, r.Client, r.XdsCache,
+		envoy_serializer.NewResourceUnmarshaller(ecr.GetSerialization(), r.APIVersion),
+		envoy_resources.NewGenerator(r.APIVersion),
+	)
+
 	// If this ecr has the RevisionPublishedCondition set to "True" pusblish the resources
 	// to the xds server cache
 	if ecr.Status.Conditions.IsTrueFor(marin3rv1alpha1.RevisionPublishedCondition) {
-		decoder := envoy_serializer.NewResourceUnmarshaller(ecr.GetSerialization(), r.APIVersion)
 
-		cacheReconciler := envoyconfigrevision.NewCacheReconciler(
-			ctx, r.Log, r.Client, r.XdsCache,
-			decoder,
-			envoy_resources.NewGenerator(r.APIVersion),
-		)
-
-		result, err := cacheReconciler.Reconcile(req.NamespacedName, ecr.Spec.EnvoyResources, ecr.Spec.NodeID, ecr.Spec.Version)
+		var result ctrl.Result
+		var err error
+		if ecr.Spec.Rollout != nil && r.XdsStats != nil && leader {
+			rollout := ecr.Status.Rollout.DeepCopy()
+			if r.Replicated {
+				result, err = cacheReconciler.SkipRollout(req.NamespacedName, ecr, time.Now())
+			} else {
+				result, err = cacheReconciler.ReconcileRollout(req.NamespacedName, ecr, r.XdsStats, time.Now())
+			}
+			if err == nil && !equality.Semantic.DeepEqual(rollout, ecr.Status.Rollout) {
+				if err := r.Client.Status().Update(ctx, ecr); err != nil {
+					log.Error(err, "unable to update EnvoyConfigRevision status")
+					return ctrl.Result{}, err
+				}
+				log.Info("rollout status updated for EnvoyConfigRevision resource")
+				return result, nil
+			}
+		} else {
+			result, err = cacheReconciler.Reconcile(req.NamespacedName, ecr.Spec.EnvoyResources, ecr.Spec.NodeID, ecr.Spec.Version,
+				ecr.GetBlockOnDanglingReferences())
+		}
 
 		// If a type errors.StatusError is returned it means that the config in spec.envoyResources is wrong
 		// and cannot be written into the xDS cache. This is true for any error loading all types of resources
 		// except for Secrets. Secrets are dynamically loaded from the API and transient failures are possible, so
 		// setting a permanent taint could occur for a transient failure, which is not desirable.
+		// A type xdss.DanglingReferencesError is returned when the publication of the resources is blocked
+		// due to references that cannot be resolved.
 		if result.Requeue || err != nil {
 			switch err.(type) {
+			case xdss.DanglingReferencesError:
+				log.Error(err, "publication blocked")
+				if err := r.taintSelf(ctx, ecr, string(envoyconfigrevision.DanglingReferencesReason), err.Error(), log); err != nil {
+					return ctrl.Result{}, err
+				}
 			case *errors.StatusError:
 				log.Error(err, fmt.Sprintf("%v", err))
 				if err := r.taintSelf(ctx, ecr, "FailedLoadingResources", err.Error(), log); err != nil {
@@ -126,7 +213,18 @@ func (r *EnvoyConfigRevisionReconciler) Reconcile(req ctrl.Request) (ctrl.Result
 		}
 	}
 
-	if ok := envoyconfigrevision.IsStatusReconciled(ecr, r.XdsCache); !ok {
+	// Canaries of a rollout that didn't complete go back to the published
+	// resources when this revision stops being published
+	if leader && !ecr.Status.Conditions.IsTrueFor(marin3rv1alpha1.RevisionPublishedCondition) {
+		if err := cacheReconciler.AbortRollout(ecr); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	if ok := envoyconfigrevision.IsStatusReconciled(ecr, r.XdsCache, r.XdsStats); !ok {
+		if !leader {
+			return ctrl.Result{RequeueAfter: pendingWritesResyncPeriod}, nil
+		}
 		if err := r.Client.Status().Update(ctx, ecr); err != nil {
 			log.Error(err, "unable to update EnvoyConfigRevision status")
 			return ctrl.Result{}, err
@@ -135,12 +233,30 @@ func (r *EnvoyConfigRevisionReconciler) Reconcile(req ctrl.Request) (ctrl.Result
 		return reconcile.Result{}, nil
 	}
 
+	// Rollouts progress as canaries acknowledge the resources, which
+	// doesn't cause any change in the API
+	if r.XdsStats != nil && ecr.Status.Rollout != nil && ecr.Status.Rollout.Phase == marin3rv1alpha1.RolloutCanaryPhase {
+		return ctrl.Result{RequeueAfter: envoyconfigrevision.RolloutResyncPeriod}, nil
+	}
+
+	// Proxies connect and disconnect without any change in the API, so the
+	// published revision is periodically requeued to keep status.proxies fresh
+	if r.XdsStats != nil && ecr.Status.IsPublished() {
+		return ctrl.Result{RequeueAfter: proxiesStatusResyncPeriod}, nil
+	}
+
 	return ctrl.Result{}, nil
 }
 
 func (r *EnvoyConfigRevisionReconciler) taintSelf(ctx context.Context, ecr *marin3rv1alpha1.EnvoyConfigRevision,
 	reason, msg string, log logr.Logger) error {
 
+	// The leader gets the same error when it writes
+	// the revision to its own xDS cache and taints it
+	if !r.isLeader() {
+		return nil
+	}
+
 	if !ecr.Status.Conditions.IsTrueFor(marin3rv1alpha1.RevisionTaintedCondition) {
 		patch := client.MergeFrom(ecr.DeepCopy())
 		ecr.Status.Conditions.SetCondition(status.Condition{
@@ -160,6 +276,11 @@ func (r *EnvoyConfigRevisionReconciler) taintSelf(ctx context.Context, ecr *mari
 	return nil
 }
 
+// isLeader returns true if this replica of the discovery service writes to the API
+func (r *EnvoyConfigRevisionReconciler) isLeader() bool {
+	return r.IsLeader == nil || r.IsLeader()
+}
+
 func filterByAPIVersion(obj runtime.Object, version envoy.APIVersion) bool {
 	switch o := obj.(type) {
 	case *marin3rv1alpha1.EnvoyConfigRevision:
@@ -192,8 +313,46 @@ func filterByAPIVersionPredicate(version envoy.APIVersion,
 
 // SetupWithManager adds the controller to the manager
 func (r *EnvoyConfigRevisionReconciler) SetupWithManager(mgr ctrl.Manager) error {
-	return ctrl.NewControllerManagedBy(mgr).
-		For(&marin3rv1alpha1.EnvoyConfigRevision{}).
-		WithEventFilter(filterByAPIVersionPredicate(r.APIVersion, filterByAPIVersion)).
-		Complete(r)
+	if r.IsLeader == nil {
+		b := ctrl.NewControllerManagedBy(mgr).
+			For(&marin3rv1alpha1.EnvoyConfigRevision{},
+				builder.WithPredicates(filterByAPIVersionPredicate(r.APIVersion, filterByAPIVersion)))
+		if r.ServesNamespace != nil {
+			b = b.Watches(&source.Kind{Type: &corev1.Namespace{}}, r.namespaceHandler())
+		}
+		return b.Complete(r)
+	}
+
+	// Every replica needs the published revisions in its own xDS
+	// cache, so the controller does not wait to be elected leader
+	c, err := controller.NewUnmanaged(fmt.Sprintf("envoyconfigrevision_%s", r.APIVersion), mgr, controller.Options{Reconciler: r})
+	if err != nil {
+		return err
+	}
+	err = c.Watch(&source.Kind{Type: &marin3rv1alpha1.EnvoyConfigRevision{}}, &handler.EnqueueRequestForObject{},
+		filterByAPIVersionPredicate(r.APIVersion, filterByAPIVersion))
+	if err != nil {
+		return err
+	}
+	if r.ServesNamespace != nil {
+		if err := c.Watch(&source.Kind{Type: &corev1.Namespace{}}, r.namespaceHandler()); err != nil {
+			return err
+		}
+	}
+	return mgr.Add(nonLeaderElectionController{c})
+}
+
+func (r *EnvoyConfigRevisionReconciler) namespaceHandler() handler.EventHandler {
+	return namespaceHandler(r.Client, func() runtime.Object { return &marin3rv1alpha1.EnvoyConfigRevisionList{} }, r.Log)
+}
+
+// nonLeaderElectionController is a controller
+// that runs regardless of leader election
+type nonLeaderElectionController struct {
+	controller.Controller
+}
+
+// NeedLeaderElection implements the manager.LeaderElectionRunnable interface
+func (nonLeaderElectionController) NeedLeaderElection() bool {
+	return false
 }
@@ -41,6 +41,31 @@ func TestEnvoyConfigRevisionReconciler_taintSelf(t *testing.T) {
 			t.Errorf("EnvoyConfigRevisionReconciler.taintSelf() ecr is not tainted")
 		}
 	})
+
+	t.Run("Does not taint the ecr object if not the leader", func(t *testing.T) {
+		ecr := &marin3rv1alpha1.EnvoyConfigRevision{
+			ObjectMeta: metav1.ObjectMeta{Name: "ecr", Namespace: "default"},
+			Spec: marin3rv1alpha1.EnvoyConfigRevisionSpec{
+				NodeID:         "node1",
+				Version:        "bbbb",
+				EnvoyResources: &marin3rv1alpha1.EnvoyResources{},
+			},
+		}
+		r := &EnvoyConfigRevisionReconciler{
+			Client:   fake.NewFakeClient(ecr),
+			Scheme:   s,
+			XdsCache: xdss_v2.NewCache(cache_v2.NewSnapshotCache(true, cache_v2.IDHash{}, nil)),
+			Log:      ctrl.Log.WithName("test"),
+			IsLeader: func() bool { return false },
+		}
+		if err := r.taintSelf(context.TODO(), ecr, "test", "test", r.Log); err != nil {
+			t.Errorf("EnvoyConfigRevisionReconciler.taintSelf() error = %v", err)
+		}
+		r.Client.Get(context.TODO(), types.NamespacedName{Name: "ecr", Namespace: "default"}, ecr)
+		if ecr.Status.Conditions.IsTrueFor(marin3rv1alpha1.RevisionTaintedCondition) {
+			t.Errorf("EnvoyConfigRevisionReconciler.taintSelf() ecr should not be tainted")
+		}
+	})
 }
 
 func Test_filterByAPIVersion(t *testing.T) {
@@ -0,0 +1,51 @@
+package controllers
+
+import (
+	"context"
+
+	"github.com/go-logr/logr"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// ServesNamespaceFunc returns true if the discovery service serves the
+// EnvoyConfigs of a namespace. A nil ServesNamespaceFunc serves all
+// the namespaces in the cache of the controller.
+type ServesNamespaceFunc func(ctx context.Context, namespace string) (bool, error)
+
+func (fn ServesNamespaceFunc) serves(ctx context.Context, namespace string) (bool, error) {
+	if fn == nil {
+		return true, nil
+	}
+	return fn(ctx, namespace)
+}
+
+// namespaceHandler enqueues all the objects of the given list type in a Namespace
+// whenever it changes, so changes in its labels start or stop serving them
+func namespaceHandler(cl client.Client, newList func() runtime.Object, log logr.Logger) handler.EventHandler {
+	return &handler.EnqueueRequestsFromMapFunc{ToRequests: handler.ToRequestsFunc(func(o handler.MapObject) []reconcile.Request {
+		list := newList()
+		if err := cl.List(context.Background(), list, client.InNamespace(o.Meta.GetName())); err != nil {
+			log.Error(err, "unable to list resources", "Namespace", o.Meta.GetName())
+			return []reconcile.Request{}
+		}
+		items, err := meta.ExtractList(list)
+		if err != nil {
+			log.Error(err, "unable to list resources", "Namespace", o.Meta.GetName())
+			return []reconcile.Request{}
+		}
+
+		requests := []reconcile.Request{}
+		for _, item := range items {
+			if m, err := meta.Accessor(item); err == nil {
+				requests = append(requests, reconcile.Request{
+					NamespacedName: types.NamespacedName{Name: m.GetName(), Namespace: m.GetNamespace()}})
+			}
+		}
+		return requests
+	})}
+}
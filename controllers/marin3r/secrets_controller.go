@@ -37,6 +37,9 @@ type SecretReconciler struct {
 	Client client.Client
 	Log    logr.Logger
 	Scheme *runtime.Scheme
+	// ServesNamespace filters the namespaces whose Secrets are watched
+	// when the discovery service watches several namespaces
+	ServesNamespace ServesNamespaceFunc
 }
 
 // +kubebuilder:rbac:groups=core,namespace=placeholder,resources=secrets,verbs=get;list;watch
@@ -45,6 +48,12 @@ type SecretReconciler struct {
 func (r *SecretReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 	ctx := context.Background()
 
+	// Secrets of namespaces this discovery service does not serve can only
+	// be referred by EnvoyConfigs served by other discovery services
+	if ok, err := r.ServesNamespace.serves(ctx, req.Namespace); !ok || err != nil {
+		return reconcile.Result{}, err
+	}
+
 	// Fetch the Secret instance
 	secret := &corev1.Secret{}
 	err := r.Client.Get(ctx, req.NamespacedName, secret)
@@ -66,7 +75,7 @@ func (r *SecretReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 	// Get the list of EnvoyConfigRevisions published and
 	// check which of them contain refs to this secret
 	list := &marin3rv1alpha1.EnvoyConfigRevisionList{}
-	if err := r.Client.List(ctx, list); err != nil {
+	if err := r.Client.List(ctx, list, client.InNamespace(req.Namespace)); err != nil {
 		return reconcile.Result{}, err
 	}
 
@@ -75,7 +84,7 @@ func (r *SecretReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 		if ecr.Status.Conditions.IsTrueFor(marin3rv1alpha1.RevisionPublishedCondition) {
 
 			for _, secret := range ecr.Spec.EnvoyResources.Secrets {
-				if secret.Ref.Name == req.Name && secret.Ref.Namespace == req.Namespace {
+				if secret.Ref.Name == req.Name && (secret.Ref.Namespace == req.Namespace || secret.Ref.Namespace == "") {
 					log.Info("Triggered EnvoyConfigRevision reconcile",
 						"EnvoyConfigRevision_Name", ecr.ObjectMeta.Name, "EnvoyConfigRevision_Namespace", ecr.GetNamespace())
 					if err != nil {
@@ -5,6 +5,7 @@ import (
 	"fmt"
 
 	operatorv1alpha1 "github.com/3scale/marin3r/apis/operator/v1alpha1"
+	discoveryservice "github.com/3scale/marin3r/pkg/reconcilers/operator/discoveryservice"
 	"github.com/go-logr/logr"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
@@ -37,10 +38,15 @@ func (r *DiscoveryServiceReconciler) reconcileCA(ctx context.Context, log logr.L
 		return reconcile.Result{}, err
 	}
 
-	// CA is not currently reconciled after initial creation, so do nothing
-	// TODO: filter out updates?
+	// The CA spec is not reconciled after initial creation, but the CA
+	// is rotated when requested or when it is close to expire
+	return r.reconcileCARotation(ctx, log)
+}
 
-	return reconcile.Result{}, nil
+// reconcileCARotation keeps the trust bundle of the root CA and progresses its rotation
+func (r *DiscoveryServiceReconciler) reconcileCARotation(ctx context.Context, log logr.Logger) (reconcile.Result, error) {
+	rr := discoveryservice.NewCARotationReconciler(ctx, log, r.Client, r.Scheme, r.ds)
+	return rr.Reconcile(types.NamespacedName{Name: getCACertName(r.ds), Namespace: OwnedObjectNamespace(r.ds)})
 }
 
 func getCACertName(ds *operatorv1alpha1.DiscoveryService) string {
@@ -0,0 +1,167 @@
+package controllers
+
+import (
+	"context"
+
+	marin3rv1alpha1 "github.com/3scale/marin3r/apis/marin3r/v1alpha1"
+	"github.com/go-logr/logr"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// The ClusterRole and ClusterRoleBinding grant the discovery service the cluster scoped
+// permissions that a Role cannot, like creating TokenReviews to verify the ServiceAccount
+// tokens of the envoy proxies or, with a namespaceSelector, serving other namespaces.
+// Cluster scoped resources cannot be owned by a namespaced DiscoveryService, so they
+// are deleted by the DiscoveryService finalizer instead of being garbage collected.
+
+func (r *DiscoveryServiceReconciler) reconcileClusterRole(ctx context.Context, log logr.Logger) (reconcile.Result, error) {
+
+	if !r.ds.NeedsClusterRole() {
+		return reconcile.Result{}, nil
+	}
+
+	existent := &rbacv1.ClusterRole{}
+	err := r.Client.Get(ctx, types.NamespacedName{Name: r.ds.ClusterObjectName()}, existent)
+
+	if err != nil {
+		if errors.IsNotFound(err) {
+			existent = r.genClusterRoleObject()
+			if err := r.Client.Create(ctx, existent); err != nil {
+				return reconcile.Result{}, err
+			}
+			log.Info("Created ClusterRole")
+			return reconcile.Result{}, nil
+		}
+		return reconcile.Result{}, err
+	}
+
+	// We just reconcile the "Rules" for the moment
+	if !equality.Semantic.DeepEqual(existent.Rules, r.genClusterRoleObject().Rules) {
+		patch := client.MergeFrom(existent.DeepCopy())
+		existent.Rules = r.genClusterRoleObject().Rules
+		if err := r.Client.Patch(ctx, existent, patch); err != nil {
+			return reconcile.Result{}, err
+		}
+		log.Info("Patched ClusterRole")
+	}
+
+	return reconcile.Result{}, nil
+}
+
+func (r *DiscoveryServiceReconciler) genClusterRoleObject() *rbacv1.ClusterRole {
+	rules := []rbacv1.PolicyRule{
+		{
+			// Required to verify the ServiceAccount tokens of the envoy proxies
+			APIGroups: []string{authenticationv1.SchemeGroupVersion.Group},
+			Resources: []string{"tokenreviews"},
+			Verbs:     []string{"create"},
+		},
+	}
+
+	if r.ds.ClusterScoped() {
+		rules = append(rules,
+			rbacv1.PolicyRule{
+				APIGroups: []string{corev1.SchemeGroupVersion.Group},
+				Resources: []string{"namespaces"},
+				Verbs:     []string{"get", "list", "watch"},
+			},
+			rbacv1.PolicyRule{
+				APIGroups: []string{corev1.SchemeGroupVersion.Group},
+				Resources: []string{"secrets"},
+				Verbs:     []string{"get", "list", "watch"},
+			},
+			rbacv1.PolicyRule{
+				APIGroups: []string{marin3rv1alpha1.GroupVersion.Group},
+				Resources: []string{rbacv1.ResourceAll},
+				Verbs:     []string{rbacv1.VerbAll},
+			},
+		)
+	}
+
+	return &rbacv1.ClusterRole{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   r.ds.ClusterObjectName(),
+			Labels: Labels(r.ds),
+		},
+		Rules: rules,
+	}
+}
+
+func (r *DiscoveryServiceReconciler) reconcileClusterRoleBinding(ctx context.Context, log logr.Logger) (reconcile.Result, error) {
+
+	if !r.ds.NeedsClusterRole() {
+		return reconcile.Result{}, nil
+	}
+
+	existent := &rbacv1.ClusterRoleBinding{}
+	err := r.Client.Get(ctx, types.NamespacedName{Name: r.ds.ClusterObjectName()}, existent)
+
+	if err != nil {
+		if errors.IsNotFound(err) {
+			existent = r.genClusterRoleBindingObject()
+			if err := r.Client.Create(ctx, existent); err != nil {
+				return reconcile.Result{}, err
+			}
+			log.Info("Created ClusterRoleBinding")
+			return reconcile.Result{}, nil
+		}
+		return reconcile.Result{}, err
+	}
+
+	// We just reconcile "Subjects" field. "RoleRef" is an immutable field.
+	if !equality.Semantic.DeepEqual(existent.Subjects, r.genClusterRoleBindingObject().Subjects) {
+		patch := client.MergeFrom(existent.DeepCopy())
+		existent.Subjects = r.genClusterRoleBindingObject().Subjects
+		if err := r.Client.Patch(ctx, existent, patch); err != nil {
+			return reconcile.Result{}, err
+		}
+		log.Info("Patched ClusterRoleBinding")
+	}
+
+	return reconcile.Result{}, nil
+}
+
+func (r *DiscoveryServiceReconciler) genClusterRoleBindingObject() *rbacv1.ClusterRoleBinding {
+	return &rbacv1.ClusterRoleBinding{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   r.ds.ClusterObjectName(),
+			Labels: Labels(r.ds),
+		},
+		RoleRef: rbacv1.RoleRef{
+			APIGroup: rbacv1.SchemeGroupVersion.Group,
+			Kind:     "ClusterRole",
+			Name:     r.ds.ClusterObjectName(),
+		},
+		Subjects: []rbacv1.Subject{
+			{
+				Kind:      rbacv1.ServiceAccountKind,
+				Name:      OwnedObjectName(r.ds),
+				Namespace: r.ds.GetNamespace(),
+			},
+		},
+	}
+}
+
+// deleteClusterScopedObjects deletes the ClusterRole and ClusterRoleBinding of the
+// discovery service when it is deleted or it no longer needs them
+func (r *DiscoveryServiceReconciler) deleteClusterScopedObjects(ctx context.Context, log logr.Logger) error {
+	for _, o := range []runtime.Object{
+		&rbacv1.ClusterRoleBinding{ObjectMeta: metav1.ObjectMeta{Name: r.ds.ClusterObjectName()}},
+		&rbacv1.ClusterRole{ObjectMeta: metav1.ObjectMeta{Name: r.ds.ClusterObjectName()}},
+	} {
+		if err := r.Client.Delete(ctx, o); err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+	}
+	log.Info("Deleted ClusterRole and ClusterRoleBinding")
+	return nil
+}
@@ -0,0 +1,109 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	operatorv1alpha1 "github.com/3scale/marin3r/apis/operator/v1alpha1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/pointer"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestDiscoveryServiceReconciler_reconcileClusterRole(t *testing.T) {
+	tests := []struct {
+		name            string
+		spec            operatorv1alpha1.DiscoveryServiceSpec
+		wantClusterRole bool
+		wantRules       int
+	}{
+		{
+			name:            "Does not create a ClusterRole for a single namespace discovery service",
+			spec:            operatorv1alpha1.DiscoveryServiceSpec{},
+			wantClusterRole: false,
+		},
+		{
+			name:            "Allows creating TokenReviews to a single namespace discovery service with ServiceAccount token authentication",
+			spec:            operatorv1alpha1.DiscoveryServiceSpec{ServiceAccountTokenAuth: pointer.BoolPtr(true)},
+			wantClusterRole: true,
+			wantRules:       1,
+		},
+		{
+			name:            "Allows creating TokenReviews to a single namespace discovery service with pod certificates",
+			spec:            operatorv1alpha1.DiscoveryServiceSpec{PodCertificates: &operatorv1alpha1.PodCertificatesConfig{}},
+			wantClusterRole: true,
+			wantRules:       1,
+		},
+		{
+			name:            "Allows a discovery service with a namespaceSelector to serve other namespaces",
+			spec:            operatorv1alpha1.DiscoveryServiceSpec{NamespaceSelector: &metav1.LabelSelector{}},
+			wantClusterRole: true,
+			wantRules:       4,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &DiscoveryServiceReconciler{
+				Client: fake.NewFakeClient(),
+				Log:    ctrl.Log.WithName("test"),
+				ds: &operatorv1alpha1.DiscoveryService{
+					ObjectMeta: metav1.ObjectMeta{Name: "ds", Namespace: "test"},
+					Spec:       tt.spec,
+				},
+			}
+			if _, err := r.reconcileClusterRole(context.TODO(), r.Log); err != nil {
+				t.Fatalf("DiscoveryServiceReconciler.reconcileClusterRole() error = %v", err)
+			}
+			if _, err := r.reconcileClusterRoleBinding(context.TODO(), r.Log); err != nil {
+				t.Fatalf("DiscoveryServiceReconciler.reconcileClusterRoleBinding() error = %v", err)
+			}
+
+			key := types.NamespacedName{Name: "marin3r-test-ds"}
+			cr := &rbacv1.ClusterRole{}
+			err := r.Client.Get(context.TODO(), key, cr)
+			if !tt.wantClusterRole {
+				if !errors.IsNotFound(err) {
+					t.Errorf("DiscoveryServiceReconciler.reconcileClusterRole() got ClusterRole, error = %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("DiscoveryServiceReconciler.reconcileClusterRole() error getting ClusterRole = %v", err)
+			}
+			if len(cr.Rules) != tt.wantRules {
+				t.Errorf("DiscoveryServiceReconciler.reconcileClusterRole() got %d rules, want %d", len(cr.Rules), tt.wantRules)
+			}
+			if !allowsTokenReviews(cr.Rules) {
+				t.Errorf("DiscoveryServiceReconciler.reconcileClusterRole() ClusterRole does not allow creating TokenReviews: %v", cr.Rules)
+			}
+
+			crb := &rbacv1.ClusterRoleBinding{}
+			if err := r.Client.Get(context.TODO(), key, crb); err != nil {
+				t.Fatalf("DiscoveryServiceReconciler.reconcileClusterRoleBinding() error getting ClusterRoleBinding = %v", err)
+			}
+			if crb.RoleRef.Name != cr.GetName() || len(crb.Subjects) != 1 ||
+				crb.Subjects[0].Name != "marin3r-ds" || crb.Subjects[0].Namespace != "test" {
+				t.Errorf("DiscoveryServiceReconciler.reconcileClusterRoleBinding() does not bind the ServiceAccount: %v", crb)
+			}
+		})
+	}
+}
+
+func allowsTokenReviews(rules []rbacv1.PolicyRule) bool {
+	for _, rule := range rules {
+		for _, group := range rule.APIGroups {
+			for _, resource := range rule.Resources {
+				for _, verb := range rule.Verbs {
+					if group == "authentication.k8s.io" && resource == "tokenreviews" && verb == "create" {
+						return true
+					}
+				}
+			}
+		}
+	}
+	return false
+}
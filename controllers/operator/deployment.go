@@ -4,6 +4,7 @@ import (
 	"fmt"
 
 	operatorv1alpha1 "github.com/3scale/marin3r/apis/operator/v1alpha1"
+	xdss "github.com/3scale/marin3r/pkg/discoveryservice/xdss"
 	"github.com/3scale/marin3r/pkg/reconcilers"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
@@ -16,7 +17,7 @@ const (
 	appLabelKey string = "app"
 )
 
-func deploymentGeneratorFn(ds *operatorv1alpha1.DiscoveryService, certificateHash string) reconcilers.DeploymentGeneratorFn {
+func deploymentGeneratorFn(ds *operatorv1alpha1.DiscoveryService) reconcilers.DeploymentGeneratorFn {
 
 	return func() *appsv1.Deployment {
 
@@ -27,7 +28,7 @@ func deploymentGeneratorFn(ds *operatorv1alpha1.DiscoveryService, certificateHas
 				Labels:    Labels(ds),
 			},
 			Spec: appsv1.DeploymentSpec{
-				Replicas: pointer.Int32Ptr(1),
+				Replicas: pointer.Int32Ptr(ds.GetReplicas()),
 				Selector: &metav1.LabelSelector{
 					MatchLabels: Labels(ds),
 				},
@@ -56,6 +57,28 @@ func deploymentGeneratorFn(ds *operatorv1alpha1.DiscoveryService, certificateHas
 									},
 								},
 							},
+							{
+								// The revocation list is only created once a certificate is revoked
+								Name: "ca-crl",
+								VolumeSource: corev1.VolumeSource{
+									Secret: &corev1.SecretVolumeSource{
+										SecretName:  operatorv1alpha1.RevocationListSecretName(getCACertName(ds)),
+										DefaultMode: pointer.Int32Ptr(420),
+										Optional:    pointer.BoolPtr(true),
+									},
+								},
+							},
+							{
+								// The trust bundle is created by the CA reconciler
+								Name: "ca-bundle",
+								VolumeSource: corev1.VolumeSource{
+									Secret: &corev1.SecretVolumeSource{
+										SecretName:  operatorv1alpha1.TrustBundleSecretName(getCACertName(ds)),
+										DefaultMode: pointer.Int32Ptr(420),
+										Optional:    pointer.BoolPtr(true),
+									},
+								},
+							},
 						},
 						Containers: []corev1.Container{
 							{
@@ -65,6 +88,8 @@ func deploymentGeneratorFn(ds *operatorv1alpha1.DiscoveryService, certificateHas
 									"discovery-service",
 									"--server-certificate-path=/etc/marin3r/tls/server",
 									"--ca-certificate-path=/etc/marin3r/tls/ca",
+									"--revocation-list-path=/etc/marin3r/tls/crl",
+									"--trust-bundle-path=/etc/marin3r/tls/bundle",
 									func() string { return fmt.Sprintf("--xdss-port=%v", ds.GetXdsServerPort()) }(),
 									func() string { return fmt.Sprintf("--metrics-addr=:%v", ds.GetMetricsPort()) }(),
 								},
@@ -101,6 +126,16 @@ func deploymentGeneratorFn(ds *operatorv1alpha1.DiscoveryService, certificateHas
 										ReadOnly:  true,
 										MountPath: "/etc/marin3r/tls/ca/",
 									},
+									{
+										Name:      "ca-crl",
+										ReadOnly:  true,
+										MountPath: "/etc/marin3r/tls/crl/",
+									},
+									{
+										Name:      "ca-bundle",
+										ReadOnly:  true,
+										MountPath: "/etc/marin3r/tls/bundle/",
+									},
 								},
 								TerminationMessagePath:   corev1.TerminationMessagePathDefault,
 								TerminationMessagePolicy: corev1.TerminationMessageReadFile,
@@ -114,21 +149,10 @@ func deploymentGeneratorFn(ds *operatorv1alpha1.DiscoveryService, certificateHas
 						DeprecatedServiceAccount:      OwnedObjectName(ds),
 						SecurityContext:               &corev1.PodSecurityContext{},
 						SchedulerName:                 corev1.DefaultSchedulerName,
+						Affinity:                      ds.GetAffinity(Labels(ds)),
 					},
 				},
-				Strategy: appsv1.DeploymentStrategy{
-					Type: appsv1.RollingUpdateDeploymentStrategyType,
-					RollingUpdate: &appsv1.RollingUpdateDeployment{
-						MaxUnavailable: &intstr.IntOrString{
-							Type:   intstr.String,
-							StrVal: "25%",
-						},
-						MaxSurge: &intstr.IntOrString{
-							Type:   intstr.String,
-							StrVal: "25%",
-						},
-					},
-				},
+				Strategy:                ds.GetDeploymentStrategy(),
 				RevisionHistoryLimit:    pointer.Int32Ptr(10),
 				ProgressDeadlineSeconds: pointer.Int32Ptr(600),
 			},
@@ -138,9 +162,122 @@ func deploymentGeneratorFn(ds *operatorv1alpha1.DiscoveryService, certificateHas
 			dep.Spec.Template.Spec.Containers[0].Args = append(dep.Spec.Template.Spec.Containers[0].Args, "--debug")
 		}
 
-		// Set a label with the server certificate hash
-		dep.Spec.Template.ObjectMeta.Labels[operatorv1alpha1.DiscoveryServiceCertificateHashLabelKey] = certificateHash
+		if ds.LeaderElection() {
+			dep.Spec.Template.Spec.Containers[0].Args = append(dep.Spec.Template.Spec.Containers[0].Args, "--enable-leader-election")
+		}
+
+		if ds.ClusterScoped() {
+			// The selector is validated before the Deployment is reconciled
+			selector, _ := ds.GetNamespaceSelector()
+			dep.Spec.Template.Spec.Containers[0].Args = append(dep.Spec.Template.Spec.Containers[0].Args,
+				fmt.Sprintf("--namespace-selector=%s", selector))
+		}
+
+		if ds.DeltaXds() {
+			dep.Spec.Template.Spec.Containers[0].Args = append(dep.Spec.Template.Spec.Containers[0].Args, "--enable-delta-xds")
+		}
+
+		if ds.ServiceAccountTokenAuth() {
+			dep.Spec.Template.Spec.Containers[0].Args = append(dep.Spec.Template.Spec.Containers[0].Args, "--enable-token-auth")
+		}
+
+		if ds.AllowUnrestrictedClients() {
+			dep.Spec.Template.Spec.Containers[0].Args = append(dep.Spec.Template.Spec.Containers[0].Args, "--allow-unrestricted-clients")
+		}
+
+		if ds.PodCertificatesEnabled() {
+			dep.Spec.Template.Spec.Containers[0].Args = append(dep.Spec.Template.Spec.Containers[0].Args,
+				"--enable-pod-certificates",
+				fmt.Sprintf("--pod-certificates-port=%v", ds.GetPodCertificatesPort()),
+				fmt.Sprintf("--pod-certificate-duration=%v", ds.GetPodCertificateDuration()),
+			)
+			dep.Spec.Template.Spec.Containers[0].Ports = append(dep.Spec.Template.Spec.Containers[0].Ports, corev1.ContainerPort{
+				Name:          "pod-certs",
+				ContainerPort: int32(ds.GetPodCertificatesPort()),
+				Protocol:      corev1.ProtocolTCP,
+			})
+		}
+
+		if port := ds.GetServiceConfig().HealthPort; port != nil {
+			container := &dep.Spec.Template.Spec.Containers[0]
+			container.Args = append(container.Args, fmt.Sprintf("--health-probe-addr=:%v", *port))
+			container.Ports = append(container.Ports, corev1.ContainerPort{
+				Name:          "health",
+				ContainerPort: int32(*port),
+				Protocol:      corev1.ProtocolTCP,
+			})
+			container.LivenessProbe = healthProbe("/healthz")
+			container.ReadinessProbe = healthProbe("/readyz")
+		}
+
+		if nodeHash := nodeHashFields(ds.GetNodeHash()); nodeHash.String() != xdss.NodeHashID {
+			dep.Spec.Template.Spec.Containers[0].Args = append(dep.Spec.Template.Spec.Containers[0].Args,
+				fmt.Sprintf("--node-hash=%s", nodeHash))
+		}
+
+		applyPodTemplateConfig(&dep.Spec.Template, ds.GetPodTemplate())
 
 		return dep
 	}
 }
+
+// applyPodTemplateConfig merges the user provided options into the Pod template.
+// The labels used by the Deployment selector cannot be overridden.
+func applyPodTemplateConfig(template *corev1.PodTemplateSpec, config *operatorv1alpha1.PodTemplateConfig) {
+	labels := map[string]string{}
+	for k, v := range config.Labels {
+		labels[k] = v
+	}
+	for k, v := range template.ObjectMeta.Labels {
+		labels[k] = v
+	}
+	template.ObjectMeta.Labels = labels
+	template.ObjectMeta.Annotations = config.Annotations
+
+	template.Spec.NodeSelector = config.NodeSelector
+	template.Spec.Tolerations = config.Tolerations
+	template.Spec.PriorityClassName = config.PriorityClassName
+	template.Spec.ImagePullSecrets = config.ImagePullSecrets
+	if config.SecurityContext != nil {
+		template.Spec.SecurityContext = config.SecurityContext
+	}
+	if config.ContainerSecurityContext != nil {
+		template.Spec.Containers[0].SecurityContext = config.ContainerSecurityContext
+	}
+}
+
+// healthProbe returns a probe against the given path of the health port. All the
+// fields defaulted by the API server are set so the Deployment is not patched in a loop.
+func healthProbe(path string) *corev1.Probe {
+	return &corev1.Probe{
+		Handler: corev1.Handler{
+			HTTPGet: &corev1.HTTPGetAction{
+				Path:   path,
+				Port:   intstr.FromString("health"),
+				Scheme: corev1.URISchemeHTTP,
+			},
+		},
+		InitialDelaySeconds: 5,
+		TimeoutSeconds:      1,
+		PeriodSeconds:       10,
+		SuccessThreshold:    1,
+		FailureThreshold:    3,
+	}
+}
+
+// nodeHashFields returns the node fields that the discovery
+// service uses to compute the nodeID of envoy nodes
+func nodeHashFields(nhc *operatorv1alpha1.NodeHashConfig) xdss.NodeHash {
+	h := xdss.NodeHash{}
+	for _, strategy := range nhc.Strategies() {
+		switch strategy {
+		case operatorv1alpha1.NodeIDHashStrategy:
+			h = append(h, xdss.NodeHashID)
+		case operatorv1alpha1.NodeClusterHashStrategy:
+			h = append(h, xdss.NodeHashCluster)
+		case operatorv1alpha1.NodeMetadataHashStrategy:
+			h = append(h, xdss.NodeHashMetadataPrefix+nhc.MetadataKey)
+		}
+	}
+	return h
+}
@@ -23,16 +23,19 @@ import (
 	marin3rv1alpha1 "github.com/3scale/marin3r/apis/marin3r/v1alpha1"
 	operatorv1alpha1 "github.com/3scale/marin3r/apis/operator/v1alpha1"
 
+	"github.com/3scale/marin3r/pkg/common"
 	"github.com/3scale/marin3r/pkg/reconcilers"
 	"github.com/go-logr/logr"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	policyv1beta1 "k8s.io/api/policy/v1beta1"
 	rbacv1 "k8s.io/api/rbac/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 )
 
 const (
@@ -61,6 +64,8 @@ type DiscoveryServiceReconciler struct {
 // +kubebuilder:rbac:groups="core",namespace=placeholder,resources=services,verbs=get;list;watch;create;update;patch
 // +kubebuilder:rbac:groups="core",namespace=placeholder,resources=serviceaccounts,verbs=get;list;watch;create;update;patch
 // +kubebuilder:rbac:groups="apps",namespace=placeholder,resources=deployments,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups="policy",namespace=placeholder,resources=poddisruptionbudgets,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups="core",namespace=placeholder,resources=events,verbs=create;patch
 // +kubebuilder:rbac:groups="rbac.authorization.k8s.io",namespace=placeholder,resources=roles,verbs=get;list;watch;create;patch
 // +kubebuilder:rbac:groups="rbac.authorization.k8s.io",namespace=placeholder,resources=rolebindings,verbs=get;list;watch;create;patch
 
@@ -79,14 +84,61 @@ func (r *DiscoveryServiceReconciler) Reconcile(request ctrl.Request) (ctrl.Resul
 		return ctrl.Result{}, err
 	}
 
+	r.ds = ds
+
+	// Cluster scoped resources are deleted by the finalizer when the DiscoveryService
+	// is deleted, or when it no longer needs cluster scoped permissions
+	switch {
+	case controllerutil.ContainsFinalizer(ds, operatorv1alpha1.DiscoveryServiceFinalizer) &&
+		(common.IsBeingDeleted(ds) || !ds.NeedsClusterRole()):
+		if err := r.deleteClusterScopedObjects(ctx, log); err != nil {
+			return ctrl.Result{}, err
+		}
+		controllerutil.RemoveFinalizer(ds, operatorv1alpha1.DiscoveryServiceFinalizer)
+		if err := r.Client.Update(ctx, ds); err != nil {
+			return ctrl.Result{}, err
+		}
+		log.Info("Removed finalizer")
+		return ctrl.Result{}, nil
+
+	case common.IsBeingDeleted(ds):
+		return ctrl.Result{}, nil
+
+	case ds.NeedsClusterRole() && !controllerutil.ContainsFinalizer(ds, operatorv1alpha1.DiscoveryServiceFinalizer):
+		controllerutil.AddFinalizer(ds, operatorv1alpha1.DiscoveryServiceFinalizer)
+		if err := r.Client.Update(ctx, ds); err != nil {
+			return ctrl.Result{}, err
+		}
+		log.Info("Added finalizer")
+		return ctrl.Result{}, nil
+	}
+
+	// The discovery service cannot start with an invalid node hash config
+	if err := ds.GetNodeHash().Validate(); err != nil {
+		log.Error(err, "Invalid 'spec.nodeHash'")
+		return ctrl.Result{}, nil
+	}
+	if _, err := ds.GetNamespaceSelector(); err != nil {
+		log.Error(err, "Invalid 'spec.namespaceSelector'")
+		return ctrl.Result{}, nil
+	}
+	if pdb := ds.GetPodDisruptionBudget(); pdb != nil {
+		if err := pdb.Validate(); err != nil {
+			log.Error(err, "Invalid 'spec.podDisruptionBudget'")
+			return ctrl.Result{}, nil
+		}
+	}
+
 	// Call reconcilers in the proper installation order
 	var result ctrl.Result
-	r.ds = ds
 
 	result, err = r.reconcileCA(ctx, log)
 	if result.Requeue || err != nil {
 		return result, err
 	}
+	// Keep the result of the CA reconcile as it might ask to
+	// requeue after some time to progress with a CA rotation
+	caResult := result
 
 	result, err = r.reconcileServerCertificate(ctx, log)
 	if result.Requeue || err != nil {
@@ -108,15 +160,27 @@ func (r *DiscoveryServiceReconciler) Reconcile(request ctrl.Request) (ctrl.Resul
 		return result, err
 	}
 
-	// Fetch the server certificate to calculate the hash and
-	// populate the deployment's label.
-	// This will trigger rollouts on server certificate changes.
+	result, err = r.reconcileClusterRole(ctx, log)
+	if result.Requeue || err != nil {
+		return result, err
+	}
+
+	result, err = r.reconcileClusterRoleBinding(ctx, log)
+	if result.Requeue || err != nil {
+		return result, err
+	}
+
+	// The discovery service cannot start until the server certificate is issued.
+	// Later renewals are reloaded by the discovery service without a rollout.
 	serverDSC := &operatorv1alpha1.DiscoveryServiceCertificate{}
 	err = r.Client.Get(ctx, types.NamespacedName{Name: getServerCertName(r.ds), Namespace: r.ds.GetNamespace()}, serverDSC)
 	if err != nil {
 		return ctrl.Result{}, err
 	}
 	if !serverDSC.Status.IsReady() {
+		if _, err := r.reconcileStatus(ctx, log); err != nil {
+			return ctrl.Result{}, err
+		}
 		log.Info("Server certificate still not available, requeue")
 		return ctrl.Result{RequeueAfter: 5 * time.Second}, nil
 	}
@@ -124,12 +188,17 @@ func (r *DiscoveryServiceReconciler) Reconcile(request ctrl.Request) (ctrl.Resul
 	dr := reconcilers.NewDeploymentReconciler(ctx, log, r.Client, r.Scheme, r.ds)
 	result, err = dr.Reconcile(
 		types.NamespacedName{Name: OwnedObjectName(r.ds), Namespace: OwnedObjectNamespace(r.ds)},
-		deploymentGeneratorFn(r.ds, *serverDSC.Status.CertificateHash),
+		deploymentGeneratorFn(r.ds),
 	)
 	if result.Requeue || err != nil {
 		return result, err
 	}
 
+	result, err = r.reconcilePodDisruptionBudget(ctx, log)
+	if result.Requeue || err != nil {
+		return result, err
+	}
+
 	result, err = r.reconcileService(ctx, log)
 	if result.Requeue || err != nil {
 		return result, err
@@ -140,7 +209,16 @@ func (r *DiscoveryServiceReconciler) Reconcile(request ctrl.Request) (ctrl.Resul
 		return result, err
 	}
 
-	return ctrl.Result{}, nil
+	result, err = r.reconcileStatus(ctx, log)
+	if result.Requeue || err != nil {
+		return result, err
+	}
+
+	// Requeue at the earliest of the status refresh and the CA rotation
+	if caResult.RequeueAfter > 0 && caResult.RequeueAfter < result.RequeueAfter {
+		return caResult, nil
+	}
+	return result, nil
 }
 
 func (r *DiscoveryServiceReconciler) SetupWithManager(mgr ctrl.Manager) error {
@@ -148,6 +226,7 @@ func (r *DiscoveryServiceReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).For(&operatorv1alpha1.DiscoveryService{}).
 		Owns(&operatorv1alpha1.DiscoveryServiceCertificate{}).
 		Owns(&appsv1.Deployment{}).
+		Owns(&policyv1beta1.PodDisruptionBudget{}).
 		Owns(&corev1.Service{}).
 		Owns(&rbacv1.Role{}).
 		Owns(&rbacv1.RoleBinding{}).
@@ -20,16 +20,24 @@ import (
 	"context"
 
 	operatorv1alpha1 "github.com/3scale/marin3r/apis/operator/v1alpha1"
+	"github.com/3scale/marin3r/pkg/common"
 	discoveryservicecertificate "github.com/3scale/marin3r/pkg/reconcilers/operator/discoveryservicecertificate"
+	"github.com/3scale/marin3r/pkg/reconcilers/operator/discoveryservicecertificate/providers"
+	certmanager_provider "github.com/3scale/marin3r/pkg/reconcilers/operator/discoveryservicecertificate/providers/certmanager"
 	marin3r_provider "github.com/3scale/marin3r/pkg/reconcilers/operator/discoveryservicecertificate/providers/marin3r"
 	"github.com/go-logr/logr"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/discovery"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 )
 
 // DiscoveryServiceCertificateReconciler reconciles a DiscoveryServiceCertificate object
@@ -46,6 +54,7 @@ type DiscoveryServiceCertificateReconciler struct {
 // +kubebuilder:rbac:groups=operator.marin3r.3scale.net,namespace=placeholder,resources=discoveryservicecertificates,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=operator.marin3r.3scale.net,namespace=placeholder,resources=discoveryservicecertificates/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups="core",namespace=placeholder,resources=secrets,verbs=get;list;watch;create;update;patch
+// +kubebuilder:rbac:groups="cert-manager.io",namespace=placeholder,resources=certificates,verbs=get;list;watch;create;update;patch;delete
 
 func (r *DiscoveryServiceCertificateReconciler) Reconcile(request ctrl.Request) (ctrl.Result, error) {
 	ctx := context.Background()
@@ -64,6 +73,25 @@ func (r *DiscoveryServiceCertificateReconciler) Reconcile(request ctrl.Request)
 		return ctrl.Result{}, err
 	}
 
+	// Revoke the certificate before the resource is deleted
+	if common.IsBeingDeleted(dsc) {
+		if !controllerutil.ContainsFinalizer(dsc, operatorv1alpha1.DiscoveryServiceCertificateFinalizer) {
+			return reconcile.Result{}, nil
+		}
+		revocationReconciler := discoveryservicecertificate.NewRevocationReconciler(ctx, log, r.Client, r.Scheme, dsc)
+		if err := revocationReconciler.Revoke(); err != nil {
+			log.Error(err, "unable to revoke certificate")
+			return reconcile.Result{}, err
+		}
+		controllerutil.RemoveFinalizer(dsc, operatorv1alpha1.DiscoveryServiceCertificateFinalizer)
+		if err := r.Client.Update(ctx, dsc); err != nil {
+			log.Error(err, "unable to update DiscoveryServiceCertificate")
+			return reconcile.Result{}, err
+		}
+		log.Info("finalized DiscoveryServiceCertificate resource")
+		return reconcile.Result{}, nil
+	}
+
 	if ok := discoveryservicecertificate.IsInitialized(dsc); !ok {
 		if err := r.Client.Update(ctx, dsc); err != nil {
 			log.Error(err, "unable to update DiscoveryServiceCertificate")
@@ -73,8 +101,12 @@ func (r *DiscoveryServiceCertificateReconciler) Reconcile(request ctrl.Request)
 		return reconcile.Result{}, nil
 	}
 
-	// Only the internal certificate provider is currently supported
-	provider := marin3r_provider.NewCertificateProvider(ctx, log, r.Client, r.Scheme, dsc)
+	var provider providers.CertificateProvider
+	if dsc.Spec.Signer.CertManager != nil {
+		provider = certmanager_provider.NewCertificateProvider(ctx, log, r.Client, r.Scheme, dsc)
+	} else {
+		provider = marin3r_provider.NewCertificateProvider(ctx, log, r.Client, r.Scheme, dsc)
+	}
 
 	certificateReconciler := discoveryservicecertificate.NewCertificateReconciler(ctx, log, r.Client, r.Scheme, dsc, provider)
 	result, err := certificateReconciler.Reconcile()
@@ -99,8 +131,73 @@ func (r *DiscoveryServiceCertificateReconciler) Reconcile(request ctrl.Request)
 
 // SetupWithManager adds the controller to the manager
 func (r *DiscoveryServiceCertificateReconciler) SetupWithManager(mgr ctrl.Manager) error {
-	return ctrl.NewControllerManagedBy(mgr).
+	var err error
+	r.discoveryClient, err = discovery.NewDiscoveryClientForConfig(mgr.GetConfig())
+	if err != nil {
+		return err
+	}
+
+	// cert-manager Certificates are only watched if cert-manager is installed
+	r.certificateWatch, err = r.isCertificateAPIAvailable()
+	if err != nil {
+		return err
+	}
+
+	builder := ctrl.NewControllerManagedBy(mgr).
 		For(&operatorv1alpha1.DiscoveryServiceCertificate{}).
 		Owns(&corev1.Secret{}).
-		Complete(r)
+		Watches(&source.Kind{Type: &corev1.Secret{}},
+			&handler.EnqueueRequestsFromMapFunc{ToRequests: handler.ToRequestsFunc(r.issuerSecretHandler)})
+
+	if r.certificateWatch {
+		certificate := &unstructured.Unstructured{}
+		certificate.SetGroupVersionKind(certmanager_provider.CertificateGVK)
+		builder = builder.Owns(certificate)
+	} else {
+		r.Log.Info("cert-manager Certificate API not found, issuances of the CertManager signer won't trigger reconciles")
+	}
+
+	return builder.Complete(r)
+}
+
+// issuerSecretHandler enqueues the DiscoveryServiceCertificates signed by the CA in a Secret
+// whenever it changes, so the certificates are reissued when the CA is rotated.
+func (r *DiscoveryServiceCertificateReconciler) issuerSecretHandler(o handler.MapObject) []reconcile.Request {
+	list := &operatorv1alpha1.DiscoveryServiceCertificateList{}
+	if err := r.Client.List(context.Background(), list); err != nil {
+		r.Log.Error(err, "unable to list DiscoveryServiceCertificate resources")
+		return []reconcile.Request{}
+	}
+
+	requests := []reconcile.Request{}
+	for _, dsc := range list.Items {
+		if dsc.Spec.Signer.CASigned == nil {
+			continue
+		}
+		issuer := dsc.Spec.Signer.CASigned.SecretRef
+		if issuer.Name == o.Meta.GetName() && issuer.Namespace == o.Meta.GetNamespace() {
+			requests = append(requests, reconcile.Request{
+				NamespacedName: types.NamespacedName{Name: dsc.GetName(), Namespace: dsc.GetNamespace()}})
+		}
+	}
+	return requests
+}
+
+// isCertificateAPIAvailable returns true if the API server
+// serves the cert-manager Certificate resource
+func (r *DiscoveryServiceCertificateReconciler) isCertificateAPIAvailable() (bool, error) {
+	gvk := certmanager_provider.CertificateGVK
+	resources, err := r.discoveryClient.ServerResourcesForGroupVersion(gvk.GroupVersion().String())
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	for _, resource := range resources.APIResources {
+		if resource.Kind == gvk.Kind {
+			return true, nil
+		}
+	}
+	return false, nil
 }
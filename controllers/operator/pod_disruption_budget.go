@@ -0,0 +1,87 @@
+package controllers
+
+import (
+	"context"
+
+	"github.com/go-logr/logr"
+	policyv1beta1 "k8s.io/api/policy/v1beta1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func (r *DiscoveryServiceReconciler) reconcilePodDisruptionBudget(ctx context.Context, log logr.Logger) (reconcile.Result, error) {
+
+	existent := &policyv1beta1.PodDisruptionBudget{}
+	err := r.Client.Get(ctx, types.NamespacedName{Name: OwnedObjectName(r.ds), Namespace: OwnedObjectNamespace(r.ds)}, existent)
+
+	if err != nil {
+		if errors.IsNotFound(err) {
+			if r.ds.GetPodDisruptionBudget() == nil {
+				return reconcile.Result{}, nil
+			}
+			existent = r.genPodDisruptionBudgetObject()
+			if err := controllerutil.SetControllerReference(r.ds, existent, r.Scheme); err != nil {
+				return reconcile.Result{}, err
+			}
+			if err := r.Client.Create(ctx, existent); err != nil {
+				return reconcile.Result{}, err
+			}
+			log.Info("Created PodDisruptionBudget")
+			return reconcile.Result{}, nil
+		}
+		return reconcile.Result{}, err
+	}
+
+	// The PodDisruptionBudget is removed when it is unset in the DiscoveryService
+	if r.ds.GetPodDisruptionBudget() == nil {
+		if err := r.Client.Delete(ctx, existent); err != nil && !errors.IsNotFound(err) {
+			return reconcile.Result{}, err
+		}
+		log.Info("Deleted PodDisruptionBudget")
+		return reconcile.Result{}, nil
+	}
+
+	// PodDisruptionBudget spec is immutable in policy/v1beta1 before
+	// kubernetes 1.15, so it is recreated when it changes
+	desired := r.genPodDisruptionBudgetObject()
+	if !equality.Semantic.DeepEqual(existent.Spec, desired.Spec) {
+		patch := client.MergeFrom(existent.DeepCopy())
+		existent.Spec = desired.Spec
+		if err := r.Client.Patch(ctx, existent, patch); err != nil {
+			if !errors.IsInvalid(err) {
+				return reconcile.Result{}, err
+			}
+			if err := r.Client.Delete(ctx, existent); err != nil && !errors.IsNotFound(err) {
+				return reconcile.Result{}, err
+			}
+			log.Info("Deleted PodDisruptionBudget to recreate it")
+			return reconcile.Result{Requeue: true}, nil
+		}
+		log.Info("Patched PodDisruptionBudget")
+	}
+
+	return reconcile.Result{}, nil
+}
+
+func (r *DiscoveryServiceReconciler) genPodDisruptionBudgetObject() *policyv1beta1.PodDisruptionBudget {
+
+	return &policyv1beta1.PodDisruptionBudget{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      OwnedObjectName(r.ds),
+			Namespace: OwnedObjectNamespace(r.ds),
+			Labels:    Labels(r.ds),
+		},
+		Spec: policyv1beta1.PodDisruptionBudgetSpec{
+			Selector: &metav1.LabelSelector{
+				MatchLabels: Labels(r.ds),
+			},
+			MinAvailable:   r.ds.GetPodDisruptionBudget().MinAvailable,
+			MaxUnavailable: r.ds.GetPodDisruptionBudget().MaxUnavailable,
+		},
+	}
+}
@@ -63,6 +63,17 @@ func (r *DiscoveryServiceReconciler) genRoleObject() *rbacv1.Role {
 				Resources: []string{"secrets"},
 				Verbs:     []string{"get", "list", "watch"},
 			},
+			{
+				// Required for leader election when there is more than one replica
+				APIGroups: []string{corev1.SchemeGroupVersion.Group},
+				Resources: []string{"configmaps"},
+				Verbs:     []string{"get", "list", "watch", "create", "update", "patch"},
+			},
+			{
+				APIGroups: []string{corev1.SchemeGroupVersion.Group},
+				Resources: []string{"events"},
+				Verbs:     []string{"create", "patch"},
+			},
 			{
 				APIGroups: []string{marin3rv1alpha1.GroupVersion.Group},
 				Resources: []string{rbacv1.ResourceAll},
@@ -3,6 +3,7 @@ package controllers
 import (
 	"context"
 	"fmt"
+	"reflect"
 
 	operatorv1alpha1 "github.com/3scale/marin3r/apis/operator/v1alpha1"
 	"github.com/go-logr/logr"
@@ -11,6 +12,7 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/utils/pointer"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 	// cert-manager
@@ -37,13 +39,33 @@ func (r *DiscoveryServiceReconciler) reconcileServerCertificate(ctx context.Cont
 		return reconcile.Result{}, err
 	}
 
-	// Server certificate is not currently reconciled after initial creation, so do nothing
+	// Only the hosts of the server certificate are reconciled after initial creation, as
+	// they change with the external address of the DiscoveryService. The certificate is
+	// reissued when it does not match them.
 	// TODO: validate if status Ready/NotReady (return requeue on NotReady so we wont progress the
 	// deployment of other resources until we have a valid certificate)
+	if hosts := getServerCertHosts(r.ds); !reflect.DeepEqual(cert.Spec.Hosts, hosts) {
+		patch := client.MergeFrom(cert.DeepCopy())
+		cert.Spec.Hosts = hosts
+		if err := r.Client.Patch(ctx, cert, patch); err != nil {
+			return reconcile.Result{}, err
+		}
+		log.Info("Patched server certificate hosts")
+	}
 
 	return reconcile.Result{}, nil
 }
 
+// getServerCertHosts returns the hosts of the server certificate: the
+// address of the Service and the external address, if any
+func getServerCertHosts(ds *operatorv1alpha1.DiscoveryService) []string {
+	hosts := []string{fmt.Sprintf("%s.%s.%s", ds.GetServiceConfig().Name, OwnedObjectNamespace(ds), "svc")}
+	if address := ds.GetServiceConfig().ExternalAddress; address != "" {
+		hosts = append(hosts, address)
+	}
+	return hosts
+}
+
 func getServerCertName(ds *operatorv1alpha1.DiscoveryService) string {
 	return fmt.Sprintf("%s-%s", serverCertSecretNamePrefix, ds.GetName())
 }
@@ -70,7 +92,7 @@ func (r *DiscoveryServiceReconciler) getServerCertObject() *operatorv1alpha1.Dis
 						Namespace: OwnedObjectNamespace(r.ds),
 					}},
 			},
-			Hosts: []string{fmt.Sprintf("%s.%s.%s", r.ds.GetServiceConfig().Name, OwnedObjectNamespace(r.ds), "svc")},
+			Hosts: getServerCertHosts(r.ds),
 			SecretRef: corev1.SecretReference{
 				Name:      getServerCertName(r.ds),
 				Namespace: OwnedObjectNamespace(r.ds),
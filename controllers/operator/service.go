@@ -36,13 +36,41 @@ func (r *DiscoveryServiceReconciler) reconcileService(ctx context.Context, log l
 		return reconcile.Result{}, err
 	}
 
-	// We just reconcile the "Spec" field
+	// We just reconcile the "Spec" field and the annotations
 	desired := r.genServiceObject()
-	// ClusterIP is a field of the Spec populated by the Service controller
+	// ClusterIP, the health check node port and the node ports not set in
+	// the DiscoveryService are fields of the Spec populated by the Service controller
 	desired.Spec.ClusterIP = existent.Spec.ClusterIP
-	if !equality.Semantic.DeepEqual(existent.Spec, desired.Spec) {
+	if desired.Spec.Type == corev1.ServiceTypeLoadBalancer &&
+		desired.Spec.ExternalTrafficPolicy == corev1.ServiceExternalTrafficPolicyTypeLocal {
+		desired.Spec.HealthCheckNodePort = existent.Spec.HealthCheckNodePort
+	}
+	if desired.Spec.Type != corev1.ServiceTypeClusterIP {
+		for i := range desired.Spec.Ports {
+			for _, port := range existent.Spec.Ports {
+				if port.Name == desired.Spec.Ports[i].Name && desired.Spec.Ports[i].NodePort == 0 {
+					desired.Spec.Ports[i].NodePort = port.NodePort
+				}
+			}
+		}
+	}
+
+	annotationsChanged := false
+	for key, value := range desired.GetAnnotations() {
+		if v, ok := existent.GetAnnotations()[key]; !ok || v != value {
+			annotationsChanged = true
+		}
+	}
+
+	if annotationsChanged || !equality.Semantic.DeepEqual(existent.Spec, desired.Spec) {
 		patch := client.MergeFrom(existent.DeepCopy())
 		existent.Spec = desired.Spec
+		if existent.GetAnnotations() == nil {
+			existent.SetAnnotations(map[string]string{})
+		}
+		for key, value := range desired.GetAnnotations() {
+			existent.GetAnnotations()[key] = value
+		}
 		if err := r.Client.Patch(ctx, existent, patch); err != nil {
 			return reconcile.Result{}, err
 		}
@@ -54,21 +82,26 @@ func (r *DiscoveryServiceReconciler) reconcileService(ctx context.Context, log l
 
 func (r *DiscoveryServiceReconciler) genServiceObject() *corev1.Service {
 
-	return &corev1.Service{
+	cfg := r.ds.GetServiceConfig()
+	svc := &corev1.Service{
 		ObjectMeta: metav1.ObjectMeta{
-			Name:      r.ds.GetServiceConfig().Name,
-			Namespace: OwnedObjectNamespace(r.ds),
-			Labels:    Labels(r.ds),
+			Name:        cfg.Name,
+			Namespace:   OwnedObjectNamespace(r.ds),
+			Labels:      Labels(r.ds),
+			Annotations: cfg.Annotations,
 		},
 		Spec: corev1.ServiceSpec{
 			Type: func() corev1.ServiceType {
-				if r.ds.GetServiceConfig().Type == operatorv1alpha1.LoadBalancerType {
+				switch cfg.Type {
+				case operatorv1alpha1.LoadBalancerType:
 					return corev1.ServiceTypeLoadBalancer
+				case operatorv1alpha1.NodePortType:
+					return corev1.ServiceTypeNodePort
 				}
 				return corev1.ServiceTypeClusterIP
 			}(),
 			ClusterIP: func() string {
-				if r.ds.GetServiceConfig().Type == operatorv1alpha1.HeadlessType {
+				if cfg.Type == operatorv1alpha1.HeadlessType {
 					return "None"
 				}
 				return ""
@@ -97,4 +130,38 @@ func (r *DiscoveryServiceReconciler) genServiceObject() *corev1.Service {
 			},
 		},
 	}
+
+	if r.ds.PodCertificatesEnabled() {
+		svc.Spec.Ports = append(svc.Spec.Ports, corev1.ServicePort{
+			Name:       "pod-certs",
+			Port:       int32(r.ds.GetPodCertificatesPort()),
+			Protocol:   corev1.ProtocolTCP,
+			TargetPort: intstr.FromString("pod-certs"),
+		})
+	}
+
+	if cfg.HealthPort != nil {
+		svc.Spec.Ports = append(svc.Spec.Ports, corev1.ServicePort{
+			Name:       "health",
+			Port:       int32(*cfg.HealthPort),
+			Protocol:   corev1.ProtocolTCP,
+			TargetPort: intstr.FromString("health"),
+		})
+	}
+
+	// Options only available to Services exposed outside of the cluster
+	if svc.Spec.Type == corev1.ServiceTypeNodePort || svc.Spec.Type == corev1.ServiceTypeLoadBalancer {
+		svc.Spec.ExternalTrafficPolicy = corev1.ServiceExternalTrafficPolicyTypeCluster
+		if cfg.ExternalTrafficPolicy != "" {
+			svc.Spec.ExternalTrafficPolicy = cfg.ExternalTrafficPolicy
+		}
+		if cfg.NodePort != nil {
+			svc.Spec.Ports[0].NodePort = *cfg.NodePort
+		}
+	}
+	if svc.Spec.Type == corev1.ServiceTypeLoadBalancer {
+		svc.Spec.LoadBalancerSourceRanges = cfg.LoadBalancerSourceRanges
+	}
+
+	return svc
 }
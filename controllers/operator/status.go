@@ -0,0 +1,121 @@
+package controllers
+
+import (
+	"context"
+	"time"
+
+	marin3rv1alpha1 "github.com/3scale/marin3r/apis/marin3r/v1alpha1"
+	operatorv1alpha1 "github.com/3scale/marin3r/apis/operator/v1alpha1"
+	discoveryservice "github.com/3scale/marin3r/pkg/reconcilers/operator/discoveryservice"
+	"github.com/go-logr/logr"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// statusRefreshPeriod is the period at which the status is refreshed to
+// keep the number of connected proxies up to date, as the EnvoyConfigs
+// that report them are not watched by this controller
+const statusRefreshPeriod time.Duration = 30 * time.Second
+
+// reconcileStatus updates the status of the DiscoveryService with the
+// state of the objects it owns and the envoy proxies connected to it
+func (r *DiscoveryServiceReconciler) reconcileStatus(ctx context.Context, log logr.Logger) (reconcile.Result, error) {
+
+	caDSC := &operatorv1alpha1.DiscoveryServiceCertificate{}
+	if exists, err := r.getOwnedObject(ctx, getCACertName(r.ds), caDSC); err != nil {
+		return reconcile.Result{}, err
+	} else if !exists {
+		caDSC = nil
+	}
+
+	serverDSC := &operatorv1alpha1.DiscoveryServiceCertificate{}
+	if exists, err := r.getOwnedObject(ctx, getServerCertName(r.ds), serverDSC); err != nil {
+		return reconcile.Result{}, err
+	} else if !exists {
+		serverDSC = nil
+	}
+
+	dep := &appsv1.Deployment{}
+	if exists, err := r.getOwnedObject(ctx, OwnedObjectName(r.ds), dep); err != nil {
+		return reconcile.Result{}, err
+	} else if !exists {
+		dep = nil
+	}
+
+	svc := &corev1.Service{}
+	if exists, err := r.getOwnedObject(ctx, r.ds.GetServiceConfig().Name, svc); err != nil {
+		return reconcile.Result{}, err
+	} else if !exists {
+		svc = nil
+	}
+
+	connectedProxies, err := r.countConnectedProxies(ctx)
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+
+	if !discoveryservice.IsStatusReconciled(r.ds, caDSC, serverDSC, dep, svc, connectedProxies) {
+		if err := r.Client.Status().Update(ctx, r.ds); err != nil {
+			return reconcile.Result{}, err
+		}
+		log.Info("Status updated", "Ready", r.ds.Status.IsReady())
+	}
+
+	return reconcile.Result{RequeueAfter: statusRefreshPeriod}, nil
+}
+
+// getOwnedObject gets the object with the given name from the namespace of the
+// DiscoveryService. Returns false if the object does not exist yet.
+func (r *DiscoveryServiceReconciler) getOwnedObject(ctx context.Context, name string, o runtime.Object) (bool, error) {
+	err := r.Client.Get(ctx, types.NamespacedName{Name: name, Namespace: OwnedObjectNamespace(r.ds)}, o)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// countConnectedProxies returns the number of envoy proxies connected to the discovery
+// service, as reported in the status of the EnvoyConfigs of the namespaces it serves
+func (r *DiscoveryServiceReconciler) countConnectedProxies(ctx context.Context) (int32, error) {
+
+	namespaces := []string{r.ds.GetNamespace()}
+	if r.ds.ClusterScoped() {
+		selector, err := metav1.LabelSelectorAsSelector(r.ds.Spec.NamespaceSelector)
+		if err != nil {
+			return 0, err
+		}
+		list := &corev1.NamespaceList{}
+		if err := r.Client.List(ctx, list, client.MatchingLabelsSelector{Selector: selector}); err != nil {
+			return 0, err
+		}
+		for _, ns := range list.Items {
+			if ns.GetName() != r.ds.GetNamespace() {
+				namespaces = append(namespaces, ns.GetName())
+			}
+		}
+	}
+
+	var connected int32
+	for _, ns := range namespaces {
+		list := &marin3rv1alpha1.EnvoyConfigList{}
+		if err := r.Client.List(ctx, list, client.InNamespace(ns)); err != nil {
+			return 0, err
+		}
+		for _, ec := range list.Items {
+			if ec.Status.Proxies != nil {
+				connected += ec.Status.Proxies.Connected
+			}
+		}
+	}
+
+	return connected, nil
+}
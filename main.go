@@ -19,9 +19,11 @@ package main
 import (
 	"context"
 	"fmt"
+	"io/ioutil"
 	"os"
 	"runtime"
 	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
 	apimachineryruntime "k8s.io/apimachinery/pkg/runtime"
@@ -39,7 +41,10 @@ import (
 	marin3rcontroller "github.com/3scale/marin3r/controllers/marin3r"
 	operatorcontroller "github.com/3scale/marin3r/controllers/operator"
 	discoveryservice "github.com/3scale/marin3r/pkg/discoveryservice"
+	"github.com/3scale/marin3r/pkg/discoveryservice/podcertificate"
+	"github.com/3scale/marin3r/pkg/envoy"
 	"github.com/3scale/marin3r/pkg/version"
+	"github.com/3scale/marin3r/pkg/webhooks/envoyconfigv1alpha1validator"
 	"github.com/3scale/marin3r/pkg/webhooks/podv1mutator"
 	// +kubebuilder:scaffold:imports
 )
@@ -61,6 +66,23 @@ var (
 	xdssPort                     int
 	xdssTLSServerCertificatePath string
 	xdssTLSCACertificatePath     string
+	xdssRevocationListPath       string
+	xdssTrustBundlePath          string
+	xdssEnableDelta              bool
+	xdssNodeHash                 string
+	xdssNamespaceSelector        string
+	xdssHealthProbeAddr          string
+	xdssEnableTokenAuth          bool
+	xdssAllowUnrestricted        bool
+	xdssEnablePodCertificates    bool
+	xdssPodCertificatesPort      int
+	xdssPodCertificateDuration   time.Duration
+	podCertSignerURLFile         string
+	podCertCAFile                string
+	podCertTokenFile             string
+	podCertOutputDir             string
+	podCertEnvoyAPI              string
+	podCertRenew                 bool
 	webhookPort                  int
 	webhookTLSCertDir            string
 	webhookTLSKeyName            string
@@ -93,10 +115,17 @@ var (
 		Run:   runDiscoveryService,
 	}
 
+	// Pod certificate subcommand
+	podCertificateCmd = &cobra.Command{
+		Use:   "pod-certificate",
+		Short: "Request a per-pod client certificate from the discovery service and, optionally, keep renewing it",
+		Run:   runPodCertificate,
+	}
+
 	// Webhook subcommand
 	webhookCmd = &cobra.Command{
 		Use:   "webhook",
-		Short: "Run the Pod mutating webhook",
+		Short: "Run the Pod mutating webhook and the EnvoyConfig validating webhooks",
 		Run:   runWebhook,
 	}
 )
@@ -116,6 +145,7 @@ func init() {
 	rootCmd.AddCommand(operatorCmd)
 	rootCmd.AddCommand(discoveryServiceCmd)
 	rootCmd.AddCommand(webhookCmd)
+	rootCmd.AddCommand(podCertificateCmd)
 
 	// Global flags
 	rootCmd.PersistentFlags().BoolVar(&debug, "debug", false, "Enable debug logs")
@@ -131,6 +161,33 @@ func init() {
 		fmt.Sprintf("The path where the server certificate '%s' and key '%s' files are located", certificateFile, certificateKeyFile))
 	discoveryServiceCmd.Flags().StringVar(&xdssTLSCACertificatePath, "ca-certificate-path", "/etc/marin3r/tls/ca",
 		fmt.Sprintf("The path where the CA certificate '%s' and key '%s' files are located", certificateFile, certificateKeyFile))
+	discoveryServiceCmd.Flags().StringVar(&xdssRevocationListPath, "revocation-list-path", "/etc/marin3r/tls/crl",
+		fmt.Sprintf("The path where the revocation list '%s' of the CA is located. It is reloaded when it changes.", operatorv1alpha1.RevocationListSecretKey))
+	discoveryServiceCmd.Flags().StringVar(&xdssTrustBundlePath, "trust-bundle-path", "/etc/marin3r/tls/bundle",
+		fmt.Sprintf("The path where the bundle '%s' of CA certificates trusted to authenticate clients is located. It is reloaded when it changes. Defaults to the CA certificate if not present.", operatorv1alpha1.TrustBundleSecretKey))
+	discoveryServiceCmd.Flags().BoolVar(&xdssEnableDelta, "enable-delta-xds", false, "Serve the incremental (delta) variant of the v3 aggregated discovery service.")
+	discoveryServiceCmd.Flags().StringVar(&xdssNodeHash, "node-hash", "",
+		"Comma separated list of the envoy node fields ('id', 'cluster' or 'metadata.<key>') that compose the nodeID. Defaults to the node id.")
+	discoveryServiceCmd.Flags().BoolVar(&xdssEnableTokenAuth, "enable-token-auth", false,
+		fmt.Sprintf("Allow xDS clients to authenticate with a ServiceAccount token issued for the '%s' audience.", operatorv1alpha1.DiscoveryServiceTokenAudience))
+	discoveryServiceCmd.Flags().BoolVar(&xdssAllowUnrestricted, "allow-unrestricted-clients", false,
+		"Allow xDS clients whose certificate was not issued by an EnvoyBootstrap, or by one without allowedNodeIDs, to fetch any nodeID.")
+	discoveryServiceCmd.Flags().BoolVar(&xdssEnablePodCertificates, "enable-pod-certificates", false,
+		"Serve the signer that issues per-pod client certificates to envoy proxies that authenticate with a ServiceAccount token.")
+	discoveryServiceCmd.Flags().IntVar(&xdssPodCertificatesPort, "pod-certificates-port", int(operatorv1alpha1.DefaultPodCertificatesPort),
+		"The port where the pod certificate signer will listen.")
+	discoveryServiceCmd.Flags().DurationVar(&xdssPodCertificateDuration, "pod-certificate-duration",
+		func() time.Duration {
+			d, _ := time.ParseDuration(operatorv1alpha1.DefaultPodCertificateDuration)
+			return d
+		}(),
+		"The duration of the per-pod client certificates.")
+	discoveryServiceCmd.Flags().BoolVar(&enableLeaderElection, "enable-leader-election", false,
+		"Enable leader election between discovery service replicas. All the replicas serve the xDS API but only the leader writes the status of the resources.")
+	discoveryServiceCmd.Flags().StringVar(&xdssNamespaceSelector, "namespace-selector", "",
+		"Serve the EnvoyConfigs of the namespaces whose labels match this label selector, in addition to the WATCH_NAMESPACE. An empty value matches all namespaces.")
+	discoveryServiceCmd.Flags().StringVar(&xdssHealthProbeAddr, "health-probe-addr", "",
+		"The address the liveness (/healthz) and readiness (/readyz) endpoints bind to. They are disabled if empty.")
 	discoveryServiceCmd.Flags().IntVar(&webhookPort, "webhook-port", int(operatorv1alpha1.DefaultWebhookPort), "The port where the pod mutator webhook server will listen.")
 
 	// Webhook flags
@@ -139,6 +196,19 @@ func init() {
 	webhookCmd.Flags().StringVar(&webhookTLSCertName, "tls-cert-name", "apiserver.crt", "The file name of the certificate for the webhook.")
 	webhookCmd.Flags().StringVar(&webhookTLSKeyName, "tls-key-name", "apiserver.key", "The file name of the private key for the webhook.")
 
+	// Pod certificate flags
+	podCertificateCmd.Flags().StringVar(&podCertSignerURLFile, "signer-url-file",
+		fmt.Sprintf("%s/%s", podv1mutator.DefaultEnvoyConfigBasePath, podcertificate.SignerURLFileName),
+		"The file that holds the URL of the discovery service pod certificate signer.")
+	podCertificateCmd.Flags().StringVar(&podCertCAFile, "ca-file", fmt.Sprintf("%s/%s", podv1mutator.DefaultEnvoyConfigBasePath, "ca.crt"),
+		"The CA certificate used to validate the discovery service server certificate.")
+	podCertificateCmd.Flags().StringVar(&podCertTokenFile, "token-file", fmt.Sprintf("%s/%s", podv1mutator.DefaultEnvoyTokenBasePath, "token"),
+		"The ServiceAccount token used to authenticate with the discovery service.")
+	podCertificateCmd.Flags().StringVar(&podCertOutputDir, "output-dir", podv1mutator.DefaultEnvoyTLSBasePath,
+		"The directory where the certificate, key and envoy SDS resource are written.")
+	podCertificateCmd.Flags().StringVar(&podCertEnvoyAPI, "envoy-api-version", podv1mutator.DefaultEnvoyAPIVersion, "Envoy's API version (v2/v3).")
+	podCertificateCmd.Flags().BoolVar(&podCertRenew, "renew", false, "Keep renewing the certificate before it expires.")
+
 }
 
 func main() {
@@ -232,17 +302,73 @@ func runDiscoveryService(cmd *cobra.Command, args []string) {
 	ctx := context.Background()
 
 	mgr := discoveryservice.Manager{
-		Namespace:             os.Getenv("WATCH_NAMESPACE"),
-		XdsServerPort:         xdssPort,
-		MetricsAddr:           metricsAddr,
-		ServerCertificatePath: xdssTLSServerCertificatePath,
-		CACertificatePath:     xdssTLSCACertificatePath,
-		Cfg:                   cfg,
+		Namespace:              os.Getenv("WATCH_NAMESPACE"),
+		XdsServerPort:          xdssPort,
+		MetricsAddr:            metricsAddr,
+		HealthProbeAddr:        xdssHealthProbeAddr,
+		ServerCertificatePath:  xdssTLSServerCertificatePath,
+		CACertificatePath:      xdssTLSCACertificatePath,
+		RevocationListPath:     xdssRevocationListPath,
+		TrustBundlePath:        xdssTrustBundlePath,
+		EnableDeltaXds:         xdssEnableDelta,
+		NodeHash:               xdssNodeHash,
+		EnableTokenAuth:        xdssEnableTokenAuth,
+		TokenAudience:          operatorv1alpha1.DiscoveryServiceTokenAudience,
+		AllowUnrestricted:      xdssAllowUnrestricted,
+		EnablePodCertificates:  xdssEnablePodCertificates,
+		PodCertificatesPort:    xdssPodCertificatesPort,
+		PodCertificateDuration: xdssPodCertificateDuration,
+		EnableLeaderElection:   enableLeaderElection,
+		Cfg:                    cfg,
+	}
+	if cmd.Flags().Changed("namespace-selector") {
+		mgr.NamespaceSelector = &xdssNamespaceSelector
 	}
 
 	mgr.Start(ctx)
 }
 
+func runPodCertificate(cmd *cobra.Command, args []string) {
+
+	ctrl.SetLogger(zap.New(zap.UseDevMode(debug)))
+	logger := ctrl.Log.WithName("podcertificate")
+
+	envoyAPI, err := envoy.ParseAPIVersion(podCertEnvoyAPI)
+	if err != nil {
+		logger.Error(err, "invalid envoy API version")
+		os.Exit(1)
+	}
+	url, err := ioutil.ReadFile(podCertSignerURLFile)
+	if err != nil {
+		logger.Error(err, "unable to read the pod certificate signer URL")
+		os.Exit(1)
+	}
+
+	client := &podcertificate.Client{
+		URL:       strings.TrimSpace(string(url)),
+		CAFile:    podCertCAFile,
+		TokenFile: podCertTokenFile,
+		Directory: podCertOutputDir,
+		EnvoyAPI:  envoyAPI,
+		Logger:    logger,
+	}
+
+	if !podCertRenew {
+		if _, err := client.Issue(context.Background()); err != nil {
+			logger.Error(err, "unable to issue pod certificate")
+			os.Exit(1)
+		}
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-signals.SetupSignalHandler()
+		cancel()
+	}()
+	client.Run(ctx)
+}
+
 func runWebhook(cmd *cobra.Command, args []string) {
 
 	ctrl.SetLogger(zap.New(zap.UseDevMode(debug)))
@@ -270,6 +396,9 @@ func runWebhook(cmd *cobra.Command, args []string) {
 	hookServer.Port = webhookPort
 	ctrl.Log.Info("registering the pod mutating webhook with webhook server")
 	hookServer.Register(podv1mutator.MutatePath, &webhook.Admission{Handler: &podv1mutator.PodMutator{Client: mgr.GetClient()}})
+	ctrl.Log.Info("registering the envoyconfig validating webhooks with webhook server")
+	hookServer.Register(envoyconfigv1alpha1validator.ValidateEnvoyConfigPath, &webhook.Admission{Handler: &envoyconfigv1alpha1validator.EnvoyConfigValidator{}})
+	hookServer.Register(envoyconfigv1alpha1validator.ValidateEnvoyConfigRevisionPath, &webhook.Admission{Handler: &envoyconfigv1alpha1validator.EnvoyConfigValidator{}})
 
 	setupLog.Info("starting the webhook")
 	if err := mgr.Start(stopCh); err != nil {
@@ -0,0 +1,98 @@
+package discoveryservice
+
+import (
+	"context"
+	"crypto/x509"
+	"fmt"
+	"strings"
+
+	xdss "github.com/3scale/marin3r/pkg/discoveryservice/xdss"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	serviceAccountUsernamePrefix = "system:serviceaccount:"
+	// podNameExtraKey is the key of the TokenReview user extra
+	// info that holds the Pod a ServiceAccount token is bound to
+	podNameExtraKey = "authentication.kubernetes.io/pod-name"
+)
+
+// TokenVerifier verifies ServiceAccount tokens and
+// returns the identity of their ServiceAccount
+type TokenVerifier interface {
+	Verify(ctx context.Context, token string) (*xdss.ClientIdentity, error)
+}
+
+// RevocationChecker checks if a client certificate has been revoked
+type RevocationChecker interface {
+	IsRevoked(cert *x509.Certificate) bool
+}
+
+// ClientAuthenticator implements xdss.Authenticator. Clients that send a bearer token are
+// authenticated with the TokenVerifier, if set. The rest of them are identified by their
+// client certificate, that is checked against the RevocationChecker, if set, so streams
+// opened before a certificate is revoked are also rejected.
+type ClientAuthenticator struct {
+	TokenVerifier     TokenVerifier
+	RevocationChecker RevocationChecker
+}
+
+// Authenticate returns the identity of the client of the given stream context
+func (a *ClientAuthenticator) Authenticate(ctx context.Context) (*xdss.ClientIdentity, error) {
+	if token := xdss.TokenFromContext(ctx); token != "" && a.TokenVerifier != nil {
+		return a.TokenVerifier.Verify(ctx, token)
+	}
+	if identity := xdss.IdentityFromContext(ctx); identity != nil {
+		if a.RevocationChecker != nil && a.RevocationChecker.IsRevoked(xdss.PeerCertificateFromContext(ctx)) {
+			return nil, fmt.Errorf("client certificate has been revoked")
+		}
+		return identity, nil
+	}
+	return nil, fmt.Errorf("the client presented neither a certificate nor a token")
+}
+
+// TokenReviewVerifier implements TokenVerifier using the
+// TokenReview API of the Kubernetes API server
+type TokenReviewVerifier struct {
+	Client client.Client
+	// Audiences are the audiences the token must be issued for
+	Audiences []string
+}
+
+// Verify creates a TokenReview for the token and returns the identity
+// of the ServiceAccount if the token is successfully authenticated
+func (v *TokenReviewVerifier) Verify(ctx context.Context, token string) (*xdss.ClientIdentity, error) {
+	tr := &authenticationv1.TokenReview{
+		Spec: authenticationv1.TokenReviewSpec{Token: token, Audiences: v.Audiences},
+	}
+	if err := v.Client.Create(ctx, tr); err != nil {
+		return nil, err
+	}
+	if !tr.Status.Authenticated {
+		return nil, fmt.Errorf("token not authenticated: %s", tr.Status.Error)
+	}
+	identity, err := identityFromUsername(tr.Status.User.Username)
+	if err != nil {
+		return nil, err
+	}
+	if pod := tr.Status.User.Extra[podNameExtraKey]; len(pod) > 0 {
+		identity.Pod = pod[0]
+	}
+	return identity, nil
+}
+
+// identityFromUsername returns the identity for the
+// username of a ServiceAccount, like "system:serviceaccount:ns:name"
+func identityFromUsername(username string) (*xdss.ClientIdentity, error) {
+	parts := strings.Split(strings.TrimPrefix(username, serviceAccountUsernamePrefix), ":")
+	if !strings.HasPrefix(username, serviceAccountUsernamePrefix) || len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return nil, fmt.Errorf("%q is not a ServiceAccount", username)
+	}
+	return &xdss.ClientIdentity{
+		CommonName:     username,
+		ServiceAccount: &types.NamespacedName{Namespace: parts[0], Name: parts[1]},
+		Namespace:      parts[0],
+	}, nil
+}
@@ -0,0 +1,321 @@
+package discoveryservice
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"reflect"
+	"testing"
+	"time"
+
+	xdss "github.com/3scale/marin3r/pkg/discoveryservice/xdss"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+type tokenReviewClient struct {
+	client.Client
+	status authenticationv1.TokenReviewStatus
+}
+
+func (c *tokenReviewClient) Create(ctx context.Context, obj runtime.Object, opts ...client.CreateOption) error {
+	if tr, ok := obj.(*authenticationv1.TokenReview); ok {
+		tr.Status = c.status
+		return nil
+	}
+	return c.Client.Create(ctx, obj, opts...)
+}
+
+func TestTokenReviewVerifier_Verify(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  authenticationv1.TokenReviewStatus
+		want    *xdss.ClientIdentity
+		wantErr bool
+	}{
+		{
+			name: "Returns the identity of an authenticated ServiceAccount",
+			status: authenticationv1.TokenReviewStatus{
+				Authenticated: true,
+				User:          authenticationv1.UserInfo{Username: "system:serviceaccount:test:envoy"},
+			},
+			want: &xdss.ClientIdentity{
+				CommonName:     "system:serviceaccount:test:envoy",
+				ServiceAccount: &types.NamespacedName{Namespace: "test", Name: "envoy"},
+				Namespace:      "test",
+			},
+			wantErr: false,
+		},
+		{
+			name: "Returns the Pod a ServiceAccount token is bound to",
+			status: authenticationv1.TokenReviewStatus{
+				Authenticated: true,
+				User: authenticationv1.UserInfo{
+					Username: "system:serviceaccount:test:envoy",
+					Extra:    map[string]authenticationv1.ExtraValue{"authentication.kubernetes.io/pod-name": {"envoy-1234"}},
+				},
+			},
+			want: &xdss.ClientIdentity{
+				CommonName:     "system:serviceaccount:test:envoy",
+				ServiceAccount: &types.NamespacedName{Namespace: "test", Name: "envoy"},
+				Pod:            "envoy-1234",
+				Namespace:      "test",
+			},
+			wantErr: false,
+		},
+		{
+			name:    "Returns an error for a not authenticated token",
+			status:  authenticationv1.TokenReviewStatus{Authenticated: false, Error: "invalid token"},
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name: "Returns an error for a token that does not belong to a ServiceAccount",
+			status: authenticationv1.TokenReviewStatus{
+				Authenticated: true,
+				User:          authenticationv1.UserInfo{Username: "admin"},
+			},
+			want:    nil,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := &TokenReviewVerifier{
+				Client: &tokenReviewClient{Client: fake.NewFakeClientWithScheme(scheme), status: tt.status},
+			}
+			got, err := v.Verify(context.TODO(), "token")
+			if (err != nil) != tt.wantErr {
+				t.Errorf("TokenReviewVerifier.Verify() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("TokenReviewVerifier.Verify() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+type testRevocationChecker struct {
+	revoked bool
+}
+
+func (c testRevocationChecker) IsRevoked(cert *x509.Certificate) bool {
+	return c.revoked
+}
+
+func TestClientAuthenticator_Authenticate(t *testing.T) {
+	verifier := &TokenReviewVerifier{
+		Client: &tokenReviewClient{
+			Client: fake.NewFakeClientWithScheme(scheme),
+			status: authenticationv1.TokenReviewStatus{
+				Authenticated: true,
+				User:          authenticationv1.UserInfo{Username: "system:serviceaccount:test:envoy"},
+			},
+		},
+	}
+
+	certCtx := peer.NewContext(context.TODO(), &peer.Peer{
+		AuthInfo: credentials.TLSInfo{State: tls.ConnectionState{
+			PeerCertificates: []*x509.Certificate{{Subject: pkix.Name{CommonName: "node1"}, SerialNumber: big.NewInt(1)}},
+		}},
+	})
+
+	tests := []struct {
+		name     string
+		verifier TokenVerifier
+		checker  RevocationChecker
+		ctx      context.Context
+		want     *types.NamespacedName
+		wantErr  bool
+	}{
+		{
+			name:     "Authenticates clients with a certificate",
+			verifier: verifier,
+			checker:  testRevocationChecker{revoked: false},
+			ctx:      certCtx,
+			want:     nil,
+			wantErr:  false,
+		},
+		{
+			name:     "Fails for clients with a revoked certificate",
+			verifier: verifier,
+			checker:  testRevocationChecker{revoked: true},
+			ctx:      certCtx,
+			want:     nil,
+			wantErr:  true,
+		},
+		{
+			name:     "Authenticates clients with a bearer token",
+			verifier: verifier,
+			ctx:      metadata.NewIncomingContext(context.TODO(), metadata.Pairs("authorization", "Bearer token")),
+			want:     &types.NamespacedName{Namespace: "test", Name: "envoy"},
+			wantErr:  false,
+		},
+		{
+			name:     "Fails for clients without token nor certificate",
+			verifier: verifier,
+			ctx:      context.TODO(),
+			want:     nil,
+			wantErr:  true,
+		},
+		{
+			name:     "Ignores tokens if there is no verifier",
+			verifier: nil,
+			ctx:      metadata.NewIncomingContext(context.TODO(), metadata.Pairs("authorization", "Bearer token")),
+			want:     nil,
+			wantErr:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := &ClientAuthenticator{TokenVerifier: tt.verifier, RevocationChecker: tt.checker}
+			got, err := a.Authenticate(tt.ctx)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ClientAuthenticator.Authenticate() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != nil && !reflect.DeepEqual(got.ServiceAccount, tt.want) {
+				t.Errorf("ClientAuthenticator.Authenticate() = %v, want %v", got.ServiceAccount, tt.want)
+			}
+		})
+	}
+}
+
+func encodeSegment(t *testing.T, v interface{}) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func b64(b []byte) string { return base64.RawURLEncoding.EncodeToString(b) }
+
+func pad32(b []byte) []byte { return append(make([]byte, 32-len(b)), b...) }
+
+func TestJWKSVerifier_Verify(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	jwks := JSONWebKeySet{Keys: []JSONWebKey{
+		{KeyID: "rsa", KeyType: "RSA", N: b64(rsaKey.N.Bytes()), E: b64(big.NewInt(int64(rsaKey.E)).Bytes())},
+		{KeyID: "ec", KeyType: "EC", Curve: "P-256", X: b64(ecKey.X.Bytes()), Y: b64(ecKey.Y.Bytes())},
+	}}
+	now := time.Unix(1600000000, 0)
+
+	sign := func(alg, kid string, key interface{}, claims map[string]interface{}) string {
+		signed := encodeSegment(t, map[string]string{"alg": alg, "kid": kid}) + "." + encodeSegment(t, claims)
+		hash := sha256.Sum256([]byte(signed))
+		var signature []byte
+		switch k := key.(type) {
+		case *rsa.PrivateKey:
+			if signature, err = rsa.SignPKCS1v15(rand.Reader, k, crypto.SHA256, hash[:]); err != nil {
+				t.Fatal(err)
+			}
+		case *ecdsa.PrivateKey:
+			r, s, err := ecdsa.Sign(rand.Reader, k, hash[:])
+			if err != nil {
+				t.Fatal(err)
+			}
+			signature = append(pad32(r.Bytes()), pad32(s.Bytes())...)
+		}
+		return signed + "." + b64(signature)
+	}
+	claims := func(overrides map[string]interface{}) map[string]interface{} {
+		c := map[string]interface{}{
+			"iss": "https://kubernetes.default.svc",
+			"sub": "system:serviceaccount:test:envoy",
+			"aud": []string{"marin3r"},
+			"exp": now.Add(time.Hour).Unix(),
+			"nbf": now.Add(-time.Hour).Unix(),
+			"kubernetes.io": map[string]interface{}{
+				"namespace":      "test",
+				"serviceaccount": map[string]string{"name": "envoy"},
+			},
+		}
+		for k, v := range overrides {
+			c[k] = v
+		}
+		return c
+	}
+
+	tests := []struct {
+		name    string
+		token   string
+		wantErr bool
+	}{
+		{"Verifies RS256 tokens", sign("RS256", "rsa", rsaKey, claims(nil)), false},
+		{"Verifies ES256 tokens", sign("ES256", "ec", ecKey, claims(nil)), false},
+		{"Verifies tokens with a single audience", sign("RS256", "rsa", rsaKey, claims(map[string]interface{}{"aud": "marin3r"})), false},
+		{"Fails for tokens signed by other keys", sign("RS256", "rsa", otherKey, claims(nil)), true},
+		{"Fails for expired tokens", sign("RS256", "rsa", rsaKey, claims(map[string]interface{}{"exp": now.Add(-time.Minute).Unix()})), true},
+		{"Fails for tokens not yet valid", sign("RS256", "rsa", rsaKey, claims(map[string]interface{}{"nbf": now.Add(time.Minute).Unix()})), true},
+		{"Fails for other audiences", sign("RS256", "rsa", rsaKey, claims(map[string]interface{}{"aud": []string{"api"}})), true},
+		{"Fails for other issuers", sign("RS256", "rsa", rsaKey, claims(map[string]interface{}{"iss": "other"})), true},
+		{"Fails for subjects other than ServiceAccounts", sign("RS256", "rsa", rsaKey, claims(map[string]interface{}{"sub": "admin"})), true},
+		{"Fails for unsupported algorithms", sign("HS256", "rsa", rsaKey, claims(nil)), true},
+		{"Fails for malformed tokens", "xxxx", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := &JWKSVerifier{
+				Keys:      jwks,
+				Issuer:    "https://kubernetes.default.svc",
+				Audiences: []string{"marin3r"},
+				Now:       func() time.Time { return now },
+			}
+			got, err := v.Verify(context.TODO(), tt.token)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("JWKSVerifier.Verify() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got.ServiceAccount, &types.NamespacedName{Namespace: "test", Name: "envoy"}) {
+				t.Errorf("JWKSVerifier.Verify() = %v", got.ServiceAccount)
+			}
+		})
+	}
+
+	t.Run("Returns the Pod a token is bound to", func(t *testing.T) {
+		v := &JWKSVerifier{Keys: jwks, Audiences: []string{"marin3r"}, Now: func() time.Time { return now }}
+		got, err := v.Verify(context.TODO(), sign("RS256", "rsa", rsaKey, claims(map[string]interface{}{
+			"kubernetes.io": map[string]interface{}{
+				"namespace":      "test",
+				"serviceaccount": map[string]string{"name": "envoy"},
+				"pod":            map[string]string{"name": "envoy-1234"},
+			},
+		})))
+		if err != nil {
+			t.Fatalf("JWKSVerifier.Verify() error = %v", err)
+		}
+		if got.Pod != "envoy-1234" {
+			t.Errorf("JWKSVerifier.Verify() Pod = %v, want envoy-1234", got.Pod)
+		}
+	})
+}
@@ -0,0 +1,93 @@
+package discoveryservice
+
+import (
+	"context"
+	"fmt"
+
+	marin3rv1alpha1 "github.com/3scale/marin3r/apis/marin3r/v1alpha1"
+	xdss "github.com/3scale/marin3r/pkg/discoveryservice/xdss"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// EnvoyBootstrapAuthorizer implements xdss.Authorizer using the policies defined in the
+// EnvoyBootstrap resources. The EnvoyBootstraps that issued the client certificate are
+// those whose client certificate Secret name matches the identity of the client.
+type EnvoyBootstrapAuthorizer struct {
+	Client client.Client
+	// Namespace is the namespace where EnvoyBootstraps are
+	// looked up. All namespaces are used if empty.
+	Namespace string
+	// IsolateNamespaces only allows clients to fetch the nodeIDs of their own
+	// namespace, which is Namespace for clients whose identity does not have
+	// one, and looks up EnvoyBootstraps in it. It is used by discovery
+	// services that serve several namespaces.
+	IsolateNamespaces bool
+	// AllowUnrestricted allows the clients whose certificate was not issued by an
+	// EnvoyBootstrap, or was issued by one without AllowedNodeIDs, to fetch any nodeID
+	AllowUnrestricted bool
+}
+
+// Authorize returns an error if none of the EnvoyBootstraps that issued the client
+// certificate allows fetching the given nodeID. EnvoyBootstraps without AllowedNodeIDs
+// allow fetching any nodeID of their own namespace, and clients whose certificate was
+// not issued by an EnvoyBootstrap are denied, unless AllowUnrestricted is set. Clients
+// that authenticate with a ServiceAccount token can only fetch nodeIDs of their own namespace.
+func (a *EnvoyBootstrapAuthorizer) Authorize(identity *xdss.ClientIdentity, namespace, nodeID string) error {
+	if identity == nil {
+		return fmt.Errorf("the client did not present a certificate")
+	}
+
+	if identity.ServiceAccount != nil {
+		if namespace != identity.ServiceAccount.Namespace {
+			return fmt.Errorf("ServiceAccount %q is not allowed to fetch nodeIDs in namespace %q", identity.ServiceAccount, namespace)
+		}
+		return nil
+	}
+
+	lookupNamespace := a.Namespace
+	if a.IsolateNamespaces {
+		if identity.Namespace != "" {
+			lookupNamespace = identity.Namespace
+		}
+		if namespace != lookupNamespace {
+			return fmt.Errorf("client %q is not allowed to fetch nodeIDs in namespace %q", identity.CommonName, namespace)
+		}
+	}
+
+	list := &marin3rv1alpha1.EnvoyBootstrapList{}
+	if err := a.Client.List(context.Background(), list, client.InNamespace(lookupNamespace)); err != nil {
+		return err
+	}
+
+	policies := 0
+	for _, eb := range list.Items {
+		if eb.Spec.ClientCertificate == nil || !identity.HasName(eb.Spec.ClientCertificate.SecretName) {
+			continue
+		}
+		if len(eb.Spec.AllowedNodeIDs) == 0 {
+			if a.AllowUnrestricted {
+				continue
+			}
+			policies++
+			if namespace == "" || namespace == eb.GetNamespace() {
+				return nil
+			}
+			continue
+		}
+		policies++
+
+		if namespace != "" && namespace != eb.GetNamespace() {
+			continue
+		}
+		for _, allowed := range eb.Spec.AllowedNodeIDs {
+			if allowed == nodeID {
+				return nil
+			}
+		}
+	}
+
+	if policies > 0 || !a.AllowUnrestricted {
+		return fmt.Errorf("client %q is not allowed to fetch nodeID %q in namespace %q", identity.CommonName, nodeID, namespace)
+	}
+	return nil
+}
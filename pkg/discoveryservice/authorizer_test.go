@@ -0,0 +1,180 @@
+package discoveryservice
+
+import (
+	"testing"
+
+	marin3rv1alpha1 "github.com/3scale/marin3r/apis/marin3r/v1alpha1"
+	xdss "github.com/3scale/marin3r/pkg/discoveryservice/xdss"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func testEnvoyBootstrap(name, namespace, secretName string, allowed ...string) *marin3rv1alpha1.EnvoyBootstrap {
+	return &marin3rv1alpha1.EnvoyBootstrap{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+		Spec: marin3rv1alpha1.EnvoyBootstrapSpec{
+			ClientCertificate: &marin3rv1alpha1.ClientCertificate{SecretName: secretName},
+			AllowedNodeIDs:    allowed,
+		},
+	}
+}
+
+func TestEnvoyBootstrapAuthorizer_Authorize(t *testing.T) {
+	type args struct {
+		identity  *xdss.ClientIdentity
+		namespace string
+		nodeID    string
+	}
+	tests := []struct {
+		name              string
+		objs              []runtime.Object
+		args              args
+		allowUnrestricted bool
+		wantErr           bool
+	}{
+		{
+			name:    "Denies clients without certificate",
+			objs:    []runtime.Object{},
+			args:    args{nil, "test", "node1"},
+			wantErr: true,
+		},
+		{
+			name:    "Denies clients whose certificate was not issued by an EnvoyBootstrap",
+			objs:    []runtime.Object{testEnvoyBootstrap("eb", "test", "other-cert", "node2")},
+			args:    args{&xdss.ClientIdentity{CommonName: "cert"}, "test", "node1"},
+			wantErr: true,
+		},
+		{
+			name:              "Allows clients whose certificate was not issued by an EnvoyBootstrap if unrestricted clients are allowed",
+			objs:              []runtime.Object{testEnvoyBootstrap("eb", "test", "other-cert", "node2")},
+			args:              args{&xdss.ClientIdentity{CommonName: "cert"}, "test", "node1"},
+			allowUnrestricted: true,
+			wantErr:           false,
+		},
+		{
+			name:    "Allows clients whose EnvoyBootstrap has no policy to fetch nodeIDs in its namespace",
+			objs:    []runtime.Object{testEnvoyBootstrap("eb", "test", "cert")},
+			args:    args{&xdss.ClientIdentity{CommonName: "cert"}, "test", "node1"},
+			wantErr: false,
+		},
+		{
+			name:    "Denies clients whose EnvoyBootstrap has no policy to fetch nodeIDs in other namespaces",
+			objs:    []runtime.Object{testEnvoyBootstrap("eb", "test", "cert")},
+			args:    args{&xdss.ClientIdentity{CommonName: "cert"}, "other", "node1"},
+			wantErr: true,
+		},
+		{
+			name:              "Allows clients whose EnvoyBootstrap has no policy to fetch any nodeID if unrestricted clients are allowed",
+			objs:              []runtime.Object{testEnvoyBootstrap("eb", "test", "cert")},
+			args:              args{&xdss.ClientIdentity{CommonName: "cert"}, "other", "node1"},
+			allowUnrestricted: true,
+			wantErr:           false,
+		},
+		{
+			name:              "Enforces the policies if unrestricted clients are allowed",
+			objs:              []runtime.Object{testEnvoyBootstrap("eb", "test", "cert", "node1")},
+			args:              args{&xdss.ClientIdentity{CommonName: "cert"}, "test", "node2"},
+			allowUnrestricted: true,
+			wantErr:           true,
+		},
+		{
+			name:    "Allows nodeIDs in the policy",
+			objs:    []runtime.Object{testEnvoyBootstrap("eb", "test", "cert", "node1", "node2")},
+			args:    args{&xdss.ClientIdentity{CommonName: "cert"}, "test", "node2"},
+			wantErr: false,
+		},
+		{
+			name:    "Matches the identity by DNS name",
+			objs:    []runtime.Object{testEnvoyBootstrap("eb", "test", "cert", "node1")},
+			args:    args{&xdss.ClientIdentity{CommonName: "xx", DNSNames: []string{"cert"}}, "test", "node1"},
+			wantErr: false,
+		},
+		{
+			name:    "Denies nodeIDs not in the policy",
+			objs:    []runtime.Object{testEnvoyBootstrap("eb", "test", "cert", "node1")},
+			args:    args{&xdss.ClientIdentity{CommonName: "cert"}, "test", "node2"},
+			wantErr: true,
+		},
+		{
+			name:    "Denies nodeIDs in other namespaces",
+			objs:    []runtime.Object{testEnvoyBootstrap("eb", "test", "cert", "node1")},
+			args:    args{&xdss.ClientIdentity{CommonName: "cert"}, "other", "node1"},
+			wantErr: true,
+		},
+		{
+			name:    "Allows ServiceAccounts to fetch nodeIDs in their namespace",
+			objs:    []runtime.Object{},
+			args:    args{&xdss.ClientIdentity{ServiceAccount: &types.NamespacedName{Namespace: "test", Name: "envoy"}}, "test", "node1"},
+			wantErr: false,
+		},
+		{
+			name:    "Denies ServiceAccounts to fetch nodeIDs in other namespaces",
+			objs:    []runtime.Object{},
+			args:    args{&xdss.ClientIdentity{ServiceAccount: &types.NamespacedName{Namespace: "test", Name: "envoy"}}, "other", "node1"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := &EnvoyBootstrapAuthorizer{Client: fake.NewFakeClientWithScheme(scheme, tt.objs...), Namespace: "test", AllowUnrestricted: tt.allowUnrestricted}
+			if err := a.Authorize(tt.args.identity, tt.args.namespace, tt.args.nodeID); (err != nil) != tt.wantErr {
+				t.Errorf("EnvoyBootstrapAuthorizer.Authorize() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestEnvoyBootstrapAuthorizer_Authorize_IsolateNamespaces(t *testing.T) {
+	type args struct {
+		identity  *xdss.ClientIdentity
+		namespace string
+		nodeID    string
+	}
+	tests := []struct {
+		name    string
+		objs    []runtime.Object
+		args    args
+		wantErr bool
+	}{
+		{
+			name:    "Allows clients to fetch nodeIDs in their namespace",
+			objs:    []runtime.Object{testEnvoyBootstrap("eb", "other", "cert")},
+			args:    args{&xdss.ClientIdentity{CommonName: "cert", Namespace: "other"}, "other", "node1"},
+			wantErr: false,
+		},
+		{
+			name:    "Denies clients to fetch nodeIDs in other namespaces",
+			objs:    []runtime.Object{},
+			args:    args{&xdss.ClientIdentity{CommonName: "cert", Namespace: "other"}, "test", "node1"},
+			wantErr: true,
+		},
+		{
+			name:    "Clients without namespace belong to the discovery service namespace",
+			objs:    []runtime.Object{},
+			args:    args{&xdss.ClientIdentity{CommonName: "cert"}, "other", "node1"},
+			wantErr: true,
+		},
+		{
+			name:    "Looks up the policies in the namespace of the client",
+			objs:    []runtime.Object{testEnvoyBootstrap("eb", "other", "cert", "node1")},
+			args:    args{&xdss.ClientIdentity{CommonName: "cert", Namespace: "other"}, "other", "node2"},
+			wantErr: true,
+		},
+		{
+			name:    "Ignores the policies of other namespaces",
+			objs:    []runtime.Object{testEnvoyBootstrap("eb", "test", "cert", "node1"), testEnvoyBootstrap("eb", "other", "cert")},
+			args:    args{&xdss.ClientIdentity{CommonName: "cert", Namespace: "other"}, "other", "node2"},
+			wantErr: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := &EnvoyBootstrapAuthorizer{Client: fake.NewFakeClientWithScheme(scheme, tt.objs...), Namespace: "test", IsolateNamespaces: true}
+			if err := a.Authorize(tt.args.identity, tt.args.namespace, tt.args.nodeID); (err != nil) != tt.wantErr {
+				t.Errorf("EnvoyBootstrapAuthorizer.Authorize() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,111 @@
+package discoveryservice
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"path/filepath"
+	"sync"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/go-logr/logr"
+)
+
+// CertificateReloader holds the certificate and key in a directory and watches the
+// directory to reload them when they change. Mounted Secrets are updated by the kubelet
+// swapping a symlink in the directory, so certificate renewals are picked up without
+// restarting the discovery service. An invalid certificate or key is ignored, keeping
+// the last valid ones.
+type CertificateReloader struct {
+	Directory string
+	Logger    logr.Logger
+
+	mu          sync.RWMutex
+	certificate *tls.Certificate
+}
+
+// NewCertificateReloader returns a CertificateReloader with
+// the certificate and key loaded from the given directory
+func NewCertificateReloader(directory string, logger logr.Logger) (*CertificateReloader, error) {
+	cr := &CertificateReloader{Directory: directory, Logger: logger}
+	if err := cr.reload(); err != nil {
+		return nil, err
+	}
+	return cr, nil
+}
+
+// GetCertificate can be used as the tls.Config GetCertificate function
+// to serve the current certificate
+func (cr *CertificateReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	cr.mu.RLock()
+	defer cr.mu.RUnlock()
+	return cr.certificate, nil
+}
+
+// KeyPair returns the current certificate and key
+func (cr *CertificateReloader) KeyPair() (*x509.Certificate, interface{}) {
+	cr.mu.RLock()
+	defer cr.mu.RUnlock()
+	return cr.certificate.Leaf, cr.certificate.PrivateKey
+}
+
+// Start watches the directory and reloads the certificate and
+// key on changes until the stop channel is closed
+func (cr *CertificateReloader) Start(stopCh <-chan struct{}) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	defer watcher.Close()
+
+	if err := watcher.Add(cr.Directory); err != nil {
+		return err
+	}
+
+	for {
+		select {
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+			if event.Op == fsnotify.Chmod {
+				continue
+			}
+			if err := cr.reload(); err != nil {
+				// The files might be in the middle of an update, keep the current ones
+				cr.Logger.V(1).Info("Unable to reload certificate", "Directory", cr.Directory, "Reason", err.Error())
+			}
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			cr.Logger.Error(err, "Certificate watcher error", "Directory", cr.Directory)
+		case <-stopCh:
+			return nil
+		}
+	}
+}
+
+// reload loads the certificate and key from the directory
+func (cr *CertificateReloader) reload() error {
+	certificate, err := tls.LoadX509KeyPair(
+		filepath.Join(cr.Directory, tlsCertificateFile),
+		filepath.Join(cr.Directory, tlsCertificateKeyFile),
+	)
+	if err != nil {
+		return err
+	}
+	if certificate.Leaf, err = x509.ParseCertificate(certificate.Certificate[0]); err != nil {
+		return fmt.Errorf("unable to parse certificate: %s", err.Error())
+	}
+
+	cr.mu.Lock()
+	defer cr.mu.Unlock()
+	if cr.certificate != nil && bytes.Equal(cr.certificate.Certificate[0], certificate.Certificate[0]) {
+		return nil
+	}
+	cr.certificate = &certificate
+	cr.Logger.Info("Loaded certificate", "Directory", cr.Directory, "SerialNumber", certificate.Leaf.SerialNumber.String())
+	return nil
+}
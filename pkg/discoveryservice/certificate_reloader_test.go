@@ -0,0 +1,108 @@
+package discoveryservice
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/3scale/marin3r/pkg/util/pki"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+func TestCertificateReloader(t *testing.T) {
+	dir, err := ioutil.TempDir("", "certificate")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// Writes the certificate the way the kubelet updates mounted Secrets,
+	// swapping a symlink to a directory with the new files
+	write := func(crt, key []byte) {
+		data, err := ioutil.TempDir(dir, "..data_")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(filepath.Join(data, tlsCertificateFile), crt, 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(filepath.Join(data, tlsCertificateKeyFile), key, 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Symlink(filepath.Base(data), filepath.Join(dir, "..data_tmp")); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Rename(filepath.Join(dir, "..data_tmp"), filepath.Join(dir, "..data")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	generate := func() ([]byte, []byte) {
+		crt, key, err := pki.GenerateCertificate(nil, nil, "server", time.Hour, true, false, "localhost")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return crt, key
+	}
+
+	if _, err := NewCertificateReloader(dir, ctrl.Log.WithName("test")); err == nil {
+		t.Fatalf("NewCertificateReloader() loads a directory without certificate")
+	}
+
+	crt, key := generate()
+	write(crt, key)
+	for _, file := range []string{tlsCertificateFile, tlsCertificateKeyFile} {
+		if err := os.Symlink(filepath.Join("..data", file), filepath.Join(dir, file)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cr, err := NewCertificateReloader(dir, ctrl.Log.WithName("test"))
+	if err != nil {
+		t.Fatalf("NewCertificateReloader() error = %v", err)
+	}
+	first, _ := cr.KeyPair()
+	if want, _ := pki.LoadX509Certificate(crt); !first.Equal(want) {
+		t.Fatalf("NewCertificateReloader() certificate not loaded")
+	}
+
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	errCh := make(chan error, 1)
+	go func() { errCh <- cr.Start(stopCh) }()
+	// Give the watcher some time to start
+	time.Sleep(100 * time.Millisecond)
+
+	waitForCertificate := func(want []byte) bool {
+		wantCert, _ := pki.LoadX509Certificate(want)
+		for i := 0; i < 50; i++ {
+			got, err := cr.GetCertificate(nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.Leaf.Equal(wantCert) {
+				return true
+			}
+			time.Sleep(20 * time.Millisecond)
+		}
+		return false
+	}
+
+	crt, key = generate()
+	write(crt, key)
+	if !waitForCertificate(crt) {
+		t.Errorf("CertificateReloader does not reload the certificate when it changes")
+	}
+
+	write([]byte("xxxx"), []byte("xxxx"))
+	time.Sleep(100 * time.Millisecond)
+	if !waitForCertificate(crt) {
+		t.Errorf("CertificateReloader discards the last valid certificate when the files are invalid")
+	}
+
+	select {
+	case err := <-errCh:
+		t.Errorf("CertificateReloader.Start() error = %v", err)
+	default:
+	}
+}
@@ -0,0 +1,235 @@
+package discoveryservice
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	xdss "github.com/3scale/marin3r/pkg/discoveryservice/xdss"
+)
+
+// JSONWebKey is a public key of a JSON Web Key Set, as served
+// in the /openid/v1/jwks endpoint of the Kubernetes API server
+type JSONWebKey struct {
+	KeyID     string `json:"kid"`
+	KeyType   string `json:"kty"`
+	Algorithm string `json:"alg,omitempty"`
+	N         string `json:"n,omitempty"`
+	E         string `json:"e,omitempty"`
+	Curve     string `json:"crv,omitempty"`
+	X         string `json:"x,omitempty"`
+	Y         string `json:"y,omitempty"`
+}
+
+// JSONWebKeySet is a set of JSON Web Keys
+type JSONWebKeySet struct {
+	Keys []JSONWebKey `json:"keys"`
+}
+
+// JWKSVerifier implements TokenVerifier offline, checking the signature of ServiceAccount
+// tokens against a JSON Web Key Set. Only RS256 and ES256 signed tokens are supported.
+type JWKSVerifier struct {
+	Keys JSONWebKeySet
+	// Issuer is the expected issuer of the tokens. Not checked if empty.
+	Issuer string
+	// Audiences are the audiences the token must be issued for, at least one must match
+	Audiences []string
+	// Now returns the current time. time.Now is used if nil.
+	Now func() time.Time
+}
+
+type jwtHeader struct {
+	Algorithm string `json:"alg"`
+	KeyID     string `json:"kid"`
+}
+
+type jwtClaims struct {
+	Issuer     string          `json:"iss"`
+	Subject    string          `json:"sub"`
+	Audience   json.RawMessage `json:"aud"`
+	Expiration int64           `json:"exp"`
+	NotBefore  int64           `json:"nbf"`
+	Kubernetes *struct {
+		Namespace      string `json:"namespace"`
+		ServiceAccount struct {
+			Name string `json:"name"`
+		} `json:"serviceaccount"`
+		Pod *struct {
+			Name string `json:"name"`
+		} `json:"pod,omitempty"`
+	} `json:"kubernetes.io,omitempty"`
+}
+
+// audiences returns the "aud" claim, that can either be a string or a list of strings
+func (c *jwtClaims) audiences() []string {
+	var aud []string
+	if err := json.Unmarshal(c.Audience, &aud); err == nil {
+		return aud
+	}
+	var single string
+	if err := json.Unmarshal(c.Audience, &single); err == nil {
+		return []string{single}
+	}
+	return nil
+}
+
+// ParseJSONWebKeySet parses a JSON Web Key Set
+func ParseJSONWebKeySet(data []byte) (JSONWebKeySet, error) {
+	jwks := JSONWebKeySet{}
+	if err := json.Unmarshal(data, &jwks); err != nil {
+		return JSONWebKeySet{}, err
+	}
+	return jwks, nil
+}
+
+// Verify checks the signature and claims of the token and
+// returns the identity of its ServiceAccount
+func (v *JWKSVerifier) Verify(ctx context.Context, token string) (*xdss.ClientIdentity, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("malformed token")
+	}
+
+	header := jwtHeader{}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, fmt.Errorf("malformed token header: %w", err)
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("malformed token signature: %w", err)
+	}
+	if err := v.verifySignature(header, []byte(parts[0]+"."+parts[1]), signature); err != nil {
+		return nil, err
+	}
+
+	claims := jwtClaims{}
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, fmt.Errorf("malformed token claims: %w", err)
+	}
+	if err := v.verifyClaims(claims); err != nil {
+		return nil, err
+	}
+
+	identity, err := identityFromUsername(claims.Subject)
+	if err != nil {
+		return nil, err
+	}
+	if claims.Kubernetes != nil &&
+		(claims.Kubernetes.Namespace != identity.ServiceAccount.Namespace ||
+			claims.Kubernetes.ServiceAccount.Name != identity.ServiceAccount.Name) {
+		return nil, fmt.Errorf("token subject does not match its kubernetes.io claims")
+	}
+	if claims.Kubernetes != nil && claims.Kubernetes.Pod != nil {
+		identity.Pod = claims.Kubernetes.Pod.Name
+	}
+	return identity, nil
+}
+
+func (v *JWKSVerifier) verifySignature(header jwtHeader, signed, signature []byte) error {
+	hash := sha256.Sum256(signed)
+
+	for _, key := range v.Keys.Keys {
+		if header.KeyID != "" && key.KeyID != header.KeyID {
+			continue
+		}
+		pub, err := key.publicKey()
+		if err != nil {
+			// Skip keys of unsupported types
+			continue
+		}
+
+		switch header.Algorithm {
+		case "RS256":
+			if rsaKey, ok := pub.(*rsa.PublicKey); ok && rsa.VerifyPKCS1v15(rsaKey, crypto.SHA256, hash[:], signature) == nil {
+				return nil
+			}
+		case "ES256":
+			if ecKey, ok := pub.(*ecdsa.PublicKey); ok && len(signature) == 64 {
+				r := new(big.Int).SetBytes(signature[:32])
+				s := new(big.Int).SetBytes(signature[32:])
+				if ecdsa.Verify(ecKey, hash[:], r, s) {
+					return nil
+				}
+			}
+		default:
+			return fmt.Errorf("unsupported token signing algorithm %q", header.Algorithm)
+		}
+	}
+
+	return fmt.Errorf("invalid token signature")
+}
+
+func (v *JWKSVerifier) verifyClaims(claims jwtClaims) error {
+	now := time.Now()
+	if v.Now != nil {
+		now = v.Now()
+	}
+
+	if v.Issuer != "" && claims.Issuer != v.Issuer {
+		return fmt.Errorf("unexpected token issuer %q", claims.Issuer)
+	}
+	if claims.Expiration == 0 || now.Unix() >= claims.Expiration {
+		return fmt.Errorf("token expired")
+	}
+	if claims.NotBefore != 0 && now.Unix() < claims.NotBefore {
+		return fmt.Errorf("token not yet valid")
+	}
+	if len(v.Audiences) > 0 {
+		for _, aud := range claims.audiences() {
+			for _, want := range v.Audiences {
+				if aud == want {
+					return nil
+				}
+			}
+		}
+		return fmt.Errorf("token not issued for audiences %v", v.Audiences)
+	}
+	return nil
+}
+
+func (k JSONWebKey) publicKey() (crypto.PublicKey, error) {
+	switch k.KeyType {
+	case "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+	case "EC":
+		if k.Curve != "P-256" {
+			return nil, fmt.Errorf("unsupported key curve %q", k.Curve)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := base64.RawURLEncoding.DecodeString(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}, nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", k.KeyType)
+	}
+}
+
+func decodeSegment(segment string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
@@ -5,26 +5,34 @@ import (
 	"crypto/tls"
 	"crypto/x509"
 	"fmt"
-	"io/ioutil"
+	"net/http"
 	"os"
+	"path/filepath"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	marin3rv1alpha1 "github.com/3scale/marin3r/apis/marin3r/v1alpha1"
+	operatorv1alpha1 "github.com/3scale/marin3r/apis/operator/v1alpha1"
 	marin3rcontroller "github.com/3scale/marin3r/controllers/marin3r"
+	xdss "github.com/3scale/marin3r/pkg/discoveryservice/xdss"
 	envoy "github.com/3scale/marin3r/pkg/envoy"
 	rollback "github.com/3scale/marin3r/pkg/reconcilers/marin3r/envoyconfig/rollback"
-	"github.com/go-logr/logr"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
 	util_runtime "k8s.io/apimachinery/pkg/util/runtime"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	"k8s.io/client-go/rest"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	"sigs.k8s.io/controller-runtime/pkg/manager/signals"
 )
 
 const (
 	tlsCertificateFile    = "tls.crt"
 	tlsCertificateKeyFile = "tls.key"
+	leaderElectionID      = "discoveryservice.marin3r.3scale.net"
 )
 
 var (
@@ -42,14 +50,54 @@ func init() {
 type Manager struct {
 	// The namespace to watch for EnvoyConfig resources
 	Namespace string
+	// NamespaceSelector is a label selector of the namespaces whose EnvoyConfig resources
+	// are served in addition to Namespace. Snapshots and secrets of each namespace are kept
+	// isolated and clients can only fetch the resources of their own namespace. Only
+	// Namespace is served if nil, and all namespaces if empty.
+	NamespaceSelector *string
 	// The xDS server port
 	XdsServerPort int
 	// The mutating webhook server port
 	MetricsAddr string
+	// HealthProbeAddr is the address where the liveness and readiness
+	// endpoints are served. They are not served if empty.
+	HealthProbeAddr string
 	// The directory where server certificate and key are located
 	ServerCertificatePath string
 	// The directory where the CA used to authenticate clients with the xDS server is
 	CACertificatePath string
+	// The directory where the revocation list of the CA is. Client certificates
+	// in the list are rejected. It is reloaded when it changes.
+	RevocationListPath string
+	// The directory where the bundle of CA certificates trusted to authenticate clients
+	// is. It holds both the current and the new CA during CA rotations. It is reloaded
+	// when it changes and defaults to the CA certificate when not present.
+	TrustBundlePath string
+	// EnableDeltaXds enables the incremental variant of the xDS protocol
+	EnableDeltaXds bool
+	// NodeHash is the comma separated list of envoy node fields
+	// that compose the nodeID. Defaults to the node id.
+	NodeHash string
+	// EnableTokenAuth allows xDS clients to authenticate with a ServiceAccount
+	// token instead of a client certificate. Tokens are verified with the
+	// TokenReview API and must be issued for the TokenAudience.
+	EnableTokenAuth bool
+	// TokenAudience is the audience ServiceAccount tokens must be issued for
+	TokenAudience string
+	// AllowUnrestricted allows the clients whose certificate is not restricted
+	// by the allowedNodeIDs of an EnvoyBootstrap to fetch any nodeID
+	AllowUnrestricted bool
+	// EnablePodCertificates serves the pod certificate signer, that issues per-pod
+	// client certificates to envoy proxies that authenticate with a ServiceAccount token
+	EnablePodCertificates bool
+	// PodCertificatesPort is the port where the pod certificate signer listens
+	PodCertificatesPort int
+	// PodCertificateDuration is the lifetime of the per-pod client certificates
+	PodCertificateDuration time.Duration
+	// EnableLeaderElection elects a leader among the discovery service replicas. All
+	// the replicas write the published EnvoyConfigRevisions to their xDS cache and
+	// serve envoy proxies, but only the leader writes to the API.
+	EnableLeaderElection bool
 	// Cfg is the config to connect to the k8s API server
 	Cfg *rest.Config
 }
@@ -58,22 +106,110 @@ type Manager struct {
 // EnvoyConfigRevision controller, the xDS server and the mutating webhook server
 func (dsm *Manager) Start(ctx context.Context) {
 
+	// Serving several namespaces requires a cache of all namespaces
+	var err error
+	cacheNamespace := dsm.Namespace
+	var selector labels.Selector
+	if dsm.NamespaceSelector != nil {
+		cacheNamespace = ""
+		selector, err = labels.Parse(*dsm.NamespaceSelector)
+		if err != nil {
+			setupLog.Error(err, "invalid namespace selector")
+			os.Exit(1)
+		}
+	}
+
 	mgr, err := ctrl.NewManager(dsm.Cfg, ctrl.Options{
-		Scheme:             scheme,
-		MetricsBindAddress: dsm.MetricsAddr,
-		LeaderElection:     false,
-		Namespace:          dsm.Namespace,
+		Scheme:                  scheme,
+		MetricsBindAddress:      dsm.MetricsAddr,
+		HealthProbeBindAddress:  dsm.HealthProbeAddr,
+		LeaderElection:          dsm.EnableLeaderElection,
+		LeaderElectionID:        leaderElectionID,
+		LeaderElectionNamespace: dsm.Namespace,
+		Namespace:               cacheNamespace,
 	})
 	if err != nil {
 		setupLog.Error(err, "unable to start manager")
 		os.Exit(1)
 	}
 
+	if dsm.HealthProbeAddr != "" {
+		if err := mgr.AddHealthzCheck("ping", healthz.Ping); err != nil {
+			setupLog.Error(err, "unable to add liveness check")
+			os.Exit(1)
+		}
+	}
+
+	var servesNamespace marin3rcontroller.ServesNamespaceFunc
+	if selector != nil {
+		servesNamespace = (&NamespaceSelector{Client: mgr.GetClient(), Namespace: dsm.Namespace, Selector: selector}).Serves
+	}
+
+	// Without leader election this replica is always the leader
+	isLeader := func() bool { return true }
+	if dsm.EnableLeaderElection {
+		isLeader = func() bool {
+			select {
+			case <-mgr.Elected():
+				return true
+			default:
+				return false
+			}
+		}
+	}
+
 	// watch for syscalls
 	stopCh := signals.SetupSignalHandler()
 
 	var wait sync.WaitGroup
 
+	nodeHash, err := xdss.ParseNodeHash(dsm.NodeHash)
+	if err != nil {
+		setupLog.Error(err, "invalid node hash")
+		os.Exit(1)
+	}
+
+	trustBundle := &TrustBundle{
+		Path:              filepath.Join(dsm.TrustBundlePath, operatorv1alpha1.TrustBundleSecretKey),
+		CACertificatePath: filepath.Join(dsm.CACertificatePath, tlsCertificateFile),
+		Logger:            setupLog.WithName("trustbundle"),
+	}
+	if len(trustBundle.Certificates()) == 0 {
+		setupLog.Info("No CA certificates to authenticate clients")
+		os.Exit(1)
+	}
+
+	// The server certificate is reloaded when it is renewed
+	serverCertificate, err := NewCertificateReloader(dsm.ServerCertificatePath, setupLog.WithName("servercertificate"))
+	if err != nil {
+		setupLog.Error(err, "Could not load server certificate")
+		os.Exit(1)
+	}
+	wait.Add(1)
+	go func() {
+		defer wait.Done()
+		if err := serverCertificate.Start(stopCh); err != nil {
+			setupLog.Error(err, "unable to watch the server certificate, shutting down")
+			os.Exit(1)
+		}
+	}()
+
+	revocationList := &RevocationList{
+		Path:    filepath.Join(dsm.RevocationListPath, operatorv1alpha1.RevocationListSecretKey),
+		Issuers: trustBundle,
+		Logger:  setupLog.WithName("revocation"),
+	}
+
+	// Client certificates are verified against the trust bundle in VerifyPeerCertificate
+	// instead of ClientCAs, so changes in the trust bundle apply to new connections
+	clientAuth := tls.RequireAnyClientCert
+	authenticator := &ClientAuthenticator{RevocationChecker: revocationList}
+	if dsm.EnableTokenAuth {
+		// Clients that present a token don't need a client certificate
+		clientAuth = tls.RequestClientCert
+		authenticator.TokenVerifier = &TokenReviewVerifier{Client: mgr.GetClient(), Audiences: []string{dsm.TokenAudience}}
+	}
+
 	// Start envoy's aggregated discovery service
 	xdss := NewDualXdsServer(
 		ctx,
@@ -87,14 +223,33 @@ func (dsm *Manager) Start(ctx context.Context) {
 				tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
 				tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
 			},
-			Certificates: []tls.Certificate{loadCertificate(dsm.ServerCertificatePath, setupLog)},
-			ClientAuth:   tls.RequireAndVerifyClientCert,
-			ClientCAs:    loadCA(dsm.CACertificatePath, setupLog),
+			GetCertificate:        serverCertificate.GetCertificate,
+			ClientAuth:            clientAuth,
+			VerifyPeerCertificate: verifyPeerCertificate(trustBundle, revocationList),
 		},
-		rollback.OnError(mgr.GetClient()),
+		onError(isLeader, rollback.OnError(mgr.GetClient()), rollback.ReportError(mgr.GetClient())),
+		dsm.EnableDeltaXds,
+		dsm.Namespace,
+		nodeHash,
+		authenticator,
+		&EnvoyBootstrapAuthorizer{Client: mgr.GetClient(), Namespace: dsm.Namespace, IsolateNamespaces: dsm.NamespaceSelector != nil,
+			AllowUnrestricted: dsm.AllowUnrestricted},
 		setupLog,
 	)
 
+	// Envoy proxies are only sent to this replica once its xDS server
+	// listens and the informers have synced the served resources
+	if dsm.HealthProbeAddr != "" {
+		if err := mgr.AddReadyzCheck("xds", xdss.Ready); err != nil {
+			setupLog.Error(err, "unable to add readiness check")
+			os.Exit(1)
+		}
+		if err := mgr.AddReadyzCheck("informers", informersSynced(mgr.GetCache(), stopCh)); err != nil {
+			setupLog.Error(err, "unable to add readiness check")
+			os.Exit(1)
+		}
+	}
+
 	wait.Add(1)
 	go func() {
 		defer wait.Done()
@@ -104,42 +259,89 @@ func (dsm *Manager) Start(ctx context.Context) {
 		}
 	}()
 
+	if dsm.EnablePodCertificates {
+		// The CA is reloaded when it is rotated
+		ca, err := NewCertificateReloader(dsm.CACertificatePath, setupLog.WithName("cacertificate"))
+		if err != nil {
+			setupLog.Error(err, "Could not load CA certificate")
+			os.Exit(1)
+		}
+		wait.Add(1)
+		go func() {
+			defer wait.Done()
+			if err := ca.Start(stopCh); err != nil {
+				setupLog.Error(err, "unable to watch the CA certificate, shutting down")
+				os.Exit(1)
+			}
+		}()
+
+		signer := &PodCertificateSigner{
+			TokenVerifier: &TokenReviewVerifier{Client: mgr.GetClient(), Audiences: []string{dsm.TokenAudience}},
+			CA:            ca,
+			Duration:      dsm.PodCertificateDuration,
+			Logger:        ctrl.Log.WithName("podcertificates"),
+		}
+		signerTLS := &tls.Config{
+			MinVersion:     tls.VersionTLS12,
+			GetCertificate: serverCertificate.GetCertificate,
+		}
+
+		wait.Add(1)
+		go func() {
+			defer wait.Done()
+			if err := signer.Start(stopCh, uint(dsm.PodCertificatesPort), signerTLS); err != nil {
+				setupLog.Error(err, "pod certificate signer returned an unrecoverable error, shutting down")
+				os.Exit(1)
+			}
+		}()
+	}
+
 	// Start controllers
 	if err := (&marin3rcontroller.EnvoyConfigReconciler{
-		Client: mgr.GetClient(),
-		Log:    ctrl.Log.WithName("controllers").WithName("envoyconfig"),
-		Scheme: mgr.GetScheme(),
+		Client:          mgr.GetClient(),
+		Log:             ctrl.Log.WithName("controllers").WithName("envoyconfig"),
+		Scheme:          mgr.GetScheme(),
+		ServesNamespace: servesNamespace,
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "envoyconfig")
 		os.Exit(1)
 	}
 
 	if err := (&marin3rcontroller.EnvoyConfigRevisionReconciler{
-		Client:     mgr.GetClient(),
-		Log:        ctrl.Log.WithName("controllers").WithName(fmt.Sprintf("envoyconfigrevision_%s", string(envoy.APIv2))),
-		Scheme:     mgr.GetScheme(),
-		XdsCache:   xdss.GetCache(envoy.APIv2),
-		APIVersion: envoy.APIv2,
+		Client:          mgr.GetClient(),
+		Log:             ctrl.Log.WithName("controllers").WithName(fmt.Sprintf("envoyconfigrevision_%s", string(envoy.APIv2))),
+		Scheme:          mgr.GetScheme(),
+		XdsCache:        xdss.GetCache(envoy.APIv2),
+		XdsStats:        xdss.GetStats(envoy.APIv2),
+		APIVersion:      envoy.APIv2,
+		IsLeader:        isLeader,
+		Replicated:      dsm.EnableLeaderElection,
+		ServesNamespace: servesNamespace,
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", fmt.Sprintf("envoyconfigrevision_%s", string(envoy.APIv2)))
 		os.Exit(1)
 	}
 
 	if err := (&marin3rcontroller.EnvoyConfigRevisionReconciler{
-		Client:     mgr.GetClient(),
-		Log:        ctrl.Log.WithName("controllers").WithName(fmt.Sprintf("envoyconfigrevision_%s", string(envoy.APIv3))),
-		Scheme:     mgr.GetScheme(),
-		XdsCache:   xdss.GetCache(envoy.APIv3),
-		APIVersion: envoy.APIv3,
+		Client:          mgr.GetClient(),
+		Log:             ctrl.Log.WithName("controllers").WithName(fmt.Sprintf("envoyconfigrevision_%s", string(envoy.APIv3))),
+		Scheme:          mgr.GetScheme(),
+		XdsCache:        xdss.GetCache(envoy.APIv3),
+		XdsStats:        xdss.GetStats(envoy.APIv3),
+		APIVersion:      envoy.APIv3,
+		IsLeader:        isLeader,
+		Replicated:      dsm.EnableLeaderElection,
+		ServesNamespace: servesNamespace,
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", fmt.Sprintf("envoyconfigrevision_%s", string(envoy.APIv3)))
 		os.Exit(1)
 	}
 
 	if err := (&marin3rcontroller.SecretReconciler{
-		Client: mgr.GetClient(),
-		Log:    ctrl.Log.WithName("controllers").WithName("secret"),
-		Scheme: mgr.GetScheme(),
+		Client:          mgr.GetClient(),
+		Log:             ctrl.Log.WithName("controllers").WithName("secret"),
+		Scheme:          mgr.GetScheme(),
+		ServesNamespace: servesNamespace,
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "secret")
 		os.Exit(1)
@@ -160,29 +362,47 @@ func (dsm *Manager) Start(ctx context.Context) {
 	setupLog.Info("Controller has shut down")
 }
 
-func loadCertificate(directory string, logger logr.Logger) tls.Certificate {
-	certificate, err := tls.LoadX509KeyPair(
-		fmt.Sprintf("%s/%s", directory, tlsCertificateFile),
-		fmt.Sprintf("%s/%s", directory, tlsCertificateKeyFile),
-	)
-	if err != nil {
-		logger.Error(err, "Could not load server certificate")
-		os.Exit(1)
+// onError returns the function the xDS server calls when a gateway returns a NACK. The
+// leader taints the failing revision while the rest of the replicas report the error to it.
+func onError(isLeader func() bool, taint, report onErrorFn) onErrorFn {
+	return func(namespace, nodeID string, rType envoy.Type, version, msg string, envoyAPI envoy.APIVersion) error {
+		if isLeader() {
+			return taint(namespace, nodeID, rType, version, msg, envoyAPI)
+		}
+		return report(namespace, nodeID, rType, version, msg, envoyAPI)
 	}
-	return certificate
 }
 
-func loadCA(directory string, logger logr.Logger) *x509.CertPool {
-	certPool := x509.NewCertPool()
-	if bs, err := ioutil.ReadFile(fmt.Sprintf("%s/%s", directory, tlsCertificateFile)); err != nil {
-		logger.Error(err, "Failed to read client ca cert")
-		os.Exit(1)
-	} else {
-		ok := certPool.AppendCertsFromPEM(bs)
-		if !ok {
-			logger.Error(err, "Failed to append client certs")
-			os.Exit(1)
+// informersSynced returns a healthz.Checker that fails until the
+// informers of the given cache have synced after the manager starts
+func informersSynced(c cache.Cache, stopCh <-chan struct{}) healthz.Checker {
+	var synced int32
+	go func() {
+		if c.WaitForCacheSync(stopCh) {
+			atomic.StoreInt32(&synced, 1)
+		}
+	}()
+	return func(req *http.Request) error {
+		if atomic.LoadInt32(&synced) == 0 {
+			return fmt.Errorf("informers have not synced")
+		}
+		return nil
+	}
+}
+
+// verifyPeerCertificate returns a tls.Config VerifyPeerCertificate function that
+// verifies client certificates, if any, against the trust bundle and rejects
+// revoked ones
+func verifyPeerCertificate(trustBundle *TrustBundle, revocationList *RevocationList) func([][]byte, [][]*x509.Certificate) error {
+	return func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+		if len(rawCerts) == 0 {
+			// The ClientAuth policy decides if clients without certificate are allowed
+			return nil
+		}
+		chains, err := trustBundle.Verify(rawCerts)
+		if err != nil {
+			return err
 		}
+		return revocationList.VerifyPeerCertificate(rawCerts, chains)
 	}
-	return certPool
 }
@@ -0,0 +1,43 @@
+package discoveryservice
+
+import (
+	"testing"
+	"time"
+
+	"sigs.k8s.io/controller-runtime/pkg/cache/informertest"
+)
+
+func Test_informersSynced(t *testing.T) {
+	tests := []struct {
+		name    string
+		synced  bool
+		wantErr bool
+	}{
+		{
+			name:    "Passes once the informers have synced",
+			synced:  true,
+			wantErr: false,
+		},
+		{
+			name:    "Fails while the informers have not synced",
+			synced:  false,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stopCh := make(chan struct{})
+			defer close(stopCh)
+			check := informersSynced(&informertest.FakeInformers{Synced: &tt.synced}, stopCh)
+
+			var err error
+			deadline := time.Now().Add(time.Second)
+			for err = check(nil); err != nil && time.Now().Before(deadline); err = check(nil) {
+				time.Sleep(10 * time.Millisecond)
+			}
+			if (err != nil) != tt.wantErr {
+				t.Errorf("informersSynced() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
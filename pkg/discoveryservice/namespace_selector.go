@@ -0,0 +1,32 @@
+package discoveryservice
+
+import (
+	"context"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// NamespaceSelector decides which namespaces a discovery service serves: its own
+// namespace and all the namespaces whose labels match the Selector
+type NamespaceSelector struct {
+	Client client.Client
+	// Namespace is the namespace of the discovery service
+	Namespace string
+	Selector  labels.Selector
+}
+
+// Serves returns true if the discovery service
+// serves the EnvoyConfigs of the given namespace
+func (ns *NamespaceSelector) Serves(ctx context.Context, namespace string) (bool, error) {
+	if namespace == ns.Namespace {
+		return true, nil
+	}
+	o := &corev1.Namespace{}
+	if err := ns.Client.Get(ctx, types.NamespacedName{Name: namespace}, o); err != nil {
+		return false, client.IgnoreNotFound(err)
+	}
+	return ns.Selector.Matches(labels.Set(o.GetLabels())), nil
+}
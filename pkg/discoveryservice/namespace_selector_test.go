@@ -0,0 +1,47 @@
+package discoveryservice
+
+import (
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestNamespaceSelector_Serves(t *testing.T) {
+	tests := []struct {
+		name      string
+		selector  labels.Selector
+		namespace string
+		want      bool
+	}{
+		{"Serves its own namespace", labels.SelectorFromSet(labels.Set{"key": "other"}), "test", true},
+		{"Serves namespaces that match the selector", labels.SelectorFromSet(labels.Set{"key": "value"}), "labeled", true},
+		{"Does not serve namespaces that don't match the selector", labels.SelectorFromSet(labels.Set{"key": "other"}), "labeled", false},
+		{"An empty selector serves all namespaces", labels.Everything(), "unlabeled", true},
+		{"Does not serve namespaces that don't exist", labels.Everything(), "missing", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ns := &NamespaceSelector{
+				Client: fake.NewFakeClientWithScheme(scheme,
+					&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "test"}},
+					&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "labeled", Labels: map[string]string{"key": "value"}}},
+					&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "unlabeled"}},
+				),
+				Namespace: "test",
+				Selector:  tt.selector,
+			}
+			got, err := ns.Serves(context.Background(), tt.namespace)
+			if err != nil {
+				t.Errorf("NamespaceSelector.Serves() error = %v", err)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("NamespaceSelector.Serves() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
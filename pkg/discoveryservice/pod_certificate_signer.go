@@ -0,0 +1,121 @@
+package discoveryservice
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/3scale/marin3r/pkg/discoveryservice/podcertificate"
+	xdss "github.com/3scale/marin3r/pkg/discoveryservice/xdss"
+	"github.com/3scale/marin3r/pkg/util/pki"
+	"github.com/go-logr/logr"
+)
+
+const (
+	// maxCertificateRequestSize is the maximum size of the certificate requests
+	maxCertificateRequestSize = 16 * 1024
+)
+
+// KeyPairSource returns the current certificate and private key of a CA
+type KeyPairSource interface {
+	KeyPair() (*x509.Certificate, interface{})
+}
+
+// PodCertificateSigner is an http.Handler that issues per-pod client certificates for
+// envoy proxies. The proxies send a PEM encoded certificate request in the body and
+// authenticate with a ServiceAccount token bound to their Pod. The certificates are
+// signed by the discovery service CA and carry the identity of the Pod in a URI SAN.
+type PodCertificateSigner struct {
+	TokenVerifier TokenVerifier
+	// CA is the CA that signs the certificates, read on every request
+	// so CA rotations don't require restarting the signer
+	CA KeyPairSource
+	// Duration is the lifetime of the issued certificates
+	Duration time.Duration
+	Logger   logr.Logger
+}
+
+func (s *PodCertificateSigner) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	token := bearerToken(r)
+	if token == "" {
+		http.Error(w, "missing bearer token", http.StatusUnauthorized)
+		return
+	}
+	identity, err := s.TokenVerifier.Verify(r.Context(), token)
+	if err != nil {
+		s.Logger.Error(err, "Client not authenticated")
+		http.Error(w, "invalid token", http.StatusUnauthorized)
+		return
+	}
+	if identity.ServiceAccount == nil || identity.Pod == "" {
+		http.Error(w, "the token is not bound to a Pod", http.StatusForbidden)
+		return
+	}
+
+	body, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, maxCertificateRequestSize))
+	if err != nil {
+		http.Error(w, "unable to read the certificate request", http.StatusBadRequest)
+		return
+	}
+	csr, err := pki.LoadCertificateRequest(body)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("invalid certificate request: %s", err), http.StatusBadRequest)
+		return
+	}
+
+	caCert, caKey := s.CA.KeyPair()
+	cert, err := pki.SignCertificateRequest(caCert, caKey, csr, identity.Pod, s.Duration,
+		xdss.PodIdentityURI(identity.ServiceAccount.Namespace, identity.ServiceAccount.Name, identity.Pod))
+	if err != nil {
+		s.Logger.Error(err, "Unable to sign certificate request", "Pod", identity.Pod, "Namespace", identity.ServiceAccount.Namespace)
+		http.Error(w, "unable to sign the certificate request", http.StatusInternalServerError)
+		return
+	}
+
+	s.Logger.Info("Issued pod certificate", "Pod", identity.Pod, "Namespace", identity.ServiceAccount.Namespace)
+	w.Header().Set("Content-Type", "application/x-pem-file")
+	w.Write(cert)
+}
+
+// Start serves the PodCertificateSigner over https in the given port
+// until the stop channel is closed or the server returns an error
+func (s *PodCertificateSigner) Start(stopCh <-chan struct{}, port uint, tlsConfig *tls.Config) error {
+	mux := http.NewServeMux()
+	mux.Handle(podcertificate.SignerPath, s)
+	srv := &http.Server{Addr: fmt.Sprintf(":%d", port), Handler: mux, TLSConfig: tlsConfig}
+
+	errCh := make(chan error)
+	go func() {
+		if err := srv.ListenAndServeTLS("", ""); err != nil && err != http.ErrServerClosed {
+			errCh <- err
+		}
+	}()
+	s.Logger.Info(fmt.Sprintf("Pod certificate signer listening on %d", port))
+
+	select {
+	case <-stopCh:
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		return srv.Shutdown(ctx)
+	case err := <-errCh:
+		return err
+	}
+}
+
+// bearerToken returns the bearer token in the Authorization header of the request
+func bearerToken(r *http.Request) string {
+	const prefix = "Bearer "
+	if header := r.Header.Get("Authorization"); len(header) > len(prefix) && header[:len(prefix)] == prefix {
+		return header[len(prefix):]
+	}
+	return ""
+}
@@ -0,0 +1,121 @@
+package discoveryservice
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/3scale/marin3r/pkg/discoveryservice/podcertificate"
+	xdss "github.com/3scale/marin3r/pkg/discoveryservice/xdss"
+	"github.com/3scale/marin3r/pkg/envoy"
+	"github.com/3scale/marin3r/pkg/util/pki"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+type testTokenVerifier map[string]*xdss.ClientIdentity
+
+func (v testTokenVerifier) Verify(ctx context.Context, token string) (*xdss.ClientIdentity, error) {
+	if identity, ok := v[token]; ok {
+		return identity, nil
+	}
+	return nil, fmt.Errorf("invalid token")
+}
+
+type testKeyPair struct {
+	cert *x509.Certificate
+	key  interface{}
+}
+
+func (kp testKeyPair) KeyPair() (*x509.Certificate, interface{}) { return kp.cert, kp.key }
+
+func TestPodCertificateSigner(t *testing.T) {
+	caPEM, caKeyPEM, err := pki.GenerateCertificate(nil, nil, "ca", time.Hour, false, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ca, _ := pki.LoadX509Certificate(caPEM)
+	caKey, _ := pki.DecodePrivateKeyBytes(caKeyPEM)
+
+	signer := &PodCertificateSigner{
+		TokenVerifier: testTokenVerifier{
+			"pod-token": {ServiceAccount: &types.NamespacedName{Namespace: "test", Name: "envoy"}, Pod: "pod-1"},
+			"sa-token":  {ServiceAccount: &types.NamespacedName{Namespace: "test", Name: "envoy"}},
+		},
+		CA:       testKeyPair{ca, caKey},
+		Duration: time.Hour,
+		Logger:   ctrl.Log,
+	}
+	mux := http.NewServeMux()
+	mux.Handle(podcertificate.SignerPath, signer)
+	srv := httptest.NewTLSServer(mux)
+	defer srv.Close()
+
+	dir, err := ioutil.TempDir("", "pod-certificate")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	caFile := filepath.Join(dir, "ca.crt")
+	ioutil.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw}), 0644)
+
+	tests := []struct {
+		name    string
+		token   string
+		wantErr bool
+	}{
+		{"Issues a certificate for a token bound to a Pod", "pod-token", false},
+		{"Refuses tokens not bound to a Pod", "sa-token", true},
+		{"Refuses invalid tokens", "xxxx", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tokenFile := filepath.Join(dir, "token")
+			ioutil.WriteFile(tokenFile, []byte(tt.token), 0644)
+			out, _ := ioutil.TempDir(dir, "out")
+
+			c := &podcertificate.Client{
+				URL:       srv.URL + podcertificate.SignerPath,
+				CAFile:    caFile,
+				TokenFile: tokenFile,
+				Directory: out,
+				EnvoyAPI:  envoy.APIv3,
+				Logger:    ctrl.Log,
+			}
+			cert, err := c.Issue(context.TODO())
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Client.Issue() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			roots := x509.NewCertPool()
+			roots.AddCert(ca)
+			if _, err := cert.Verify(x509.VerifyOptions{Roots: roots, KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}}); err != nil {
+				t.Errorf("Client.Issue() certificate not signed by the CA: %v", err)
+			}
+			if len(cert.URIs) != 1 || cert.URIs[0].String() != "spiffe://marin3r.3scale.net/ns/test/sa/envoy/pod/pod-1" {
+				t.Errorf("Client.Issue() certificate URIs = %v", cert.URIs)
+			}
+			for _, file := range []string{"tls.crt", "tls.key", "tls_certificate_sds_secret.json"} {
+				if _, err := os.Stat(filepath.Join(out, file)); err != nil {
+					t.Errorf("Client.Issue() file %s not written: %v", file, err)
+				}
+			}
+			sds, _ := ioutil.ReadFile(filepath.Join(out, "tls_certificate_sds_secret.json"))
+			if !strings.Contains(string(sds), filepath.Join(out, "tls.crt")) {
+				t.Errorf("Client.Issue() SDS resource = %s", sds)
+			}
+		})
+	}
+}
@@ -0,0 +1,196 @@
+package podcertificate
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/3scale/marin3r/pkg/envoy"
+	envoy_bootstrap "github.com/3scale/marin3r/pkg/envoy/bootstrap"
+	envoy_bootstrap_options "github.com/3scale/marin3r/pkg/envoy/bootstrap/options"
+	"github.com/3scale/marin3r/pkg/util/pki"
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+)
+
+const (
+	// SignerPath is the path where the discovery service pod certificate signer is served
+	SignerPath = "/v1/pod-certificate"
+	// SignerURLFileName is the key of the bootstrap ConfigMap
+	// that holds the URL of the pod certificate signer
+	SignerURLFileName = "pod-certificate-signer-url"
+	// retryInterval is the time to wait before retrying a failed request
+	retryInterval = 10 * time.Second
+)
+
+// Client requests per-pod client certificates from the discovery service and writes them,
+// along with the SDS resource that envoy uses to load them, to a directory. Files are
+// replaced atomically so envoy picks up renewed certificates when the SDS resource moves.
+type Client struct {
+	// URL is the URL of the discovery service pod certificate signer
+	URL string
+	// CAFile is the CA certificate used to validate the signer server certificate
+	CAFile string
+	// TokenFile is the ServiceAccount token used to authenticate with the signer
+	TokenFile string
+	// Directory is where the certificate, key and SDS resource are written
+	Directory string
+	// EnvoyAPI is the envoy API version of the SDS resource
+	EnvoyAPI envoy.APIVersion
+	Logger   logr.Logger
+}
+
+// Issue requests a new certificate and writes it to the Directory
+func (c *Client) Issue(ctx context.Context) (*x509.Certificate, error) {
+
+	key, err := pki.GeneratePrivateKey()
+	if err != nil {
+		return nil, err
+	}
+	csr, err := pki.GenerateCertificateRequest(key, "envoy")
+	if err != nil {
+		return nil, err
+	}
+
+	certPEM, err := c.sign(ctx, csr)
+	if err != nil {
+		return nil, err
+	}
+	cert, err := pki.LoadX509Certificate(certPEM)
+	if err != nil {
+		return nil, err
+	}
+
+	keyBytes, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyBytes})
+
+	sds, err := envoy_bootstrap.NewConfig(c.EnvoyAPI, envoy_bootstrap_options.ConfigOptions{
+		XdsClientCertificatePath:    filepath.Join(c.Directory, corev1.TLSCertKey),
+		XdsClientCertificateKeyPath: filepath.Join(c.Directory, corev1.TLSPrivateKeyKey),
+	}).GenerateSdsResources()
+	if err != nil {
+		return nil, err
+	}
+
+	// The SDS resource is written last, as envoy reloads
+	// the certificate files when the SDS resource is moved
+	if err := writeFile(filepath.Join(c.Directory, corev1.TLSPrivateKeyKey), keyPEM, 0600); err != nil {
+		return nil, err
+	}
+	if err := writeFile(filepath.Join(c.Directory, corev1.TLSCertKey), certPEM, 0644); err != nil {
+		return nil, err
+	}
+	for file, content := range sds {
+		if err := writeFile(filepath.Join(c.Directory, file), []byte(content), 0644); err != nil {
+			return nil, err
+		}
+	}
+
+	return cert, nil
+}
+
+// Run issues a certificate and renews it when two thirds of its lifetime have
+// passed, until the context is cancelled. Failed requests are retried.
+func (c *Client) Run(ctx context.Context) error {
+	for {
+		wait := retryInterval
+		cert, err := c.Issue(ctx)
+		if err != nil {
+			c.Logger.Error(err, "Unable to issue pod certificate")
+		} else {
+			c.Logger.Info("Issued pod certificate", "NotAfter", cert.NotAfter)
+			wait = time.Until(RenewalTime(cert))
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(wait):
+		}
+	}
+}
+
+// RenewalTime returns the time when a certificate should be
+// renewed, once two thirds of its lifetime have passed
+func RenewalTime(cert *x509.Certificate) time.Time {
+	return cert.NotBefore.Add(cert.NotAfter.Sub(cert.NotBefore) * 2 / 3)
+}
+
+func (c *Client) sign(ctx context.Context, csr []byte) ([]byte, error) {
+
+	ca, err := ioutil.ReadFile(c.CAFile)
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(ca) {
+		return nil, fmt.Errorf("no certificates found in %s", c.CAFile)
+	}
+
+	// The token is read for every request as it is rotated by the kubelet
+	token, err := ioutil.ReadFile(c.TokenFile)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, c.URL, bytes.NewReader(csr))
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Authorization", "Bearer "+strings.TrimSpace(string(token)))
+	req.Header.Set("Content-Type", "application/x-pem-file")
+
+	client := &http.Client{
+		Timeout:   30 * time.Second,
+		Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12}},
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("pod certificate signer returned %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	return body, nil
+}
+
+// writeFile atomically replaces the file with the given content
+func writeFile(path string, content []byte, mode os.FileMode) error {
+	tmp, err := ioutil.TempFile(filepath.Dir(path), "."+filepath.Base(path))
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(content); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(mode); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,218 @@
+package podcertificate
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/3scale/marin3r/pkg/envoy"
+	"github.com/3scale/marin3r/pkg/util/pki"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+// testSigner signs the certificate requests of clients that present the token
+type testSigner struct {
+	ca       *x509.Certificate
+	caKey    interface{}
+	token    string
+	validFor time.Duration
+	mu       sync.Mutex
+	issued   [][]byte
+}
+
+func (s *testSigner) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost || r.Header.Get("Authorization") != "Bearer "+s.token {
+		http.Error(w, "invalid token", http.StatusUnauthorized)
+		return
+	}
+	body, _ := ioutil.ReadAll(r.Body)
+	csr, err := pki.LoadCertificateRequest(body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	cert, err := pki.SignCertificateRequest(s.ca, s.caKey, csr, "pod", s.validFor)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	s.mu.Lock()
+	s.issued = append(s.issued, cert)
+	s.mu.Unlock()
+	w.Write(cert)
+}
+
+func (s *testSigner) getIssued() [][]byte {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([][]byte{}, s.issued...)
+}
+
+// testClient starts a signer that issues certificates valid for the given duration
+// and returns a Client configured to use it, writing to a temporary directory
+func testClient(t *testing.T, validFor time.Duration) (*Client, *testSigner, func()) {
+	caPEM, caKeyPEM, err := pki.GenerateCertificate(nil, nil, "ca", time.Hour, false, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ca, _ := pki.LoadX509Certificate(caPEM)
+	caKey, _ := pki.DecodePrivateKeyBytes(caKeyPEM)
+
+	signer := &testSigner{ca: ca, caKey: caKey, token: "pod-token", validFor: validFor}
+	mux := http.NewServeMux()
+	mux.Handle(SignerPath, signer)
+	srv := httptest.NewTLSServer(mux)
+
+	dir, err := ioutil.TempDir("", "pod-certificate")
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := filepath.Join(dir, "out")
+	os.Mkdir(out, 0755)
+	caFile := filepath.Join(dir, "ca.crt")
+	ioutil.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw}), 0644)
+	tokenFile := filepath.Join(dir, "token")
+	ioutil.WriteFile(tokenFile, []byte("pod-token\n"), 0644)
+
+	c := &Client{
+		URL:       srv.URL + SignerPath,
+		CAFile:    caFile,
+		TokenFile: tokenFile,
+		Directory: out,
+		EnvoyAPI:  envoy.APIv3,
+		Logger:    ctrl.Log,
+	}
+	return c, signer, func() {
+		srv.Close()
+		os.RemoveAll(dir)
+	}
+}
+
+func TestClient_Issue(t *testing.T) {
+	tests := []struct {
+		name      string
+		modify    func(c *Client)
+		wantErr   bool
+		wantFiles []string
+	}{
+		{
+			name:      "Writes the certificate, key and SDS resource",
+			modify:    func(c *Client) {},
+			wantErr:   false,
+			wantFiles: []string{"tls.crt", "tls.key", "tls_certificate_sds_secret.json"},
+		},
+		{
+			name: "Fails if the signer refuses the token",
+			modify: func(c *Client) {
+				ioutil.WriteFile(c.TokenFile, []byte("xxxx"), 0644)
+			},
+			wantErr:   true,
+			wantFiles: []string{},
+		},
+		{
+			name: "Fails if the signer certificate is not signed by the CA",
+			modify: func(c *Client) {
+				caPEM, _, _ := pki.GenerateCertificate(nil, nil, "other-ca", time.Hour, false, true)
+				ioutil.WriteFile(c.CAFile, caPEM, 0644)
+			},
+			wantErr:   true,
+			wantFiles: []string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, signer, cleanup := testClient(t, time.Hour)
+			defer cleanup()
+			tt.modify(c)
+
+			cert, err := c.Issue(context.TODO())
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Client.Issue() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			files, _ := ioutil.ReadDir(c.Directory)
+			got := []string{}
+			for _, f := range files {
+				got = append(got, f.Name())
+			}
+			if strings.Join(got, ",") != strings.Join(tt.wantFiles, ",") {
+				t.Errorf("Client.Issue() files = %v, want %v", got, tt.wantFiles)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			certPEM, _ := ioutil.ReadFile(filepath.Join(c.Directory, "tls.crt"))
+			if issued := signer.getIssued(); len(issued) != 1 || !bytes.Equal(certPEM, issued[0]) {
+				t.Errorf("Client.Issue() written certificate is not the one issued by the signer")
+			}
+			if onDisk, _ := pki.LoadX509Certificate(certPEM); onDisk == nil || !onDisk.Equal(cert) {
+				t.Errorf("Client.Issue() returned certificate is not the written one")
+			}
+			if _, err := tls.LoadX509KeyPair(filepath.Join(c.Directory, "tls.crt"), filepath.Join(c.Directory, "tls.key")); err != nil {
+				t.Errorf("Client.Issue() key does not match the certificate: %v", err)
+			}
+			if info, _ := os.Stat(filepath.Join(c.Directory, "tls.key")); info.Mode().Perm() != 0600 {
+				t.Errorf("Client.Issue() key file mode = %v, want 0600", info.Mode().Perm())
+			}
+			sds, _ := ioutil.ReadFile(filepath.Join(c.Directory, "tls_certificate_sds_secret.json"))
+			for _, file := range []string{"tls.crt", "tls.key"} {
+				if !strings.Contains(string(sds), filepath.Join(c.Directory, file)) {
+					t.Errorf("Client.Issue() SDS resource does not reference %s: %s", file, sds)
+				}
+			}
+		})
+	}
+}
+
+func TestClient_Run(t *testing.T) {
+	// Certificates are renewed after two thirds of their lifetime, about two seconds
+	c, signer, cleanup := testClient(t, 3*time.Second)
+	defer cleanup()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- c.Run(ctx) }()
+
+	deadline := time.Now().Add(10 * time.Second)
+	for len(signer.getIssued()) < 2 && time.Now().Before(deadline) {
+		time.Sleep(100 * time.Millisecond)
+	}
+	cancel()
+	if err := <-done; err != nil {
+		t.Errorf("Client.Run() error = %v", err)
+	}
+
+	issued := signer.getIssued()
+	if len(issued) < 2 {
+		t.Fatalf("Client.Run() issued %d certificates, want the certificate to be renewed", len(issued))
+	}
+	first, _ := pki.LoadX509Certificate(issued[0])
+	second, _ := pki.LoadX509Certificate(issued[1])
+	if renewedAt := second.NotBefore; renewedAt.Before(RenewalTime(first).Add(-time.Second)) {
+		t.Errorf("Client.Run() renewed the certificate at %v, before its renewal time %v", renewedAt, RenewalTime(first))
+	}
+	certPEM, _ := ioutil.ReadFile(filepath.Join(c.Directory, "tls.crt"))
+	if !bytes.Equal(certPEM, issued[len(issued)-1]) {
+		t.Errorf("Client.Run() written certificate is not the renewed one")
+	}
+}
+
+func TestRenewalTime(t *testing.T) {
+	notBefore := time.Unix(1600000000, 0)
+	cert := &x509.Certificate{NotBefore: notBefore, NotAfter: notBefore.Add(3 * time.Hour)}
+	if got := RenewalTime(cert); !got.Equal(notBefore.Add(2 * time.Hour)) {
+		t.Errorf("RenewalTime() = %v, want %v", got, notBefore.Add(2*time.Hour))
+	}
+}
@@ -0,0 +1,101 @@
+package discoveryservice
+
+import (
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/3scale/marin3r/pkg/util/pki"
+	"github.com/go-logr/logr"
+)
+
+// CertificateSource returns a set of certificates
+type CertificateSource interface {
+	Certificates() []*x509.Certificate
+}
+
+// RevocationList checks client certificates against the revocation list in a file,
+// signed by any of the Issuers. The file is reloaded when it changes, so updates of the
+// mounted Secret are enforced without restarting the discovery service. A missing file
+// means that no certificate is revoked and an invalid one is ignored, keeping the last
+// valid revocation list.
+type RevocationList struct {
+	Path    string
+	Issuers CertificateSource
+	Logger  logr.Logger
+
+	mu      sync.Mutex
+	modTime time.Time
+	size    int64
+	list    *pkix.CertificateList
+	// unverified is true when the file could not be verified against
+	// the issuers, so it is retried when the issuers change
+	unverified bool
+}
+
+// IsRevoked returns true if the certificate is in the revocation list
+func (rl *RevocationList) IsRevoked(cert *x509.Certificate) bool {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	rl.reload()
+	if rl.list == nil {
+		return false
+	}
+	return pki.IsRevoked(rl.list, cert)
+}
+
+// VerifyPeerCertificate can be used as the tls.Config VerifyPeerCertificate
+// function to reject the connections of clients with a revoked certificate
+func (rl *RevocationList) VerifyPeerCertificate(rawCerts [][]byte, verifiedChains [][]*x509.Certificate) error {
+	for _, chain := range verifiedChains {
+		if len(chain) > 0 && rl.IsRevoked(chain[0]) {
+			return fmt.Errorf("client certificate %s has been revoked", chain[0].SerialNumber)
+		}
+	}
+	return nil
+}
+
+// reload loads the revocation list if the file has changed since the last
+// time it was loaded. Must be called with the lock held.
+func (rl *RevocationList) reload() {
+	info, err := os.Stat(rl.Path)
+	if err != nil {
+		if os.IsNotExist(err) && rl.list != nil {
+			rl.Logger.Info("Revocation list removed", "Path", rl.Path)
+			rl.list, rl.modTime, rl.size = nil, time.Time{}, 0
+		}
+		return
+	}
+	changed := !info.ModTime().Equal(rl.modTime) || info.Size() != rl.size
+	if !changed && !rl.unverified {
+		return
+	}
+	rl.modTime, rl.size, rl.unverified = info.ModTime(), info.Size(), false
+
+	data, err := ioutil.ReadFile(rl.Path)
+	if err != nil {
+		rl.Logger.Error(err, "Unable to read revocation list", "Path", rl.Path)
+		return
+	}
+	var list *pkix.CertificateList
+	err = fmt.Errorf("no issuer certificates")
+	for _, issuer := range rl.Issuers.Certificates() {
+		if list, err = pki.LoadRevocationList(data, issuer); err == nil {
+			break
+		}
+	}
+	if list == nil {
+		if changed {
+			rl.Logger.Error(err, "Invalid revocation list", "Path", rl.Path)
+		}
+		rl.unverified = true
+		return
+	}
+	rl.list = list
+	rl.Logger.Info("Loaded revocation list", "Path", rl.Path, "RevokedCertificates", len(list.TBSCertList.RevokedCertificates))
+}
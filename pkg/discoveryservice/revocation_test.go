@@ -0,0 +1,103 @@
+package discoveryservice
+
+import (
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/3scale/marin3r/pkg/util/pki"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+type testIssuers []*x509.Certificate
+
+func (ti *testIssuers) Certificates() []*x509.Certificate { return *ti }
+
+func TestRevocationList(t *testing.T) {
+	caPEM, caKeyPEM, err := pki.GenerateCertificate(nil, nil, "ca", time.Hour, false, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ca, _ := pki.LoadX509Certificate(caPEM)
+	caKey, _ := pki.DecodePrivateKeyBytes(caKeyPEM)
+
+	certPEM, _, err := pki.GenerateCertificate(ca, caKey, "envoy", time.Hour, false, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, _ := pki.LoadX509Certificate(certPEM)
+
+	dir, err := ioutil.TempDir("", "revocation")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	issuers := &testIssuers{ca}
+	rl := &RevocationList{Path: filepath.Join(dir, "ca.crl"), Issuers: issuers, Logger: ctrl.Log.WithName("test")}
+	writeCRL := func(crl []byte, modTime time.Time) {
+		if err := ioutil.WriteFile(rl.Path, crl, 0644); err != nil {
+			t.Fatal(err)
+		}
+		// Ensure the change is detected regardless of the filesystem timestamp resolution
+		if err := os.Chtimes(rl.Path, modTime, modTime); err != nil {
+			t.Fatal(err)
+		}
+	}
+	verify := func() error {
+		return rl.VerifyPeerCertificate(nil, [][]*x509.Certificate{{cert, ca}})
+	}
+
+	if rl.IsRevoked(cert) || verify() != nil {
+		t.Errorf("RevocationList revokes certificates without a revocation list")
+	}
+
+	crl, err := pki.GenerateRevocationList(ca, caKey,
+		[]pkix.RevokedCertificate{{SerialNumber: cert.SerialNumber, RevocationTime: time.Now()}}, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeCRL(crl, time.Now().Add(-time.Minute))
+	if !rl.IsRevoked(cert) || verify() == nil {
+		t.Errorf("RevocationList does not revoke certificates in the revocation list")
+	}
+
+	writeCRL([]byte("xxxx"), time.Now())
+	if !rl.IsRevoked(cert) {
+		t.Errorf("RevocationList discards the last valid revocation list when the file is invalid")
+	}
+
+	empty, err := pki.GenerateRevocationList(ca, caKey, nil, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeCRL(empty, time.Now().Add(time.Minute))
+	if rl.IsRevoked(cert) {
+		t.Errorf("RevocationList does not reload the revocation list when the file changes")
+	}
+
+	newCAPEM, newCAKeyPEM, err := pki.GenerateCertificate(nil, nil, "new-ca", time.Hour, false, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	newCA, _ := pki.LoadX509Certificate(newCAPEM)
+	newCAKey, _ := pki.DecodePrivateKeyBytes(newCAKeyPEM)
+	resigned, err := pki.GenerateRevocationList(newCA, newCAKey,
+		[]pkix.RevokedCertificate{{SerialNumber: cert.SerialNumber, RevocationTime: time.Now()}}, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeCRL(resigned, time.Now().Add(2*time.Minute))
+	if rl.IsRevoked(cert) {
+		t.Errorf("RevocationList loads revocation lists not signed by an issuer")
+	}
+
+	*issuers = append(*issuers, newCA)
+	if !rl.IsRevoked(cert) {
+		t.Errorf("RevocationList does not load the revocation list when it is signed by a new issuer")
+	}
+}
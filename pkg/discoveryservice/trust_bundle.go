@@ -0,0 +1,108 @@
+package discoveryservice
+
+import (
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/3scale/marin3r/pkg/util/pki"
+	"github.com/go-logr/logr"
+)
+
+// TrustBundle holds the CA certificates client certificates are verified against. They are
+// loaded from the trust bundle file, which holds both the current and the new CA while the
+// CA is being rotated, and fall back to the CA certificate file when there is no trust bundle.
+// The files are reloaded when they change, so CA rotations are enforced without restarting
+// the discovery service.
+type TrustBundle struct {
+	Path              string
+	CACertificatePath string
+	Logger            logr.Logger
+
+	mu           sync.Mutex
+	path         string
+	modTime      time.Time
+	size         int64
+	certificates []*x509.Certificate
+}
+
+// Certificates returns the CA certificates in the trust bundle
+func (tb *TrustBundle) Certificates() []*x509.Certificate {
+	tb.mu.Lock()
+	defer tb.mu.Unlock()
+
+	tb.reload()
+	return tb.certificates
+}
+
+// Pool returns a x509.CertPool with the CA certificates in the trust bundle
+func (tb *TrustBundle) Pool() *x509.CertPool {
+	pool := x509.NewCertPool()
+	for _, cert := range tb.Certificates() {
+		pool.AddCert(cert)
+	}
+	return pool
+}
+
+// Verify verifies the raw client certificates presented in a TLS handshake against the
+// trust bundle and returns the verified chains. The first certificate is the client
+// certificate and the rest are used as intermediates.
+func (tb *TrustBundle) Verify(rawCerts [][]byte) ([][]*x509.Certificate, error) {
+	certs := make([]*x509.Certificate, len(rawCerts))
+	for i, raw := range rawCerts {
+		cert, err := x509.ParseCertificate(raw)
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse client certificate: %s", err.Error())
+		}
+		certs[i] = cert
+	}
+	if len(certs) == 0 {
+		return nil, fmt.Errorf("no client certificate provided")
+	}
+
+	opts := x509.VerifyOptions{
+		Roots:         tb.Pool(),
+		Intermediates: x509.NewCertPool(),
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	for _, cert := range certs[1:] {
+		opts.Intermediates.AddCert(cert)
+	}
+	return certs[0].Verify(opts)
+}
+
+// reload loads the CA certificates if the file has changed since the last
+// time it was loaded. Must be called with the lock held.
+func (tb *TrustBundle) reload() {
+	path := tb.Path
+	info, err := os.Stat(path)
+	if err != nil {
+		path = tb.CACertificatePath
+		if info, err = os.Stat(path); err != nil {
+			if tb.certificates == nil {
+				tb.Logger.Error(err, "Unable to read CA certificate", "Path", path)
+			}
+			return
+		}
+	}
+	if path == tb.path && info.ModTime().Equal(tb.modTime) && info.Size() == tb.size {
+		return
+	}
+	tb.path, tb.modTime, tb.size = path, info.ModTime(), info.Size()
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		tb.Logger.Error(err, "Unable to read CA certificates", "Path", path)
+		return
+	}
+	certificates, err := pki.LoadX509Certificates(data)
+	if err != nil {
+		tb.Logger.Error(err, "Invalid CA certificates", "Path", path)
+		return
+	}
+	tb.certificates = certificates
+	tb.Logger.Info("Loaded CA certificates", "Path", path, "Certificates", len(certificates))
+}
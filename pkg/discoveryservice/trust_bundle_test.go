@@ -0,0 +1,97 @@
+package discoveryservice
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/3scale/marin3r/pkg/util/pki"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+func TestTrustBundle(t *testing.T) {
+	newCA := func(cn string) ([]byte, *x509.Certificate, interface{}) {
+		caPEM, caKeyPEM, err := pki.GenerateCertificate(nil, nil, cn, time.Hour, false, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ca, _ := pki.LoadX509Certificate(caPEM)
+		caKey, _ := pki.DecodePrivateKeyBytes(caKeyPEM)
+		return caPEM, ca, caKey
+	}
+	newClientCert := func(ca *x509.Certificate, caKey interface{}) [][]byte {
+		certPEM, _, err := pki.GenerateCertificate(ca, caKey, "envoy", time.Hour, false, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		block, _ := pem.Decode(certPEM)
+		return [][]byte{block.Bytes}
+	}
+
+	currentPEM, current, currentKey := newCA("current")
+	nextPEM, next, nextKey := newCA("next")
+	currentClient := newClientCert(current, currentKey)
+	nextClient := newClientCert(next, nextKey)
+
+	dir, err := ioutil.TempDir("", "trustbundle")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	tb := &TrustBundle{
+		Path:              filepath.Join(dir, "ca.crt"),
+		CACertificatePath: filepath.Join(dir, "tls.crt"),
+		Logger:            ctrl.Log.WithName("test"),
+	}
+	writeFile := func(path string, data []byte, modTime time.Time) {
+		if err := ioutil.WriteFile(path, data, 0644); err != nil {
+			t.Fatal(err)
+		}
+		// Ensure the change is detected regardless of the filesystem timestamp resolution
+		if err := os.Chtimes(path, modTime, modTime); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if len(tb.Certificates()) != 0 {
+		t.Errorf("TrustBundle returns certificates without files")
+	}
+
+	writeFile(tb.CACertificatePath, currentPEM, time.Now().Add(-time.Minute))
+	if _, err := tb.Verify(currentClient); err != nil {
+		t.Errorf("TrustBundle does not fall back to the CA certificate: %v", err)
+	}
+	if _, err := tb.Verify(nextClient); err == nil {
+		t.Errorf("TrustBundle verifies certificates of an untrusted CA")
+	}
+
+	writeFile(tb.Path, append(append([]byte{}, currentPEM...), nextPEM...), time.Now())
+	if len(tb.Certificates()) != 2 {
+		t.Errorf("TrustBundle does not load all the certificates in the bundle")
+	}
+	if _, err := tb.Verify(currentClient); err != nil {
+		t.Errorf("TrustBundle does not verify certificates of the current CA: %v", err)
+	}
+	if _, err := tb.Verify(nextClient); err != nil {
+		t.Errorf("TrustBundle does not verify certificates of the new CA: %v", err)
+	}
+
+	writeFile(tb.Path, []byte("xxxx"), time.Now().Add(time.Minute))
+	if len(tb.Certificates()) != 2 {
+		t.Errorf("TrustBundle discards the last valid bundle when the file is invalid")
+	}
+
+	writeFile(tb.Path, nextPEM, time.Now().Add(2*time.Minute))
+	if _, err := tb.Verify(currentClient); err == nil {
+		t.Errorf("TrustBundle does not reload the bundle when the file changes")
+	}
+
+	if _, err := tb.Verify(nil); err == nil {
+		t.Errorf("TrustBundle verifies an empty list of certificates")
+	}
+}
@@ -19,9 +19,12 @@ import (
 	"crypto/tls"
 	"fmt"
 	"net"
+	"net/http"
+	"sync/atomic"
 	"time"
 
 	xdss "github.com/3scale/marin3r/pkg/discoveryservice/xdss"
+	"github.com/3scale/marin3r/pkg/discoveryservice/xdss/stats"
 	xdss_v2 "github.com/3scale/marin3r/pkg/discoveryservice/xdss/v2"
 	xdss_v3 "github.com/3scale/marin3r/pkg/discoveryservice/xdss/v3"
 	envoy "github.com/3scale/marin3r/pkg/envoy"
@@ -51,9 +54,10 @@ const (
 type XdsServer interface {
 	Start(<-chan struct{}) error
 	GetCache(envoy.APIVersion) xdss.Cache
+	GetStats(envoy.APIVersion) *stats.Stats
 }
 
-type onErrorFn func(nodeID, previousVersion, msg string, envoyAPI envoy.APIVersion) error
+type onErrorFn func(namespace, nodeID string, rType envoy.Type, failingVersion, msg string, envoyAPI envoy.APIVersion) error
 
 // DualXdsServer is a type that holds configuration
 // and runtime objects for the envoy xds server
@@ -67,37 +71,73 @@ type DualXdsServer struct {
 	snapshotCacheV3 cache_v3.SnapshotCache
 	callbacksV2     *xdss_v2.Callbacks
 	callbacksV3     *xdss_v3.Callbacks
+	statsV2         *stats.Stats
+	statsV3         *stats.Stats
+	proxiesV2       *xdss.Proxies
+	proxiesV3       *xdss.Proxies
+	// listening is set to 1 while the server accepts connections
+	listening int32
 }
 
-// NewDualXdsServer creates a new DualXdsServer object fron the given params
-func NewDualXdsServer(ctx context.Context, xDSPort uint, tlsConfig *tls.Config, fn onErrorFn, logger logr.Logger) *DualXdsServer {
+// NewDualXdsServer creates a new DualXdsServer object fron the given params. If enableDelta
+// is true the incremental variant of the v3 aggregated discovery service is also served. Proxies
+// that don't report their namespace in the node metadata are considered to belong to namespace. The
+// nodeID of the proxies is computed from the node fields selected by nodeHash. Requests are only
+// served if the given authorizer, when not nil, allows the client to fetch the requested nodeID.
+// Clients are identified by the given authenticator or, if nil, by their client certificate.
+func NewDualXdsServer(ctx context.Context, xDSPort uint, tlsConfig *tls.Config, fn onErrorFn, enableDelta bool,
+	namespace string, nodeHash xdss.NodeHash, authenticator xdss.Authenticator, authorizer xdss.Authorizer,
+	logger logr.Logger) *DualXdsServer {
 
 	xdsLogger := logger.WithName("xds")
 
+	// The snapshot caches are keyed per proxy so proxies that share a nodeID
+	// can receive different snapshots. Keys are qualified by namespace.
+	proxiesV2 := xdss.NewProxies()
+	proxiesV3 := xdss.NewProxies()
+
 	snapshotCacheV2 := cache_v2.NewSnapshotCache(
 		true,
-		cache_v2.IDHash{},
+		xdss_v2.ProxyHash{Proxies: proxiesV2, Namespace: namespace, NodeHash: nodeHash},
 		clogger{Logger: xdsLogger.WithName("cache").WithName("v2")},
 	)
 	snapshotCacheV3 := cache_v3.NewSnapshotCache(
 		true,
-		cache_v3.IDHash{},
+		xdss_v3.ProxyHash{Proxies: proxiesV3, Namespace: namespace, NodeHash: nodeHash},
 		clogger{Logger: xdsLogger.WithName("cache").WithName("v3")},
 	)
 
+	statsV2 := stats.New()
+	statsV3 := stats.New()
+
 	callbacksV2 := &xdss_v2.Callbacks{
 		OnError:       fn,
 		SnapshotCache: &snapshotCacheV2,
 		Logger:        xdsLogger.WithName("server").WithName("v2"),
+		Stats:         statsV2,
+		Proxies:       proxiesV2,
+		Namespace:     namespace,
+		NodeHash:      nodeHash,
+		Authenticator: authenticator,
+		Authorizer:    authorizer,
 	}
 	callbacksV3 := &xdss_v3.Callbacks{
 		OnError:       fn,
 		SnapshotCache: &snapshotCacheV3,
 		Logger:        xdsLogger.WithName("server").WithName("v3"),
+		Stats:         statsV3,
+		Proxies:       proxiesV3,
+		Namespace:     namespace,
+		NodeHash:      nodeHash,
+		Authenticator: authenticator,
+		Authorizer:    authorizer,
 	}
 
 	srvV2 := server_v2.NewServer(ctx, snapshotCacheV2, callbacksV2)
 	srvV3 := server_v3.NewServer(ctx, snapshotCacheV3, callbacksV3)
+	if enableDelta {
+		srvV3 = xdss_v3.NewDeltaServer(ctx, srvV3, snapshotCacheV3, callbacksV3)
+	}
 
 	return &DualXdsServer{
 		ctx:             ctx,
@@ -109,6 +149,10 @@ func NewDualXdsServer(ctx context.Context, xDSPort uint, tlsConfig *tls.Config,
 		snapshotCacheV3: snapshotCacheV3,
 		callbacksV2:     callbacksV2,
 		callbacksV3:     callbacksV3,
+		statsV2:         statsV2,
+		statsV3:         statsV3,
+		proxiesV2:       proxiesV2,
+		proxiesV3:       proxiesV3,
 	}
 }
 
@@ -136,6 +180,8 @@ func (xdss *DualXdsServer) Start(stopCh <-chan struct{}) error {
 		setupLog.Error(err, "Error starting aDS server")
 		return err
 	}
+	atomic.StoreInt32(&xdss.listening, 1)
+	defer atomic.StoreInt32(&xdss.listening, 0)
 
 	// channel to receive errors from the gorutine running the server
 	errCh := make(chan error)
@@ -180,12 +226,30 @@ func (xdss *DualXdsServer) Start(stopCh <-chan struct{}) error {
 
 }
 
+// Ready is a healthz.Checker that fails
+// until the xDS server is listening
+func (xdss *DualXdsServer) Ready(req *http.Request) error {
+	if atomic.LoadInt32(&xdss.listening) == 0 {
+		return fmt.Errorf("xDS server is not listening")
+	}
+	return nil
+}
+
 // GetCache returns the Cache
 func (xdss *DualXdsServer) GetCache(version envoy.APIVersion) xdss.Cache {
 	if version == envoy.APIv2 {
-		return xdss_v2.NewCache(xdss.snapshotCacheV2)
+		return xdss_v2.NewCacheWithProxies(xdss.snapshotCacheV2, xdss.proxiesV2)
+	}
+	return xdss_v3.NewCacheWithProxies(xdss.snapshotCacheV3, xdss.proxiesV3)
+}
+
+// GetStats returns the stats of the streams opened
+// against the xDS server for the given API version
+func (xdss *DualXdsServer) GetStats(version envoy.APIVersion) *stats.Stats {
+	if version == envoy.APIv2 {
+		return xdss.statsV2
 	}
-	return xdss_v3.NewCache(xdss.snapshotCacheV3)
+	return xdss.statsV3
 }
 
 type clogger struct {
@@ -20,8 +20,10 @@ import (
 	"reflect"
 	"sync"
 	"testing"
+	"time"
 
 	xdss "github.com/3scale/marin3r/pkg/discoveryservice/xdss"
+	"github.com/3scale/marin3r/pkg/discoveryservice/xdss/stats"
 	xdss_v2 "github.com/3scale/marin3r/pkg/discoveryservice/xdss/v2"
 	xdss_v3 "github.com/3scale/marin3r/pkg/discoveryservice/xdss/v3"
 	envoy "github.com/3scale/marin3r/pkg/envoy"
@@ -36,35 +38,52 @@ import (
 var (
 	snapshotCacheV2 = cache_v2.NewSnapshotCache(true, cache_v2.IDHash{}, nil)
 	snapshotCacheV3 = cache_v3.NewSnapshotCache(true, cache_v3.IDHash{}, nil)
-	fn              = func(a, b, c string, d envoy.APIVersion) error { return nil }
+	fn              = func(ns, a string, b envoy.Type, c, d string, e envoy.APIVersion) error { return nil }
 )
 
 func TestNewDualXdsServer(t *testing.T) {
 
 	type args struct {
-		ctx       context.Context
-		adsPort   uint
-		tlsConfig *tls.Config
-		fn        onErrorFn
-		logger    logr.Logger
+		ctx         context.Context
+		adsPort     uint
+		tlsConfig   *tls.Config
+		fn          onErrorFn
+		enableDelta bool
+		namespace   string
+		nodeHash    xdss.NodeHash
+		authn       xdss.Authenticator
+		authorizer  xdss.Authorizer
+		logger      logr.Logger
 	}
 	tests := []struct {
-		name string
-		args args
+		name      string
+		args      args
+		wantDelta bool
 	}{
 		{
 			"Returns a new DualXdsServer from the given params",
-			args{context.Background(), 10000, &tls.Config{}, fn, ctrl.Log},
+			args{context.Background(), 10000, &tls.Config{}, fn, false, "default", nil, nil, nil, ctrl.Log},
+			false,
+		},
+		{
+			"Returns a new DualXdsServer with delta xDS enabled",
+			args{context.Background(), 10000, &tls.Config{}, fn, true, "default", xdss.NodeHash{xdss.NodeHashCluster}, &ClientAuthenticator{}, nil, ctrl.Log},
+			true,
 		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got := NewDualXdsServer(tt.args.ctx, tt.args.adsPort, tt.args.tlsConfig, tt.args.fn, tt.args.logger)
+			got := NewDualXdsServer(tt.args.ctx, tt.args.adsPort, tt.args.tlsConfig, tt.args.fn, tt.args.enableDelta, tt.args.namespace, tt.args.nodeHash, tt.args.authn, tt.args.authorizer, tt.args.logger)
 			if got.snapshotCacheV2 == nil || got.snapshotCacheV3 == nil ||
 				got.serverV2 == nil || got.serverV3 == nil ||
-				got.callbacksV2 == nil || got.callbacksV3 == nil {
+				got.callbacksV2 == nil || got.callbacksV3 == nil ||
+				got.statsV2 == nil || got.statsV3 == nil ||
+				got.proxiesV2 == nil || got.proxiesV3 == nil {
 				t.Errorf("TestNewDualXdsServer = expected non-empty caches")
 			}
+			if _, ok := got.serverV3.(*xdss_v3.DeltaServer); ok != tt.wantDelta {
+				t.Errorf("TestNewDualXdsServer = got delta server %v, want %v", ok, tt.wantDelta)
+			}
 		})
 	}
 }
@@ -87,6 +106,11 @@ func TestDualXdsServer_Start(t *testing.T) {
 				snapshotCacheV3,
 				&xdss_v2.Callbacks{Logger: ctrl.Log},
 				&xdss_v3.Callbacks{Logger: ctrl.Log},
+				stats.New(),
+				stats.New(),
+				nil,
+				nil,
+				0,
 			},
 		},
 	}
@@ -107,6 +131,39 @@ func TestDualXdsServer_Start(t *testing.T) {
 	}
 }
 
+func TestDualXdsServer_Ready(t *testing.T) {
+	xdss := &DualXdsServer{
+		ctx:       context.Background(),
+		xDSPort:   10001,
+		tlsConfig: &tls.Config{},
+		serverV2:  server_v2.NewServer(context.Background(), snapshotCacheV2, &xdss_v2.Callbacks{Logger: ctrl.Log}),
+		serverV3:  server_v3.NewServer(context.Background(), snapshotCacheV3, &xdss_v3.Callbacks{Logger: ctrl.Log}),
+	}
+	if err := xdss.Ready(nil); err == nil {
+		t.Errorf("DualXdsServer.Ready() = nil before the server listens")
+	}
+
+	stopCh := make(chan struct{})
+	done := make(chan error)
+	go func() { done <- xdss.Start(stopCh) }()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for xdss.Ready(nil) != nil && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err := xdss.Ready(nil); err != nil {
+		t.Errorf("DualXdsServer.Ready() = %v once the server listens", err)
+	}
+
+	close(stopCh)
+	if err := <-done; err != nil {
+		t.Fatalf("DualXdsServer.Start() = %v", err)
+	}
+	if err := xdss.Ready(nil); err == nil {
+		t.Errorf("DualXdsServer.Ready() = nil after the server stops")
+	}
+}
+
 func TestDualXdsServer_GetCache(t *testing.T) {
 	tests := []struct {
 		name    string
@@ -126,6 +183,11 @@ func TestDualXdsServer_GetCache(t *testing.T) {
 				snapshotCacheV3,
 				&xdss_v2.Callbacks{Logger: ctrl.Log},
 				&xdss_v3.Callbacks{Logger: ctrl.Log},
+				stats.New(),
+				stats.New(),
+				nil,
+				nil,
+				0,
 			},
 			xdss_v2.NewCache(snapshotCacheV2),
 			envoy.APIv2,
@@ -142,6 +204,11 @@ func TestDualXdsServer_GetCache(t *testing.T) {
 				snapshotCacheV3,
 				&xdss_v2.Callbacks{Logger: ctrl.Log},
 				&xdss_v3.Callbacks{Logger: ctrl.Log},
+				stats.New(),
+				stats.New(),
+				nil,
+				nil,
+				0,
 			},
 			xdss_v3.NewCache(snapshotCacheV3),
 			envoy.APIv3,
@@ -155,3 +222,16 @@ func TestDualXdsServer_GetCache(t *testing.T) {
 		})
 	}
 }
+
+func TestDualXdsServer_GetStats(t *testing.T) {
+	statsV2 := stats.New()
+	statsV3 := stats.New()
+	xdss := &DualXdsServer{statsV2: statsV2, statsV3: statsV3}
+
+	if got := xdss.GetStats(envoy.APIv2); got != statsV2 {
+		t.Errorf("DualXdsServer.GetStats() = %v, want %v", got, statsV2)
+	}
+	if got := xdss.GetStats(envoy.APIv3); got != statsV3 {
+		t.Errorf("DualXdsServer.GetStats() = %v, want %v", got, statsV3)
+	}
+}
@@ -0,0 +1,167 @@
+package discoveryservice
+
+import (
+	"context"
+	"crypto/x509"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+const (
+	authorizationHeader = "authorization"
+	bearerPrefix        = "Bearer "
+
+	// IdentityURIScheme and IdentityURIHost are the scheme and host of the URI SANs
+	// that identify the namespace, ServiceAccount and Pod of client certificates
+	IdentityURIScheme = "spiffe"
+	IdentityURIHost   = "marin3r.3scale.net"
+)
+
+// ClientIdentity is the identity of an xDS client, taken from its
+// TLS client certificate or from its ServiceAccount token
+type ClientIdentity struct {
+	CommonName string
+	DNSNames   []string
+	// ServiceAccount is set for clients that
+	// authenticate with a ServiceAccount token
+	ServiceAccount *types.NamespacedName
+	// Pod is the name of the Pod the client runs in, if known
+	Pod string
+	// Namespace is the namespace the client belongs to, if known
+	Namespace string
+}
+
+// NamespaceIdentityURI returns the URI that identifies the namespace of the client
+// certificates issued for a discovery service that serves several namespaces,
+// like "spiffe://marin3r.3scale.net/ns/<ns>"
+func NamespaceIdentityURI(namespace string) *url.URL {
+	return &url.URL{
+		Scheme: IdentityURIScheme,
+		Host:   IdentityURIHost,
+		Path:   fmt.Sprintf("/ns/%s", namespace),
+	}
+}
+
+// parseNamespaceIdentityURI returns the namespace of a URI returned by
+// NamespaceIdentityURI, or false if the URI does not identify a namespace
+func parseNamespaceIdentityURI(uri *url.URL) (string, bool) {
+	if uri.Scheme != IdentityURIScheme || uri.Host != IdentityURIHost {
+		return "", false
+	}
+	parts := strings.Split(strings.TrimPrefix(uri.Path, "/"), "/")
+	if len(parts) != 2 || parts[0] != "ns" || parts[1] == "" {
+		return "", false
+	}
+	return parts[1], true
+}
+
+// PodIdentityURI returns the URI that identifies a Pod and its ServiceAccount in
+// per-pod client certificates, like "spiffe://marin3r.3scale.net/ns/<ns>/sa/<sa>/pod/<pod>"
+func PodIdentityURI(namespace, serviceAccount, pod string) *url.URL {
+	return &url.URL{
+		Scheme: IdentityURIScheme,
+		Host:   IdentityURIHost,
+		Path:   fmt.Sprintf("/ns/%s/sa/%s/pod/%s", namespace, serviceAccount, pod),
+	}
+}
+
+// parsePodIdentityURI returns the ServiceAccount and Pod of a URI returned by
+// PodIdentityURI, or false if the URI does not identify a Pod
+func parsePodIdentityURI(uri *url.URL) (*types.NamespacedName, string, bool) {
+	if uri.Scheme != IdentityURIScheme || uri.Host != IdentityURIHost {
+		return nil, "", false
+	}
+	parts := strings.Split(strings.TrimPrefix(uri.Path, "/"), "/")
+	if len(parts) != 6 || parts[0] != "ns" || parts[2] != "sa" || parts[4] != "pod" {
+		return nil, "", false
+	}
+	return &types.NamespacedName{Namespace: parts[1], Name: parts[3]}, parts[5], true
+}
+
+// HasName returns true if the given name is the
+// CommonName or one of the DNSNames of the identity
+func (ci *ClientIdentity) HasName(name string) bool {
+	if ci == nil || name == "" {
+		return false
+	}
+	if ci.CommonName == name {
+		return true
+	}
+	for _, dnsName := range ci.DNSNames {
+		if dnsName == name {
+			return true
+		}
+	}
+	return false
+}
+
+// IdentityFromContext returns the identity of the client certificate presented by the
+// peer of a gRPC stream, or nil if the peer did not present a certificate. Per-pod
+// certificates also identify the ServiceAccount and Pod of the client, and the client
+// certificates of discovery services that serve several namespaces its namespace.
+func IdentityFromContext(ctx context.Context) *ClientIdentity {
+	cert := PeerCertificateFromContext(ctx)
+	if cert == nil {
+		return nil
+	}
+	identity := &ClientIdentity{CommonName: cert.Subject.CommonName, DNSNames: cert.DNSNames}
+	for _, uri := range cert.URIs {
+		if sa, pod, ok := parsePodIdentityURI(uri); ok {
+			identity.ServiceAccount = sa
+			identity.Pod = pod
+			identity.Namespace = sa.Namespace
+			break
+		}
+		if ns, ok := parseNamespaceIdentityURI(uri); ok {
+			identity.Namespace = ns
+			break
+		}
+	}
+	return identity
+}
+
+// PeerCertificateFromContext returns the client certificate presented by the
+// peer of a gRPC stream, or nil if the peer did not present a certificate
+func PeerCertificateFromContext(ctx context.Context) *x509.Certificate {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return nil
+	}
+	tlsInfo, ok := p.AuthInfo.(credentials.TLSInfo)
+	if !ok || len(tlsInfo.State.PeerCertificates) == 0 {
+		return nil
+	}
+	return tlsInfo.State.PeerCertificates[0]
+}
+
+// TokenFromContext returns the bearer token sent by the peer of a gRPC
+// stream in the authorization header, or an empty string if there is none
+func TokenFromContext(ctx context.Context) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+	for _, value := range md.Get(authorizationHeader) {
+		if strings.HasPrefix(value, bearerPrefix) {
+			return strings.TrimPrefix(value, bearerPrefix)
+		}
+	}
+	return ""
+}
+
+// Authenticator returns the identity of the client of a gRPC stream
+type Authenticator interface {
+	Authenticate(ctx context.Context) (*ClientIdentity, error)
+}
+
+// Authorizer decides if an xDS client is allowed to
+// fetch the resources of a nodeID in a namespace
+type Authorizer interface {
+	Authorize(identity *ClientIdentity, namespace, nodeID string) error
+}
@@ -0,0 +1,73 @@
+package discoveryservice
+
+import (
+	"net/url"
+	"reflect"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func TestPodIdentityURI(t *testing.T) {
+	uri := PodIdentityURI("test", "envoy", "pod-1")
+	if uri.String() != "spiffe://marin3r.3scale.net/ns/test/sa/envoy/pod/pod-1" {
+		t.Errorf("PodIdentityURI() = %v", uri)
+	}
+	sa, pod, ok := parsePodIdentityURI(uri)
+	if !ok || pod != "pod-1" || !reflect.DeepEqual(sa, &types.NamespacedName{Namespace: "test", Name: "envoy"}) {
+		t.Errorf("parsePodIdentityURI() = %v, %v, %v", sa, pod, ok)
+	}
+}
+
+func Test_parsePodIdentityURI(t *testing.T) {
+	tests := []struct {
+		name string
+		uri  string
+		want bool
+	}{
+		{"Parses pod identity URIs", "spiffe://marin3r.3scale.net/ns/test/sa/envoy/pod/pod-1", true},
+		{"Ignores URIs of other hosts", "spiffe://cluster.local/ns/test/sa/envoy/pod/pod-1", false},
+		{"Ignores URIs of other schemes", "https://marin3r.3scale.net/ns/test/sa/envoy/pod/pod-1", false},
+		{"Ignores URIs with other paths", "spiffe://marin3r.3scale.net/ns/test/sa/envoy", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			uri, _ := url.Parse(tt.uri)
+			if _, _, got := parsePodIdentityURI(uri); got != tt.want {
+				t.Errorf("parsePodIdentityURI() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNamespaceIdentityURI(t *testing.T) {
+	uri := NamespaceIdentityURI("test")
+	if uri.String() != "spiffe://marin3r.3scale.net/ns/test" {
+		t.Errorf("NamespaceIdentityURI() = %v", uri)
+	}
+	ns, ok := parseNamespaceIdentityURI(uri)
+	if !ok || ns != "test" {
+		t.Errorf("parseNamespaceIdentityURI() = %v, %v", ns, ok)
+	}
+}
+
+func Test_parseNamespaceIdentityURI(t *testing.T) {
+	tests := []struct {
+		name string
+		uri  string
+		want bool
+	}{
+		{"Parses namespace identity URIs", "spiffe://marin3r.3scale.net/ns/test", true},
+		{"Ignores URIs of other hosts", "spiffe://cluster.local/ns/test", false},
+		{"Ignores pod identity URIs", "spiffe://marin3r.3scale.net/ns/test/sa/envoy/pod/pod-1", false},
+		{"Ignores URIs without namespace", "spiffe://marin3r.3scale.net/ns/", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			uri, _ := url.Parse(tt.uri)
+			if _, got := parseNamespaceIdentityURI(uri); got != tt.want {
+				t.Errorf("parseNamespaceIdentityURI() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -14,6 +14,7 @@ import (
 // eventually.
 type Cache interface {
 	SetSnapshot(string, Snapshot) error
+	SetProxySnapshot(string, string, Snapshot) error
 	GetSnapshot(string) (Snapshot, error)
 	ClearSnapshot(string)
 	NewSnapshot(string) Snapshot
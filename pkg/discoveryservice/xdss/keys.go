@@ -0,0 +1,30 @@
+package discoveryservice
+
+import (
+	_struct "github.com/golang/protobuf/ptypes/struct"
+)
+
+const (
+	// NamespaceMetadataKey is the key of the node metadata field that envoy
+	// proxies use to report the namespace they belong to
+	NamespaceMetadataKey string = "marin3r.3scale.net/namespace"
+)
+
+// NodeKey returns the key in the snapshot cache for the given namespace and nodeID.
+// Keys are qualified by namespace so proxies from different namespaces that use the
+// same nodeID never receive each other's resources.
+func NodeKey(namespace, nodeID string) string {
+	if namespace == "" {
+		return nodeID
+	}
+	return namespace + "/" + nodeID
+}
+
+// NamespaceFromMetadata returns the namespace that a proxy reports in its node
+// metadata, or the given default namespace if the proxy does not report one
+func NamespaceFromMetadata(metadata *_struct.Struct, defaultNamespace string) string {
+	if v, ok := metadata.GetFields()[NamespaceMetadataKey]; ok && v.GetStringValue() != "" {
+		return v.GetStringValue()
+	}
+	return defaultNamespace
+}
@@ -0,0 +1,166 @@
+package metrics
+
+import (
+	"sync"
+	"time"
+
+	"github.com/3scale/marin3r/pkg/envoy"
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+const (
+	namespace = "marin3r"
+	subsystem = "xds"
+)
+
+var (
+	streamsOpen = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: subsystem,
+		Name:      "streams_open",
+		Help:      "Number of open xDS streams, by API version and subscribed type URL",
+	}, []string{"api_version", "type_url"})
+
+	discoveryRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: subsystem,
+		Name:      "discovery_requests_total",
+		Help:      "Total number of discovery requests received",
+	}, []string{"api_version", "type_url"})
+
+	discoveryResponses = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: subsystem,
+		Name:      "discovery_responses_total",
+		Help:      "Total number of discovery responses sent",
+	}, []string{"api_version", "type_url"})
+
+	nacks = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: subsystem,
+		Name:      "nacks_total",
+		Help:      "Total number of discovery responses rejected by the envoy proxies",
+	}, []string{"api_version", "type_url", "node_id"})
+
+	snapshotWrites = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: subsystem,
+		Name:      "snapshot_writes_total",
+		Help:      "Total number of snapshots written to the xDS cache",
+	}, []string{"api_version", "node_id"})
+
+	snapshotACKDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: subsystem,
+		Name:      "snapshot_ack_duration_seconds",
+		Help:      "Time elapsed between a snapshot being written to the xDS cache and the first ACK received for it",
+		Buckets:   prometheus.ExponentialBuckets(0.01, 2, 15),
+	}, []string{"api_version"})
+)
+
+func init() {
+	metrics.Registry.MustRegister(
+		streamsOpen,
+		discoveryRequests,
+		discoveryResponses,
+		nacks,
+		snapshotWrites,
+		snapshotACKDuration,
+	)
+}
+
+// tracker holds the state required to calculate metrics
+// that span several xDS callbacks
+type tracker struct {
+	mu sync.Mutex
+	// subscriptions holds the type URLs requested in each stream
+	subscriptions map[streamKey]map[string]bool
+	// pending holds the snapshots written to the cache that
+	// have not yet been acknowledged by any envoy proxy
+	pending map[nodeKey]*pendingSnapshot
+}
+
+type streamKey struct {
+	version envoy.APIVersion
+	id      int64
+}
+
+type nodeKey struct {
+	version envoy.APIVersion
+	nodeID  string
+}
+
+type pendingSnapshot struct {
+	versions  map[string]string
+	writtenAt time.Time
+}
+
+var state = &tracker{
+	subscriptions: map[streamKey]map[string]bool{},
+	pending:       map[nodeKey]*pendingSnapshot{},
+}
+
+// ReportStreamOpened records a new xDS stream
+func ReportStreamOpened(version envoy.APIVersion, id int64) {
+	state.mu.Lock()
+	defer state.mu.Unlock()
+	state.subscriptions[streamKey{version, id}] = map[string]bool{}
+}
+
+// ReportStreamClosed records that an xDS stream has been closed
+func ReportStreamClosed(version envoy.APIVersion, id int64) {
+	state.mu.Lock()
+	defer state.mu.Unlock()
+	key := streamKey{version, id}
+	for typeURL := range state.subscriptions[key] {
+		streamsOpen.WithLabelValues(version.String(), typeURL).Dec()
+	}
+	delete(state.subscriptions, key)
+}
+
+// ReportRequest records a discovery request. If the request acknowledges the
+// last snapshot written for the node, the time elapsed since it was written
+// is observed.
+func ReportRequest(version envoy.APIVersion, id int64, nodeID, typeURL, versionInfo string) {
+	discoveryRequests.WithLabelValues(version.String(), typeURL).Inc()
+
+	state.mu.Lock()
+	defer state.mu.Unlock()
+
+	subs, ok := state.subscriptions[streamKey{version, id}]
+	if !ok {
+		subs = map[string]bool{}
+		state.subscriptions[streamKey{version, id}] = subs
+	}
+	if !subs[typeURL] {
+		subs[typeURL] = true
+		streamsOpen.WithLabelValues(version.String(), typeURL).Inc()
+	}
+
+	key := nodeKey{version, nodeID}
+	if p, ok := state.pending[key]; ok && versionInfo != "" && p.versions[typeURL] == versionInfo {
+		snapshotACKDuration.WithLabelValues(version.String()).Observe(time.Since(p.writtenAt).Seconds())
+		delete(state.pending, key)
+	}
+}
+
+// ReportNACK records a discovery response rejected by an envoy proxy
+func ReportNACK(version envoy.APIVersion, nodeID, typeURL string) {
+	nacks.WithLabelValues(version.String(), typeURL, nodeID).Inc()
+}
+
+// ReportResponse records a discovery response
+func ReportResponse(version envoy.APIVersion, typeURL string) {
+	discoveryResponses.WithLabelValues(version.String(), typeURL).Inc()
+}
+
+// ReportSnapshotWrite records a snapshot written to the xDS cache. The versions
+// param holds the version of each type URL that changed in the snapshot.
+func ReportSnapshotWrite(version envoy.APIVersion, nodeID string, versions map[string]string) {
+	snapshotWrites.WithLabelValues(version.String(), nodeID).Inc()
+
+	state.mu.Lock()
+	defer state.mu.Unlock()
+	state.pending[nodeKey{version, nodeID}] = &pendingSnapshot{versions: versions, writtenAt: time.Now()}
+}
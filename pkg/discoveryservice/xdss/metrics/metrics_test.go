@@ -0,0 +1,43 @@
+package metrics
+
+import (
+	"testing"
+
+	"github.com/3scale/marin3r/pkg/envoy"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestReportStreams(t *testing.T) {
+	gauge := streamsOpen.WithLabelValues("v3", "test.streams")
+
+	ReportStreamOpened(envoy.APIv3, 100)
+	ReportRequest(envoy.APIv3, 100, "node1", "test.streams", "")
+	ReportRequest(envoy.APIv3, 100, "node1", "test.streams", "1")
+	if got := testutil.ToFloat64(gauge); got != 1 {
+		t.Errorf("streams_open = %v, want 1", got)
+	}
+
+	ReportStreamClosed(envoy.APIv3, 100)
+	if got := testutil.ToFloat64(gauge); got != 0 {
+		t.Errorf("streams_open = %v, want 0", got)
+	}
+}
+
+func TestReportSnapshotWrite(t *testing.T) {
+	ReportSnapshotWrite(envoy.APIv2, "node-ack", map[string]string{"test.ack": "2"})
+	if got := testutil.ToFloat64(snapshotWrites.WithLabelValues("v2", "node-ack")); got != 1 {
+		t.Errorf("snapshot_writes_total = %v, want 1", got)
+	}
+
+	// A request with the old version is not an ACK of the written snapshot
+	ReportRequest(envoy.APIv2, 200, "node-ack", "test.ack", "1")
+	if _, ok := state.pending[nodeKey{envoy.APIv2, "node-ack"}]; !ok {
+		t.Errorf("snapshot for node 'node-ack' should still be pending")
+	}
+
+	ReportRequest(envoy.APIv2, 200, "node-ack", "test.ack", "2")
+	if _, ok := state.pending[nodeKey{envoy.APIv2, "node-ack"}]; ok {
+		t.Errorf("snapshot for node 'node-ack' should have been acknowledged")
+	}
+	ReportStreamClosed(envoy.APIv2, 200)
+}
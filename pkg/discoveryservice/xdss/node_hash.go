@@ -0,0 +1,78 @@
+package discoveryservice
+
+import (
+	"fmt"
+	"strings"
+
+	_struct "github.com/golang/protobuf/ptypes/struct"
+)
+
+const (
+	// NodeHashID is the NodeHash field for the id of the node
+	NodeHashID string = "id"
+	// NodeHashCluster is the NodeHash field for the cluster of the node
+	NodeHashCluster string = "cluster"
+	// NodeHashMetadataPrefix is the prefix of the NodeHash fields that
+	// refer to a key of the node metadata, like "metadata.version"
+	NodeHashMetadataPrefix string = "metadata."
+	// NodeHashSeparator separates the values of the
+	// fields in the nodeID computed by a NodeHash
+	NodeHashSeparator string = "_"
+)
+
+// Node is the interface that both v2 and v3 envoy nodes implement
+type Node interface {
+	GetId() string
+	GetCluster() string
+	GetMetadata() *_struct.Struct
+}
+
+// NodeHash computes the nodeID that EnvoyConfigs are matched against from the fields
+// of an envoy node. The values of the fields are joined, in order, by NodeHashSeparator.
+// An empty NodeHash uses the id of the node.
+type NodeHash []string
+
+// ParseNodeHash returns the NodeHash for the given comma separated list of fields
+func ParseNodeHash(s string) (NodeHash, error) {
+	if s == "" {
+		return NodeHash{}, nil
+	}
+
+	h := NodeHash{}
+	for _, field := range strings.Split(s, ",") {
+		switch {
+		case field == NodeHashID, field == NodeHashCluster:
+		case strings.HasPrefix(field, NodeHashMetadataPrefix) && len(field) > len(NodeHashMetadataPrefix):
+		default:
+			return nil, fmt.Errorf("unknown node hash field %q", field)
+		}
+		h = append(h, field)
+	}
+	return h, nil
+}
+
+// NodeID returns the nodeID of the given node
+func (h NodeHash) NodeID(node Node) string {
+	if len(h) == 0 {
+		return node.GetId()
+	}
+
+	values := make([]string, len(h))
+	for idx, field := range h {
+		switch field {
+		case NodeHashID:
+			values[idx] = node.GetId()
+		case NodeHashCluster:
+			values[idx] = node.GetCluster()
+		default:
+			key := strings.TrimPrefix(field, NodeHashMetadataPrefix)
+			values[idx] = node.GetMetadata().GetFields()[key].GetStringValue()
+		}
+	}
+	return strings.Join(values, NodeHashSeparator)
+}
+
+// String returns the comma separated list of fields of the NodeHash
+func (h NodeHash) String() string {
+	return strings.Join(h, ",")
+}
@@ -0,0 +1,84 @@
+package discoveryservice
+
+import (
+	"reflect"
+	"testing"
+
+	envoy_config_core_v3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	_struct "github.com/golang/protobuf/ptypes/struct"
+)
+
+func TestParseNodeHash(t *testing.T) {
+	tests := []struct {
+		name    string
+		s       string
+		want    NodeHash
+		wantErr bool
+	}{
+		{
+			name:    "Returns an empty NodeHash for an empty string",
+			s:       "",
+			want:    NodeHash{},
+			wantErr: false,
+		},
+		{
+			name:    "Returns the NodeHash for the given fields",
+			s:       "cluster,metadata.version,id",
+			want:    NodeHash{"cluster", "metadata.version", "id"},
+			wantErr: false,
+		},
+		{
+			name:    "Returns an error for an unknown field",
+			s:       "cluster,locality",
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name:    "Returns an error for a metadata field without key",
+			s:       "metadata.",
+			want:    nil,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseNodeHash(tt.s)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseNodeHash() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseNodeHash() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNodeHash_NodeID(t *testing.T) {
+	node := &envoy_config_core_v3.Node{
+		Id:      "pod-1",
+		Cluster: "gateway",
+		Metadata: &_struct.Struct{Fields: map[string]*_struct.Value{
+			"version": {Kind: &_struct.Value_StringValue{StringValue: "v1"}},
+		}},
+	}
+
+	tests := []struct {
+		name string
+		h    NodeHash
+		want string
+	}{
+		{"Returns the node id by default", NodeHash{}, "pod-1"},
+		{"Returns the node cluster", NodeHash{NodeHashCluster}, "gateway"},
+		{"Returns the value of a node metadata key", NodeHash{"metadata.version"}, "v1"},
+		{"Returns an empty value for a missing node metadata key", NodeHash{"metadata.other"}, ""},
+		{"Returns the composite of several fields", NodeHash{NodeHashCluster, "metadata.version"}, "gateway_v1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.h.NodeID(node); got != tt.want {
+				t.Errorf("NodeHash.NodeID() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,222 @@
+package discoveryservice
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// Proxies keeps track of the envoy proxies that have streams open against the
+// xDS server so each one of them can be addressed individually in the snapshot
+// cache. Proxies are identified by their nodeID and peer address and get their own
+// key in the snapshot cache, which allows publishing a snapshot to just a subset of
+// the proxies that share a nodeID. A nil *Proxies is valid and keys the snapshot
+// cache by nodeID only. It is safe for concurrent use.
+type Proxies struct {
+	// writeMu serializes the writes of snapshots to the keys of the proxies so
+	// a proxy that connects while a snapshot is being written does not miss it
+	writeMu sync.Mutex
+	// mu protects the fields below. It is never held while calling back into the
+	// snapshot cache as the cache calls Key() with its own lock held.
+	mu      sync.RWMutex
+	streams map[int64]*proxyStream
+	keys    map[interface{}]string
+}
+
+type proxyStream struct {
+	address string
+	nodeID  string
+	node    interface{}
+	key     string
+}
+
+// NewProxies returns a new Proxies object
+func NewProxies() *Proxies {
+	return &Proxies{
+		streams: map[int64]*proxyStream{},
+		keys:    map[interface{}]string{},
+	}
+}
+
+// ProxyKey returns the key in the snapshot cache for the proxy with
+// the given nodeID and address
+func ProxyKey(nodeID, address string) string {
+	return fmt.Sprintf("%s@%s", nodeID, address)
+}
+
+// OpenStream registers a new stream. The address is the
+// peer address of the envoy proxy that opened the stream.
+func (p *Proxies) OpenStream(id int64, address string) {
+	if p == nil || address == "" {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.streams[id] = &proxyStream{address: address}
+}
+
+// Register assigns a key in the snapshot cache to the node of the given stream. It
+// must be called for every request of the stream with the node object the xDS server
+// passes to the snapshot cache for that request, as the server replaces it each time
+// the proxy sends its node again. The seed function is called with the key of the proxy
+// when it is the first stream that uses it, so the caller can write the snapshot the
+// proxy should receive before any request reaches the snapshot cache.
+func (p *Proxies) Register(id int64, node interface{}, nodeID string, seed func(key string)) {
+	if p == nil {
+		return
+	}
+	p.writeMu.Lock()
+	defer p.writeMu.Unlock()
+
+	p.mu.Lock()
+	st, ok := p.streams[id]
+	if !ok {
+		p.mu.Unlock()
+		return
+	}
+	if st.key != "" {
+		// The key of the stream does not change, only the node that maps to it
+		if node != st.node {
+			delete(p.keys, st.node)
+			st.node = node
+			p.keys[node] = st.key
+		}
+		p.mu.Unlock()
+		return
+	}
+	st.nodeID = nodeID
+	st.node = node
+	st.key = ProxyKey(nodeID, st.address)
+	p.keys[node] = st.key
+	first := p.streamsWithKey(st.key) == 1
+	p.mu.Unlock()
+
+	if first && seed != nil {
+		seed(st.key)
+	}
+}
+
+// CloseStream removes a stream. The clear function is called with the
+// key of the proxy when no other streams use it.
+func (p *Proxies) CloseStream(id int64, clear func(key string)) {
+	if p == nil {
+		return
+	}
+	p.writeMu.Lock()
+	defer p.writeMu.Unlock()
+
+	p.mu.Lock()
+	st, ok := p.streams[id]
+	if !ok {
+		p.mu.Unlock()
+		return
+	}
+	delete(p.streams, id)
+	if st.key == "" {
+		p.mu.Unlock()
+		return
+	}
+	delete(p.keys, st.node)
+	last := p.streamsWithKey(st.key) == 0
+	p.mu.Unlock()
+
+	if last && clear != nil {
+		clear(st.key)
+	}
+}
+
+// Key returns the key in the snapshot cache for the given node, or
+// the nodeID if the node has not been registered
+func (p *Proxies) Key(node interface{}, nodeID string) string {
+	if p == nil {
+		return nodeID
+	}
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if key, ok := p.keys[node]; ok {
+		return key
+	}
+	return nodeID
+}
+
+// Lookup returns the key in the snapshot cache for the proxy with the given
+// nodeID and address. An error is returned if the proxy is not connected.
+func (p *Proxies) Lookup(nodeID, address string) (string, error) {
+	if p != nil {
+		p.mu.RLock()
+		defer p.mu.RUnlock()
+		for _, st := range p.streams {
+			if st.nodeID == nodeID && st.address == address && st.key != "" {
+				return st.key, nil
+			}
+		}
+	}
+	return "", fmt.Errorf("proxy %q with nodeID %q is not connected", address, nodeID)
+}
+
+// ForEachKey calls fn with the nodeID and then with the key of each one of the
+// proxies connected with that nodeID, stopping at the first error. No proxy can
+// register in the meantime so all of them get whatever fn writes to the cache.
+func (p *Proxies) ForEachKey(nodeID string, fn func(key string) error) error {
+	if p == nil {
+		return fn(nodeID)
+	}
+	p.writeMu.Lock()
+	defer p.writeMu.Unlock()
+
+	if err := fn(nodeID); err != nil {
+		return err
+	}
+	for _, key := range p.keysFor(nodeID) {
+		if err := fn(key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WithKey calls fn with the key of the proxy with the given nodeID and address
+// while holding the same lock as ForEachKey. An error is returned if the proxy is
+// not connected.
+func (p *Proxies) WithKey(nodeID, address string, fn func(key string) error) error {
+	if p != nil {
+		p.writeMu.Lock()
+		defer p.writeMu.Unlock()
+	}
+	key, err := p.Lookup(nodeID, address)
+	if err != nil {
+		return err
+	}
+	return fn(key)
+}
+
+// keysFor returns the sorted list of keys of the proxies with the given nodeID
+func (p *Proxies) keysFor(nodeID string) []string {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	set := map[string]bool{}
+	for _, st := range p.streams {
+		if st.nodeID == nodeID && st.key != "" {
+			set[st.key] = true
+		}
+	}
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// streamsWithKey returns the number of streams using the given key.
+// Must be called with mu held.
+func (p *Proxies) streamsWithKey(key string) int {
+	count := 0
+	for _, st := range p.streams {
+		if st.key == key {
+			count++
+		}
+	}
+	return count
+}
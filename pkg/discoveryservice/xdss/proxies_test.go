@@ -0,0 +1,112 @@
+package discoveryservice
+
+import (
+	"reflect"
+	"testing"
+)
+
+type testNode struct{ id string }
+
+func TestProxies_Register(t *testing.T) {
+	p := NewProxies()
+	n1, n2, n3 := &testNode{"node1"}, &testNode{"node1"}, &testNode{"node1"}
+	p.OpenStream(1, "10.0.0.1:5000")
+	p.OpenStream(2, "10.0.0.1:5000")
+	p.OpenStream(3, "10.0.0.2:5000")
+
+	seeded := []string{}
+	seed := func(key string) { seeded = append(seeded, key) }
+	p.Register(1, n1, "node1", seed)
+	p.Register(1, n1, "node1", seed)
+	p.Register(2, n2, "node1", seed)
+	p.Register(3, n3, "node1", seed)
+
+	// Only the first stream of each proxy seeds its key
+	if want := []string{"node1@10.0.0.1:5000", "node1@10.0.0.2:5000"}; !reflect.DeepEqual(seeded, want) {
+		t.Errorf("Proxies.Register() seeded = %v, want %v", seeded, want)
+	}
+	if got := p.Key(n2, "node1"); got != "node1@10.0.0.1:5000" {
+		t.Errorf("Proxies.Key() = %v, want 'node1@10.0.0.1:5000'", got)
+	}
+	if got := p.Key(&testNode{"node1"}, "node1"); got != "node1" {
+		t.Errorf("Proxies.Key() = %v, want 'node1' for a node that is not registered", got)
+	}
+
+	// A new node object for an already registered stream takes over its key
+	n4 := &testNode{"node1"}
+	p.Register(3, n4, "node1", seed)
+	if got := p.Key(n4, "node1"); got != "node1@10.0.0.2:5000" {
+		t.Errorf("Proxies.Key() = %v, want 'node1@10.0.0.2:5000'", got)
+	}
+	if got := p.Key(n3, "node1"); got != "node1" {
+		t.Errorf("Proxies.Key() = %v, want 'node1' for a node that has been replaced", got)
+	}
+	if len(seeded) != 2 {
+		t.Errorf("Proxies.Register() seeded = %v, want no new seeds", seeded)
+	}
+}
+
+func TestProxies_CloseStream(t *testing.T) {
+	p := NewProxies()
+	n1, n2 := &testNode{"node1"}, &testNode{"node1"}
+	p.OpenStream(1, "10.0.0.1:5000")
+	p.OpenStream(2, "10.0.0.1:5000")
+	p.Register(1, n1, "node1", nil)
+	p.Register(2, n2, "node1", nil)
+
+	cleared := []string{}
+	clear := func(key string) { cleared = append(cleared, key) }
+	p.CloseStream(1, clear)
+	if len(cleared) != 0 {
+		t.Errorf("Proxies.CloseStream() cleared = %v, want none while other streams use the key", cleared)
+	}
+	p.CloseStream(2, clear)
+	if want := []string{"node1@10.0.0.1:5000"}; !reflect.DeepEqual(cleared, want) {
+		t.Errorf("Proxies.CloseStream() cleared = %v, want %v", cleared, want)
+	}
+	if _, err := p.Lookup("node1", "10.0.0.1:5000"); err == nil {
+		t.Errorf("Proxies.Lookup() expected an error for a disconnected proxy")
+	}
+}
+
+func TestProxies_ForEachKey(t *testing.T) {
+	tests := []struct {
+		name    string
+		proxies func() *Proxies
+		want    []string
+	}{
+		{
+			name:    "Nil Proxies only return the nodeID",
+			proxies: func() *Proxies { return nil },
+			want:    []string{"node1"},
+		},
+		{
+			name: "Returns the nodeID and the keys of its proxies",
+			proxies: func() *Proxies {
+				p := NewProxies()
+				p.OpenStream(1, "10.0.0.2:5000")
+				p.OpenStream(2, "10.0.0.1:5000")
+				p.OpenStream(3, "10.0.0.3:5000")
+				p.OpenStream(4, "")
+				p.Register(1, &testNode{}, "node1", nil)
+				p.Register(2, &testNode{}, "node1", nil)
+				p.Register(3, &testNode{}, "node2", nil)
+				p.Register(4, &testNode{}, "node1", nil)
+				return p
+			},
+			want: []string{"node1", "node1@10.0.0.1:5000", "node1@10.0.0.2:5000"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := []string{}
+			tt.proxies().ForEachKey("node1", func(key string) error {
+				got = append(got, key)
+				return nil
+			})
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Proxies.ForEachKey() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,231 @@
+package discoveryservice
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	envoy "github.com/3scale/marin3r/pkg/envoy"
+	protov1 "github.com/golang/protobuf/proto"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+)
+
+// DanglingReference is a reference from a resource in a snapshot to
+// another resource that the snapshot does not hold.
+type DanglingReference struct {
+	// Type and Name of the resource that holds the reference
+	Type envoy.Type
+	Name string
+	// ReferencedType and ReferencedName of the resource that cannot be found
+	ReferencedType envoy.Type
+	ReferencedName string
+}
+
+// String returns the string representation of the DanglingReference
+func (dr DanglingReference) String() string {
+	return fmt.Sprintf("%s %q references %s %q",
+		strings.ToLower(string(dr.Type)), dr.Name, strings.ToLower(string(dr.ReferencedType)), dr.ReferencedName)
+}
+
+// DanglingReferencesError is the error returned when a snapshot holds
+// references to resources that are not in the snapshot.
+type DanglingReferencesError []DanglingReference
+
+// Error implements the error interface
+func (e DanglingReferencesError) Error() string {
+	refs := make([]string, len(e))
+	for idx, dr := range e {
+		refs[idx] = dr.String()
+	}
+	return fmt.Sprintf("dangling references found: %s", strings.Join(refs, ", "))
+}
+
+// ValidateReferences resolves the references between the resources of the snapshot
+// and returns a DanglingReferencesError if any of them points to a resource that
+// the snapshot does not hold. The following references are resolved:
+// - listeners to route configurations through RDS
+// - routes and tcp proxies to clusters
+// - any resource to secrets through SDS
+// - clusters to endpoints through EDS
+// Only references to resources served by the aggregated discovery service are
+// resolved, as resources fetched from other sources cannot be checked.
+func ValidateReferences(snap Snapshot) error {
+
+	known := map[envoy.Type]map[string]envoy.Resource{}
+	for _, rType := range []envoy.Type{envoy.Endpoint, envoy.Cluster, envoy.Route, envoy.Secret} {
+		known[rType] = snap.GetResources(rType)
+	}
+
+	dangling := DanglingReferencesError{}
+	for _, rType := range []envoy.Type{envoy.Cluster, envoy.Route, envoy.Listener} {
+		for name, res := range snap.GetResources(rType) {
+			w := &referenceWalker{refs: map[envoy.Type]map[string]bool{}}
+			w.walk(protov1.MessageReflect(res))
+			for refType, names := range w.refs {
+				for refName := range names {
+					if _, ok := known[refType][refName]; !ok {
+						dangling = append(dangling, DanglingReference{
+							Type: rType, Name: name, ReferencedType: refType, ReferencedName: refName,
+						})
+					}
+				}
+			}
+		}
+	}
+
+	if len(dangling) == 0 {
+		return nil
+	}
+
+	// Sort the list to get a stable error message
+	sort.SliceStable(dangling, func(i, j int) bool { return dangling[i].String() < dangling[j].String() })
+	return dangling
+}
+
+// referenceWalker traverses a resource collecting the names of the resources
+// it references. The traversal uses protobuf reflection so the same code works
+// for any version of the envoy API.
+type referenceWalker struct {
+	refs map[envoy.Type]map[string]bool
+}
+
+func (w *referenceWalker) add(rType envoy.Type, name string) {
+	if name == "" {
+		return
+	}
+	if _, ok := w.refs[rType]; !ok {
+		w.refs[rType] = map[string]bool{}
+	}
+	w.refs[rType][name] = true
+}
+
+func (w *referenceWalker) walk(m protoreflect.Message) {
+
+	name := string(m.Descriptor().FullName())
+
+	switch {
+	case name == "google.protobuf.Any":
+		// Typed configs of filters and extensions are unpacked so
+		// the references they hold are also resolved
+		if msg := unpackAny(m); msg != nil {
+			w.walk(msg)
+		}
+		return
+
+	case strings.HasSuffix(name, ".http_connection_manager.v2.Rds") ||
+		strings.HasSuffix(name, ".http_connection_manager.v3.Rds"):
+		if isServedByADS(m, "config_source") {
+			w.add(envoy.Route, stringField(m, "route_config_name"))
+		}
+
+	case strings.HasSuffix(name, ".RouteAction"),
+		strings.HasSuffix(name, ".TcpProxy"):
+		w.add(envoy.Cluster, stringField(m, "cluster"))
+
+	case strings.HasSuffix(name, ".WeightedCluster.ClusterWeight"):
+		w.add(envoy.Cluster, stringField(m, "name"))
+
+	case strings.HasSuffix(name, ".SdsSecretConfig"):
+		if isServedByADS(m, "sds_config") {
+			w.add(envoy.Secret, stringField(m, "name"))
+		}
+
+	case name == "envoy.api.v2.Cluster" || name == "envoy.config.cluster.v3.Cluster":
+		if isEnumField(m, "type", "EDS") {
+			eds := messageField(m, "eds_cluster_config")
+			if eds == nil || isServedByADS(eds, "eds_config") {
+				serviceName := ""
+				if eds != nil {
+					serviceName = stringField(eds, "service_name")
+				}
+				if serviceName == "" {
+					serviceName = stringField(m, "name")
+				}
+				w.add(envoy.Endpoint, serviceName)
+			}
+		}
+	}
+
+	m.Range(func(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+		switch {
+		case fd.IsList() && fd.Message() != nil:
+			list := v.List()
+			for i := 0; i < list.Len(); i++ {
+				w.walk(list.Get(i).Message())
+			}
+		case fd.IsMap() && fd.MapValue().Message() != nil:
+			v.Map().Range(func(_ protoreflect.MapKey, mv protoreflect.Value) bool {
+				w.walk(mv.Message())
+				return true
+			})
+		case !fd.IsList() && !fd.IsMap() && fd.Message() != nil:
+			w.walk(v.Message())
+		}
+		return true
+	})
+}
+
+// isServedByADS returns true if the ConfigSource held in the given field
+// is unset or points to the aggregated discovery service
+func isServedByADS(m protoreflect.Message, field string) bool {
+	cs := messageField(m, field)
+	if cs == nil {
+		return true
+	}
+	for _, name := range []protoreflect.Name{"ads", "self"} {
+		if fd := cs.Descriptor().Fields().ByName(name); fd != nil && cs.Has(fd) {
+			return true
+		}
+	}
+	// The config source is unset if none of the specifiers is set
+	if oneof := cs.Descriptor().Oneofs().ByName("config_source_specifier"); oneof != nil {
+		return cs.WhichOneof(oneof) == nil
+	}
+	return false
+}
+
+func stringField(m protoreflect.Message, field string) string {
+	fd := m.Descriptor().Fields().ByName(protoreflect.Name(field))
+	if fd == nil || fd.Kind() != protoreflect.StringKind || fd.IsList() || !m.Has(fd) {
+		return ""
+	}
+	return m.Get(fd).String()
+}
+
+func isEnumField(m protoreflect.Message, field, value string) bool {
+	fd := m.Descriptor().Fields().ByName(protoreflect.Name(field))
+	if fd == nil || fd.Enum() == nil || fd.IsList() {
+		return false
+	}
+	ev := fd.Enum().Values().ByNumber(m.Get(fd).Enum())
+	return ev != nil && string(ev.Name()) == value
+}
+
+func messageField(m protoreflect.Message, field string) protoreflect.Message {
+	fd := m.Descriptor().Fields().ByName(protoreflect.Name(field))
+	if fd == nil || fd.Message() == nil || fd.IsList() || fd.IsMap() || !m.Has(fd) {
+		return nil
+	}
+	return m.Get(fd).Message()
+}
+
+// unpackAny returns the message held in an Any or nil if the
+// type is not registered or the value cannot be unmarshalled
+func unpackAny(m protoreflect.Message) protoreflect.Message {
+	url := stringField(m, "type_url")
+	fd := m.Descriptor().Fields().ByName("value")
+	if url == "" || fd == nil {
+		return nil
+	}
+	mt, err := protoregistry.GlobalTypes.FindMessageByURL(url)
+	if err != nil {
+		return nil
+	}
+	msg := mt.New()
+	if err := proto.Unmarshal(m.Get(fd).Bytes(), msg.Interface()); err != nil {
+		return nil
+	}
+	return msg
+}
@@ -0,0 +1,163 @@
+package stats
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/3scale/marin3r/pkg/envoy"
+)
+
+// Stats keeps track of the streams that envoy proxies open against the
+// xDS server, together with the versions each proxy has acknowledged (ACK)
+// or rejected (NACK). It is safe for concurrent use.
+type Stats struct {
+	mu      sync.RWMutex
+	streams map[int64]*stream
+}
+
+type stream struct {
+	nodeID        string
+	address       string
+	openedAt      time.Time
+	requests      int64
+	responses     int64
+	ackedVersions map[envoy.Type]string
+	lastNack      *NACK
+}
+
+// NACK holds the details of the last discovery response rejected by a proxy
+type NACK struct {
+	Type    envoy.Type
+	Version string
+	Message string
+	At      time.Time
+}
+
+// Proxy is a point in time view of the streams that a single envoy proxy,
+// identified by its nodeID and peer address, has open against the xDS server.
+type Proxy struct {
+	NodeID        string
+	Address       string
+	Streams       int
+	Requests      int64
+	Responses     int64
+	ConnectedAt   time.Time
+	AckedVersions map[envoy.Type]string
+	LastNack      *NACK
+}
+
+// New returns a new Stats object
+func New() *Stats {
+	return &Stats{streams: map[int64]*stream{}}
+}
+
+// OpenStream registers a new stream. The address is the
+// peer address of the envoy proxy that opened the stream.
+func (s *Stats) OpenStream(id int64, address string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.streams[id] = &stream{
+		address:       address,
+		openedAt:      time.Now(),
+		ackedVersions: map[envoy.Type]string{},
+	}
+}
+
+// CloseStream removes a stream
+func (s *Stats) CloseStream(id int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.streams, id)
+}
+
+// ReportRequest records a discovery request received in a stream. A non empty
+// version means that the proxy has applied that version of the given resource type.
+// Any version applied after a NACK of the same resource type clears the NACK, as
+// it is either the rejected version being fixed or a rollback to a previous one.
+func (s *Stats) ReportRequest(id int64, nodeID string, rType envoy.Type, version string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	st := s.getOrCreate(id)
+	st.nodeID = nodeID
+	st.requests++
+	if version != "" {
+		st.ackedVersions[rType] = version
+		if st.lastNack != nil && st.lastNack.Type == rType {
+			st.lastNack = nil
+		}
+	}
+}
+
+// ReportNACK records that the proxy in the given stream has rejected
+// the given version of a resource type.
+func (s *Stats) ReportNACK(id int64, nodeID string, rType envoy.Type, version, msg string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	st := s.getOrCreate(id)
+	st.nodeID = nodeID
+	st.lastNack = &NACK{Type: rType, Version: version, Message: msg, At: time.Now()}
+}
+
+// ReportResponse records a discovery response sent in a stream
+func (s *Stats) ReportResponse(id int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.getOrCreate(id).responses++
+}
+
+// GetProxies returns the list of proxies connected for the given nodeID,
+// sorted by address.
+func (s *Stats) GetProxies(nodeID string) []Proxy {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	proxies := map[string]*Proxy{}
+	for _, st := range s.streams {
+		if st.nodeID != nodeID {
+			continue
+		}
+		p, ok := proxies[st.address]
+		if !ok {
+			p = &Proxy{
+				NodeID:        nodeID,
+				Address:       st.address,
+				ConnectedAt:   st.openedAt,
+				AckedVersions: map[envoy.Type]string{},
+			}
+			proxies[st.address] = p
+		}
+		p.Streams++
+		p.Requests += st.requests
+		p.Responses += st.responses
+		if st.openedAt.Before(p.ConnectedAt) {
+			p.ConnectedAt = st.openedAt
+		}
+		for rType, version := range st.ackedVersions {
+			p.AckedVersions[rType] = version
+		}
+		if st.lastNack != nil && (p.LastNack == nil || st.lastNack.At.After(p.LastNack.At)) {
+			nack := *st.lastNack
+			p.LastNack = &nack
+		}
+	}
+
+	list := make([]Proxy, 0, len(proxies))
+	for _, p := range proxies {
+		list = append(list, *p)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Address < list[j].Address })
+
+	return list
+}
+
+func (s *Stats) getOrCreate(id int64) *stream {
+	st, ok := s.streams[id]
+	if !ok {
+		// The stream was opened before stats were being
+		// collected, register it with an unknown address
+		st = &stream{openedAt: time.Now(), ackedVersions: map[envoy.Type]string{}}
+		s.streams[id] = st
+	}
+	return st
+}
@@ -0,0 +1,95 @@
+package stats
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/3scale/marin3r/pkg/envoy"
+)
+
+func TestStats_ReportRequest(t *testing.T) {
+	s := New()
+	s.OpenStream(1, "10.0.0.1:5000")
+	s.ReportRequest(1, "node1", envoy.Cluster, "")
+	s.ReportRequest(1, "node1", envoy.Cluster, "xxxx")
+	s.ReportRequest(1, "node1", envoy.Listener, "xxxx")
+
+	got := s.GetProxies("node1")
+	if len(got) != 1 {
+		t.Fatalf("Stats.GetProxies() returned %v proxies, want 1", len(got))
+	}
+	if got[0].Requests != 3 {
+		t.Errorf("Stats.GetProxies() Requests = %v, want 3", got[0].Requests)
+	}
+	want := map[envoy.Type]string{envoy.Cluster: "xxxx", envoy.Listener: "xxxx"}
+	if !reflect.DeepEqual(got[0].AckedVersions, want) {
+		t.Errorf("Stats.GetProxies() AckedVersions = %v, want %v", got[0].AckedVersions, want)
+	}
+}
+
+func TestStats_ReportNACK(t *testing.T) {
+	s := New()
+	s.OpenStream(1, "10.0.0.1:5000")
+	s.ReportRequest(1, "node1", envoy.Cluster, "xxxx")
+	s.ReportNACK(1, "node1", envoy.Cluster, "zzzz", "error")
+
+	got := s.GetProxies("node1")
+	if got[0].LastNack == nil || got[0].LastNack.Version != "zzzz" || got[0].LastNack.Message != "error" {
+		t.Fatalf("Stats.GetProxies() LastNack = %v, want version 'zzzz'", got[0].LastNack)
+	}
+
+	// An ACK of the rejected version clears the NACK
+	s.ReportRequest(1, "node1", envoy.Cluster, "zzzz")
+	if got := s.GetProxies("node1"); got[0].LastNack != nil {
+		t.Errorf("Stats.GetProxies() LastNack = %v, want nil", got[0].LastNack)
+	}
+}
+
+func TestStats_ReportNACK_Rollback(t *testing.T) {
+	s := New()
+	s.OpenStream(1, "10.0.0.1:5000")
+	s.ReportRequest(1, "node1", envoy.Cluster, "xxxx")
+	s.ReportNACK(1, "node1", envoy.Cluster, "zzzz", "error")
+
+	// An ACK of another resource type keeps the NACK
+	s.ReportRequest(1, "node1", envoy.Listener, "yyyy")
+	if got := s.GetProxies("node1"); got[0].LastNack == nil {
+		t.Fatalf("Stats.GetProxies() LastNack = nil, want version 'zzzz'")
+	}
+
+	// An ACK of the previous version after a rollback clears the NACK
+	s.ReportRequest(1, "node1", envoy.Cluster, "xxxx")
+	if got := s.GetProxies("node1"); got[0].LastNack != nil {
+		t.Errorf("Stats.GetProxies() LastNack = %v, want nil", got[0].LastNack)
+	}
+}
+
+func TestStats_GetProxies(t *testing.T) {
+	s := New()
+	s.OpenStream(1, "10.0.0.2:5000")
+	s.OpenStream(2, "10.0.0.1:5000")
+	s.OpenStream(3, "10.0.0.1:5000")
+	s.OpenStream(4, "10.0.0.3:5000")
+	s.ReportRequest(1, "node1", envoy.Cluster, "")
+	s.ReportRequest(2, "node1", envoy.Cluster, "")
+	s.ReportRequest(3, "node1", envoy.Listener, "")
+	s.ReportRequest(4, "node2", envoy.Cluster, "")
+	s.ReportResponse(2)
+	s.ReportResponse(3)
+
+	got := s.GetProxies("node1")
+	if len(got) != 2 {
+		t.Fatalf("Stats.GetProxies() returned %v proxies, want 2", len(got))
+	}
+	if got[0].Address != "10.0.0.1:5000" || got[0].Streams != 2 || got[0].Responses != 2 {
+		t.Errorf("Stats.GetProxies()[0] = %+v, want address '10.0.0.1:5000' with 2 streams and 2 responses", got[0])
+	}
+	if got[1].Address != "10.0.0.2:5000" || got[1].Streams != 1 {
+		t.Errorf("Stats.GetProxies()[1] = %+v, want address '10.0.0.2:5000' with 1 stream", got[1])
+	}
+
+	s.CloseStream(1)
+	if got := s.GetProxies("node1"); len(got) != 1 {
+		t.Errorf("Stats.GetProxies() returned %v proxies after closing a stream, want 1", len(got))
+	}
+}
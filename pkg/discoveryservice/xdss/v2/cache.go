@@ -2,13 +2,17 @@ package discoveryservice
 
 import (
 	xdss "github.com/3scale/marin3r/pkg/discoveryservice/xdss"
+	"github.com/3scale/marin3r/pkg/discoveryservice/xdss/metrics"
+	"github.com/3scale/marin3r/pkg/envoy"
+	envoy_resources_v2 "github.com/3scale/marin3r/pkg/envoy/resources/v2"
 	cache_types "github.com/envoyproxy/go-control-plane/pkg/cache/types"
 	cache_v2 "github.com/envoyproxy/go-control-plane/pkg/cache/v2"
 )
 
 // Cache implements "github.com/3scale/marin3r/pkg/discoveryservice/xdss".Cache for envoy API v2.
 type Cache struct {
-	v2 cache_v2.SnapshotCache
+	v2      cache_v2.SnapshotCache
+	proxies *xdss.Proxies
 }
 
 // NewCache returns a Cache object.
@@ -16,10 +20,46 @@ func NewCache(v2 cache_v2.SnapshotCache) Cache {
 	return Cache{v2: v2}
 }
 
-// SetSnapshot updates a snapshot for a node.
+// NewCacheWithProxies returns a Cache object that writes snapshots to each one of the
+// proxies tracked by the given Proxies object. The snapshot cache must use a ProxyHash
+// built from the same Proxies object.
+func NewCacheWithProxies(v2 cache_v2.SnapshotCache, proxies *xdss.Proxies) Cache {
+	return Cache{v2: v2, proxies: proxies}
+}
+
+// SetSnapshot updates a snapshot for a node. The snapshot is written
+// for all the proxies connected with that nodeID.
 func (c Cache) SetSnapshot(nodeID string, snap xdss.Snapshot) error {
 
-	return c.v2.SetSnapshot(nodeID, *snap.(Snapshot).v2)
+	// Only the resource types that change are pushed to the proxies
+	old, _ := c.v2.GetSnapshot(nodeID)
+	versions := map[string]string{}
+	for rType, typeURL := range envoy_resources_v2.Mappings() {
+		if version := snap.GetVersion(rType); version != old.GetVersion(typeURL) {
+			versions[typeURL] = version
+		}
+	}
+
+	err := c.proxies.ForEachKey(nodeID, func(key string) error {
+		return c.v2.SetSnapshot(key, toSnapshot(snap))
+	})
+	if err != nil {
+		return err
+	}
+
+	metrics.ReportSnapshotWrite(envoy.APIv2, nodeID, versions)
+
+	return nil
+}
+
+// SetProxySnapshot updates the snapshot for a single proxy, identified by its nodeID
+// and address. An error is returned if the proxy is not connected. The proxy gets
+// the snapshot of the node again on the next call to SetSnapshot.
+func (c Cache) SetProxySnapshot(nodeID, address string, snap xdss.Snapshot) error {
+
+	return c.proxies.WithKey(nodeID, address, func(key string) error {
+		return c.v2.SetSnapshot(key, toSnapshot(snap))
+	})
 }
 
 // GetSnapshot gets the snapshot for a node, and returns an error if not found.
@@ -32,10 +72,23 @@ func (c Cache) GetSnapshot(nodeID string) (xdss.Snapshot, error) {
 	return &Snapshot{v2: &snap}, nil
 }
 
-// ClearSnapshot clears snapshot and info for a node.
+// ClearSnapshot clears snapshot and info for a node
+// and for all the proxies connected with that nodeID.
 func (c Cache) ClearSnapshot(nodeID string) {
 
-	c.v2.ClearSnapshot(nodeID)
+	c.proxies.ForEachKey(nodeID, func(key string) error {
+		c.v2.ClearSnapshot(key)
+		return nil
+	})
+}
+
+// toSnapshot returns the go-control-plane snapshot held by the given
+// xdss.Snapshot, which can be a Snapshot or a *Snapshot
+func toSnapshot(snap xdss.Snapshot) cache_v2.Snapshot {
+	if s, ok := snap.(*Snapshot); ok {
+		return *s.v2
+	}
+	return *snap.(Snapshot).v2
 }
 
 // NewSnapshot returns a Snapshot object
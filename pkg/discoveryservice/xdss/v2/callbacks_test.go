@@ -19,6 +19,7 @@ import (
 	"fmt"
 	"testing"
 
+	"github.com/3scale/marin3r/pkg/discoveryservice/xdss/stats"
 	"github.com/3scale/marin3r/pkg/envoy"
 	envoy_api_v2 "github.com/envoyproxy/go-control-plane/envoy/api/v2"
 	envoy_api_v2_core "github.com/envoyproxy/go-control-plane/envoy/api/v2/core"
@@ -126,7 +127,7 @@ func TestCallbacks_OnStreamRequest(t *testing.T) {
 		{
 			"OnStreamRequest() NACK received",
 			&Callbacks{
-				OnError:       func(a, b, c string, d envoy.APIVersion) error { return nil },
+				OnError:       func(ns, a string, b envoy.Type, c, d string, e envoy.APIVersion) error { return nil },
 				SnapshotCache: fakeTestCache(),
 				Logger:        ctrl.Log,
 			},
@@ -138,10 +139,26 @@ func TestCallbacks_OnStreamRequest(t *testing.T) {
 			}},
 			false,
 		},
+		{
+			"OnStreamRequest() NACK received, stats enabled",
+			&Callbacks{
+				OnError:       func(ns, a string, b envoy.Type, c, d string, e envoy.APIVersion) error { return nil },
+				SnapshotCache: fakeTestCache(),
+				Logger:        ctrl.Log,
+				Stats:         stats.New(),
+			},
+			args{1, &envoy_api_v2.DiscoveryRequest{
+				Node:          &envoy_api_v2_core.Node{Id: "node1", Cluster: "cluster1"},
+				ResourceNames: []string{"string1", "string2"},
+				TypeUrl:       "some-type",
+				ErrorDetail:   &status.Status{Code: 0, Message: "xxxx"},
+			}},
+			false,
+		},
 		{
 			"OnStreamRequest() error",
 			&Callbacks{
-				OnError:       func(a, b, c string, d envoy.APIVersion) error { return nil },
+				OnError:       func(ns, a string, b envoy.Type, c, d string, e envoy.APIVersion) error { return nil },
 				SnapshotCache: fakeTestCache(),
 				Logger:        ctrl.Log,
 			},
@@ -156,7 +173,7 @@ func TestCallbacks_OnStreamRequest(t *testing.T) {
 		{
 			"OnStreamRequest() error calling OnErrorFn",
 			&Callbacks{
-				OnError:       func(a, b, c string, d envoy.APIVersion) error { return fmt.Errorf("err") },
+				OnError:       func(ns, a string, b envoy.Type, c, d string, e envoy.APIVersion) error { return fmt.Errorf("err") },
 				SnapshotCache: fakeTestCache(),
 				Logger:        ctrl.Log,
 			},
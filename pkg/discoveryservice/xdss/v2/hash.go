@@ -0,0 +1,35 @@
+package discoveryservice
+
+import (
+	xdss "github.com/3scale/marin3r/pkg/discoveryservice/xdss"
+	envoy_api_v2_core "github.com/envoyproxy/go-control-plane/envoy/api/v2/core"
+)
+
+// ProxyHash implements go-control-plane/pkg/cache/v2.NodeHash. Proxies registered
+// in the given Proxies object are keyed by namespace, nodeID and address, the rest
+// of them by namespace and nodeID. Proxies that don't report their namespace in the
+// node metadata are considered to belong to Namespace. The nodeID is computed from
+// the fields of the node selected by NodeHash.
+type ProxyHash struct {
+	Proxies   *xdss.Proxies
+	Namespace string
+	NodeHash  xdss.NodeHash
+}
+
+// ID returns the key in the snapshot cache for the given node
+func (h ProxyHash) ID(node *envoy_api_v2_core.Node) string {
+	if node == nil {
+		return ""
+	}
+	return h.Proxies.Key(node, nodeKey(node, h.NodeHash, h.Namespace))
+}
+
+// nodeKey returns the namespace qualified nodeID of the given node
+func nodeKey(node *envoy_api_v2_core.Node, nodeHash xdss.NodeHash, defaultNamespace string) string {
+	return xdss.NodeKey(nodeNamespace(node, defaultNamespace), nodeHash.NodeID(node))
+}
+
+// nodeNamespace returns the namespace of the given node
+func nodeNamespace(node *envoy_api_v2_core.Node, defaultNamespace string) string {
+	return xdss.NamespaceFromMetadata(node.GetMetadata(), defaultNamespace)
+}
@@ -1,6 +1,7 @@
 package discoveryservice
 
 import (
+	xdss "github.com/3scale/marin3r/pkg/discoveryservice/xdss"
 	envoy "github.com/3scale/marin3r/pkg/envoy"
 	envoy_resources_v2 "github.com/3scale/marin3r/pkg/envoy/resources/v2"
 	envoy_api_v2 "github.com/envoyproxy/go-control-plane/envoy/api/v2"
@@ -19,16 +20,17 @@ func NewSnapshot(v2 *cache_v2.Snapshot) Snapshot {
 	return Snapshot{v2: v2}
 }
 
-// Consistent check verifies that the dependent resources are exactly listed in the
-// snapshot:
-// - all EDS resources are listed by name in CDS resources
-// - all RDS resources are listed by name in LDS resources
+// Consistent check verifies that all the references between the resources
+// of the snapshot can be resolved within the snapshot:
+// - all RDS route configurations referenced by listeners exist
+// - all clusters referenced by routes and tcp proxies exist
+// - all SDS secrets referenced by listeners and clusters exist
+// - all EDS endpoints referenced by clusters exist
 //
-// Note that clusters and listeners are requested without name references, so
-// Envoy will accept the snapshot list of clusters as-is even if it does not match
-// all references found in xDS.
+// A xdss.DanglingReferencesError is returned if any of the references cannot
+// be resolved.
 func (s Snapshot) Consistent() error {
-	return s.v2.Consistent()
+	return xdss.ValidateReferences(s)
 }
 
 // SetResource writes the given v2 resource in the Snapshot object.
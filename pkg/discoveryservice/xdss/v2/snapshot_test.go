@@ -6,6 +6,7 @@ import (
 	xdss "github.com/3scale/marin3r/pkg/discoveryservice/xdss"
 	envoy "github.com/3scale/marin3r/pkg/envoy"
 	envoy_resources "github.com/3scale/marin3r/pkg/envoy/resources"
+	envoy_serializer "github.com/3scale/marin3r/pkg/envoy/serializer"
 	testutil "github.com/3scale/marin3r/pkg/util/test"
 	envoy_api_v2 "github.com/envoyproxy/go-control-plane/envoy/api/v2"
 	cache_types "github.com/envoyproxy/go-control-plane/pkg/cache/types"
@@ -13,22 +14,78 @@ import (
 )
 
 func TestSnapshot_Consistent(t *testing.T) {
-	type fields struct {
-		v2 *cache_v2.Snapshot
+	listener := func(configSource string) string {
+		return `{"name":"listener","address":{"socket_address":{"address":"0.0.0.0","port_value":8443}},` +
+			`"filter_chains":[{"filters":[{"name":"envoy.filters.network.http_connection_manager","typed_config":{` +
+			`"@type":"type.googleapis.com/envoy.config.filter.network.http_connection_manager.v2.HttpConnectionManager","stat_prefix":"https",` +
+			`"rds":{"route_config_name":"route","config_source":` + configSource + `}}}],` +
+			`"transport_socket":{"name":"envoy.transport_sockets.tls","typed_config":{` +
+			`"@type":"type.googleapis.com/envoy.api.v2.auth.DownstreamTlsContext","common_tls_context":{` +
+			`"tls_certificate_sds_secret_configs":[{"name":"cert","sds_config":{"ads":{}}}]}}}}]}`
 	}
+	route := `{"name":"route","virtual_hosts":[{"name":"vh","domains":["*"],"routes":[{"match":{"prefix":"/"},"route":{"cluster":"cluster"}}]}]}`
+	cluster := `{"name":"cluster","type":"EDS","eds_cluster_config":{"eds_config":{"ads":{}}}}`
+	endpoint := `{"cluster_name":"cluster"}`
+	secret := `{"name":"cert"}`
+
 	tests := []struct {
-		name    string
-		fields  fields
-		wantErr bool
+		name      string
+		resources map[envoy.Type]map[string]string
+		wantErr   string
 	}{
-		// TODO: Add test cases.
+		{
+			name: "All references resolve within the snapshot",
+			resources: map[envoy.Type]map[string]string{
+				envoy.Listener: {"listener": listener(`{"ads":{}}`)},
+				envoy.Route:    {"route": route},
+				envoy.Cluster:  {"cluster": cluster},
+				envoy.Endpoint: {"cluster": endpoint},
+				envoy.Secret:   {"cert": secret},
+			},
+			wantErr: "",
+		},
+		{
+			name: "Reports dangling references",
+			resources: map[envoy.Type]map[string]string{
+				envoy.Listener: {"listener": listener(`{"ads":{}}`)},
+				envoy.Route:    {"route": route},
+			},
+			wantErr: `dangling references found: listener "listener" references secret "cert", route "route" references cluster "cluster"`,
+		},
+		{
+			name: "Reports dangling EDS and RDS references",
+			resources: map[envoy.Type]map[string]string{
+				envoy.Listener: {"listener": listener(`{"ads":{}}`)},
+				envoy.Cluster:  {"cluster": cluster},
+				envoy.Secret:   {"cert": secret},
+			},
+			wantErr: `dangling references found: cluster "cluster" references endpoint "cluster", listener "listener" references route "route"`,
+		},
+		{
+			name: "Ignores references to resources not served by the aggregated discovery service",
+			resources: map[envoy.Type]map[string]string{
+				envoy.Listener: {"listener": listener(`{"api_config_source":{"api_type":"GRPC","grpc_services":[{"envoy_grpc":{"cluster_name":"xds"}}]}}`)},
+				envoy.Secret:   {"cert": secret},
+			},
+			wantErr: "",
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			s := Snapshot{
-				v2: tt.fields.v2,
+			s := NewCache(nil).NewSnapshot("")
+			decoder := envoy_serializer.NewResourceUnmarshaller(envoy_serializer.JSON, envoy.APIv2)
+			generator := envoy_resources.NewGenerator(envoy.APIv2)
+			for rType, resources := range tt.resources {
+				for name, value := range resources {
+					res := generator.New(rType)
+					if err := decoder.Unmarshal(value, res); err != nil {
+						t.Fatalf("error decoding resource: %v", err)
+					}
+					s.SetResource(name, res)
+				}
 			}
-			if err := s.Consistent(); (err != nil) != tt.wantErr {
+			err := s.Consistent()
+			if (err != nil) != (tt.wantErr != "") || (err != nil && err.Error() != tt.wantErr) {
 				t.Errorf("Snapshot.Consistent() error = %v, wantErr %v", err, tt.wantErr)
 			}
 		})
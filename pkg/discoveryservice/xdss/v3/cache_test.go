@@ -1,13 +1,22 @@
 package discoveryservice
 
 import (
+	"context"
+	"net"
 	"testing"
 
 	xdss "github.com/3scale/marin3r/pkg/discoveryservice/xdss"
+	"github.com/3scale/marin3r/pkg/envoy"
 	testutil "github.com/3scale/marin3r/pkg/util/test"
 	envoy_api_v2 "github.com/envoyproxy/go-control-plane/envoy/api/v2"
+	envoy_config_core_v3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	envoy_service_discovery_v3 "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
 	cache_types "github.com/envoyproxy/go-control-plane/pkg/cache/types"
 	cache_v3 "github.com/envoyproxy/go-control-plane/pkg/cache/v3"
+	resource_v3 "github.com/envoyproxy/go-control-plane/pkg/resource/v3"
+	"google.golang.org/genproto/googleapis/rpc/status"
+	"google.golang.org/grpc/peer"
+	ctrl "sigs.k8s.io/controller-runtime"
 )
 
 func TestCache_SetSnapshot(t *testing.T) {
@@ -198,6 +207,104 @@ func TestCache_ClearSnapshot(t *testing.T) {
 	}
 }
 
+func TestCache_SetProxySnapshot(t *testing.T) {
+	proxies := xdss.NewProxies()
+	c := NewCacheWithProxies(cache_v3.NewSnapshotCache(true, ProxyHash{Proxies: proxies}, nil), proxies)
+	node1, node2 := &envoy_config_core_v3.Node{Id: "node"}, &envoy_config_core_v3.Node{Id: "node"}
+	proxies.OpenStream(1, "10.0.0.1:5000")
+	proxies.OpenStream(2, "10.0.0.2:5000")
+	proxies.Register(1, node1, "node", nil)
+	proxies.Register(2, node2, "node", nil)
+
+	if err := c.SetSnapshot("node", c.NewSnapshot("aaaa")); err != nil {
+		t.Fatalf("Cache.SetSnapshot() error = %v", err)
+	}
+	if err := c.SetProxySnapshot("node", "10.0.0.1:5000", c.NewSnapshot("bbbb")); err != nil {
+		t.Fatalf("Cache.SetProxySnapshot() error = %v", err)
+	}
+	if err := c.SetProxySnapshot("node", "10.0.0.3:5000", c.NewSnapshot("bbbb")); err == nil {
+		t.Errorf("Cache.SetProxySnapshot() expected an error for a proxy that is not connected")
+	}
+
+	for node, want := range map[*envoy_config_core_v3.Node]string{node1: "bbbb", node2: "aaaa"} {
+		snap, err := c.v3.GetSnapshot(ProxyHash{Proxies: proxies}.ID(node))
+		if err != nil || snap.GetVersion(resource_v3.ClusterType) != want {
+			t.Errorf("Cache.SetProxySnapshot() got version %q for proxy, want %q", snap.GetVersion(resource_v3.ClusterType), want)
+		}
+	}
+	if snap, _ := c.GetSnapshot("node"); snap.GetVersion(envoy.Cluster) != "aaaa" {
+		t.Errorf("Cache.SetProxySnapshot() got version %q for nodeID, want 'aaaa'", snap.GetVersion(envoy.Cluster))
+	}
+
+	// Writing the snapshot for the nodeID reaches all the proxies
+	if err := c.SetSnapshot("node", c.NewSnapshot("cccc")); err != nil {
+		t.Fatalf("Cache.SetSnapshot() error = %v", err)
+	}
+	for _, node := range []*envoy_config_core_v3.Node{node1, node2} {
+		if snap, _ := c.v3.GetSnapshot(ProxyHash{Proxies: proxies}.ID(node)); snap.GetVersion(resource_v3.ClusterType) != "cccc" {
+			t.Errorf("Cache.SetSnapshot() got version %q for proxy, want 'cccc'", snap.GetVersion(resource_v3.ClusterType))
+		}
+	}
+}
+
+func TestCache_SetProxySnapshot_freshNodes(t *testing.T) {
+	proxies := xdss.NewProxies()
+	snapshotCache := cache_v3.NewSnapshotCache(true, ProxyHash{Proxies: proxies}, nil)
+	c := NewCacheWithProxies(snapshotCache, proxies)
+	var failingVersion string
+	cb := &Callbacks{
+		OnError: func(ns, nodeID string, rType envoy.Type, version, msg string, envoyAPI envoy.APIVersion) error {
+			failingVersion = version
+			return nil
+		},
+		SnapshotCache: &snapshotCache,
+		Logger:        ctrl.Log,
+		Proxies:       proxies,
+	}
+	ctx := peer.NewContext(context.Background(), &peer.Peer{Addr: &net.TCPAddr{IP: net.ParseIP("10.0.0.1"), Port: 5000}})
+
+	if err := c.SetSnapshot("node", c.NewSnapshot("aaaa")); err != nil {
+		t.Fatalf("Cache.SetSnapshot() error = %v", err)
+	}
+	if err := cb.OnStreamOpen(ctx, 1, resource_v3.ClusterType); err != nil {
+		t.Fatalf("Callbacks.OnStreamOpen() error = %v", err)
+	}
+	if err := cb.OnStreamRequest(1, &envoy_service_discovery_v3.DiscoveryRequest{
+		Node: &envoy_config_core_v3.Node{Id: "node"}, TypeUrl: resource_v3.ClusterType,
+	}); err != nil {
+		t.Fatalf("Callbacks.OnStreamRequest() error = %v", err)
+	}
+	if err := c.SetProxySnapshot("node", "10.0.0.1:5000", c.NewSnapshot("bbbb")); err != nil {
+		t.Fatalf("Cache.SetProxySnapshot() error = %v", err)
+	}
+
+	// The xDS server passes a new node object to the callbacks and the snapshot
+	// cache each time the proxy sends its node in a request
+	req := &envoy_service_discovery_v3.DiscoveryRequest{
+		Node:        &envoy_config_core_v3.Node{Id: "node"},
+		TypeUrl:     resource_v3.ClusterType,
+		VersionInfo: "aaaa",
+		ErrorDetail: &status.Status{Message: "rejected"},
+	}
+	if err := cb.OnStreamRequest(1, req); err != nil {
+		t.Fatalf("Callbacks.OnStreamRequest() error = %v", err)
+	}
+	if failingVersion != "bbbb" {
+		t.Errorf("Callbacks.OnStreamRequest() got failing version %q, want 'bbbb'", failingVersion)
+	}
+	if got := (ProxyHash{Proxies: proxies}).ID(req.Node); got != "node@10.0.0.1:5000" {
+		t.Errorf("ProxyHash.ID() = %q, want 'node@10.0.0.1:5000'", got)
+	}
+
+	// The watch gets the proxy snapshot right away
+	if rsp, _ := snapshotCache.CreateWatch(req); len(rsp) != 1 {
+		t.Errorf("SnapshotCache.CreateWatch() expected a response with the proxy snapshot")
+	}
+	if keys := snapshotCache.GetStatusKeys(); len(keys) != 1 || keys[0] != "node@10.0.0.1:5000" {
+		t.Errorf("SnapshotCache.CreateWatch() got status keys %v, want [node@10.0.0.1:5000]", keys)
+	}
+}
+
 func TestCache_NewSnapshot(t *testing.T) {
 	type fields struct {
 		v3 cache_v3.SnapshotCache
@@ -17,13 +17,20 @@ package discoveryservice
 import (
 	"context"
 	"fmt"
+	"sync"
 
+	xdss "github.com/3scale/marin3r/pkg/discoveryservice/xdss"
+	"github.com/3scale/marin3r/pkg/discoveryservice/xdss/metrics"
+	"github.com/3scale/marin3r/pkg/discoveryservice/xdss/stats"
 	"github.com/3scale/marin3r/pkg/envoy"
 	envoy_resources_v3 "github.com/3scale/marin3r/pkg/envoy/resources/v3"
 	envoy_serializer "github.com/3scale/marin3r/pkg/envoy/serializer"
 	envoy_service_discovery_v3 "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
 	cache_v3 "github.com/envoyproxy/go-control-plane/pkg/cache/v3"
 	"github.com/go-logr/logr"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	grpc_status "google.golang.org/grpc/status"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 )
 
@@ -31,15 +38,45 @@ var logger = log.Log.WithName("xds_server").WithName("v3")
 
 // Callbacks is a type that implements go-control-plane/pkg/server/Callbacks
 type Callbacks struct {
-	OnError       func(nodeID, previousVersion, msg string, envoyAPI envoy.APIVersion) error
+	OnError       func(namespace, nodeID string, rType envoy.Type, failingVersion, msg string, envoyAPI envoy.APIVersion) error
 	SnapshotCache *cache_v3.SnapshotCache
 	Logger        logr.Logger
+	Stats         *stats.Stats
+	Proxies       *xdss.Proxies
+	// Namespace is the namespace of the proxies that don't
+	// report their namespace in the node metadata
+	Namespace string
+	// NodeHash computes the nodeID of the proxies
+	NodeHash xdss.NodeHash
+	// Authenticator, when set, identifies the client of each stream when the
+	// stream is opened. Streams of clients that cannot be identified are closed.
+	// Clients are identified by their certificate if not set.
+	Authenticator xdss.Authenticator
+	// Authorizer, when set, decides if the client of a stream
+	// can fetch the resources of the nodeID it requests
+	Authorizer xdss.Authorizer
+	identities sync.Map
 }
 
 // OnStreamOpen implements go-control-plane/pkg/server/Callbacks.OnStreamOpen
 // Returning an error will end processing and close the stream. OnStreamClosed will still be called.
 func (cb *Callbacks) OnStreamOpen(ctx context.Context, id int64, typ string) error {
 	cb.Logger.V(1).Info("Stream opened", "StreamId", id)
+	metrics.ReportStreamOpened(envoy.APIv3, id)
+	var address string
+	if p, ok := peer.FromContext(ctx); ok {
+		address = p.Addr.String()
+	}
+	if cb.Stats != nil {
+		cb.Stats.OpenStream(id, address)
+	}
+	cb.Proxies.OpenStream(id, address)
+	identity, err := cb.authenticate(ctx)
+	if err != nil {
+		cb.Logger.Error(err, "Client not authenticated", "StreamID", id)
+		return err
+	}
+	cb.identities.Store(id, identity)
 	return nil
 }
 
@@ -47,6 +84,14 @@ func (cb *Callbacks) OnStreamOpen(ctx context.Context, id int64, typ string) err
 // OnStreamClosed is called immediately prior to closing an xDS stream with a stream ID.
 func (cb *Callbacks) OnStreamClosed(id int64) {
 	cb.Logger.V(1).Info("Stream closed", "StreamID", id)
+	metrics.ReportStreamClosed(envoy.APIv3, id)
+	if cb.Stats != nil {
+		cb.Stats.CloseStream(id)
+	}
+	cb.Proxies.CloseStream(id, func(key string) {
+		(*cb.SnapshotCache).ClearSnapshot(key)
+	})
+	cb.identities.Delete(id)
 }
 
 // OnStreamRequest implements go-control-plane/pkg/server/Callbacks.OnStreamRequest
@@ -55,16 +100,46 @@ func (cb *Callbacks) OnStreamClosed(id int64) {
 func (cb *Callbacks) OnStreamRequest(id int64, req *envoy_service_discovery_v3.DiscoveryRequest) error {
 	cb.Logger.V(1).Info("Received request", "ResourceNames", req.ResourceNames, "Version", req.VersionInfo, "TypeURL", req.TypeUrl, "NodeID", req.Node.Id, "StreamID", id)
 
+	// Proxies are tracked by their namespace qualified nodeID
+	namespace := nodeNamespace(req.Node, cb.Namespace)
+	nodeID := cb.NodeHash.NodeID(req.Node)
+	nodeKey := xdss.NodeKey(namespace, nodeID)
+
+	var identity *xdss.ClientIdentity
+	if v, ok := cb.identities.Load(id); ok {
+		identity = v.(*xdss.ClientIdentity)
+	}
+	if err := cb.authorize(identity, namespace, nodeID); err != nil {
+		cb.Logger.Error(err, "Request not authorized", "NodeID", nodeID, "Namespace", namespace, "StreamID", id)
+		return err
+	}
+
+	metrics.ReportRequest(envoy.APIv3, id, nodeKey, req.TypeUrl, req.VersionInfo)
+	if cb.Stats != nil {
+		cb.Stats.ReportRequest(id, nodeKey, resourceType(req.TypeUrl), req.VersionInfo)
+	}
+
+	// Proxies get the snapshot of their nodeID when they connect
+	cb.Proxies.Register(id, req.Node, nodeKey, func(key string) {
+		if snap, err := (*cb.SnapshotCache).GetSnapshot(nodeKey); err == nil {
+			(*cb.SnapshotCache).SetSnapshot(key, snap)
+		}
+	})
+
 	if req.ErrorDetail != nil {
-		snap, err := (*cb.SnapshotCache).GetSnapshot(req.Node.Id)
+		snap, err := (*cb.SnapshotCache).GetSnapshot(cb.Proxies.Key(req.Node, nodeKey))
 		if err != nil {
 			return err
 		}
-		// All resource types are always kept at the same version
+		// Each resource type is versioned independently
 		failingVersion := snap.GetVersion(req.TypeUrl)
-		cb.Logger.Error(fmt.Errorf(req.ErrorDetail.Message), "A gateway reported an error", "CurrentVersion", req.VersionInfo, "FailingVersion", failingVersion, "NodeID", req.Node.Id, "StreamID", id)
-		if err := cb.OnError(req.Node.Id, failingVersion, req.ErrorDetail.Message, envoy.APIv3); err != nil {
-			cb.Logger.Error(err, "Error calling OnErrorFn", "NodeID", req.Node.Id, "StreamID", id)
+		cb.Logger.Error(fmt.Errorf(req.ErrorDetail.Message), "A gateway reported an error", "CurrentVersion", req.VersionInfo, "FailingVersion", failingVersion, "NodeID", nodeID, "StreamID", id)
+		metrics.ReportNACK(envoy.APIv3, nodeKey, req.TypeUrl)
+		if cb.Stats != nil {
+			cb.Stats.ReportNACK(id, nodeKey, resourceType(req.TypeUrl), failingVersion, req.ErrorDetail.Message)
+		}
+		if err := cb.OnError(namespace, nodeID, resourceType(req.TypeUrl), failingVersion, req.ErrorDetail.Message, envoy.APIv3); err != nil {
+			cb.Logger.Error(err, "Error calling OnErrorFn", "NodeID", nodeID, "StreamID", id)
 			return err
 		}
 	}
@@ -74,6 +149,10 @@ func (cb *Callbacks) OnStreamRequest(id int64, req *envoy_service_discovery_v3.D
 // OnStreamResponse implements go-control-plane/pkgserver/Callbacks.OnStreamResponse
 // OnStreamResponse is called immediately prior to sending a response on a stream.
 func (cb *Callbacks) OnStreamResponse(id int64, req *envoy_service_discovery_v3.DiscoveryRequest, rsp *envoy_service_discovery_v3.DiscoveryResponse) {
+	metrics.ReportResponse(envoy.APIv3, rsp.TypeUrl)
+	if cb.Stats != nil {
+		cb.Stats.ReportResponse(id)
+	}
 	resources := []string{}
 	for _, r := range rsp.Resources {
 		j, _ := envoy_serializer.NewResourceMarshaller(envoy_serializer.JSON, envoy.APIv3).Marshal(r)
@@ -92,6 +171,17 @@ func (cb *Callbacks) OnStreamResponse(id int64, req *envoy_service_discovery_v3.
 // OnFetchRequest is called for each Fetch request. Returning an error will end processing of the
 // request and respond with an error.
 func (cb *Callbacks) OnFetchRequest(ctx context.Context, req *envoy_service_discovery_v3.DiscoveryRequest) error {
+	identity, err := cb.authenticate(ctx)
+	if err != nil {
+		cb.Logger.Error(err, "Client not authenticated")
+		return err
+	}
+	namespace := nodeNamespace(req.Node, cb.Namespace)
+	nodeID := cb.NodeHash.NodeID(req.Node)
+	if err := cb.authorize(identity, namespace, nodeID); err != nil {
+		cb.Logger.Error(err, "Request not authorized", "NodeID", nodeID, "Namespace", namespace)
+		return err
+	}
 	return nil
 }
 
@@ -99,3 +189,38 @@ func (cb *Callbacks) OnFetchRequest(ctx context.Context, req *envoy_service_disc
 // OnFetchResponse is called immediately prior to sending a response.
 func (cb *Callbacks) OnFetchResponse(req *envoy_service_discovery_v3.DiscoveryRequest, resp *envoy_service_discovery_v3.DiscoveryResponse) {
 }
+
+// authenticate returns the identity of the client of the given stream context.
+// An Unauthenticated gRPC error is returned if the client cannot be identified.
+func (cb *Callbacks) authenticate(ctx context.Context) (*xdss.ClientIdentity, error) {
+	if cb.Authenticator == nil {
+		return xdss.IdentityFromContext(ctx), nil
+	}
+	identity, err := cb.Authenticator.Authenticate(ctx)
+	if err != nil {
+		return nil, grpc_status.Error(codes.Unauthenticated, err.Error())
+	}
+	return identity, nil
+}
+
+// authorize checks that the client with the given identity can fetch the resources
+// of the nodeID. A PermissionDenied gRPC error is returned if it cannot.
+func (cb *Callbacks) authorize(identity *xdss.ClientIdentity, namespace, nodeID string) error {
+	if cb.Authorizer == nil {
+		return nil
+	}
+	if err := cb.Authorizer.Authorize(identity, namespace, nodeID); err != nil {
+		return grpc_status.Error(codes.PermissionDenied, err.Error())
+	}
+	return nil
+}
+
+// resourceType returns the envoy.Type that corresponds to the given type URL
+func resourceType(typeURL string) envoy.Type {
+	for rType, url := range envoy_resources_v3.Mappings() {
+		if url == typeURL {
+			return rType
+		}
+	}
+	return envoy.Type(typeURL)
+}
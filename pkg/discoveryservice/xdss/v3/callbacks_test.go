@@ -19,16 +19,20 @@ import (
 	"fmt"
 	"testing"
 
+	xdss "github.com/3scale/marin3r/pkg/discoveryservice/xdss"
+	"github.com/3scale/marin3r/pkg/discoveryservice/xdss/stats"
 	"github.com/3scale/marin3r/pkg/envoy"
 	envoy_api_v2 "github.com/envoyproxy/go-control-plane/envoy/api/v2"
 	envoy_config_core_v3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
 	envoy_service_discovery_v3 "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
 	cache_types "github.com/envoyproxy/go-control-plane/pkg/cache/types"
 	cache_v3 "github.com/envoyproxy/go-control-plane/pkg/cache/v3"
+	resource_v3 "github.com/envoyproxy/go-control-plane/pkg/resource/v3"
 
 	"github.com/golang/protobuf/ptypes/any"
 	"google.golang.org/genproto/googleapis/rpc/status"
 	ctrl "sigs.k8s.io/controller-runtime"
+	ctrlmetrics "sigs.k8s.io/controller-runtime/pkg/metrics"
 )
 
 func fakeTestCache() *cache_v3.SnapshotCache {
@@ -53,6 +57,24 @@ func fakeTestCache() *cache_v3.SnapshotCache {
 	return &snapshotCache
 }
 
+type testAuthorizer struct{ allowed string }
+
+func (a testAuthorizer) Authorize(identity *xdss.ClientIdentity, namespace, nodeID string) error {
+	if nodeID != a.allowed {
+		return fmt.Errorf("not allowed")
+	}
+	return nil
+}
+
+type testAuthenticator struct{ err error }
+
+func (a testAuthenticator) Authenticate(ctx context.Context) (*xdss.ClientIdentity, error) {
+	if a.err != nil {
+		return nil, a.err
+	}
+	return &xdss.ClientIdentity{CommonName: "client"}, nil
+}
+
 func TestCallbacks_OnStreamOpen(t *testing.T) {
 	type args struct {
 		ctx context.Context
@@ -71,6 +93,18 @@ func TestCallbacks_OnStreamOpen(t *testing.T) {
 			args{context.Background(), 1, "xxxx"},
 			false,
 		},
+		{
+			"OnStreamOpen() authenticated",
+			&Callbacks{Logger: ctrl.Log, Authenticator: testAuthenticator{}},
+			args{context.Background(), 1, "xxxx"},
+			false,
+		},
+		{
+			"OnStreamOpen() not authenticated",
+			&Callbacks{Logger: ctrl.Log, Authenticator: testAuthenticator{fmt.Errorf("invalid token")}},
+			args{context.Background(), 1, "xxxx"},
+			true,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -124,12 +158,46 @@ func TestCallbacks_OnStreamRequest(t *testing.T) {
 			}},
 			false,
 		},
+		{
+			"OnStreamRequest() authorized",
+			&Callbacks{Logger: ctrl.Log, Authorizer: testAuthorizer{"node1"}},
+			args{1, &envoy_service_discovery_v3.DiscoveryRequest{
+				Node:    &envoy_config_core_v3.Node{Id: "node1", Cluster: "cluster1"},
+				TypeUrl: "some-type",
+			}},
+			false,
+		},
+		{
+			"OnStreamRequest() not authorized",
+			&Callbacks{Logger: ctrl.Log, Authorizer: testAuthorizer{"node2"}},
+			args{1, &envoy_service_discovery_v3.DiscoveryRequest{
+				Node:    &envoy_config_core_v3.Node{Id: "node1", Cluster: "cluster1"},
+				TypeUrl: "some-type",
+			}},
+			true,
+		},
 		{
 			"OnStreamRequest() NACK received",
 			&Callbacks{
-				OnError:       func(a, b, c string, d envoy.APIVersion) error { return nil },
+				OnError:       func(ns, a string, b envoy.Type, c, d string, e envoy.APIVersion) error { return nil },
+				SnapshotCache: fakeTestCache(),
+				Logger:        ctrl.Log,
+			},
+			args{1, &envoy_service_discovery_v3.DiscoveryRequest{
+				Node:          &envoy_config_core_v3.Node{Id: "node1", Cluster: "cluster1"},
+				ResourceNames: []string{"string1", "string2"},
+				TypeUrl:       "some-type",
+				ErrorDetail:   &status.Status{Code: 0, Message: "xxxx"},
+			}},
+			false,
+		},
+		{
+			"OnStreamRequest() NACK received, stats enabled",
+			&Callbacks{
+				OnError:       func(ns, a string, b envoy.Type, c, d string, e envoy.APIVersion) error { return nil },
 				SnapshotCache: fakeTestCache(),
 				Logger:        ctrl.Log,
+				Stats:         stats.New(),
 			},
 			args{1, &envoy_service_discovery_v3.DiscoveryRequest{
 				Node:          &envoy_config_core_v3.Node{Id: "node1", Cluster: "cluster1"},
@@ -142,7 +210,7 @@ func TestCallbacks_OnStreamRequest(t *testing.T) {
 		{
 			"OnStreamRequest() error",
 			&Callbacks{
-				OnError:       func(a, b, c string, d envoy.APIVersion) error { return nil },
+				OnError:       func(ns, a string, b envoy.Type, c, d string, e envoy.APIVersion) error { return nil },
 				SnapshotCache: fakeTestCache(),
 				Logger:        ctrl.Log,
 			},
@@ -157,7 +225,7 @@ func TestCallbacks_OnStreamRequest(t *testing.T) {
 		{
 			"OnStreamRequest() error calling OnErrorFn",
 			&Callbacks{
-				OnError:       func(a, b, c string, d envoy.APIVersion) error { return fmt.Errorf("err") },
+				OnError:       func(ns, a string, b envoy.Type, c, d string, e envoy.APIVersion) error { return fmt.Errorf("err") },
 				SnapshotCache: fakeTestCache(),
 				Logger:        ctrl.Log,
 			},
@@ -179,6 +247,46 @@ func TestCallbacks_OnStreamRequest(t *testing.T) {
 	}
 }
 
+func TestCallbacks_OnStreamRequest_snapshotACKMetric(t *testing.T) {
+	// ackCount returns the number of snapshot ACKs observed by the v3 histogram
+	ackCount := func() uint64 {
+		families, _ := ctrlmetrics.Registry.Gather()
+		for _, family := range families {
+			if family.GetName() != "marin3r_xds_snapshot_ack_duration_seconds" {
+				continue
+			}
+			for _, m := range family.GetMetric() {
+				for _, l := range m.GetLabel() {
+					if l.GetName() == "api_version" && l.GetValue() == envoy.APIv3.String() {
+						return m.GetHistogram().GetSampleCount()
+					}
+				}
+			}
+		}
+		return 0
+	}
+
+	proxies := xdss.NewProxies()
+	snapshotCache := cache_v3.NewSnapshotCache(true, ProxyHash{Proxies: proxies, Namespace: "test"}, nil)
+	c := NewCacheWithProxies(snapshotCache, proxies)
+	cb := &Callbacks{SnapshotCache: &snapshotCache, Logger: ctrl.Log, Proxies: proxies, Namespace: "test"}
+
+	if err := c.SetSnapshot(xdss.NodeKey("test", "node-ack"), c.NewSnapshot("aaaa")); err != nil {
+		t.Fatalf("Cache.SetSnapshot() error = %v", err)
+	}
+	before := ackCount()
+	if err := cb.OnStreamRequest(1, &envoy_service_discovery_v3.DiscoveryRequest{
+		Node:        &envoy_config_core_v3.Node{Id: "node-ack"},
+		TypeUrl:     resource_v3.ClusterType,
+		VersionInfo: "aaaa",
+	}); err != nil {
+		t.Fatalf("Callbacks.OnStreamRequest() error = %v", err)
+	}
+	if got := ackCount() - before; got != 1 {
+		t.Errorf("Callbacks.OnStreamRequest() observed %d snapshot ACKs, want 1", got)
+	}
+}
+
 func TestCallbacks_OnStreamResponse(t *testing.T) {
 	type args struct {
 		id       int64
@@ -0,0 +1,383 @@
+package discoveryservice
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"strconv"
+	"sync/atomic"
+
+	envoy_config_core_v3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	envoy_service_discovery_v3 "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+	cache_v3 "github.com/envoyproxy/go-control-plane/pkg/cache/v3"
+	resource_v3 "github.com/envoyproxy/go-control-plane/pkg/resource/v3"
+	server_v3 "github.com/envoyproxy/go-control-plane/pkg/server/v3"
+	"github.com/golang/protobuf/ptypes/any"
+	"k8s.io/apimachinery/pkg/util/rand"
+)
+
+// DeltaServer is a go-control-plane v3 server that also implements the incremental
+// variant of the aggregated discovery service (DeltaAggregatedResources). Resources
+// are served from the same snapshot cache used by the state-of-the-world server, so
+// the proxies only receive the resources that have changed between snapshots.
+type DeltaServer struct {
+	server_v3.Server
+	ctx       context.Context
+	cache     cache_v3.SnapshotCache
+	callbacks *Callbacks
+	// streamCount is used to generate the delta stream IDs. Delta stream IDs
+	// are negative so they don't collide with the ones of the state-of-the-world server.
+	streamCount int64
+}
+
+// NewDeltaServer returns a DeltaServer that wraps the given server
+func NewDeltaServer(ctx context.Context, srv server_v3.Server, cache cache_v3.SnapshotCache, callbacks *Callbacks) *DeltaServer {
+	return &DeltaServer{Server: srv, ctx: ctx, cache: cache, callbacks: callbacks}
+}
+
+// DeltaAggregatedResources implements the incremental aggregated discovery service
+func (s *DeltaServer) DeltaAggregatedResources(stream envoy_service_discovery_v3.AggregatedDiscoveryService_DeltaAggregatedResourcesServer) error {
+
+	id := -atomic.AddInt64(&s.streamCount, 1)
+	// Like in the SotW server, OnStreamClosed is called even if OnStreamOpen fails
+	defer s.callbacks.OnStreamClosed(id)
+	if err := s.callbacks.OnStreamOpen(stream.Context(), id, resource_v3.AnyType); err != nil {
+		return err
+	}
+
+	ds := &deltaStream{
+		id:        id,
+		stream:    stream,
+		cache:     s.cache,
+		callbacks: s.callbacks,
+		types:     map[string]*deltaTypeState{},
+		watchCh:   make(chan deltaWatchEvent),
+	}
+	defer ds.cancelWatches()
+
+	reqCh := make(chan *envoy_service_discovery_v3.DeltaDiscoveryRequest)
+	errCh := make(chan error, 1)
+	go func() {
+		for {
+			req, err := stream.Recv()
+			if err != nil {
+				errCh <- err
+				return
+			}
+			select {
+			case reqCh <- req:
+			case <-stream.Context().Done():
+				return
+			}
+		}
+	}()
+
+	for {
+		select {
+		case <-s.ctx.Done():
+			return nil
+		case <-stream.Context().Done():
+			return nil
+		case err := <-errCh:
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		case req := <-reqCh:
+			if err := ds.handleRequest(req); err != nil {
+				return err
+			}
+		case ev := <-ds.watchCh:
+			if err := ds.handleWatchEvent(ev); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// deltaStream holds the state of a single delta xDS stream
+type deltaStream struct {
+	id        int64
+	stream    envoy_service_discovery_v3.AggregatedDiscoveryService_DeltaAggregatedResourcesServer
+	cache     cache_v3.SnapshotCache
+	callbacks *Callbacks
+	node      *envoy_config_core_v3.Node
+	types     map[string]*deltaTypeState
+	watchCh   chan deltaWatchEvent
+	nonce     int64
+}
+
+// deltaTypeState holds the state of a resource type within a delta xDS stream
+type deltaTypeState struct {
+	typeURL    string
+	wildcard   bool
+	subscribed map[string]bool
+	// known holds the versions of the resources the proxy has
+	known map[string]string
+	// resources and versions hold the resources currently in the
+	// snapshot cache and the version of each one of them
+	resources map[string][]byte
+	versions  map[string]string
+	// snapshotVersion is the version of the resource type in the snapshot
+	snapshotVersion string
+	// acked is the last snapshot version acknowledged by the proxy
+	acked   string
+	synced  bool
+	pending map[string]deltaPendingResponse
+	cancel  func()
+}
+
+type deltaPendingResponse struct {
+	version  string
+	previous map[string]string
+}
+
+type deltaWatchEvent struct {
+	typeURL  string
+	response cache_v3.Response
+}
+
+func (ds *deltaStream) handleRequest(req *envoy_service_discovery_v3.DeltaDiscoveryRequest) error {
+
+	if ds.node == nil {
+		if req.GetNode() == nil {
+			return errors.New("missing node information in first delta discovery request")
+		}
+		ds.node = req.GetNode()
+	}
+
+	if req.GetTypeUrl() == "" {
+		return errors.New("type URL is required for delta discovery requests")
+	}
+
+	st, subscribed := ds.types[req.GetTypeUrl()]
+	if !subscribed {
+		st = &deltaTypeState{
+			typeURL:    req.GetTypeUrl(),
+			wildcard:   len(req.GetResourceNamesSubscribe()) == 0,
+			subscribed: map[string]bool{},
+			known:      map[string]string{},
+			pending:    map[string]deltaPendingResponse{},
+		}
+		for name, version := range req.GetInitialResourceVersions() {
+			st.known[name] = version
+		}
+		ds.types[req.GetTypeUrl()] = st
+	}
+
+	for _, name := range req.GetResourceNamesSubscribe() {
+		if name == "*" {
+			st.wildcard = true
+			continue
+		}
+		st.subscribed[name] = true
+	}
+	for _, name := range req.GetResourceNamesUnsubscribe() {
+		if name == "*" {
+			st.wildcard = false
+			continue
+		}
+		delete(st.subscribed, name)
+		delete(st.known, name)
+	}
+
+	if nonce := req.GetResponseNonce(); nonce != "" {
+		if p, ok := st.pending[nonce]; ok {
+			delete(st.pending, nonce)
+			if req.GetErrorDetail() != nil {
+				// The proxy has not applied the changes
+				st.known = p.previous
+			} else {
+				st.acked = p.version
+			}
+		}
+	}
+
+	// Let the callbacks handle the request as if it were a state-of-the-world
+	// request, so stats, metrics and the rollback on NACK work the same
+	if err := ds.callbacks.OnStreamRequest(ds.id, ds.sotwRequest(st, req)); err != nil {
+		return err
+	}
+
+	// The first watch of a type is opened once the callbacks have registered the
+	// proxy, so the snapshot cache keys it the same way the state-of-the-world
+	// server does
+	if !subscribed {
+		ds.watch(st, "")
+	}
+
+	// Send any resources that are now visible due to subscription changes. Nothing
+	// is sent after a NACK: a new snapshot is expected to be written to the cache.
+	if st.synced && req.GetErrorDetail() == nil {
+		return ds.send(st, false)
+	}
+
+	return nil
+}
+
+func (ds *deltaStream) handleWatchEvent(ev deltaWatchEvent) error {
+
+	st, ok := ds.types[ev.typeURL]
+	if !ok {
+		return nil
+	}
+
+	rsp, ok := ev.response.(*cache_v3.RawResponse)
+	if !ok {
+		return fmt.Errorf("unexpected response type %T from the xDS cache", ev.response)
+	}
+
+	st.snapshotVersion = rsp.Version
+	st.resources = make(map[string][]byte, len(rsp.Resources))
+	st.versions = make(map[string]string, len(rsp.Resources))
+	for _, r := range rsp.Resources {
+		b, err := cache_v3.MarshalResource(r)
+		if err != nil {
+			return err
+		}
+		name := cache_v3.GetResourceName(r)
+		st.resources[name] = b
+		st.versions[name] = resourceVersion(b)
+	}
+
+	// The first response for a type is always sent, even if empty,
+	// so the proxy can complete its initialization
+	force := !st.synced
+	st.synced = true
+	ds.watch(st, rsp.Version)
+
+	return ds.send(st, force)
+}
+
+// send sends to the proxy the differences between the resources it
+// has and the resources in the cache. Nothing is sent if there are no
+// differences, unless force is true.
+func (ds *deltaStream) send(st *deltaTypeState, force bool) error {
+
+	visible := func(name string) bool { return st.wildcard || st.subscribed[name] }
+
+	resources := []*envoy_service_discovery_v3.Resource{}
+	for name, b := range st.resources {
+		if visible(name) && st.known[name] != st.versions[name] {
+			resources = append(resources, &envoy_service_discovery_v3.Resource{
+				Name:     name,
+				Version:  st.versions[name],
+				Resource: &any.Any{TypeUrl: st.typeURL, Value: b},
+			})
+		}
+	}
+
+	removed := []string{}
+	for name := range st.known {
+		if _, ok := st.resources[name]; !ok && visible(name) {
+			removed = append(removed, name)
+		}
+	}
+
+	if len(resources) == 0 && len(removed) == 0 && !force {
+		return nil
+	}
+
+	ds.nonce++
+	nonce := strconv.FormatInt(ds.nonce, 10)
+
+	previous := make(map[string]string, len(st.known))
+	for name, version := range st.known {
+		previous[name] = version
+	}
+	for _, r := range resources {
+		st.known[r.Name] = r.Version
+	}
+	for _, name := range removed {
+		delete(st.known, name)
+	}
+	st.pending[nonce] = deltaPendingResponse{version: st.snapshotVersion, previous: previous}
+
+	rsp := &envoy_service_discovery_v3.DeltaDiscoveryResponse{
+		SystemVersionInfo: st.snapshotVersion,
+		Resources:         resources,
+		TypeUrl:           st.typeURL,
+		RemovedResources:  removed,
+		Nonce:             nonce,
+	}
+	if err := ds.stream.Send(rsp); err != nil {
+		return err
+	}
+
+	anys := make([]*any.Any, 0, len(resources))
+	for _, r := range resources {
+		anys = append(anys, r.Resource)
+	}
+	ds.callbacks.OnStreamResponse(ds.id,
+		&envoy_service_discovery_v3.DiscoveryRequest{Node: ds.node, TypeUrl: st.typeURL, VersionInfo: st.acked},
+		&envoy_service_discovery_v3.DiscoveryResponse{VersionInfo: st.snapshotVersion, Resources: anys, TypeUrl: st.typeURL, Nonce: nonce},
+	)
+
+	return nil
+}
+
+// watch opens a watch in the xDS cache for the given type that will
+// fire whenever the version of the type in the snapshot differs from the given one.
+func (ds *deltaStream) watch(st *deltaTypeState, version string) {
+	if st.cancel != nil {
+		st.cancel()
+	}
+
+	value, cancel := ds.cache.CreateWatch(&cache_v3.Request{Node: ds.node, TypeUrl: st.typeURL, VersionInfo: version})
+	done := make(chan struct{})
+	st.cancel = func() {
+		if cancel != nil {
+			cancel()
+		}
+		close(done)
+	}
+
+	go func() {
+		select {
+		case rsp, ok := <-value:
+			if !ok {
+				return
+			}
+			select {
+			case ds.watchCh <- deltaWatchEvent{typeURL: st.typeURL, response: rsp}:
+			case <-done:
+			}
+		case <-done:
+		}
+	}()
+}
+
+func (ds *deltaStream) cancelWatches() {
+	for _, st := range ds.types {
+		if st.cancel != nil {
+			st.cancel()
+			st.cancel = nil
+		}
+	}
+}
+
+// sotwRequest returns a state-of-the-world request equivalent to the given delta request
+func (ds *deltaStream) sotwRequest(st *deltaTypeState, req *envoy_service_discovery_v3.DeltaDiscoveryRequest) *envoy_service_discovery_v3.DiscoveryRequest {
+	names := make([]string, 0, len(st.subscribed))
+	for name := range st.subscribed {
+		names = append(names, name)
+	}
+	return &envoy_service_discovery_v3.DiscoveryRequest{
+		VersionInfo:   st.acked,
+		Node:          ds.node,
+		ResourceNames: names,
+		TypeUrl:       st.typeURL,
+		ResponseNonce: req.GetResponseNonce(),
+		ErrorDetail:   req.GetErrorDetail(),
+	}
+}
+
+// resourceVersion returns a version for a resource calculated
+// from the hash of its serialized representation
+func resourceVersion(b []byte) string {
+	hasher := fnv.New32a()
+	hasher.Write(b)
+	return rand.SafeEncodeString(fmt.Sprint(hasher.Sum32()))
+}
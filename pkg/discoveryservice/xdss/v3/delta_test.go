@@ -0,0 +1,194 @@
+package discoveryservice
+
+import (
+	"context"
+	"net"
+	"sort"
+	"testing"
+	"time"
+
+	xdss "github.com/3scale/marin3r/pkg/discoveryservice/xdss"
+	envoy_config_cluster_v3 "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	envoy_config_core_v3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	envoy_service_discovery_v3 "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+	cache_types "github.com/envoyproxy/go-control-plane/pkg/cache/types"
+	cache_v3 "github.com/envoyproxy/go-control-plane/pkg/cache/v3"
+	resource_v3 "github.com/envoyproxy/go-control-plane/pkg/resource/v3"
+	server_v3 "github.com/envoyproxy/go-control-plane/pkg/server/v3"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/test/bufconn"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+func testDeltaSnapshot(version string, clusters ...*envoy_config_cluster_v3.Cluster) cache_v3.Snapshot {
+	resources := make([]cache_types.Resource, 0, len(clusters))
+	for _, c := range clusters {
+		resources = append(resources, c)
+	}
+	return cache_v3.NewSnapshot(version, nil, resources, nil, nil, nil, nil)
+}
+
+func testDeltaClient(t *testing.T, ctx context.Context, cache cache_v3.SnapshotCache) (envoy_service_discovery_v3.AggregatedDiscoveryService_DeltaAggregatedResourcesClient, func()) {
+	return testDeltaClientWithCallbacks(t, ctx, cache, &Callbacks{SnapshotCache: &cache, Logger: ctrl.Log})
+}
+
+func testDeltaClientWithCallbacks(t *testing.T, ctx context.Context, cache cache_v3.SnapshotCache, cb *Callbacks) (envoy_service_discovery_v3.AggregatedDiscoveryService_DeltaAggregatedResourcesClient, func()) {
+	srv := NewDeltaServer(ctx, server_v3.NewServer(ctx, cache, cb), cache, cb)
+
+	lis := bufconn.Listen(1024 * 1024)
+	grpcServer := grpc.NewServer()
+	envoy_service_discovery_v3.RegisterAggregatedDiscoveryServiceServer(grpcServer, srv)
+	go grpcServer.Serve(lis)
+
+	conn, err := grpc.DialContext(ctx, "bufnet",
+		grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) { return lis.Dial() }),
+		grpc.WithInsecure(),
+	)
+	if err != nil {
+		t.Fatalf("error dialing the delta server: %v", err)
+	}
+	stop := func() {
+		conn.Close()
+		grpcServer.Stop()
+	}
+
+	stream, err := envoy_service_discovery_v3.NewAggregatedDiscoveryServiceClient(conn).DeltaAggregatedResources(ctx)
+	if err != nil {
+		t.Fatalf("error opening the delta stream: %v", err)
+	}
+	return stream, stop
+}
+
+func resourceNames(rsp *envoy_service_discovery_v3.DeltaDiscoveryResponse) []string {
+	names := []string{}
+	for _, r := range rsp.GetResources() {
+		names = append(names, r.GetName())
+	}
+	sort.Strings(names)
+	return names
+}
+
+func TestDeltaServer_DeltaAggregatedResources(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	node := &envoy_config_core_v3.Node{Id: "node1"}
+	cache := cache_v3.NewSnapshotCache(true, cache_v3.IDHash{}, nil)
+	cache.SetSnapshot("node1", testDeltaSnapshot("1",
+		&envoy_config_cluster_v3.Cluster{Name: "c1"},
+		&envoy_config_cluster_v3.Cluster{Name: "c2"},
+	))
+
+	stream, stop := testDeltaClient(t, ctx, cache)
+	defer stop()
+
+	// Initial wildcard request receives all the clusters
+	if err := stream.Send(&envoy_service_discovery_v3.DeltaDiscoveryRequest{Node: node, TypeUrl: resource_v3.ClusterType}); err != nil {
+		t.Fatalf("error sending request: %v", err)
+	}
+	rsp, err := stream.Recv()
+	if err != nil {
+		t.Fatalf("error receiving response: %v", err)
+	}
+	if got := resourceNames(rsp); len(got) != 2 || got[0] != "c1" || got[1] != "c2" {
+		t.Errorf("DeltaAggregatedResources() resources = %v, want [c1 c2]", got)
+	}
+	if rsp.GetSystemVersionInfo() != "1" {
+		t.Errorf("DeltaAggregatedResources() version = %v, want 1", rsp.GetSystemVersionInfo())
+	}
+
+	// ACK the response
+	if err := stream.Send(&envoy_service_discovery_v3.DeltaDiscoveryRequest{TypeUrl: resource_v3.ClusterType, ResponseNonce: rsp.GetNonce()}); err != nil {
+		t.Fatalf("error sending request: %v", err)
+	}
+
+	// Only the differences are sent after a new snapshot is written
+	cache.SetSnapshot("node1", testDeltaSnapshot("2",
+		&envoy_config_cluster_v3.Cluster{Name: "c1"},
+		&envoy_config_cluster_v3.Cluster{Name: "c3"},
+	))
+	rsp, err = stream.Recv()
+	if err != nil {
+		t.Fatalf("error receiving response: %v", err)
+	}
+	if got := resourceNames(rsp); len(got) != 1 || got[0] != "c3" {
+		t.Errorf("DeltaAggregatedResources() resources = %v, want [c3]", got)
+	}
+	if got := rsp.GetRemovedResources(); len(got) != 1 || got[0] != "c2" {
+		t.Errorf("DeltaAggregatedResources() removed resources = %v, want [c2]", got)
+	}
+}
+
+func TestDeltaServer_DeltaAggregatedResources_subscriptions(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	node := &envoy_config_core_v3.Node{Id: "node1"}
+	cache := cache_v3.NewSnapshotCache(true, cache_v3.IDHash{}, nil)
+	cache.SetSnapshot("node1", testDeltaSnapshot("1",
+		&envoy_config_cluster_v3.Cluster{Name: "c1"},
+		&envoy_config_cluster_v3.Cluster{Name: "c2"},
+	))
+
+	stream, stop := testDeltaClient(t, ctx, cache)
+	defer stop()
+
+	// Only subscribed resources are sent
+	if err := stream.Send(&envoy_service_discovery_v3.DeltaDiscoveryRequest{
+		Node: node, TypeUrl: resource_v3.ClusterType, ResourceNamesSubscribe: []string{"c1"},
+	}); err != nil {
+		t.Fatalf("error sending request: %v", err)
+	}
+	rsp, err := stream.Recv()
+	if err != nil {
+		t.Fatalf("error receiving response: %v", err)
+	}
+	if got := resourceNames(rsp); len(got) != 1 || got[0] != "c1" {
+		t.Errorf("DeltaAggregatedResources() resources = %v, want [c1]", got)
+	}
+
+	// Subscribing to a new resource sends it right away
+	if err := stream.Send(&envoy_service_discovery_v3.DeltaDiscoveryRequest{
+		TypeUrl: resource_v3.ClusterType, ResponseNonce: rsp.GetNonce(), ResourceNamesSubscribe: []string{"c2"},
+	}); err != nil {
+		t.Fatalf("error sending request: %v", err)
+	}
+	rsp, err = stream.Recv()
+	if err != nil {
+		t.Fatalf("error receiving response: %v", err)
+	}
+	if got := resourceNames(rsp); len(got) != 1 || got[0] != "c2" {
+		t.Errorf("DeltaAggregatedResources() resources = %v, want [c2]", got)
+	}
+}
+
+func TestDeltaServer_DeltaAggregatedResources_proxies(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	proxies := xdss.NewProxies()
+	cache := cache_v3.NewSnapshotCache(true, ProxyHash{Proxies: proxies}, nil)
+	cache.SetSnapshot("node1", testDeltaSnapshot("1", &envoy_config_cluster_v3.Cluster{Name: "c1"}))
+
+	stream, stop := testDeltaClientWithCallbacks(t, ctx, cache, &Callbacks{SnapshotCache: &cache, Proxies: proxies, Logger: ctrl.Log})
+	defer stop()
+
+	if err := stream.Send(&envoy_service_discovery_v3.DeltaDiscoveryRequest{
+		Node: &envoy_config_core_v3.Node{Id: "node1"}, TypeUrl: resource_v3.ClusterType}); err != nil {
+		t.Fatalf("error sending request: %v", err)
+	}
+	if _, err := stream.Recv(); err != nil {
+		t.Fatalf("error receiving response: %v", err)
+	}
+
+	// The first watch is opened once the proxy is registered, so
+	// it is keyed by proxy and never by the plain nodeID
+	for _, key := range cache.GetStatusKeys() {
+		if key == "node1" {
+			t.Errorf("DeltaAggregatedResources() opened a watch for the nodeID, want only the proxy key")
+		}
+	}
+	if len(cache.GetStatusKeys()) != 1 {
+		t.Errorf("DeltaAggregatedResources() status keys = %v, want the proxy key", cache.GetStatusKeys())
+	}
+}
@@ -0,0 +1,35 @@
+package discoveryservice
+
+import (
+	xdss "github.com/3scale/marin3r/pkg/discoveryservice/xdss"
+	envoy_config_core_v3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+)
+
+// ProxyHash implements go-control-plane/pkg/cache/v3.NodeHash. Proxies registered
+// in the given Proxies object are keyed by namespace, nodeID and address, the rest
+// of them by namespace and nodeID. Proxies that don't report their namespace in the
+// node metadata are considered to belong to Namespace. The nodeID is computed from
+// the fields of the node selected by NodeHash.
+type ProxyHash struct {
+	Proxies   *xdss.Proxies
+	Namespace string
+	NodeHash  xdss.NodeHash
+}
+
+// ID returns the key in the snapshot cache for the given node
+func (h ProxyHash) ID(node *envoy_config_core_v3.Node) string {
+	if node == nil {
+		return ""
+	}
+	return h.Proxies.Key(node, nodeKey(node, h.NodeHash, h.Namespace))
+}
+
+// nodeKey returns the namespace qualified nodeID of the given node
+func nodeKey(node *envoy_config_core_v3.Node, nodeHash xdss.NodeHash, defaultNamespace string) string {
+	return xdss.NodeKey(nodeNamespace(node, defaultNamespace), nodeHash.NodeID(node))
+}
+
+// nodeNamespace returns the namespace of the given node
+func nodeNamespace(node *envoy_config_core_v3.Node, defaultNamespace string) string {
+	return xdss.NamespaceFromMetadata(node.GetMetadata(), defaultNamespace)
+}
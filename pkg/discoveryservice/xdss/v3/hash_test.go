@@ -0,0 +1,68 @@
+package discoveryservice
+
+import (
+	"testing"
+
+	xdss "github.com/3scale/marin3r/pkg/discoveryservice/xdss"
+	envoy_config_core_v3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	_struct "github.com/golang/protobuf/ptypes/struct"
+)
+
+func TestProxyHash_ID(t *testing.T) {
+	withNamespace := func(namespace string) *_struct.Struct {
+		return &_struct.Struct{Fields: map[string]*_struct.Value{
+			xdss.NamespaceMetadataKey: {Kind: &_struct.Value_StringValue{StringValue: namespace}},
+		}}
+	}
+
+	tests := []struct {
+		name string
+		hash ProxyHash
+		node *envoy_config_core_v3.Node
+		want string
+	}{
+		{
+			name: "Returns an empty key for a nil node",
+			hash: ProxyHash{Namespace: "default"},
+			node: nil,
+			want: "",
+		},
+		{
+			name: "Returns the nodeID if no namespace is known",
+			hash: ProxyHash{},
+			node: &envoy_config_core_v3.Node{Id: "node1"},
+			want: "node1",
+		},
+		{
+			name: "Qualifies the nodeID with the default namespace",
+			hash: ProxyHash{Namespace: "default"},
+			node: &envoy_config_core_v3.Node{Id: "node1"},
+			want: "default/node1",
+		},
+		{
+			name: "Qualifies the nodeID with the namespace in the node metadata",
+			hash: ProxyHash{Namespace: "default"},
+			node: &envoy_config_core_v3.Node{Id: "node1", Metadata: withNamespace("test")},
+			want: "test/node1",
+		},
+		{
+			name: "Computes the nodeID with the given NodeHash",
+			hash: ProxyHash{Namespace: "default", NodeHash: xdss.NodeHash{xdss.NodeHashCluster}},
+			node: &envoy_config_core_v3.Node{Id: "pod-1", Cluster: "gateway"},
+			want: "default/gateway",
+		},
+		{
+			name: "Ignores an empty namespace in the node metadata",
+			hash: ProxyHash{Namespace: "default"},
+			node: &envoy_config_core_v3.Node{Id: "node1", Metadata: withNamespace("")},
+			want: "default/node1",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.hash.ID(tt.node); got != tt.want {
+				t.Errorf("ProxyHash.ID() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
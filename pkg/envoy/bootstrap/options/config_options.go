@@ -3,12 +3,20 @@ package envoy
 const (
 	TlsCertificateSdsSecretFileName string = "tls_certificate_sds_secret.json"
 	XdsClusterName                  string = "xds_cluster"
+	// FileBasedMetadataCredentialsName is the name of the envoy gRPC call
+	// credentials plugin that reads the credentials from a file
+	FileBasedMetadataCredentialsName string = "envoy.grpc_credentials.file_based_metadata"
+	// XdsTokenHeader is the header that carries the ServiceAccount token in xDS requests
+	XdsTokenHeader string = "authorization"
+	// XdsTokenHeaderPrefix is the prefix of the ServiceAccount token in the XdsTokenHeader
+	XdsTokenHeaderPrefix string = "Bearer "
 )
 
 // ConfigOptions has options to configure the way the bootstrap config is generated
 type ConfigOptions struct {
 	XdsHost                     string
 	XdsPort                     uint32
+	XdsDeltaAPI                 bool
 	XdsClientCertificatePath    string
 	XdsClientCertificateKeyPath string
 	SdsConfigSourcePath         string
@@ -16,4 +24,14 @@ type ConfigOptions struct {
 	AdminAddress                string
 	AdminPort                   uint32
 	AdminAccessLogPath          string
+	// Namespace is reported to the discovery service in the node
+	// metadata so it can tell apart proxies that use the same nodeID
+	Namespace string
+	// XdsTokenPath is the path of the ServiceAccount token the proxy sends to
+	// authenticate with the discovery service. When set, no client certificate
+	// is used and the connection is established with envoy's google gRPC client.
+	XdsTokenPath string
+	// XdsCACertificatePath is the path of the CA certificate used to validate the
+	// discovery service server certificate when XdsTokenPath is set
+	XdsCACertificatePath string
 }
@@ -2,8 +2,10 @@ package envoy
 
 import (
 	"bytes"
+	"fmt"
 	"time"
 
+	xdss "github.com/3scale/marin3r/pkg/discoveryservice/xdss"
 	"github.com/3scale/marin3r/pkg/envoy"
 	envoy_bootstrap_options "github.com/3scale/marin3r/pkg/envoy/bootstrap/options"
 	envoy_resources "github.com/3scale/marin3r/pkg/envoy/resources"
@@ -12,10 +14,12 @@ import (
 	envoy_api_v2_core "github.com/envoyproxy/go-control-plane/envoy/api/v2/core"
 	envoy_api_v2_endpoint "github.com/envoyproxy/go-control-plane/envoy/api/v2/endpoint"
 	envoy_config_bootstrap_v2 "github.com/envoyproxy/go-control-plane/envoy/config/bootstrap/v2"
+	envoy_config_grpc_credential_v2alpha "github.com/envoyproxy/go-control-plane/envoy/config/grpc_credential/v2alpha"
 	"github.com/envoyproxy/go-control-plane/pkg/wellknown"
 	"github.com/golang/protobuf/jsonpb"
 	"github.com/golang/protobuf/ptypes"
 	"github.com/golang/protobuf/ptypes/any"
+	_struct "github.com/golang/protobuf/ptypes/struct"
 )
 
 // Config is a struct with options and methods to generate an envoy bootstrap config
@@ -29,6 +33,64 @@ func (c *Config) getAdminAccessLogPath() string {
 	return stringOrDefault(c.Options.AdminAccessLogPath, "/dev/null")
 }
 
+// getNode returns the node that holds the namespace in its metadata. The id of
+// the node is not set as it is passed to envoy through the command line.
+func (c *Config) getNode() *envoy_api_v2_core.Node {
+	if c.Options.Namespace == "" {
+		return nil
+	}
+	return &envoy_api_v2_core.Node{
+		Metadata: &_struct.Struct{Fields: map[string]*_struct.Value{
+			xdss.NamespaceMetadataKey: {Kind: &_struct.Value_StringValue{StringValue: c.Options.Namespace}},
+		}},
+	}
+}
+
+// getTokenGrpcService returns a google gRPC service that authenticates with the
+// discovery service sending the ServiceAccount token as call credentials. The token
+// file is read by envoy for each stream so rotated tokens are picked up.
+func (c *Config) getTokenGrpcService() (*envoy_api_v2_core.GrpcService, error) {
+	credentials, err := ptypes.MarshalAny(&envoy_config_grpc_credential_v2alpha.FileBasedMetadataConfig{
+		SecretData: &envoy_api_v2_core.DataSource{
+			Specifier: &envoy_api_v2_core.DataSource_Filename{Filename: c.Options.XdsTokenPath},
+		},
+		HeaderKey:    envoy_bootstrap_options.XdsTokenHeader,
+		HeaderPrefix: envoy_bootstrap_options.XdsTokenHeaderPrefix,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &envoy_api_v2_core.GrpcService{
+		TargetSpecifier: &envoy_api_v2_core.GrpcService_GoogleGrpc_{
+			GoogleGrpc: &envoy_api_v2_core.GrpcService_GoogleGrpc{
+				TargetUri:  fmt.Sprintf("%s:%d", c.Options.XdsHost, c.Options.XdsPort),
+				StatPrefix: envoy_bootstrap_options.XdsClusterName,
+				ChannelCredentials: &envoy_api_v2_core.GrpcService_GoogleGrpc_ChannelCredentials{
+					CredentialSpecifier: &envoy_api_v2_core.GrpcService_GoogleGrpc_ChannelCredentials_SslCredentials{
+						SslCredentials: &envoy_api_v2_core.GrpcService_GoogleGrpc_SslCredentials{
+							RootCerts: &envoy_api_v2_core.DataSource{
+								Specifier: &envoy_api_v2_core.DataSource_Filename{Filename: c.Options.XdsCACertificatePath},
+							},
+						},
+					},
+				},
+				CallCredentials: []*envoy_api_v2_core.GrpcService_GoogleGrpc_CallCredentials{{
+					CredentialSpecifier: &envoy_api_v2_core.GrpcService_GoogleGrpc_CallCredentials_FromPlugin{
+						FromPlugin: &envoy_api_v2_core.GrpcService_GoogleGrpc_CallCredentials_MetadataCredentialsFromPlugin{
+							Name: envoy_bootstrap_options.FileBasedMetadataCredentialsName,
+							ConfigType: &envoy_api_v2_core.GrpcService_GoogleGrpc_CallCredentials_MetadataCredentialsFromPlugin_TypedConfig{
+								TypedConfig: credentials,
+							},
+						},
+					},
+				}},
+				CredentialsFactoryName: envoy_bootstrap_options.FileBasedMetadataCredentialsName,
+			},
+		},
+	}, nil
+}
+
 // GenerateStatic returns the json serialized representation of an envoy
 // bootstrap object that can be passed as the configuration file to an envoy proxy
 // so it can connect to the discovery service.
@@ -54,6 +116,7 @@ func (c *Config) GenerateStatic() (string, error) {
 	}
 
 	cfg := &envoy_config_bootstrap_v2.Bootstrap{
+		Node: c.getNode(),
 		Admin: &envoy_config_bootstrap_v2.Admin{
 			AccessLogPath: c.getAdminAccessLogPath(),
 			Address: &envoy_api_v2_core.Address{
@@ -155,6 +218,17 @@ func (c *Config) GenerateStatic() (string, error) {
 		},
 	}
 
+	// Proxies that authenticate with a ServiceAccount token connect to the
+	// discovery service directly, without the client certificate xds_cluster
+	if c.Options.XdsTokenPath != "" {
+		grpcService, err := c.getTokenGrpcService()
+		if err != nil {
+			return "", err
+		}
+		cfg.DynamicResources.AdsConfig.GrpcServices = []*envoy_api_v2_core.GrpcService{grpcService}
+		cfg.StaticResources = nil
+	}
+
 	m := jsonpb.Marshaler{OrigName: true}
 	json := bytes.NewBuffer([]byte{})
 	err = m.Marshal(json, cfg)
@@ -169,6 +243,10 @@ func (c *Config) GenerateStatic() (string, error) {
 // filesystem discovery of certificates.
 func (c *Config) GenerateSdsResources() (map[string]string, error) {
 
+	if c.Options.XdsTokenPath != "" {
+		return map[string]string{}, nil
+	}
+
 	generator := envoy_resources.NewGenerator(envoy.APIv2)
 	secret := generator.NewSecretFromPath("xds_client_certificate", c.Options.XdsClientCertificatePath, c.Options.XdsClientCertificateKeyPath)
 
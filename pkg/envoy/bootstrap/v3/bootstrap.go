@@ -2,8 +2,10 @@ package envoy
 
 import (
 	"bytes"
+	"fmt"
 	"time"
 
+	xdss "github.com/3scale/marin3r/pkg/discoveryservice/xdss"
 	"github.com/3scale/marin3r/pkg/envoy"
 	envoy_bootstrap_options "github.com/3scale/marin3r/pkg/envoy/bootstrap/options"
 	envoy_resources "github.com/3scale/marin3r/pkg/envoy/resources"
@@ -11,12 +13,14 @@ import (
 	envoy_config_cluster_v3 "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
 	envoy_config_core_v3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
 	envoy_config_endpoint_v3 "github.com/envoyproxy/go-control-plane/envoy/config/endpoint/v3"
+	envoy_config_grpc_credential_v3 "github.com/envoyproxy/go-control-plane/envoy/config/grpc_credential/v3"
 	envoy_extensions_transport_sockets_tls_v3 "github.com/envoyproxy/go-control-plane/envoy/extensions/transport_sockets/tls/v3"
 	envoy_service_discovery_v3 "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
 	"github.com/envoyproxy/go-control-plane/pkg/wellknown"
 	"github.com/golang/protobuf/jsonpb"
 	"github.com/golang/protobuf/ptypes"
 	"github.com/golang/protobuf/ptypes/any"
+	_struct "github.com/golang/protobuf/ptypes/struct"
 )
 
 // Config is a struct with options and methods to generate an envoy bootstrap config
@@ -30,6 +34,71 @@ func (c *Config) getAdminAccessLogPath() string {
 	return stringOrDefault(c.Options.AdminAccessLogPath, "/dev/null")
 }
 
+// getNode returns the node that holds the namespace in its metadata. The id of
+// the node is not set as it is passed to envoy through the command line.
+func (c *Config) getNode() *envoy_config_core_v3.Node {
+	if c.Options.Namespace == "" {
+		return nil
+	}
+	return &envoy_config_core_v3.Node{
+		Metadata: &_struct.Struct{Fields: map[string]*_struct.Value{
+			xdss.NamespaceMetadataKey: {Kind: &_struct.Value_StringValue{StringValue: c.Options.Namespace}},
+		}},
+	}
+}
+
+func (c *Config) getAdsAPIType() envoy_config_core_v3.ApiConfigSource_ApiType {
+	if c.Options.XdsDeltaAPI {
+		return envoy_config_core_v3.ApiConfigSource_DELTA_GRPC
+	}
+	return envoy_config_core_v3.ApiConfigSource_GRPC
+}
+
+// getTokenGrpcService returns a google gRPC service that authenticates with the
+// discovery service sending the ServiceAccount token as call credentials. The token
+// file is read by envoy for each stream so rotated tokens are picked up.
+func (c *Config) getTokenGrpcService() (*envoy_config_core_v3.GrpcService, error) {
+	credentials, err := ptypes.MarshalAny(&envoy_config_grpc_credential_v3.FileBasedMetadataConfig{
+		SecretData: &envoy_config_core_v3.DataSource{
+			Specifier: &envoy_config_core_v3.DataSource_Filename{Filename: c.Options.XdsTokenPath},
+		},
+		HeaderKey:    envoy_bootstrap_options.XdsTokenHeader,
+		HeaderPrefix: envoy_bootstrap_options.XdsTokenHeaderPrefix,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &envoy_config_core_v3.GrpcService{
+		TargetSpecifier: &envoy_config_core_v3.GrpcService_GoogleGrpc_{
+			GoogleGrpc: &envoy_config_core_v3.GrpcService_GoogleGrpc{
+				TargetUri:  fmt.Sprintf("%s:%d", c.Options.XdsHost, c.Options.XdsPort),
+				StatPrefix: envoy_bootstrap_options.XdsClusterName,
+				ChannelCredentials: &envoy_config_core_v3.GrpcService_GoogleGrpc_ChannelCredentials{
+					CredentialSpecifier: &envoy_config_core_v3.GrpcService_GoogleGrpc_ChannelCredentials_SslCredentials{
+						SslCredentials: &envoy_config_core_v3.GrpcService_GoogleGrpc_SslCredentials{
+							RootCerts: &envoy_config_core_v3.DataSource{
+								Specifier: &envoy_config_core_v3.DataSource_Filename{Filename: c.Options.XdsCACertificatePath},
+							},
+						},
+					},
+				},
+				CallCredentials: []*envoy_config_core_v3.GrpcService_GoogleGrpc_CallCredentials{{
+					CredentialSpecifier: &envoy_config_core_v3.GrpcService_GoogleGrpc_CallCredentials_FromPlugin{
+						FromPlugin: &envoy_config_core_v3.GrpcService_GoogleGrpc_CallCredentials_MetadataCredentialsFromPlugin{
+							Name: envoy_bootstrap_options.FileBasedMetadataCredentialsName,
+							ConfigType: &envoy_config_core_v3.GrpcService_GoogleGrpc_CallCredentials_MetadataCredentialsFromPlugin_TypedConfig{
+								TypedConfig: credentials,
+							},
+						},
+					},
+				}},
+				CredentialsFactoryName: envoy_bootstrap_options.FileBasedMetadataCredentialsName,
+			},
+		},
+	}, nil
+}
+
 // GenerateStatic returns the json serialized representation of an envoy
 // bootstrap object that can be passed as the configuration file to an envoy proxy
 // so it can connect to the discovery service.
@@ -55,6 +124,7 @@ func (c *Config) GenerateStatic() (string, error) {
 	}
 
 	cfg := &envoy_config_bootstrap_v3.Bootstrap{
+		Node: c.getNode(),
 		Admin: &envoy_config_bootstrap_v3.Admin{
 			AccessLogPath: c.getAdminAccessLogPath(),
 			Address: &envoy_config_core_v3.Address{
@@ -70,7 +140,7 @@ func (c *Config) GenerateStatic() (string, error) {
 		},
 		DynamicResources: &envoy_config_bootstrap_v3.Bootstrap_DynamicResources{
 			AdsConfig: &envoy_config_core_v3.ApiConfigSource{
-				ApiType:             envoy_config_core_v3.ApiConfigSource_GRPC,
+				ApiType:             c.getAdsAPIType(),
 				TransportApiVersion: envoy_config_core_v3.ApiVersion_V3,
 				GrpcServices: []*envoy_config_core_v3.GrpcService{
 					{
@@ -156,6 +226,17 @@ func (c *Config) GenerateStatic() (string, error) {
 		},
 	}
 
+	// Proxies that authenticate with a ServiceAccount token connect to the
+	// discovery service directly, without the client certificate xds_cluster
+	if c.Options.XdsTokenPath != "" {
+		grpcService, err := c.getTokenGrpcService()
+		if err != nil {
+			return "", err
+		}
+		cfg.DynamicResources.AdsConfig.GrpcServices = []*envoy_config_core_v3.GrpcService{grpcService}
+		cfg.StaticResources = nil
+	}
+
 	m := jsonpb.Marshaler{OrigName: true}
 	json := bytes.NewBuffer([]byte{})
 	err = m.Marshal(json, cfg)
@@ -170,6 +251,10 @@ func (c *Config) GenerateStatic() (string, error) {
 // filesystem discovery of certificates.
 func (c *Config) GenerateSdsResources() (map[string]string, error) {
 
+	if c.Options.XdsTokenPath != "" {
+		return map[string]string{}, nil
+	}
+
 	generator := envoy_resources.NewGenerator(envoy.APIv3)
 	secret := generator.NewSecretFromPath("xds_client_certificate", c.Options.XdsClientCertificatePath, c.Options.XdsClientCertificateKeyPath)
 
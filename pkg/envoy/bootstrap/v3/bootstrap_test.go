@@ -29,6 +29,52 @@ func TestConfig_GenerateStatic(t *testing.T) {
 			want:    `{"static_resources":{"clusters":[{"name":"xds_cluster","type":"STRICT_DNS","connect_timeout":"1s","load_assignment":{"cluster_name":"xds_cluster","endpoints":[{"lb_endpoints":[{"endpoint":{"address":{"socket_address":{"address":"localhost","port_value":10000}}}}]}]},"http2_protocol_options":{},"transport_socket":{"name":"envoy.transport_sockets.tls","typed_config":{"@type":"type.googleapis.com/envoy.extensions.transport_sockets.tls.v3.UpstreamTlsContext","common_tls_context":{"tls_certificate_sds_secret_configs":[{"sds_config":{"path":"/sds-config-source.json"}}]}}}}]},"dynamic_resources":{"lds_config":{"ads":{},"resource_api_version":"V3"},"cds_config":{"ads":{},"resource_api_version":"V3"},"ads_config":{"api_type":"GRPC","transport_api_version":"V3","grpc_services":[{"envoy_grpc":{"cluster_name":"xds_cluster"}}]}},"layered_runtime":{"layers":[{"name":"runtime","rtds_layer":{"name":"runtime","rtds_config":{"ads":{},"resource_api_version":"V3"}}}]},"admin":{"access_log_path":"/dev/null","address":{"socket_address":{"address":"0.0.0.0","port_value":9001}}}}`,
 			wantErr: false,
 		},
+		{
+			name: "Returns a bootstrap configuration that uses delta xDS",
+			c: &Config{
+				Options: envoy_bootstrap_options.ConfigOptions{
+					XdsHost:                     "localhost",
+					XdsPort:                     10000,
+					XdsClientCertificatePath:    "/tls.crt",
+					XdsClientCertificateKeyPath: "/tls.key",
+					SdsConfigSourcePath:         "/sds-config-source.json",
+					RtdsLayerResourceName:       "runtime",
+					XdsDeltaAPI:                 true,
+				},
+			},
+			want:    `{"static_resources":{"clusters":[{"name":"xds_cluster","type":"STRICT_DNS","connect_timeout":"1s","load_assignment":{"cluster_name":"xds_cluster","endpoints":[{"lb_endpoints":[{"endpoint":{"address":{"socket_address":{"address":"localhost","port_value":10000}}}}]}]},"http2_protocol_options":{},"transport_socket":{"name":"envoy.transport_sockets.tls","typed_config":{"@type":"type.googleapis.com/envoy.extensions.transport_sockets.tls.v3.UpstreamTlsContext","common_tls_context":{"tls_certificate_sds_secret_configs":[{"sds_config":{"path":"/sds-config-source.json"}}]}}}}]},"dynamic_resources":{"lds_config":{"ads":{},"resource_api_version":"V3"},"cds_config":{"ads":{},"resource_api_version":"V3"},"ads_config":{"api_type":"DELTA_GRPC","transport_api_version":"V3","grpc_services":[{"envoy_grpc":{"cluster_name":"xds_cluster"}}]}},"layered_runtime":{"layers":[{"name":"runtime","rtds_layer":{"name":"runtime","rtds_config":{"ads":{},"resource_api_version":"V3"}}}]},"admin":{"access_log_path":"/dev/null","address":{"socket_address":{"address":"0.0.0.0","port_value":9001}}}}`,
+			wantErr: false,
+		},
+		{
+			name: "Returns a bootstrap configuration that reports the namespace in the node metadata",
+			c: &Config{
+				Options: envoy_bootstrap_options.ConfigOptions{
+					XdsHost:                     "localhost",
+					XdsPort:                     10000,
+					XdsClientCertificatePath:    "/tls.crt",
+					XdsClientCertificateKeyPath: "/tls.key",
+					SdsConfigSourcePath:         "/sds-config-source.json",
+					RtdsLayerResourceName:       "runtime",
+					Namespace:                   "test",
+				},
+			},
+			want:    `{"node":{"metadata":{"marin3r.3scale.net/namespace":"test"}},"static_resources":{"clusters":[{"name":"xds_cluster","type":"STRICT_DNS","connect_timeout":"1s","load_assignment":{"cluster_name":"xds_cluster","endpoints":[{"lb_endpoints":[{"endpoint":{"address":{"socket_address":{"address":"localhost","port_value":10000}}}}]}]},"http2_protocol_options":{},"transport_socket":{"name":"envoy.transport_sockets.tls","typed_config":{"@type":"type.googleapis.com/envoy.extensions.transport_sockets.tls.v3.UpstreamTlsContext","common_tls_context":{"tls_certificate_sds_secret_configs":[{"sds_config":{"path":"/sds-config-source.json"}}]}}}}]},"dynamic_resources":{"lds_config":{"ads":{},"resource_api_version":"V3"},"cds_config":{"ads":{},"resource_api_version":"V3"},"ads_config":{"api_type":"GRPC","transport_api_version":"V3","grpc_services":[{"envoy_grpc":{"cluster_name":"xds_cluster"}}]}},"layered_runtime":{"layers":[{"name":"runtime","rtds_layer":{"name":"runtime","rtds_config":{"ads":{},"resource_api_version":"V3"}}}]},"admin":{"access_log_path":"/dev/null","address":{"socket_address":{"address":"0.0.0.0","port_value":9001}}}}`,
+			wantErr: false,
+		},
+		{
+			name: "Returns a bootstrap configuration that authenticates with a ServiceAccount token",
+			c: &Config{
+				Options: envoy_bootstrap_options.ConfigOptions{
+					XdsHost:               "localhost",
+					XdsPort:               10000,
+					RtdsLayerResourceName: "runtime",
+					XdsTokenPath:          "/token",
+					XdsCACertificatePath:  "/ca.crt",
+				},
+			},
+			want:    `{"dynamic_resources":{"lds_config":{"ads":{},"resource_api_version":"V3"},"cds_config":{"ads":{},"resource_api_version":"V3"},"ads_config":{"api_type":"GRPC","transport_api_version":"V3","grpc_services":[{"google_grpc":{"target_uri":"localhost:10000","channel_credentials":{"ssl_credentials":{"root_certs":{"filename":"/ca.crt"}}},"call_credentials":[{"from_plugin":{"name":"envoy.grpc_credentials.file_based_metadata","typed_config":{"@type":"type.googleapis.com/envoy.config.grpc_credential.v3.FileBasedMetadataConfig","secret_data":{"filename":"/token"},"header_key":"authorization","header_prefix":"Bearer "}}}],"stat_prefix":"xds_cluster","credentials_factory_name":"envoy.grpc_credentials.file_based_metadata"}}]}},"layered_runtime":{"layers":[{"name":"runtime","rtds_layer":{"name":"runtime","rtds_config":{"ads":{},"resource_api_version":"V3"}}}]},"admin":{"access_log_path":"/dev/null","address":{"socket_address":{"address":"0.0.0.0","port_value":9001}}}}`,
+			wantErr: false,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
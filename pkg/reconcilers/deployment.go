@@ -17,6 +17,10 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 )
 
+// restartedAtAnnotation is the Pod template annotation that "kubectl rollout restart"
+// sets to trigger a rollout of the Deployment
+const restartedAtAnnotation = "kubectl.kubernetes.io/restartedAt"
+
 // DeploymentGeneratorFn is a function that when called returns an appsv1.Deployment object
 type DeploymentGeneratorFn func() *appsv1.Deployment
 
@@ -100,6 +104,18 @@ func (r *DeploymentReconciler) reconcileDeployment(existentObj, desiredObj commo
 
 	}
 
+	// The annotation added to the Pod template by "kubectl rollout restart" is kept
+	// so the rollout it triggers is not reverted. Any other annotation not in the
+	// desired Pod template is removed.
+	if value, ok := existent.Spec.Template.GetAnnotations()[restartedAtAnnotation]; ok {
+		if _, ok := desired.Spec.Template.GetAnnotations()[restartedAtAnnotation]; !ok {
+			if desired.Spec.Template.Annotations == nil {
+				desired.Spec.Template.Annotations = map[string]string{}
+			}
+			desired.Spec.Template.Annotations[restartedAtAnnotation] = value
+		}
+	}
+
 	// reconcile the spec
 	if !equality.Semantic.DeepEqual(existent.Spec, desired.Spec) {
 		r.logger.V(1).Info("Deployment spec needs reconcile")
@@ -149,10 +149,11 @@ func TestDeploymentReconciler_reconcileDeployment(t *testing.T) {
 		desiredObj  common.KubernetesObject
 	}
 	tests := []struct {
-		name    string
-		args    args
-		want    bool
-		wantErr bool
+		name            string
+		args            args
+		want            bool
+		wantErr         bool
+		wantAnnotations map[string]string
 	}{
 		{
 			name: "Labels match desired labels after reconcile",
@@ -401,6 +402,81 @@ func TestDeploymentReconciler_reconcileDeployment(t *testing.T) {
 			want:    false,
 			wantErr: false,
 		},
+		{
+			name: "Pod template annotations added by others are kept",
+			args: args{
+				existentObj: &appsv1.Deployment{
+					ObjectMeta: metav1.ObjectMeta{Name: "aaaa", Namespace: "aaaa"},
+					Spec: appsv1.DeploymentSpec{
+						Template: corev1.PodTemplateSpec{
+							ObjectMeta: metav1.ObjectMeta{
+								Annotations: map[string]string{"kubectl.kubernetes.io/restartedAt": "2021-01-01T00:00:00Z"},
+							},
+						},
+					},
+				},
+				desiredObj: &appsv1.Deployment{
+					ObjectMeta: metav1.ObjectMeta{Name: "aaaa", Namespace: "aaaa"},
+					Spec: appsv1.DeploymentSpec{
+						Template: corev1.PodTemplateSpec{},
+					},
+				},
+			},
+			want:    false,
+			wantErr: false,
+		},
+		{
+			name: "Pod template annotations not in the desired Pod template are removed",
+			args: args{
+				existentObj: &appsv1.Deployment{
+					ObjectMeta: metav1.ObjectMeta{Name: "aaaa", Namespace: "aaaa"},
+					Spec: appsv1.DeploymentSpec{
+						Template: corev1.PodTemplateSpec{
+							ObjectMeta: metav1.ObjectMeta{
+								Annotations: map[string]string{"key1": "value1", "kubectl.kubernetes.io/restartedAt": "2021-01-01T00:00:00Z"},
+							},
+						},
+					},
+				},
+				desiredObj: &appsv1.Deployment{
+					ObjectMeta: metav1.ObjectMeta{Name: "aaaa", Namespace: "aaaa"},
+					Spec: appsv1.DeploymentSpec{
+						Template: corev1.PodTemplateSpec{},
+					},
+				},
+			},
+			want:            true,
+			wantErr:         false,
+			wantAnnotations: map[string]string{"kubectl.kubernetes.io/restartedAt": "2021-01-01T00:00:00Z"},
+		},
+		{
+			name: "Pod template annotations are reconciled",
+			args: args{
+				existentObj: &appsv1.Deployment{
+					ObjectMeta: metav1.ObjectMeta{Name: "aaaa", Namespace: "aaaa"},
+					Spec: appsv1.DeploymentSpec{
+						Template: corev1.PodTemplateSpec{
+							ObjectMeta: metav1.ObjectMeta{
+								Annotations: map[string]string{"key1": "value1", "key2": "value2"},
+							},
+						},
+					},
+				},
+				desiredObj: &appsv1.Deployment{
+					ObjectMeta: metav1.ObjectMeta{Name: "aaaa", Namespace: "aaaa"},
+					Spec: appsv1.DeploymentSpec{
+						Template: corev1.PodTemplateSpec{
+							ObjectMeta: metav1.ObjectMeta{
+								Annotations: map[string]string{"key1": "value2"},
+							},
+						},
+					},
+				},
+			},
+			want:            true,
+			wantErr:         false,
+			wantAnnotations: map[string]string{"key1": "value2"},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -434,6 +510,9 @@ func TestDeploymentReconciler_reconcileDeployment(t *testing.T) {
 			if !equality.Semantic.DeepEqual(existent.Spec, desired.Spec) {
 				t.Errorf("DeploymentReconciler.reconcileDeployment() Spec don't match. Got %v, want %v", existent.Spec, desired.Spec)
 			}
+			if tt.wantAnnotations != nil && !equality.Semantic.DeepEqual(existent.Spec.Template.GetAnnotations(), tt.wantAnnotations) {
+				t.Errorf("DeploymentReconciler.reconcileDeployment() Pod template annotations = %v, want %v", existent.Spec.Template.GetAnnotations(), tt.wantAnnotations)
+			}
 		})
 	}
 }
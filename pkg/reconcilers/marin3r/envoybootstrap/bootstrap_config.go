@@ -4,11 +4,13 @@ import (
 	"context"
 	"fmt"
 	"net"
+	"path/filepath"
 	"strconv"
 	"strings"
 
 	marin3rv1alpha1 "github.com/3scale/marin3r/apis/marin3r/v1alpha1"
 	operatorv1alpha1 "github.com/3scale/marin3r/apis/operator/v1alpha1"
+	"github.com/3scale/marin3r/pkg/discoveryservice/podcertificate"
 	"github.com/3scale/marin3r/pkg/envoy"
 	envoy_bootstrap "github.com/3scale/marin3r/pkg/envoy/bootstrap"
 	envoy_bootstrap_options "github.com/3scale/marin3r/pkg/envoy/bootstrap/options"
@@ -48,9 +50,8 @@ func NewBootstrapConfigReconciler(ctx context.Context, logger logr.Logger, clien
 func (r *BootstrapConfigReconciler) Reconcile(envoyAPI envoy.APIVersion) (ctrl.Result, error) {
 
 	// Get the DiscoveryService instance this client want to connect to
-	ds := &operatorv1alpha1.DiscoveryService{}
-	key := types.NamespacedName{Name: r.eb.Spec.DiscoveryService, Namespace: r.eb.GetNamespace()}
-	if err := r.client.Get(r.ctx, key, ds); err != nil {
+	ds, err := getDiscoveryService(r.ctx, r.client, r.eb)
+	if err != nil {
 		if errors.IsNotFound(err) {
 			r.logger.Error(err, "DiscoveryService does not exist", "DiscoveryService", r.eb.Spec.DiscoveryService)
 		}
@@ -62,7 +63,7 @@ func (r *BootstrapConfigReconciler) Reconcile(envoyAPI envoy.APIVersion) (ctrl.R
 
 	// Get this client's bootstrap ConfigMap
 	cm := &corev1.ConfigMap{}
-	err := r.client.Get(r.ctx, types.NamespacedName{Name: cmName, Namespace: cmNamespace}, cm)
+	err = r.client.Get(r.ctx, types.NamespacedName{Name: cmName, Namespace: cmNamespace}, cm)
 
 	if err != nil {
 		if errors.IsNotFound(err) {
@@ -114,7 +115,7 @@ func (r *BootstrapConfigReconciler) Reconcile(envoyAPI envoy.APIVersion) (ctrl.R
 		return ctrl.Result{}, err
 	}
 
-	if equality.Semantic.DeepEqual(desired.Data, cm.Data) {
+	if !equality.Semantic.DeepEqual(desired.Data, cm.Data) {
 		patch := client.MergeFrom(cm.DeepCopy())
 		cm.Data = desired.Data
 		if err := r.client.Patch(r.ctx, cm, patch); err != nil {
@@ -134,9 +135,14 @@ func (r *BootstrapConfigReconciler) getBootstrapConfigMapObject(ds *operatorv1al
 		r.logger.Error(err, "Error parsing 'spec.EnvoyStaticConfig.AdminBindAddress'")
 	}
 
-	bootstrap := envoy_bootstrap.NewConfig(envoyAPI, envoy_bootstrap_options.ConfigOptions{
-		XdsHost:                     fmt.Sprintf("%s.%s.%s", ds.GetServiceConfig().Name, ds.GetNamespace(), "svc"),
-		XdsPort:                     ds.GetXdsServerPort(),
+	// Proxies connect to the external address of the discovery service when set. The
+	// pod certificate signer is only used by Pods, so it is always reached through the Service.
+	xdsHost, xdsPort := ds.GetXdsEndpoint()
+	serviceHost := fmt.Sprintf("%s.%s.%s", ds.GetServiceConfig().Name, ds.GetNamespace(), "svc")
+	opts := envoy_bootstrap_options.ConfigOptions{
+		XdsHost:                     xdsHost,
+		XdsPort:                     xdsPort,
+		XdsDeltaAPI:                 ds.DeltaXds(),
 		XdsClientCertificatePath:    fmt.Sprintf("%s/%s", r.eb.Spec.ClientCertificate.Directory, corev1.TLSCertKey),
 		XdsClientCertificateKeyPath: fmt.Sprintf("%s/%s", r.eb.Spec.ClientCertificate.Directory, corev1.TLSPrivateKeyKey),
 		SdsConfigSourcePath:         fmt.Sprintf("%s/%s", r.eb.Spec.EnvoyStaticConfig.ResourcesDir, envoy_bootstrap_options.TlsCertificateSdsSecretFileName),
@@ -144,7 +150,28 @@ func (r *BootstrapConfigReconciler) getBootstrapConfigMapObject(ds *operatorv1al
 		AdminAddress:                host,
 		AdminPort:                   port,
 		AdminAccessLogPath:          r.eb.Spec.EnvoyStaticConfig.AdminAccessLogPath,
-	})
+		Namespace:                   r.eb.GetNamespace(),
+	}
+
+	// Proxies that authenticate with a ServiceAccount token or request per-pod certificates validate the
+	// server certificate with the discovery service CA, shipped in the bootstrap ConfigMap next to the config file
+	var caCertificate []byte
+	if r.eb.Spec.ServiceAccountToken != nil || r.eb.Spec.ClientCertificate.PerPod {
+		caCertificate, err = r.getCACertificate(ds)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if r.eb.Spec.ServiceAccountToken != nil {
+		opts.XdsTokenPath = fmt.Sprintf("%s/%s", r.eb.Spec.ServiceAccountToken.Directory, corev1.ServiceAccountTokenKey)
+		opts.XdsCACertificatePath = fmt.Sprintf("%s/%s", filepath.Dir(r.eb.Spec.EnvoyStaticConfig.ConfigFile), corev1.ServiceAccountRootCAKey)
+	}
+	// Per-pod certificates are written, along with their SDS resource, to the client certificate directory
+	if r.eb.Spec.ClientCertificate.PerPod {
+		opts.SdsConfigSourcePath = fmt.Sprintf("%s/%s", r.eb.Spec.ClientCertificate.Directory, envoy_bootstrap_options.TlsCertificateSdsSecretFileName)
+	}
+
+	bootstrap := envoy_bootstrap.NewConfig(envoyAPI, opts)
 
 	config, err := bootstrap.GenerateStatic()
 	if err != nil {
@@ -168,13 +195,48 @@ func (r *BootstrapConfigReconciler) getBootstrapConfigMapObject(ds *operatorv1al
 		},
 	}
 
-	for file, content := range sdsResources {
-		cm.Data[file] = content
+	if !r.eb.Spec.ClientCertificate.PerPod {
+		for file, content := range sdsResources {
+			cm.Data[file] = content
+		}
+	} else {
+		cm.Data[podcertificate.SignerURLFileName] = fmt.Sprintf("https://%s:%d%s", serviceHost, ds.GetPodCertificatesPort(), podcertificate.SignerPath)
+	}
+
+	if caCertificate != nil {
+		cm.Data[corev1.ServiceAccountRootCAKey] = string(caCertificate)
 	}
 
 	return cm, nil
 }
 
+// getCACertificate returns the CA certificates of the given DiscoveryService. The trust
+// bundle is preferred, as it holds both the current and the new CA during CA rotations.
+func (r *BootstrapConfigReconciler) getCACertificate(ds *operatorv1alpha1.DiscoveryService) ([]byte, error) {
+	caSecretName := ds.GetRootCertificateAuthorityOptions().SecretName
+
+	bundle := &corev1.Secret{}
+	key := types.NamespacedName{Name: operatorv1alpha1.TrustBundleSecretName(caSecretName), Namespace: ds.GetNamespace()}
+	if err := r.client.Get(r.ctx, key, bundle); err != nil {
+		if !errors.IsNotFound(err) {
+			return nil, err
+		}
+	} else if ca, ok := bundle.Data[operatorv1alpha1.TrustBundleSecretKey]; ok {
+		return ca, nil
+	}
+
+	secret := &corev1.Secret{}
+	key = types.NamespacedName{Name: caSecretName, Namespace: ds.GetNamespace()}
+	if err := r.client.Get(r.ctx, key, secret); err != nil {
+		return nil, err
+	}
+	ca, ok := secret.Data[corev1.TLSCertKey]
+	if !ok {
+		return nil, fmt.Errorf("secret %s has no '%s' key", key, corev1.TLSCertKey)
+	}
+	return ca, nil
+}
+
 func parseBindAddress(address string) (string, uint32, error) {
 
 	var err error
@@ -124,11 +124,181 @@ func TestBootstrapConfigReconciler_Reconcile(t *testing.T) {
 			wantCM: &corev1.ConfigMap{
 				ObjectMeta: v1.ObjectMeta{Name: "cm-v2", Namespace: "default"},
 				Data: map[string]string{
-					"config.json":                     `{"static_resources":{"clusters":[{"name":"xds_cluster","type":"STRICT_DNS","connect_timeout":"1s","load_assignment":{"cluster_name":"xds_cluster","endpoints":[{"lb_endpoints":[{"endpoint":{"address":{"socket_address":{"address":"marin3r-ds.default.svc","port_value":18000}}}}]}]},"http2_protocol_options":{},"transport_socket":{"name":"envoy.transport_sockets.tls","typed_config":{"@type":"type.googleapis.com/envoy.api.v2.auth.UpstreamTlsContext","common_tls_context":{"tls_certificate_sds_secret_configs":[{"sds_config":{"path":"/resdir/tls_certificate_sds_secret.json"}}]}}}}]},"dynamic_resources":{"lds_config":{"ads":{},"resource_api_version":"V2"},"cds_config":{"ads":{},"resource_api_version":"V2"},"ads_config":{"api_type":"GRPC","transport_api_version":"V2","grpc_services":[{"envoy_grpc":{"cluster_name":"xds_cluster"}}]}},"layered_runtime":{"layers":[{"name":"runtime","rtds_layer":{"name":"runtime","rtds_config":{"ads":{},"resource_api_version":"V2"}}}]},"admin":{"access_log_path":"/dev/null","address":{"socket_address":{"address":"127.0.0.1","port_value":1000}}}}`,
+					"config.json":                     `{"node":{"metadata":{"marin3r.3scale.net/namespace":"default"}},"static_resources":{"clusters":[{"name":"xds_cluster","type":"STRICT_DNS","connect_timeout":"1s","load_assignment":{"cluster_name":"xds_cluster","endpoints":[{"lb_endpoints":[{"endpoint":{"address":{"socket_address":{"address":"marin3r-ds.default.svc","port_value":18000}}}}]}]},"http2_protocol_options":{},"transport_socket":{"name":"envoy.transport_sockets.tls","typed_config":{"@type":"type.googleapis.com/envoy.api.v2.auth.UpstreamTlsContext","common_tls_context":{"tls_certificate_sds_secret_configs":[{"sds_config":{"path":"/resdir/tls_certificate_sds_secret.json"}}]}}}}]},"dynamic_resources":{"lds_config":{"ads":{},"resource_api_version":"V2"},"cds_config":{"ads":{},"resource_api_version":"V2"},"ads_config":{"api_type":"GRPC","transport_api_version":"V2","grpc_services":[{"envoy_grpc":{"cluster_name":"xds_cluster"}}]}},"layered_runtime":{"layers":[{"name":"runtime","rtds_layer":{"name":"runtime","rtds_config":{"ads":{},"resource_api_version":"V2"}}}]},"admin":{"access_log_path":"/dev/null","address":{"socket_address":{"address":"127.0.0.1","port_value":1000}}}}`,
 					"tls_certificate_sds_secret.json": `{"resources":[{"@type":"type.googleapis.com/envoy.api.v2.auth.Secret","tls_certificate":{"certificate_chain":{"filename":"/tls/tls.crt"},"private_key":{"filename":"/tls/tls.key"}}}]}`,
 				},
 			},
 		},
+		{
+			name: "Updates an outdated ConfigMap for v2",
+			r: &BootstrapConfigReconciler{
+				ctx:    context.TODO(),
+				logger: ctrl.Log.WithName("test"),
+				client: fake.NewFakeClientWithScheme(
+					s,
+					&operatorv1alpha1.DiscoveryService{
+						ObjectMeta: v1.ObjectMeta{Name: "ds", Namespace: "default"},
+						Spec: operatorv1alpha1.DiscoveryServiceSpec{
+							Image: pointer.StringPtr("xxx"),
+							Debug: pointer.BoolPtr(false),
+						},
+					},
+					&corev1.ConfigMap{
+						ObjectMeta: v1.ObjectMeta{Name: "cm-v2", Namespace: "default"},
+						Data:       map[string]string{"config.json": "{}"},
+					},
+				),
+				scheme: s,
+				eb: &marin3rv1alpha1.EnvoyBootstrap{
+					ObjectMeta: v1.ObjectMeta{Name: "eb", Namespace: "default"},
+					Spec: marin3rv1alpha1.EnvoyBootstrapSpec{
+						DiscoveryService: "ds",
+						ClientCertificate: &marin3rv1alpha1.ClientCertificate{
+							Directory:  "/tls",
+							SecretName: "client-certificate",
+							Duration: metav1.Duration{
+								Duration: func() time.Duration { d, _ := time.ParseDuration("24h"); return d }(),
+							},
+						},
+						EnvoyStaticConfig: &marin3rv1alpha1.EnvoyStaticConfig{
+							ConfigMapNameV2:       "cm-v2",
+							ConfigMapNameV3:       "cm-v3",
+							ConfigFile:            "config.json",
+							ResourcesDir:          "/resdir",
+							RtdsLayerResourceName: "runtime",
+							AdminBindAddress:      "127.0.0.1:1000",
+							AdminAccessLogPath:    "/dev/null",
+						},
+					},
+				},
+			},
+			args:    args{envoyAPI: envoy.APIv2},
+			want:    ctrl.Result{},
+			wantErr: false,
+			wantCM: &corev1.ConfigMap{
+				ObjectMeta: v1.ObjectMeta{Name: "cm-v2", Namespace: "default"},
+				Data: map[string]string{
+					"config.json":                     `{"node":{"metadata":{"marin3r.3scale.net/namespace":"default"}},"static_resources":{"clusters":[{"name":"xds_cluster","type":"STRICT_DNS","connect_timeout":"1s","load_assignment":{"cluster_name":"xds_cluster","endpoints":[{"lb_endpoints":[{"endpoint":{"address":{"socket_address":{"address":"marin3r-ds.default.svc","port_value":18000}}}}]}]},"http2_protocol_options":{},"transport_socket":{"name":"envoy.transport_sockets.tls","typed_config":{"@type":"type.googleapis.com/envoy.api.v2.auth.UpstreamTlsContext","common_tls_context":{"tls_certificate_sds_secret_configs":[{"sds_config":{"path":"/resdir/tls_certificate_sds_secret.json"}}]}}}}]},"dynamic_resources":{"lds_config":{"ads":{},"resource_api_version":"V2"},"cds_config":{"ads":{},"resource_api_version":"V2"},"ads_config":{"api_type":"GRPC","transport_api_version":"V2","grpc_services":[{"envoy_grpc":{"cluster_name":"xds_cluster"}}]}},"layered_runtime":{"layers":[{"name":"runtime","rtds_layer":{"name":"runtime","rtds_config":{"ads":{},"resource_api_version":"V2"}}}]},"admin":{"access_log_path":"/dev/null","address":{"socket_address":{"address":"127.0.0.1","port_value":1000}}}}`,
+					"tls_certificate_sds_secret.json": `{"resources":[{"@type":"type.googleapis.com/envoy.api.v2.auth.Secret","tls_certificate":{"certificate_chain":{"filename":"/tls/tls.crt"},"private_key":{"filename":"/tls/tls.key"}}}]}`,
+				},
+			},
+		},
+		{
+			name: "Creates a ConfigMap for v3 that authenticates with a ServiceAccount token",
+			r: &BootstrapConfigReconciler{
+				ctx:    context.TODO(),
+				logger: ctrl.Log.WithName("test"),
+				client: fake.NewFakeClientWithScheme(
+					s,
+					&operatorv1alpha1.DiscoveryService{
+						ObjectMeta: v1.ObjectMeta{Name: "ds", Namespace: "default"},
+						Spec: operatorv1alpha1.DiscoveryServiceSpec{
+							Image: pointer.StringPtr("xxx"),
+							Debug: pointer.BoolPtr(false),
+						},
+					},
+					&corev1.Secret{
+						ObjectMeta: v1.ObjectMeta{Name: "marin3r-ca-cert-ds", Namespace: "default"},
+						Data:       map[string][]byte{"tls.crt": []byte("ca")},
+					},
+				),
+				scheme: s,
+				eb: &marin3rv1alpha1.EnvoyBootstrap{
+					ObjectMeta: v1.ObjectMeta{Name: "eb", Namespace: "default"},
+					Spec: marin3rv1alpha1.EnvoyBootstrapSpec{
+						DiscoveryService: "ds",
+						ClientCertificate: &marin3rv1alpha1.ClientCertificate{
+							Directory:  "/tls",
+							SecretName: "client-certificate",
+							Duration: metav1.Duration{
+								Duration: func() time.Duration { d, _ := time.ParseDuration("24h"); return d }(),
+							},
+						},
+						EnvoyStaticConfig: &marin3rv1alpha1.EnvoyStaticConfig{
+							ConfigMapNameV2:       "cm-v2",
+							ConfigMapNameV3:       "cm-v3",
+							ConfigFile:            "/etc/envoy/config.json",
+							ResourcesDir:          "/resdir",
+							RtdsLayerResourceName: "runtime",
+							AdminBindAddress:      "127.0.0.1:1000",
+							AdminAccessLogPath:    "/dev/null",
+						},
+						ServiceAccountToken: &marin3rv1alpha1.ServiceAccountToken{Directory: "/token"},
+					},
+				},
+			},
+			args:    args{envoyAPI: envoy.APIv3},
+			want:    ctrl.Result{},
+			wantErr: false,
+			wantCM: &corev1.ConfigMap{
+				ObjectMeta: v1.ObjectMeta{Name: "cm-v3", Namespace: "default"},
+				Data: map[string]string{
+					"config.json": `{"node":{"metadata":{"marin3r.3scale.net/namespace":"default"}},"dynamic_resources":{"lds_config":{"ads":{},"resource_api_version":"V3"},"cds_config":{"ads":{},"resource_api_version":"V3"},"ads_config":{"api_type":"GRPC","transport_api_version":"V3","grpc_services":[{"google_grpc":{"target_uri":"marin3r-ds.default.svc:18000","channel_credentials":{"ssl_credentials":{"root_certs":{"filename":"/etc/envoy/ca.crt"}}},"call_credentials":[{"from_plugin":{"name":"envoy.grpc_credentials.file_based_metadata","typed_config":{"@type":"type.googleapis.com/envoy.config.grpc_credential.v3.FileBasedMetadataConfig","secret_data":{"filename":"/token/token"},"header_key":"authorization","header_prefix":"Bearer "}}}],"stat_prefix":"xds_cluster","credentials_factory_name":"envoy.grpc_credentials.file_based_metadata"}}]}},"layered_runtime":{"layers":[{"name":"runtime","rtds_layer":{"name":"runtime","rtds_config":{"ads":{},"resource_api_version":"V3"}}}]},"admin":{"access_log_path":"/dev/null","address":{"socket_address":{"address":"127.0.0.1","port_value":1000}}}}`,
+					"ca.crt":      "ca",
+				},
+			},
+		},
+		{
+			name: "Creates a ConfigMap for v2 with per-pod client certificates and the CA trust bundle",
+			r: &BootstrapConfigReconciler{
+				ctx:    context.TODO(),
+				logger: ctrl.Log.WithName("test"),
+				client: fake.NewFakeClientWithScheme(
+					s,
+					&operatorv1alpha1.DiscoveryService{
+						ObjectMeta: v1.ObjectMeta{Name: "ds", Namespace: "default"},
+						Spec: operatorv1alpha1.DiscoveryServiceSpec{
+							Image:           pointer.StringPtr("xxx"),
+							Debug:           pointer.BoolPtr(false),
+							PodCertificates: &operatorv1alpha1.PodCertificatesConfig{},
+						},
+					},
+					&corev1.Secret{
+						ObjectMeta: v1.ObjectMeta{Name: "marin3r-ca-cert-ds", Namespace: "default"},
+						Data:       map[string][]byte{"tls.crt": []byte("ca")},
+					},
+					&corev1.Secret{
+						ObjectMeta: v1.ObjectMeta{Name: "marin3r-ca-cert-ds-bundle", Namespace: "default"},
+						Data:       map[string][]byte{"ca.crt": []byte("ca-bundle")},
+					},
+				),
+				scheme: s,
+				eb: &marin3rv1alpha1.EnvoyBootstrap{
+					ObjectMeta: v1.ObjectMeta{Name: "eb", Namespace: "default"},
+					Spec: marin3rv1alpha1.EnvoyBootstrapSpec{
+						DiscoveryService: "ds",
+						ClientCertificate: &marin3rv1alpha1.ClientCertificate{
+							Directory:  "/tls",
+							SecretName: "client-certificate",
+							Duration: metav1.Duration{
+								Duration: func() time.Duration { d, _ := time.ParseDuration("24h"); return d }(),
+							},
+							PerPod: true,
+						},
+						EnvoyStaticConfig: &marin3rv1alpha1.EnvoyStaticConfig{
+							ConfigMapNameV2:       "cm-v2",
+							ConfigMapNameV3:       "cm-v3",
+							ConfigFile:            "config.json",
+							ResourcesDir:          "/resdir",
+							RtdsLayerResourceName: "runtime",
+							AdminBindAddress:      "127.0.0.1:1000",
+							AdminAccessLogPath:    "/dev/null",
+						},
+					},
+				},
+			},
+			args:    args{envoyAPI: envoy.APIv2},
+			want:    ctrl.Result{},
+			wantErr: false,
+			wantCM: &corev1.ConfigMap{
+				ObjectMeta: v1.ObjectMeta{Name: "cm-v2", Namespace: "default"},
+				Data: map[string]string{
+					"config.json":                `{"node":{"metadata":{"marin3r.3scale.net/namespace":"default"}},"static_resources":{"clusters":[{"name":"xds_cluster","type":"STRICT_DNS","connect_timeout":"1s","load_assignment":{"cluster_name":"xds_cluster","endpoints":[{"lb_endpoints":[{"endpoint":{"address":{"socket_address":{"address":"marin3r-ds.default.svc","port_value":18000}}}}]}]},"http2_protocol_options":{},"transport_socket":{"name":"envoy.transport_sockets.tls","typed_config":{"@type":"type.googleapis.com/envoy.api.v2.auth.UpstreamTlsContext","common_tls_context":{"tls_certificate_sds_secret_configs":[{"sds_config":{"path":"/tls/tls_certificate_sds_secret.json"}}]}}}}]},"dynamic_resources":{"lds_config":{"ads":{},"resource_api_version":"V2"},"cds_config":{"ads":{},"resource_api_version":"V2"},"ads_config":{"api_type":"GRPC","transport_api_version":"V2","grpc_services":[{"envoy_grpc":{"cluster_name":"xds_cluster"}}]}},"layered_runtime":{"layers":[{"name":"runtime","rtds_layer":{"name":"runtime","rtds_config":{"ads":{},"resource_api_version":"V2"}}}]},"admin":{"access_log_path":"/dev/null","address":{"socket_address":{"address":"127.0.0.1","port_value":1000}}}}`,
+					"ca.crt":                     "ca-bundle",
+					"pod-certificate-signer-url": "https://marin3r-ds.default.svc:18001/v1/pod-certificate",
+				},
+			},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -5,8 +5,10 @@ import (
 
 	marin3rv1alpha1 "github.com/3scale/marin3r/apis/marin3r/v1alpha1"
 	operatorv1alpha1 "github.com/3scale/marin3r/apis/operator/v1alpha1"
+	xdss "github.com/3scale/marin3r/pkg/discoveryservice/xdss"
 	"github.com/go-logr/logr"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
@@ -39,9 +41,8 @@ func NewClientCertificateReconciler(ctx context.Context, logger logr.Logger, cli
 func (r *ClientCertificateReconciler) Reconcile() (ctrl.Result, error) {
 
 	// Get the DiscoveryService instance this client want to connect to
-	ds := &operatorv1alpha1.DiscoveryService{}
-	key := types.NamespacedName{Name: r.eb.Spec.DiscoveryService, Namespace: r.eb.GetNamespace()}
-	if err := r.client.Get(r.ctx, key, ds); err != nil {
+	ds, err := getDiscoveryService(r.ctx, r.client, r.eb)
+	if err != nil {
 		if errors.IsNotFound(err) {
 			r.logger.Error(err, "DiscoveryService does not exist", "DiscoveryService", r.eb.Spec.DiscoveryService)
 		}
@@ -52,6 +53,7 @@ func (r *ClientCertificateReconciler) Reconcile() (ctrl.Result, error) {
 	// to keep backwards compatibility
 	dscName := r.eb.Spec.ClientCertificate.SecretName
 	dscNamespace := r.eb.GetNamespace()
+	uris := clientCertificateURIs(ds, r.eb)
 
 	// Get this client's DiscoveryServiceCertificate
 	dsc := &operatorv1alpha1.DiscoveryServiceCertificate{}
@@ -66,6 +68,7 @@ func (r *ClientCertificateReconciler) Reconcile() (ctrl.Result, error) {
 					Name:      ds.GetRootCertificateAuthorityOptions().SecretName,
 					Namespace: ds.GetNamespace(),
 				},
+				uris,
 			)
 			if err := controllerutil.SetControllerReference(r.eb, dsc, r.scheme); err != nil {
 				return ctrl.Result{}, err
@@ -110,7 +113,8 @@ func (r *ClientCertificateReconciler) Reconcile() (ctrl.Result, error) {
 	// the old DiscoveryServiceCertificate and let the controller create a new one
 	// in the next reconcile loop
 	if int64(r.eb.Spec.ClientCertificate.Duration.Seconds()) != dsc.Spec.ValidFor ||
-		r.eb.Spec.ClientCertificate.SecretName != dsc.Spec.SecretRef.Name {
+		r.eb.Spec.ClientCertificate.SecretName != dsc.Spec.SecretRef.Name ||
+		!equality.Semantic.DeepEqual(uris, dsc.Spec.URIs) {
 		// Delete the current DiscoveryServiceCertificate and let it be recreated
 		// in the next loop
 		if err := r.client.Delete(r.ctx, dsc); err != nil {
@@ -122,7 +126,18 @@ func (r *ClientCertificateReconciler) Reconcile() (ctrl.Result, error) {
 	return ctrl.Result{}, nil
 }
 
-func (r *ClientCertificateReconciler) genClientCertResource(certificateKey, signingCertificateKey types.NamespacedName) *operatorv1alpha1.DiscoveryServiceCertificate {
+// clientCertificateURIs returns the URI SANs of the client certificate. Client certificates of
+// discovery services that serve several namespaces identify the namespace of the client, so
+// they can only fetch the resources of their own namespace.
+func clientCertificateURIs(ds *operatorv1alpha1.DiscoveryService, eb *marin3rv1alpha1.EnvoyBootstrap) []string {
+	if !ds.ClusterScoped() {
+		return nil
+	}
+	return []string{xdss.NamespaceIdentityURI(eb.GetNamespace()).String()}
+}
+
+func (r *ClientCertificateReconciler) genClientCertResource(certificateKey, signingCertificateKey types.NamespacedName,
+	uris []string) *operatorv1alpha1.DiscoveryServiceCertificate {
 	return &operatorv1alpha1.DiscoveryServiceCertificate{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      certificateKey.Name,
@@ -142,6 +157,7 @@ func (r *ClientCertificateReconciler) genClientCertResource(certificateKey, sign
 			SecretRef: corev1.SecretReference{
 				Name: r.eb.Spec.ClientCertificate.SecretName,
 			},
+			URIs: uris,
 		},
 	}
 }
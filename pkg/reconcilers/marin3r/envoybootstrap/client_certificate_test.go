@@ -131,7 +131,94 @@ func TestClientCertificateReconciler_Reconcile(t *testing.T) {
 					},
 				},
 			},
-		}}
+		},
+		{
+			name: "Creates a DiscoveryServiceCertificate for a DiscoveryService in another namespace",
+			r: &ClientCertificateReconciler{
+				ctx:    context.TODO(),
+				logger: ctrl.Log.WithName("test"),
+				client: fake.NewFakeClientWithScheme(
+					s,
+					&operatorv1alpha1.DiscoveryService{
+						ObjectMeta: v1.ObjectMeta{Name: "ds", Namespace: "marin3r"},
+						Spec: operatorv1alpha1.DiscoveryServiceSpec{
+							Image:             pointer.StringPtr("xxx"),
+							NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"key": "value"}},
+						},
+					},
+					&corev1.Namespace{ObjectMeta: v1.ObjectMeta{Name: "default", Labels: map[string]string{"key": "value"}}},
+				),
+				scheme: s,
+				eb: &marin3rv1alpha1.EnvoyBootstrap{
+					ObjectMeta: v1.ObjectMeta{Name: "eb", Namespace: "default"},
+					Spec: marin3rv1alpha1.EnvoyBootstrapSpec{
+						DiscoveryService:          "ds",
+						DiscoveryServiceNamespace: "marin3r",
+						ClientCertificate: &marin3rv1alpha1.ClientCertificate{
+							Directory:  "/tls",
+							SecretName: "client-certificate",
+							Duration: metav1.Duration{
+								Duration: func() time.Duration { d, _ := time.ParseDuration("1h"); return d }(),
+							},
+						},
+					},
+				},
+			},
+			want:    ctrl.Result{},
+			wantErr: false,
+			wantDSC: &operatorv1alpha1.DiscoveryServiceCertificate{
+				ObjectMeta: v1.ObjectMeta{Name: "client-certificate", Namespace: "default"},
+				Spec: operatorv1alpha1.DiscoveryServiceCertificateSpec{
+					CommonName: "client-certificate",
+					ValidFor:   3600,
+					Signer: operatorv1alpha1.DiscoveryServiceCertificateSigner{
+						CASigned: &operatorv1alpha1.CASignedConfig{
+							SecretRef: corev1.SecretReference{
+								Name:      "marin3r-ca-cert-ds",
+								Namespace: "marin3r",
+							}},
+					},
+					SecretRef: corev1.SecretReference{
+						Name: "client-certificate",
+					},
+					URIs: []string{"spiffe://marin3r.3scale.net/ns/default"},
+				},
+			},
+		},
+		{
+			name: "Fails for a DiscoveryService in another namespace that does not serve it",
+			r: &ClientCertificateReconciler{
+				ctx:    context.TODO(),
+				logger: ctrl.Log.WithName("test"),
+				client: fake.NewFakeClientWithScheme(
+					s,
+					&operatorv1alpha1.DiscoveryService{
+						ObjectMeta: v1.ObjectMeta{Name: "ds", Namespace: "marin3r"},
+						Spec: operatorv1alpha1.DiscoveryServiceSpec{
+							Image:             pointer.StringPtr("xxx"),
+							NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"key": "value"}},
+						},
+					},
+					&corev1.Namespace{ObjectMeta: v1.ObjectMeta{Name: "default"}},
+				),
+				scheme: s,
+				eb: &marin3rv1alpha1.EnvoyBootstrap{
+					ObjectMeta: v1.ObjectMeta{Name: "eb", Namespace: "default"},
+					Spec: marin3rv1alpha1.EnvoyBootstrapSpec{
+						DiscoveryService:          "ds",
+						DiscoveryServiceNamespace: "marin3r",
+						ClientCertificate: &marin3rv1alpha1.ClientCertificate{
+							Directory:  "/tls",
+							SecretName: "client-certificate",
+						},
+					},
+				},
+			},
+			want:    ctrl.Result{},
+			wantErr: true,
+			wantDSC: &operatorv1alpha1.DiscoveryServiceCertificate{},
+		},
+	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			got, err := tt.r.Reconcile()
@@ -0,0 +1,45 @@
+package reconcilers
+
+import (
+	"context"
+	"fmt"
+
+	marin3rv1alpha1 "github.com/3scale/marin3r/apis/marin3r/v1alpha1"
+	operatorv1alpha1 "github.com/3scale/marin3r/apis/operator/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// getDiscoveryService returns the DiscoveryService an EnvoyBootstrap points to. A DiscoveryService
+// in another namespace than the EnvoyBootstrap's must select its namespace with the namespaceSelector.
+func getDiscoveryService(ctx context.Context, cl client.Client, eb *marin3rv1alpha1.EnvoyBootstrap) (*operatorv1alpha1.DiscoveryService, error) {
+	ds := &operatorv1alpha1.DiscoveryService{}
+	key := types.NamespacedName{Name: eb.Spec.DiscoveryService, Namespace: eb.GetDiscoveryServiceNamespace()}
+	if err := cl.Get(ctx, key, ds); err != nil {
+		return nil, err
+	}
+
+	if ds.GetNamespace() == eb.GetNamespace() {
+		return ds, nil
+	}
+
+	if !ds.ClusterScoped() {
+		return nil, fmt.Errorf("DiscoveryService %q does not serve namespace %q", key, eb.GetNamespace())
+	}
+	selector, err := metav1.LabelSelectorAsSelector(ds.Spec.NamespaceSelector)
+	if err != nil {
+		return nil, err
+	}
+	ns := &corev1.Namespace{}
+	if err := cl.Get(ctx, types.NamespacedName{Name: eb.GetNamespace()}, ns); err != nil {
+		return nil, err
+	}
+	if !selector.Matches(labels.Set(ns.GetLabels())) {
+		return nil, fmt.Errorf("DiscoveryService %q does not serve namespace %q", key, eb.GetNamespace())
+	}
+
+	return ds, nil
+}
@@ -3,15 +3,18 @@ package reconcilers
 import (
 	"context"
 	"fmt"
+	"time"
 
 	marin3rv1alpha1 "github.com/3scale/marin3r/apis/marin3r/v1alpha1"
 	"github.com/3scale/marin3r/pkg/common"
 	"github.com/3scale/marin3r/pkg/envoy"
 	"github.com/3scale/marin3r/pkg/reconcilers/marin3r/envoyconfig/filters"
 	"github.com/3scale/marin3r/pkg/reconcilers/marin3r/envoyconfig/revisions"
+	"github.com/3scale/marin3r/pkg/reconcilers/marin3r/envoyconfig/rollback"
 	"github.com/go-logr/logr"
 	"github.com/operator-framework/operator-lib/status"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/utils/pointer"
@@ -20,10 +23,6 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 )
 
-const (
-	maxRevisions = 10
-)
-
 // RevisionReconciler is a struct with methods to reconcile EnvoyConfig revisions
 type RevisionReconciler struct {
 	ctx    context.Context
@@ -124,7 +123,7 @@ func (r *RevisionReconciler) Reconcile() (ctrl.Result, error) {
 		}
 	}
 
-	_, err = revisions.Get(r.ctx, r.client, r.Namespace(),
+	ecr, err := revisions.Get(r.ctx, r.client, r.Namespace(),
 		filters.ByNodeID(r.NodeID()), filters.ByVersion(r.DesiredVersion()), filters.ByEnvoyAPI(r.EnvoyAPI()))
 	if err != nil {
 		if revisions.ErrorIsNoMatchesForFilter(err) {
@@ -149,12 +148,49 @@ func (r *RevisionReconciler) Reconcile() (ctrl.Result, error) {
 		return ctrl.Result{}, err
 	}
 
+	// The blockOnDanglingReferences setting is not part of the version hash, so it
+	// can change without a new revision being created
+	if ecr.GetBlockOnDanglingReferences() != r.Instance().GetBlockOnDanglingReferences() {
+		ecr.Spec.BlockOnDanglingReferences = pointer.BoolPtr(r.Instance().GetBlockOnDanglingReferences())
+		if err := r.client.Update(r.ctx, ecr); err != nil {
+			log.Error(err, "unable to update EnvoyConfigRevision resource", "Phase", "ReconcileRevisionForCurrentResources")
+			return ctrl.Result{}, err
+		}
+		log.Info("updated blockOnDanglingReferences for current resources revision", "version", r.DesiredVersion())
+		return ctrl.Result{Requeue: true}, nil
+	}
+
+	// The rollout policy is not part of the version hash either
+	if !equality.Semantic.DeepEqual(ecr.Spec.Rollout, r.Instance().Spec.Rollout) {
+		ecr.Spec.Rollout = r.Instance().Spec.Rollout
+		if err := r.client.Update(r.ctx, ecr); err != nil {
+			log.Error(err, "unable to update EnvoyConfigRevision resource", "Phase", "ReconcileRevisionForCurrentResources")
+			return ctrl.Result{}, err
+		}
+		log.Info("updated rollout policy for current resources revision", "version", r.DesiredVersion())
+		return ctrl.Result{Requeue: true}, nil
+	}
+
 	list, err = revisions.List(r.ctx, r.client, r.Namespace(), filters.ByNodeID(r.NodeID()), filters.ByEnvoyAPI(r.EnvoyAPI()))
 	if err != nil {
 		log.Error(err, "unable to list revisions", "Phase", "BuildRevisionList")
 		return ctrl.Result{}, err
 	}
 	r.revisionList = revisions.SortByPublication(r.DesiredVersion(), list)
+
+	toBeUntainted, nextExpiration := r.isRevisionTaintedConditionReconciled(r.Instance().GetUntaintAfter(), time.Now())
+	if len(toBeUntainted) > 0 {
+		for _, ecr := range toBeUntainted {
+			if err := r.untaintRevision(&ecr); err != nil {
+				log.Error(err, "unable to untaint revision", "Phase", "UntaintRevisions", "Name/Namespace", common.ObjectKey(&ecr))
+				return ctrl.Result{}, err
+			}
+			log.Info("untainted EnvoyConfigRevision", "Namespace/Name", common.ObjectKey(&ecr))
+		}
+		// Revisions untainted, trigger a new reconcile loop
+		return ctrl.Result{Requeue: true}, nil
+	}
+
 	publishedVersion, cacheState := r.getVersionToPublish()
 	r.cacheState = &cacheState
 	r.publishedVersion = &publishedVersion
@@ -178,7 +214,7 @@ func (r *RevisionReconciler) Reconcile() (ctrl.Result, error) {
 		log.Info("updated the published EnvoyConfigRevision", "Namespace/Name", common.ObjectKey(shouldBeTrue))
 	}
 
-	shouldBeDeleted := r.isRevisionRetentionReconciled(maxRevisions)
+	shouldBeDeleted := r.isRevisionRetentionReconciled(r.Instance().Spec.RevisionHistory)
 	if shouldBeDeleted != nil {
 		for _, ecr := range shouldBeDeleted {
 			if err := r.client.Delete(r.ctx, &ecr); err != nil {
@@ -190,7 +226,8 @@ func (r *RevisionReconciler) Reconcile() (ctrl.Result, error) {
 	}
 
 	log.Info(fmt.Sprintf("CacheState is %s after revision reconcile", cacheState))
-	return ctrl.Result{}, nil
+	// Requeue when the next taint expires, if any
+	return ctrl.Result{RequeueAfter: nextExpiration}, nil
 }
 
 // areRevisionLabelsOk ensures all the EnvoyConfigRevisions owned by the EnvoyConfig have
@@ -217,12 +254,86 @@ func (r *RevisionReconciler) areRevisionLabelsOk(list *marin3rv1alpha1.EnvoyConf
 	return ok
 }
 
+// isRevisionTaintedConditionReconciled returns the revisions that need the RevisionTainted condition cleared,
+// either because they have the untaint annotation or because their taint is older than 'untaintAfter'.
+// Taints never expire if 'untaintAfter' is zero. Only taints caused by a gateway NACK expire, as the other
+// reasons (failed loading of resources, dangling references) do not go away unless the revision changes.
+// The second return value is the time left until the next taint expires, zero if there is none.
+func (r *RevisionReconciler) isRevisionTaintedConditionReconciled(untaintAfter time.Duration, now time.Time) ([]marin3rv1alpha1.EnvoyConfigRevision, time.Duration) {
+
+	var toBeUntainted []marin3rv1alpha1.EnvoyConfigRevision = []marin3rv1alpha1.EnvoyConfigRevision{}
+	var nextExpiration time.Duration
+
+	for _, ecr := range r.revisionList.Items {
+
+		if _, ok := ecr.GetAnnotations()[marin3rv1alpha1.RevisionUntaintAnnotation]; ok {
+			toBeUntainted = append(toBeUntainted, ecr)
+			continue
+		}
+
+		taint := ecr.Status.Conditions.GetCondition(marin3rv1alpha1.RevisionTaintedCondition)
+		if untaintAfter == 0 || taint == nil || !taint.IsTrue() || taint.Reason != rollback.GatewayReturnedNACKReason {
+			continue
+		}
+
+		if left := taint.LastTransitionTime.Add(untaintAfter).Sub(now); left <= 0 {
+			toBeUntainted = append(toBeUntainted, ecr)
+		} else if nextExpiration == 0 || left < nextExpiration {
+			nextExpiration = left
+		}
+	}
+
+	return toBeUntainted, nextExpiration
+}
+
+// untaintRevision clears the RevisionTainted condition of the given revision and
+// removes the untaint annotation if present
+func (r *RevisionReconciler) untaintRevision(ecr *marin3rv1alpha1.EnvoyConfigRevision) error {
+
+	reason, msg := "TaintExpired", "Taint has expired"
+	if _, ok := ecr.GetAnnotations()[marin3rv1alpha1.RevisionUntaintAnnotation]; ok {
+		reason, msg = "UntaintRequested", "Taint cleared by annotation"
+		// The annotation is removed first as patching the object does not patch its status
+		patch := client.MergeFrom(ecr.DeepCopy())
+		annotations := ecr.GetAnnotations()
+		delete(annotations, marin3rv1alpha1.RevisionUntaintAnnotation)
+		ecr.SetAnnotations(annotations)
+		if err := r.client.Patch(r.ctx, ecr, patch); err != nil {
+			return err
+		}
+	}
+
+	if ecr.Status.Conditions.IsTrueFor(marin3rv1alpha1.RevisionTaintedCondition) {
+		patch := client.MergeFrom(ecr.DeepCopy())
+		ecr.Status.Conditions.SetCondition(status.Condition{
+			Type:    marin3rv1alpha1.RevisionTaintedCondition,
+			Status:  corev1.ConditionFalse,
+			Reason:  status.ConditionReason(reason),
+			Message: msg,
+		})
+		if err := r.client.Status().Patch(r.ctx, ecr, patch); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 // getVersionToPublish takes an EnvoyConfigRevisionList and returns the version that should be
 // published. It also returns the state of the cache based on the position of the revision
-// with the returned version in the list of revisions.
+// with the returned version in the list of revisions. The pinned version, if any, takes
+// precedence as long as its revision exists and is not tainted.
 func (r *RevisionReconciler) getVersionToPublish() (string, string) {
 	var versionToPublish string
 
+	if pinned := r.Instance().GetPinnedVersion(); pinned != "" {
+		for _, ecr := range r.revisionList.Items {
+			if ecr.Spec.Version == pinned && !ecr.Status.Conditions.IsTrueFor(marin3rv1alpha1.RevisionTaintedCondition) {
+				return pinned, marin3rv1alpha1.PinnedState
+			}
+		}
+	}
+
 	topIdx := len(r.revisionList.Items) - 1
 
 	// Starting from the highest index in the list and going
@@ -279,27 +390,57 @@ func (r *RevisionReconciler) isRevisionPublishedConditionReconciled(versionToPub
 	return shouldBeTrue, shouldBeFalse
 }
 
-// isRevisionRetentionReconciled removes items from the revisionList until the list holds the number of items
-// determined by the 'retention' parameter
-func (r *RevisionReconciler) isRevisionRetentionReconciled(retention int) []marin3rv1alpha1.EnvoyConfigRevision {
+// isRevisionRetentionReconciled removes items from the revisionList until the list honors the given
+// revision history policy. The removed items are returned. Items are removed starting from the lowest
+// index in the list, but the published revision, the revision for the current resources (the highest
+// index in the list) and the last 'minUntainted' untainted revisions are never removed.
+func (r *RevisionReconciler) isRevisionRetentionReconciled(policy *marin3rv1alpha1.RevisionHistoryPolicy) []marin3rv1alpha1.EnvoyConfigRevision {
 
 	var toBeDeleted []marin3rv1alpha1.EnvoyConfigRevision = []marin3rv1alpha1.EnvoyConfigRevision{}
 	var revisionList *[]marin3rv1alpha1.EnvoyConfigRevision = &(r.GetRevisionList().Items)
 
-	for len(*revisionList) > retention {
-		toBeDeleted = append(toBeDeleted, popRevision(revisionList))
+	keep := make([]bool, len(*revisionList))
+	untainted := 0
+	for idx := len(*revisionList) - 1; idx >= 0; idx-- {
+		ecr := (*revisionList)[idx]
+		if idx == len(*revisionList)-1 || ecr.Status.Conditions.IsTrueFor(marin3rv1alpha1.RevisionPublishedCondition) ||
+			(r.publishedVersion != nil && ecr.Spec.Version == *r.publishedVersion) {
+			keep[idx] = true
+		}
+		if !ecr.Status.Conditions.IsTrueFor(marin3rv1alpha1.RevisionTaintedCondition) && untainted < policy.GetMinUntainted() {
+			keep[idx] = true
+			untainted++
+		}
+	}
+
+	now := time.Now()
+	kept := []marin3rv1alpha1.EnvoyConfigRevision{}
+	remaining := len(*revisionList)
+	for idx, ecr := range *revisionList {
+		if !keep[idx] && (remaining > policy.GetLimit() || isRevisionExpired(ecr, policy.GetMaxAge(), now)) {
+			toBeDeleted = append(toBeDeleted, ecr)
+			remaining--
+			continue
+		}
+		kept = append(kept, ecr)
 	}
+	*revisionList = kept
 
 	return toBeDeleted
 }
 
-// popRevision removes an EnvoyConfigRevision from a list of EnvoyConfigRevision resources, starting from
-// the lowest index in the slice. The removed element is returned as a return value and the list is
-// modified "in place".
-func popRevision(list *[]marin3rv1alpha1.EnvoyConfigRevision) marin3rv1alpha1.EnvoyConfigRevision {
-	item := (*list)[0]
-	*list = (*list)[1:]
-	return item
+// isRevisionExpired returns true if the revision is older than maxAge. The age of
+// the revision is measured since it was last published, or since it was created if
+// it has never been published. Revisions never expire if maxAge is zero.
+func isRevisionExpired(ecr marin3rv1alpha1.EnvoyConfigRevision, maxAge time.Duration, now time.Time) bool {
+	if maxAge == 0 {
+		return false
+	}
+	timestamp := ecr.GetCreationTimestamp()
+	if !ecr.Status.LastPublishedAt.IsZero() {
+		timestamp = *ecr.Status.LastPublishedAt
+	}
+	return now.Sub(timestamp.Time) > maxAge
 }
 
 // newRevisionForCurrentResources generates an EnvoyConfigRevision resource for the current
@@ -321,11 +462,13 @@ func (r *RevisionReconciler) newRevisionForCurrentResources() *marin3rv1alpha1.E
 			},
 		},
 		Spec: marin3rv1alpha1.EnvoyConfigRevisionSpec{
-			NodeID:         r.NodeID(),
-			EnvoyAPI:       pointer.StringPtr(r.EnvoyAPI().String()),
-			Version:        r.DesiredVersion(),
-			Serialization:  pointer.StringPtr(string(r.Instance().GetSerialization())),
-			EnvoyResources: r.Instance().Spec.EnvoyResources,
+			NodeID:                    r.NodeID(),
+			EnvoyAPI:                  pointer.StringPtr(r.EnvoyAPI().String()),
+			Version:                   r.DesiredVersion(),
+			Serialization:             pointer.StringPtr(string(r.Instance().GetSerialization())),
+			EnvoyResources:            r.Instance().Spec.EnvoyResources,
+			BlockOnDanglingReferences: r.Instance().Spec.BlockOnDanglingReferences,
+			Rollout:                   r.Instance().Spec.Rollout,
 		},
 	}
 }
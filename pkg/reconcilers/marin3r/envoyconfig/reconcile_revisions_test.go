@@ -4,12 +4,14 @@ import (
 	"context"
 	"reflect"
 	"testing"
+	"time"
 
 	marin3rv1alpha1 "github.com/3scale/marin3r/apis/marin3r/v1alpha1"
 	"github.com/3scale/marin3r/pkg/common"
 	envoy "github.com/3scale/marin3r/pkg/envoy"
 	envoy_serializer "github.com/3scale/marin3r/pkg/envoy/serializer"
 	"github.com/3scale/marin3r/pkg/reconcilers/marin3r/envoyconfig/filters"
+	"github.com/3scale/marin3r/pkg/reconcilers/marin3r/envoyconfig/rollback"
 	"github.com/go-logr/logr"
 	"github.com/operator-framework/operator-lib/status"
 	corev1 "k8s.io/api/core/v1"
@@ -462,6 +464,40 @@ func TestRevisionReconciler_Reconcile(t *testing.T) {
 			want:    ctrl.Result{},
 			wantErr: false,
 		},
+		{
+			name: "EnvoyConfigRevision for current version needs blockOnDanglingReferences update, no error and requeue",
+			fields: fields{
+				ctx:    context.TODO(),
+				logger: ctrl.Log.WithName("test"),
+				client: fake.NewFakeClientWithScheme(s,
+					&marin3rv1alpha1.EnvoyConfigRevision{
+						TypeMeta: metav1.TypeMeta{Kind: "EnvoyConfigRevision", APIVersion: "v1alpha1"},
+						ObjectMeta: metav1.ObjectMeta{
+							Name: "ecr1", Namespace: "test",
+							Labels: map[string]string{
+								filters.NodeIDTag:   "node",
+								filters.EnvoyAPITag: envoy.APIv3.String(),
+								filters.VersionTag:  common.Hash(&marin3rv1alpha1.EnvoyResources{}),
+							},
+						},
+						Spec: marin3rv1alpha1.EnvoyConfigRevisionSpec{},
+					},
+				),
+				scheme: s,
+				ec: &marin3rv1alpha1.EnvoyConfig{
+					TypeMeta:   metav1.TypeMeta{Kind: "EnvoyConfig", APIVersion: "v1alpha1"},
+					ObjectMeta: metav1.ObjectMeta{Name: "ec", Namespace: "test"},
+					Spec: marin3rv1alpha1.EnvoyConfigSpec{
+						NodeID:                    "node",
+						EnvoyAPI:                  pointer.StringPtr(envoy.APIv3.String()),
+						BlockOnDanglingReferences: pointer.BoolPtr(true),
+						EnvoyResources:            &marin3rv1alpha1.EnvoyResources{},
+					},
+				},
+			},
+			want:    ctrl.Result{Requeue: true},
+			wantErr: false,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -582,6 +618,7 @@ func TestRevisionReconciler_areRevisionLabelsOk(t *testing.T) {
 func TestRevisionReconciler_getVersionToPublish(t *testing.T) {
 	tests := []struct {
 		name           string
+		pinnedVersion  *string
 		revisionList   *marin3rv1alpha1.EnvoyConfigRevisionList
 		wantVersion    string
 		wantCacheState string
@@ -634,10 +671,54 @@ func TestRevisionReconciler_getVersionToPublish(t *testing.T) {
 			wantVersion:    "",
 			wantCacheState: marin3rv1alpha1.RollbackFailedState,
 		},
+		{
+			name:          "Returns the pinned version and Pinned state",
+			pinnedVersion: pointer.StringPtr("aaaa"),
+			revisionList: &marin3rv1alpha1.EnvoyConfigRevisionList{
+				Items: []marin3rv1alpha1.EnvoyConfigRevision{
+					{Spec: marin3rv1alpha1.EnvoyConfigRevisionSpec{Version: "aaaa"}},
+					{Spec: marin3rv1alpha1.EnvoyConfigRevisionSpec{Version: "bbbb"}},
+					{Spec: marin3rv1alpha1.EnvoyConfigRevisionSpec{Version: "xxxx"}},
+				},
+			},
+			wantVersion:    "aaaa",
+			wantCacheState: marin3rv1alpha1.PinnedState,
+		},
+		{
+			name:          "Ignores the pinned version if its revision is tainted",
+			pinnedVersion: pointer.StringPtr("aaaa"),
+			revisionList: &marin3rv1alpha1.EnvoyConfigRevisionList{
+				Items: []marin3rv1alpha1.EnvoyConfigRevision{
+					{Spec: marin3rv1alpha1.EnvoyConfigRevisionSpec{Version: "aaaa"},
+						Status: marin3rv1alpha1.EnvoyConfigRevisionStatus{
+							Conditions: []status.Condition{{
+								Type:   marin3rv1alpha1.RevisionTaintedCondition,
+								Status: corev1.ConditionTrue,
+							}}}},
+					{Spec: marin3rv1alpha1.EnvoyConfigRevisionSpec{Version: "xxxx"}},
+				},
+			},
+			wantVersion:    "xxxx",
+			wantCacheState: marin3rv1alpha1.InSyncState,
+		},
+		{
+			name:          "Ignores the pinned version if its revision does not exist",
+			pinnedVersion: pointer.StringPtr("zzzz"),
+			revisionList: &marin3rv1alpha1.EnvoyConfigRevisionList{
+				Items: []marin3rv1alpha1.EnvoyConfigRevision{
+					{Spec: marin3rv1alpha1.EnvoyConfigRevisionSpec{Version: "aaaa"}},
+					{Spec: marin3rv1alpha1.EnvoyConfigRevisionSpec{Version: "xxxx"}},
+				},
+			},
+			wantVersion:    "xxxx",
+			wantCacheState: marin3rv1alpha1.InSyncState,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			r := testRevisionReconcilerBuilder(s, &marin3rv1alpha1.EnvoyConfig{})
+			r := testRevisionReconcilerBuilder(s, &marin3rv1alpha1.EnvoyConfig{
+				Spec: marin3rv1alpha1.EnvoyConfigSpec{PinnedVersion: tt.pinnedVersion},
+			})
 			r.revisionList = tt.revisionList
 			gotVersion, gotCacheState := r.getVersionToPublish()
 			if gotVersion != tt.wantVersion {
@@ -914,8 +995,13 @@ func TestRevisionReconciler_isRevisionRetentionReconciled(t *testing.T) {
 		revisionList     *marin3rv1alpha1.EnvoyConfigRevisionList
 	}
 	type args struct {
-		retention int
+		policy *marin3rv1alpha1.RevisionHistoryPolicy
 	}
+	published := status.Conditions{{Type: marin3rv1alpha1.RevisionPublishedCondition, Status: corev1.ConditionTrue}}
+	tainted := status.Conditions{{Type: marin3rv1alpha1.RevisionTaintedCondition, Status: corev1.ConditionTrue}}
+	old := metav1.NewTime(time.Now().Add(-2 * time.Hour))
+	recent := metav1.NewTime(time.Now().Add(-1 * time.Minute))
+
 	tests := []struct {
 		name        string
 		fields      fields
@@ -934,7 +1020,7 @@ func TestRevisionReconciler_isRevisionRetentionReconciled(t *testing.T) {
 					},
 				},
 			},
-			args: args{retention: 1},
+			args: args{policy: &marin3rv1alpha1.RevisionHistoryPolicy{Limit: pointer.Int32Ptr(1)}},
 			wantTrimmed: []marin3rv1alpha1.EnvoyConfigRevision{
 				{ObjectMeta: metav1.ObjectMeta{Name: "ecr1"}},
 				{ObjectMeta: metav1.ObjectMeta{Name: "ecr2"}},
@@ -954,7 +1040,7 @@ func TestRevisionReconciler_isRevisionRetentionReconciled(t *testing.T) {
 					},
 				},
 			},
-			args:        args{retention: 1},
+			args:        args{policy: &marin3rv1alpha1.RevisionHistoryPolicy{Limit: pointer.Int32Ptr(1)}},
 			wantTrimmed: []marin3rv1alpha1.EnvoyConfigRevision{},
 			wantList: &marin3rv1alpha1.EnvoyConfigRevisionList{
 				Items: []marin3rv1alpha1.EnvoyConfigRevision{
@@ -962,6 +1048,74 @@ func TestRevisionReconciler_isRevisionRetentionReconciled(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "Never deletes the published revision",
+			fields: fields{nil, nil, nil, nil, nil, nil, nil, nil,
+				&marin3rv1alpha1.EnvoyConfigRevisionList{
+					Items: []marin3rv1alpha1.EnvoyConfigRevision{
+						{ObjectMeta: metav1.ObjectMeta{Name: "ecr1"}, Status: marin3rv1alpha1.EnvoyConfigRevisionStatus{Conditions: published}},
+						{ObjectMeta: metav1.ObjectMeta{Name: "ecr2"}},
+						{ObjectMeta: metav1.ObjectMeta{Name: "ecr3"}, Status: marin3rv1alpha1.EnvoyConfigRevisionStatus{Conditions: tainted}},
+					},
+				},
+			},
+			args: args{policy: &marin3rv1alpha1.RevisionHistoryPolicy{Limit: pointer.Int32Ptr(1)}},
+			wantTrimmed: []marin3rv1alpha1.EnvoyConfigRevision{
+				{ObjectMeta: metav1.ObjectMeta{Name: "ecr2"}},
+			},
+			wantList: &marin3rv1alpha1.EnvoyConfigRevisionList{
+				Items: []marin3rv1alpha1.EnvoyConfigRevision{
+					{ObjectMeta: metav1.ObjectMeta{Name: "ecr1"}, Status: marin3rv1alpha1.EnvoyConfigRevisionStatus{Conditions: published}},
+					{ObjectMeta: metav1.ObjectMeta{Name: "ecr3"}, Status: marin3rv1alpha1.EnvoyConfigRevisionStatus{Conditions: tainted}},
+				},
+			},
+		},
+		{
+			name: "Keeps the last 'minUntainted' untainted revisions",
+			fields: fields{nil, nil, nil, nil, nil, nil, nil, nil,
+				&marin3rv1alpha1.EnvoyConfigRevisionList{
+					Items: []marin3rv1alpha1.EnvoyConfigRevision{
+						{ObjectMeta: metav1.ObjectMeta{Name: "ecr1"}},
+						{ObjectMeta: metav1.ObjectMeta{Name: "ecr2"}},
+						{ObjectMeta: metav1.ObjectMeta{Name: "ecr3"}, Status: marin3rv1alpha1.EnvoyConfigRevisionStatus{Conditions: tainted}},
+						{ObjectMeta: metav1.ObjectMeta{Name: "ecr4"}, Status: marin3rv1alpha1.EnvoyConfigRevisionStatus{Conditions: tainted}},
+					},
+				},
+			},
+			args: args{policy: &marin3rv1alpha1.RevisionHistoryPolicy{Limit: pointer.Int32Ptr(1), MinUntainted: pointer.Int32Ptr(1)}},
+			wantTrimmed: []marin3rv1alpha1.EnvoyConfigRevision{
+				{ObjectMeta: metav1.ObjectMeta{Name: "ecr1"}},
+				{ObjectMeta: metav1.ObjectMeta{Name: "ecr3"}, Status: marin3rv1alpha1.EnvoyConfigRevisionStatus{Conditions: tainted}},
+			},
+			wantList: &marin3rv1alpha1.EnvoyConfigRevisionList{
+				Items: []marin3rv1alpha1.EnvoyConfigRevision{
+					{ObjectMeta: metav1.ObjectMeta{Name: "ecr2"}},
+					{ObjectMeta: metav1.ObjectMeta{Name: "ecr4"}, Status: marin3rv1alpha1.EnvoyConfigRevisionStatus{Conditions: tainted}},
+				},
+			},
+		},
+		{
+			name: "Deletes revisions older than 'maxAge'",
+			fields: fields{nil, nil, nil, nil, nil, nil, nil, nil,
+				&marin3rv1alpha1.EnvoyConfigRevisionList{
+					Items: []marin3rv1alpha1.EnvoyConfigRevision{
+						{ObjectMeta: metav1.ObjectMeta{Name: "ecr1", CreationTimestamp: old}},
+						{ObjectMeta: metav1.ObjectMeta{Name: "ecr2", CreationTimestamp: recent}},
+						{ObjectMeta: metav1.ObjectMeta{Name: "ecr3", CreationTimestamp: old}},
+					},
+				},
+			},
+			args: args{policy: &marin3rv1alpha1.RevisionHistoryPolicy{MaxAge: &metav1.Duration{Duration: time.Hour}}},
+			wantTrimmed: []marin3rv1alpha1.EnvoyConfigRevision{
+				{ObjectMeta: metav1.ObjectMeta{Name: "ecr1", CreationTimestamp: old}},
+			},
+			wantList: &marin3rv1alpha1.EnvoyConfigRevisionList{
+				Items: []marin3rv1alpha1.EnvoyConfigRevision{
+					{ObjectMeta: metav1.ObjectMeta{Name: "ecr2", CreationTimestamp: recent}},
+					{ObjectMeta: metav1.ObjectMeta{Name: "ecr3", CreationTimestamp: old}},
+				},
+			},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -976,7 +1130,7 @@ func TestRevisionReconciler_isRevisionRetentionReconciled(t *testing.T) {
 				cacheState:       tt.fields.cacheState,
 				revisionList:     tt.fields.revisionList,
 			}
-			if got := r.isRevisionRetentionReconciled(tt.args.retention); !reflect.DeepEqual(got, tt.wantTrimmed) {
+			if got := r.isRevisionRetentionReconciled(tt.args.policy); !reflect.DeepEqual(got, tt.wantTrimmed) {
 				t.Errorf("RevisionReconciler.isRevisionRetentionReconciled() = %v, want %v", got, tt.wantTrimmed)
 			}
 			if !reflect.DeepEqual(r.GetRevisionList(), tt.wantList) {
@@ -986,37 +1140,190 @@ func TestRevisionReconciler_isRevisionRetentionReconciled(t *testing.T) {
 	}
 }
 
-func Test_popRevision(t *testing.T) {
+func Test_isRevisionExpired(t *testing.T) {
+	now := time.Now()
 	type args struct {
-		list *[]marin3rv1alpha1.EnvoyConfigRevision
+		ecr    marin3rv1alpha1.EnvoyConfigRevision
+		maxAge time.Duration
 	}
 	tests := []struct {
-		name     string
-		list     []marin3rv1alpha1.EnvoyConfigRevision
-		wantItem marin3rv1alpha1.EnvoyConfigRevision
-		wantList []marin3rv1alpha1.EnvoyConfigRevision
+		name string
+		args args
+		want bool
 	}{
 		{
-			name: "Pops an element from the list",
-			list: []marin3rv1alpha1.EnvoyConfigRevision{
-				{ObjectMeta: metav1.ObjectMeta{Name: "ecr1"}},
-				{ObjectMeta: metav1.ObjectMeta{Name: "ecr2"}},
-				{ObjectMeta: metav1.ObjectMeta{Name: "ecr3"}},
+			name: "Never expires if maxAge is zero",
+			args: args{
+				ecr:    marin3rv1alpha1.EnvoyConfigRevision{ObjectMeta: metav1.ObjectMeta{CreationTimestamp: metav1.NewTime(now.Add(-time.Hour))}},
+				maxAge: 0,
 			},
-			wantItem: marin3rv1alpha1.EnvoyConfigRevision{ObjectMeta: metav1.ObjectMeta{Name: "ecr1"}},
-			wantList: []marin3rv1alpha1.EnvoyConfigRevision{
-				{ObjectMeta: metav1.ObjectMeta{Name: "ecr2"}},
-				{ObjectMeta: metav1.ObjectMeta{Name: "ecr3"}},
+			want: false,
+		},
+		{
+			name: "Expires if created before maxAge",
+			args: args{
+				ecr:    marin3rv1alpha1.EnvoyConfigRevision{ObjectMeta: metav1.ObjectMeta{CreationTimestamp: metav1.NewTime(now.Add(-time.Hour))}},
+				maxAge: time.Minute,
+			},
+			want: true,
+		},
+		{
+			name: "Age is measured from the last publication",
+			args: args{
+				ecr: marin3rv1alpha1.EnvoyConfigRevision{
+					ObjectMeta: metav1.ObjectMeta{CreationTimestamp: metav1.NewTime(now.Add(-time.Hour))},
+					Status: marin3rv1alpha1.EnvoyConfigRevisionStatus{
+						LastPublishedAt: func(t metav1.Time) *metav1.Time { return &t }(metav1.NewTime(now.Add(-time.Second))),
+					},
+				},
+				maxAge: time.Minute,
 			},
+			want: false,
 		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if got := popRevision(&tt.list); !reflect.DeepEqual(got, tt.wantItem) {
-				t.Errorf("popRevision() = %v, want %v", got, tt.wantItem)
+			if got := isRevisionExpired(tt.args.ecr, tt.args.maxAge, now); got != tt.want {
+				t.Errorf("isRevisionExpired() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRevisionReconciler_isRevisionTaintedConditionReconciled(t *testing.T) {
+	now := time.Now()
+	taintedAt := func(t time.Time) status.Conditions {
+		return status.Conditions{{
+			Type:               marin3rv1alpha1.RevisionTaintedCondition,
+			Status:             corev1.ConditionTrue,
+			Reason:             rollback.GatewayReturnedNACKReason,
+			LastTransitionTime: metav1.NewTime(t),
+		}}
+	}
+	tests := []struct {
+		name               string
+		revisionList       *marin3rv1alpha1.EnvoyConfigRevisionList
+		untaintAfter       time.Duration
+		wantUntainted      []string
+		wantNextExpiration time.Duration
+	}{
+		{
+			name: "Returns the revisions with the untaint annotation",
+			revisionList: &marin3rv1alpha1.EnvoyConfigRevisionList{
+				Items: []marin3rv1alpha1.EnvoyConfigRevision{
+					{ObjectMeta: metav1.ObjectMeta{Name: "ecr1"}, Status: marin3rv1alpha1.EnvoyConfigRevisionStatus{Conditions: taintedAt(now)}},
+					{
+						ObjectMeta: metav1.ObjectMeta{Name: "ecr2", Annotations: map[string]string{marin3rv1alpha1.RevisionUntaintAnnotation: ""}},
+						Status:     marin3rv1alpha1.EnvoyConfigRevisionStatus{Conditions: taintedAt(now)},
+					},
+				},
+			},
+			untaintAfter:       0,
+			wantUntainted:      []string{"ecr2"},
+			wantNextExpiration: 0,
+		},
+		{
+			name: "Returns the revisions whose taint has expired",
+			revisionList: &marin3rv1alpha1.EnvoyConfigRevisionList{
+				Items: []marin3rv1alpha1.EnvoyConfigRevision{
+					{ObjectMeta: metav1.ObjectMeta{Name: "ecr1"}, Status: marin3rv1alpha1.EnvoyConfigRevisionStatus{Conditions: taintedAt(now.Add(-2 * time.Hour))}},
+					{ObjectMeta: metav1.ObjectMeta{Name: "ecr2"}, Status: marin3rv1alpha1.EnvoyConfigRevisionStatus{Conditions: taintedAt(now.Add(-30 * time.Minute))}},
+					{ObjectMeta: metav1.ObjectMeta{Name: "ecr3"}},
+				},
+			},
+			untaintAfter:       time.Hour,
+			wantUntainted:      []string{"ecr1"},
+			wantNextExpiration: 30 * time.Minute,
+		},
+		{
+			name: "Taints not caused by a NACK do not expire",
+			revisionList: &marin3rv1alpha1.EnvoyConfigRevisionList{
+				Items: []marin3rv1alpha1.EnvoyConfigRevision{
+					{ObjectMeta: metav1.ObjectMeta{Name: "ecr1"}, Status: marin3rv1alpha1.EnvoyConfigRevisionStatus{Conditions: status.Conditions{{
+						Type:               marin3rv1alpha1.RevisionTaintedCondition,
+						Status:             corev1.ConditionTrue,
+						Reason:             "FailedLoadingResources",
+						LastTransitionTime: metav1.NewTime(now.Add(-2 * time.Hour)),
+					}}}},
+					{ObjectMeta: metav1.ObjectMeta{Name: "ecr2"}, Status: marin3rv1alpha1.EnvoyConfigRevisionStatus{Conditions: taintedAt(now.Add(-2 * time.Hour))}},
+				},
+			},
+			untaintAfter:       time.Hour,
+			wantUntainted:      []string{"ecr2"},
+			wantNextExpiration: 0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := testRevisionReconcilerBuilder(s, &marin3rv1alpha1.EnvoyConfig{})
+			r.revisionList = tt.revisionList
+			got, gotNextExpiration := r.isRevisionTaintedConditionReconciled(tt.untaintAfter, now)
+			gotNames := []string{}
+			for _, ecr := range got {
+				gotNames = append(gotNames, ecr.GetName())
+			}
+			if !reflect.DeepEqual(gotNames, tt.wantUntainted) {
+				t.Errorf("RevisionReconciler.isRevisionTaintedConditionReconciled() got = %v, want %v", gotNames, tt.wantUntainted)
+			}
+			// LastTransitionTime is serialized with second precision
+			if diff := gotNextExpiration - tt.wantNextExpiration; diff > time.Second || diff < -time.Second {
+				t.Errorf("RevisionReconciler.isRevisionTaintedConditionReconciled() got1 = %v, want %v", gotNextExpiration, tt.wantNextExpiration)
+			}
+		})
+	}
+}
+
+func TestRevisionReconciler_untaintRevision(t *testing.T) {
+	tests := []struct {
+		name       string
+		ecr        *marin3rv1alpha1.EnvoyConfigRevision
+		wantReason status.ConditionReason
+	}{
+		{
+			name: "Clears the taint and removes the annotation",
+			ecr: &marin3rv1alpha1.EnvoyConfigRevision{
+				ObjectMeta: metav1.ObjectMeta{Name: "ecr", Namespace: "test",
+					Annotations: map[string]string{marin3rv1alpha1.RevisionUntaintAnnotation: "true", "other": "value"}},
+				Status: marin3rv1alpha1.EnvoyConfigRevisionStatus{Conditions: status.Conditions{
+					{Type: marin3rv1alpha1.RevisionTaintedCondition, Status: corev1.ConditionTrue},
+				}},
+			},
+			wantReason: "UntaintRequested",
+		},
+		{
+			name: "Clears an expired taint",
+			ecr: &marin3rv1alpha1.EnvoyConfigRevision{
+				ObjectMeta: metav1.ObjectMeta{Name: "ecr", Namespace: "test"},
+				Status: marin3rv1alpha1.EnvoyConfigRevisionStatus{Conditions: status.Conditions{
+					{Type: marin3rv1alpha1.RevisionTaintedCondition, Status: corev1.ConditionTrue},
+				}},
+			},
+			wantReason: "TaintExpired",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := testRevisionReconcilerBuilder(s, &marin3rv1alpha1.EnvoyConfig{}, tt.ecr)
+			if err := r.untaintRevision(tt.ecr.DeepCopy()); err != nil {
+				t.Errorf("RevisionReconciler.untaintRevision() error = %v", err)
+				return
+			}
+			ecr := &marin3rv1alpha1.EnvoyConfigRevision{}
+			if err := r.client.Get(context.TODO(), types.NamespacedName{Name: "ecr", Namespace: "test"}, ecr); err != nil {
+				t.Errorf("RevisionReconciler.untaintRevision() error getting revision = %v", err)
+				return
+			}
+			if _, ok := ecr.GetAnnotations()[marin3rv1alpha1.RevisionUntaintAnnotation]; ok {
+				t.Errorf("RevisionReconciler.untaintRevision() annotation has not been removed")
+			}
+			for k, v := range tt.ecr.GetAnnotations() {
+				if got := ecr.GetAnnotations()[k]; k != marin3rv1alpha1.RevisionUntaintAnnotation && got != v {
+					t.Errorf("RevisionReconciler.untaintRevision() annotation %q = %q, want %q", k, got, v)
+				}
 			}
-			if !reflect.DeepEqual(tt.list, tt.wantList) {
-				t.Errorf("popRevision() = %v, want %v", tt.list, tt.wantList)
+			cond := ecr.Status.Conditions.GetCondition(marin3rv1alpha1.RevisionTaintedCondition)
+			if cond == nil || cond.IsTrue() || cond.Reason != tt.wantReason {
+				t.Errorf("RevisionReconciler.untaintRevision() condition = %v, want reason %v", cond, tt.wantReason)
 			}
 		})
 	}
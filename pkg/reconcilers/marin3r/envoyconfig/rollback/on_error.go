@@ -15,35 +15,151 @@ import (
 
 const (
 	previousVersionPrefix string = "ReceivedPreviousVersion_"
+
+	// GatewayReturnedNACKReason is the reason used to taint revisions
+	// whose resources have been rejected by a gateway
+	GatewayReturnedNACKReason status.ConditionReason = "GatewayReturnedNACK"
 )
 
 // OnError returns a function that should be called when the envoy xDS server receives
-// a NACK to a discovery response from any of the gateways
-func OnError(cl client.Client) func(nodeID, version, msg string, envoyAPI envoy.APIVersion) error {
+// a NACK to a discovery response from any of the gateways. Only revisions in the namespace
+// of the gateway are considered, as the same nodeID can be used in other namespaces.
+func OnError(cl client.Client) func(namespace, nodeID string, rType envoy.Type, version, msg string, envoyAPI envoy.APIVersion) error {
 
-	return func(nodeID, version, msg string, envoyAPI envoy.APIVersion) error {
+	return func(namespace, nodeID string, rType envoy.Type, version, msg string, envoyAPI envoy.APIVersion) error {
 
-		// Get the envoyconfig that corresponds to the envoy node that returned the error
-		ecr, err := revisions.Get(context.Background(), cl, "",
-			filters.ByNodeID(nodeID), filters.ByVersion(version), filters.ByEnvoyAPI(envoyAPI))
+		// Get the envoyconfigrevision that corresponds to the envoy node that returned the error
+		ecr, err := getFailingRevision(cl, namespace, nodeID, rType, version, envoyAPI)
 		if err != nil {
 			return err
 		}
 
-		if !ecr.Status.Conditions.IsTrueFor(marin3rv1alpha1.RevisionTaintedCondition) {
-			patch := client.MergeFrom(ecr.DeepCopy())
-			ecr.Status.Conditions.SetCondition(status.Condition{
-				Type:    marin3rv1alpha1.RevisionTaintedCondition,
-				Status:  "True",
-				Reason:  status.ConditionReason("GatewayReturnedNACK"),
-				Message: fmt.Sprintf("A gateway returned NACK to the discovery response: '%s'", msg),
-			})
-
-			if err := cl.Status().Patch(context.Background(), ecr, patch); err != nil {
-				return err
-			}
+		return taint(cl, ecr, nackMessage(rType, msg))
+	}
+}
+
+// ReportError returns a function that should be called instead of the one returned by OnError
+// when the envoy xDS server of a discovery service replica that is not the leader receives a
+// NACK. Only the leader writes the status of the revisions, so the failing revision is annotated
+// with the error and the leader taints it when it reconciles the revision.
+func ReportError(cl client.Client) func(namespace, nodeID string, rType envoy.Type, version, msg string, envoyAPI envoy.APIVersion) error {
+
+	return func(namespace, nodeID string, rType envoy.Type, version, msg string, envoyAPI envoy.APIVersion) error {
+
+		ecr, err := getFailingRevision(cl, namespace, nodeID, rType, version, envoyAPI)
+		if err != nil {
+			return err
+		}
+
+		if ecr.Status.Conditions.IsTrueFor(marin3rv1alpha1.RevisionTaintedCondition) {
+			return nil
+		}
+		if _, ok := ecr.GetAnnotations()[marin3rv1alpha1.RevisionReportedNACKAnnotation]; ok {
+			return nil
 		}
 
+		patch := client.MergeFrom(ecr.DeepCopy())
+		annotations := ecr.GetAnnotations()
+		if annotations == nil {
+			annotations = map[string]string{}
+		}
+		annotations[marin3rv1alpha1.RevisionReportedNACKAnnotation] = nackMessage(rType, msg)
+		ecr.SetAnnotations(annotations)
+
+		return cl.Patch(context.Background(), ecr, patch)
+	}
+}
+
+// TaintReported taints a revision with the error reported by a discovery service replica
+// that is not the leader, if any, and removes the annotation that holds the error. It
+// returns true if the revision had a reported error.
+func TaintReported(cl client.Client, ecr *marin3rv1alpha1.EnvoyConfigRevision) (bool, error) {
+
+	msg, ok := ecr.GetAnnotations()[marin3rv1alpha1.RevisionReportedNACKAnnotation]
+	if !ok {
+		return false, nil
+	}
+
+	if err := taint(cl, ecr, msg); err != nil {
+		return true, err
+	}
+
+	patch := client.MergeFrom(ecr.DeepCopy())
+	annotations := ecr.GetAnnotations()
+	delete(annotations, marin3rv1alpha1.RevisionReportedNACKAnnotation)
+	ecr.SetAnnotations(annotations)
+
+	return true, cl.Patch(context.Background(), ecr, patch)
+}
+
+// taint sets the RevisionTainted condition of a revision rejected by a gateway
+func taint(cl client.Client, ecr *marin3rv1alpha1.EnvoyConfigRevision, msg string) error {
+
+	if ecr.Status.Conditions.IsTrueFor(marin3rv1alpha1.RevisionTaintedCondition) {
 		return nil
 	}
+
+	patch := client.MergeFrom(ecr.DeepCopy())
+	ecr.Status.Conditions.SetCondition(status.Condition{
+		Type:    marin3rv1alpha1.RevisionTaintedCondition,
+		Status:  "True",
+		Reason:  GatewayReturnedNACKReason,
+		Message: msg,
+	})
+
+	return cl.Status().Patch(context.Background(), ecr, patch)
+}
+
+func nackMessage(rType envoy.Type, msg string) string {
+	return fmt.Sprintf("A gateway returned NACK to the %s discovery response: '%s'", rType, msg)
+}
+
+// getFailingRevision returns the revision that generated the given version of the resource type. Resource
+// types are versioned with the hash of their contents, so several revisions might match the version if they
+// share the same resources of that type. In that case the published revision is returned, as the rejected
+// resources are the ones currently in the xDS cache. If none of them is published, the one that was published
+// most recently is returned or, if none of them has ever been published, the newest one.
+func getFailingRevision(cl client.Client, namespace, nodeID string, rType envoy.Type, version string,
+	envoyAPI envoy.APIVersion) (*marin3rv1alpha1.EnvoyConfigRevision, error) {
+
+	list, err := revisions.List(context.Background(), cl, namespace, filters.ByNodeID(nodeID), filters.ByEnvoyAPI(envoyAPI))
+	if err != nil {
+		return nil, err
+	}
+
+	var match *marin3rv1alpha1.EnvoyConfigRevision
+	for idx := range list.Items {
+		ecr := &list.Items[idx]
+		if ecr.Spec.EnvoyResources.MatchesVersion(rType, version) {
+			if ecr.Status.Conditions.IsTrueFor(marin3rv1alpha1.RevisionPublishedCondition) {
+				return ecr, nil
+			}
+			if match == nil || isMoreRecent(ecr, match) {
+				match = ecr
+			}
+		}
+	}
+
+	if match == nil {
+		return nil, revisions.NewError(revisions.NoMatchesForFilterError, "getFailingRevision",
+			fmt.Sprintf("no EnvoyConfigRevisions found for %s version %q", rType, version))
+	}
+
+	return match, nil
+}
+
+// isMoreRecent returns true if revision a has been published after revision b or, if
+// neither of them has a publication time to compare, if a has been created after b
+func isMoreRecent(a, b *marin3rv1alpha1.EnvoyConfigRevision) bool {
+	pa, pb := a.Status.LastPublishedAt, b.Status.LastPublishedAt
+	switch {
+	case pa != nil && pb != nil && !pa.Equal(pb):
+		return pb.Before(pa)
+	case pa != nil && pb == nil:
+		return true
+	case pa == nil && pb != nil:
+		return false
+	}
+	ca, cb := a.GetCreationTimestamp(), b.GetCreationTimestamp()
+	return cb.Before(&ca)
 }
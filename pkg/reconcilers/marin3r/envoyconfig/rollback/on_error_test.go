@@ -1,13 +1,18 @@
 package rollback
 
 import (
+	"context"
 	"testing"
+	"time"
 
 	marin3rv1alpha1 "github.com/3scale/marin3r/apis/marin3r/v1alpha1"
 	envoy "github.com/3scale/marin3r/pkg/envoy"
 	"github.com/3scale/marin3r/pkg/reconcilers/marin3r/envoyconfig/filters"
+	"github.com/operator-framework/operator-lib/status"
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/kubernetes/scheme"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
@@ -24,39 +29,125 @@ func init() {
 }
 
 func TestOnError(t *testing.T) {
+	resources := func(cluster string) *marin3rv1alpha1.EnvoyResources {
+		return &marin3rv1alpha1.EnvoyResources{
+			Clusters: []marin3rv1alpha1.EnvoyResource{{Name: cluster, Value: "{\"name\": \"" + cluster + "\"}"}},
+		}
+	}
+	revision := func(name, cluster string, published bool) *marin3rv1alpha1.EnvoyConfigRevision {
+		ecr := &marin3rv1alpha1.EnvoyConfigRevision{
+			TypeMeta: metav1.TypeMeta{Kind: "EnvoyConfigRevision", APIVersion: "v1alpha1"},
+			ObjectMeta: metav1.ObjectMeta{
+				Name: name, Namespace: "test",
+				Labels: map[string]string{
+					filters.NodeIDTag:   "node",
+					filters.EnvoyAPITag: envoy.APIv3.String(),
+					filters.VersionTag:  name,
+				},
+			},
+			Spec: marin3rv1alpha1.EnvoyConfigRevisionSpec{EnvoyResources: resources(cluster)},
+		}
+		if published {
+			ecr.Status.Conditions = status.Conditions{
+				{Type: marin3rv1alpha1.RevisionPublishedCondition, Status: corev1.ConditionTrue},
+			}
+		}
+		return ecr
+	}
+
 	type args struct {
-		nodeID   string
-		version  string
-		msg      string
-		envoyAPI envoy.APIVersion
+		namespace string
+		nodeID    string
+		rType     envoy.Type
+		version   string
+		msg       string
+		envoyAPI  envoy.APIVersion
 	}
 	tests := []struct {
-		name    string
-		cl      client.Client
-		args    args
-		wantErr bool
+		name        string
+		cl          client.Client
+		args        args
+		wantErr     bool
+		wantTainted string
 	}{
 		{
-			name: "Returns a function that does not return error when called",
+			name:        "Returns a function that taints the revision that matches the failing version",
+			cl:          fake.NewFakeClientWithScheme(s, revision("ecr1", "c1", false), revision("ecr2", "c2", false)),
+			args:        args{"test", "node", envoy.Cluster, resources("c2").VersionFor(envoy.Cluster), "test", envoy.APIv3},
+			wantErr:     false,
+			wantTainted: "ecr2",
+		},
+		{
+			name:        "Returns a function that taints the published revision if several revisions match the failing version",
+			cl:          fake.NewFakeClientWithScheme(s, revision("ecr1", "c1", false), revision("ecr2", "c1", true)),
+			args:        args{"test", "node", envoy.Cluster, resources("c1").VersionFor(envoy.Cluster), "test", envoy.APIv3},
+			wantErr:     false,
+			wantTainted: "ecr2",
+		},
+		{
+			name: "Returns a function that only considers revisions in the namespace of the gateway",
+			cl: fake.NewFakeClientWithScheme(s, revision("ecr1", "c1", false), func() *marin3rv1alpha1.EnvoyConfigRevision {
+				ecr := revision("ecr2", "c1", true)
+				ecr.SetNamespace("other")
+				return ecr
+			}()),
+			args:        args{"test", "node", envoy.Cluster, resources("c1").VersionFor(envoy.Cluster), "test", envoy.APIv3},
+			wantErr:     false,
+			wantTainted: "ecr1",
+		},
+		{
+			name: "Returns a function that taints the most recently published revision if several unpublished revisions match the failing version",
 			cl: fake.NewFakeClientWithScheme(s,
-				&marin3rv1alpha1.EnvoyConfigRevision{
-					TypeMeta: metav1.TypeMeta{Kind: "EnvoyConfigRevision", APIVersion: "v1alpha1"},
-					ObjectMeta: metav1.ObjectMeta{
-						Name: "ecr1", Namespace: "test",
-						Labels: map[string]string{
-							filters.NodeIDTag:   "node",
-							filters.EnvoyAPITag: envoy.APIv3.String(),
-							filters.VersionTag:  "xxxx",
-						},
-					},
-				}),
-			args:    args{"node", "xxxx", "test", envoy.APIv3},
-			wantErr: false,
+				func() *marin3rv1alpha1.EnvoyConfigRevision {
+					ecr := revision("ecr1", "c1", false)
+					ecr.SetCreationTimestamp(metav1.NewTime(time.Unix(300, 0)))
+					ecr.Status.LastPublishedAt = func() *metav1.Time { t := metav1.NewTime(time.Unix(400, 0)); return &t }()
+					return ecr
+				}(),
+				func() *marin3rv1alpha1.EnvoyConfigRevision {
+					ecr := revision("ecr2", "c1", false)
+					ecr.SetCreationTimestamp(metav1.NewTime(time.Unix(100, 0)))
+					ecr.Status.LastPublishedAt = func() *metav1.Time { t := metav1.NewTime(time.Unix(500, 0)); return &t }()
+					return ecr
+				}(),
+				func() *marin3rv1alpha1.EnvoyConfigRevision {
+					ecr := revision("ecr3", "c1", false)
+					ecr.SetCreationTimestamp(metav1.NewTime(time.Unix(600, 0)))
+					return ecr
+				}(),
+			),
+			args:        args{"test", "node", envoy.Cluster, resources("c1").VersionFor(envoy.Cluster), "test", envoy.APIv3},
+			wantErr:     false,
+			wantTainted: "ecr2",
 		},
 		{
-			name:    "Returns a function that does returns an error when called",
+			name: "Returns a function that taints the newest revision if several never published revisions match the failing version",
+			cl: fake.NewFakeClientWithScheme(s,
+				func() *marin3rv1alpha1.EnvoyConfigRevision {
+					ecr := revision("ecr1", "c1", false)
+					ecr.SetCreationTimestamp(metav1.NewTime(time.Unix(200, 0)))
+					return ecr
+				}(),
+				func() *marin3rv1alpha1.EnvoyConfigRevision {
+					ecr := revision("ecr2", "c1", false)
+					ecr.SetCreationTimestamp(metav1.NewTime(time.Unix(100, 0)))
+					return ecr
+				}(),
+			),
+			args:        args{"test", "node", envoy.Cluster, resources("c1").VersionFor(envoy.Cluster), "test", envoy.APIv3},
+			wantErr:     false,
+			wantTainted: "ecr1",
+		},
+		{
+			name:    "Returns a function that returns an error if no revision matches the failing version",
+			cl:      fake.NewFakeClientWithScheme(s, revision("ecr1", "c1", false)),
+			args:    args{"test", "node", envoy.Cluster, "xxxx", "test", envoy.APIv3},
+			wantErr: true,
+		},
+		{
+			name:    "Returns a function that returns an error when called",
 			cl:      fake.NewFakeClientWithScheme(s),
-			args:    args{"node", "xxxx", "test", envoy.APIv3},
+			args:    args{"test", "node", envoy.Cluster, "xxxx", "test", envoy.APIv3},
 			wantErr: true,
 		},
 	}
@@ -64,12 +155,76 @@ func TestOnError(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 
 			fn := OnError(tt.cl)
-			err := fn(tt.args.nodeID, tt.args.version, tt.args.msg, tt.args.envoyAPI)
+			err := fn(tt.args.namespace, tt.args.nodeID, tt.args.rType, tt.args.version, tt.args.msg, tt.args.envoyAPI)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("OnError() error = %v, wantErr %v", err, tt.wantErr)
 				return
 			}
 
+			if tt.wantTainted != "" {
+				ecr := &marin3rv1alpha1.EnvoyConfigRevision{}
+				if err := tt.cl.Get(context.TODO(), types.NamespacedName{Name: tt.wantTainted, Namespace: "test"}, ecr); err != nil {
+					t.Errorf("OnError() error getting revision = %v", err)
+					return
+				}
+				if !ecr.Status.Conditions.IsTrueFor(marin3rv1alpha1.RevisionTaintedCondition) {
+					t.Errorf("OnError() revision %q should be tainted", tt.wantTainted)
+				}
+			}
 		})
 	}
 }
+
+func TestReportError(t *testing.T) {
+	resources := &marin3rv1alpha1.EnvoyResources{
+		Clusters: []marin3rv1alpha1.EnvoyResource{{Name: "c1", Value: "{\"name\": \"c1\"}"}},
+	}
+	ecr := &marin3rv1alpha1.EnvoyConfigRevision{
+		TypeMeta: metav1.TypeMeta{Kind: "EnvoyConfigRevision", APIVersion: "v1alpha1"},
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "ecr1", Namespace: "test",
+			Labels: map[string]string{
+				filters.NodeIDTag:   "node",
+				filters.EnvoyAPITag: envoy.APIv3.String(),
+				filters.VersionTag:  "ecr1",
+			},
+		},
+		Spec: marin3rv1alpha1.EnvoyConfigRevisionSpec{EnvoyResources: resources},
+	}
+	cl := fake.NewFakeClientWithScheme(s, ecr)
+	key := types.NamespacedName{Name: "ecr1", Namespace: "test"}
+
+	fn := ReportError(cl)
+	if err := fn("test", "node", envoy.Cluster, resources.VersionFor(envoy.Cluster), "test", envoy.APIv3); err != nil {
+		t.Fatalf("ReportError() error = %v", err)
+	}
+
+	got := &marin3rv1alpha1.EnvoyConfigRevision{}
+	if err := cl.Get(context.TODO(), key, got); err != nil {
+		t.Fatalf("ReportError() error getting revision = %v", err)
+	}
+	if got.Status.Conditions.IsTrueFor(marin3rv1alpha1.RevisionTaintedCondition) {
+		t.Errorf("ReportError() revision should not be tainted")
+	}
+	if _, ok := got.GetAnnotations()[marin3rv1alpha1.RevisionReportedNACKAnnotation]; !ok {
+		t.Fatalf("ReportError() revision should be annotated with the error")
+	}
+
+	reported, err := TaintReported(cl, got)
+	if err != nil || !reported {
+		t.Fatalf("TaintReported() = %v, %v, want true, nil", reported, err)
+	}
+	if err := cl.Get(context.TODO(), key, got); err != nil {
+		t.Fatalf("TaintReported() error getting revision = %v", err)
+	}
+	if !got.Status.Conditions.IsTrueFor(marin3rv1alpha1.RevisionTaintedCondition) {
+		t.Errorf("TaintReported() revision should be tainted")
+	}
+	if _, ok := got.GetAnnotations()[marin3rv1alpha1.RevisionReportedNACKAnnotation]; ok {
+		t.Errorf("TaintReported() the annotation should be removed")
+	}
+
+	if reported, err := TaintReported(cl, got); err != nil || reported {
+		t.Errorf("TaintReported() = %v, %v, want false, nil", reported, err)
+	}
+}
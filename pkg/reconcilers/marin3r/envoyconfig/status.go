@@ -1,6 +1,7 @@
 package reconcilers
 
 import (
+	"fmt"
 	"reflect"
 
 	marin3rv1alpha1 "github.com/3scale/marin3r/apis/marin3r/v1alpha1"
@@ -36,6 +37,12 @@ func IsStatusReconciled(ec *marin3rv1alpha1.EnvoyConfig, cacheState, publishedVe
 		ok = false
 	}
 
+	proxies := generateProxiesStatus(list, publishedVersion)
+	if !reflect.DeepEqual(ec.Status.Proxies, proxies) {
+		ec.Status.Proxies = proxies
+		ok = false
+	}
+
 	// Reconcile the CacheOutOfSyncCondition
 	if desiredVersion != publishedVersion && !ec.Status.Conditions.IsTrueFor(marin3rv1alpha1.CacheOutOfSyncCondition) {
 		ec.Status.Conditions.SetCondition(status.Condition{
@@ -75,9 +82,82 @@ func IsStatusReconciled(ec *marin3rv1alpha1.EnvoyConfig, cacheState, publishedVe
 		ok = false
 	}
 
+	// Reconcile the RevisionPinnedCondition
+	if cond := calculateRevisionPinnedCondition(ec, cacheState, publishedVersion, desiredVersion); cond != nil {
+		current := ec.Status.Conditions.GetCondition(marin3rv1alpha1.RevisionPinnedCondition)
+		if current == nil || current.Status != cond.Status || current.Reason != cond.Reason || current.Message != cond.Message {
+			ec.Status.Conditions.SetCondition(*cond)
+			ok = false
+		}
+	} else if ec.Status.Conditions.GetCondition(marin3rv1alpha1.RevisionPinnedCondition) != nil {
+		ec.Status.Conditions.RemoveCondition(marin3rv1alpha1.RevisionPinnedCondition)
+		ok = false
+	}
+
+	// Reconcile the DanglingReferencesCondition from the revision that holds the desired resources
+	if cond := getDanglingReferencesCondition(list, desiredVersion); cond != nil {
+		current := ec.Status.Conditions.GetCondition(marin3rv1alpha1.DanglingReferencesCondition)
+		if current == nil || current.Status != cond.Status || current.Reason != cond.Reason || current.Message != cond.Message {
+			ec.Status.Conditions.SetCondition(status.Condition{
+				Type:    marin3rv1alpha1.DanglingReferencesCondition,
+				Status:  cond.Status,
+				Reason:  cond.Reason,
+				Message: cond.Message,
+			})
+			ok = false
+		}
+	} else if ec.Status.Conditions.GetCondition(marin3rv1alpha1.DanglingReferencesCondition) != nil {
+		ec.Status.Conditions.RemoveCondition(marin3rv1alpha1.DanglingReferencesCondition)
+		ok = false
+	}
+
 	return ok
 }
 
+// calculateRevisionPinnedCondition returns the RevisionPinnedCondition for the EnvoyConfig,
+// or nil if no version is pinned
+func calculateRevisionPinnedCondition(ec *marin3rv1alpha1.EnvoyConfig, cacheState, publishedVersion, desiredVersion string) *status.Condition {
+
+	pinned := ec.GetPinnedVersion()
+	if pinned == "" {
+		return nil
+	}
+
+	if cacheState != marin3rv1alpha1.PinnedState {
+		return &status.Condition{
+			Type:    marin3rv1alpha1.RevisionPinnedCondition,
+			Status:  corev1.ConditionFalse,
+			Reason:  "PinnedVersionUnavailable",
+			Message: fmt.Sprintf("Pinned version %q cannot be published, the revision does not exist or is tainted", pinned),
+		}
+	}
+
+	msg := fmt.Sprintf("Pinned version %q is published", publishedVersion)
+	if publishedVersion != desiredVersion {
+		msg = fmt.Sprintf("Pinned version %q is published instead of desired version %q", publishedVersion, desiredVersion)
+	}
+	return &status.Condition{
+		Type:    marin3rv1alpha1.RevisionPinnedCondition,
+		Status:  corev1.ConditionTrue,
+		Reason:  "VersionPinned",
+		Message: msg,
+	}
+}
+
+// getDanglingReferencesCondition returns the DanglingReferencesCondition of the revision
+// for the given version. The condition is calculated by the discovery service, so it might
+// not be available yet.
+func getDanglingReferencesCondition(list *marin3rv1alpha1.EnvoyConfigRevisionList, version string) *status.Condition {
+
+	for _, ecr := range list.Items {
+		if ecr.Spec.Version == version {
+			return ecr.Status.Conditions.GetCondition(marin3rv1alpha1.DanglingReferencesCondition)
+		}
+	}
+
+	return nil
+}
+
 func generateRevisionList(list *marin3rv1alpha1.EnvoyConfigRevisionList) []marin3rv1alpha1.ConfigRevisionRef {
 
 	revisionList := make([]marin3rv1alpha1.ConfigRevisionRef, len(list.Items))
@@ -96,3 +176,20 @@ func generateRevisionList(list *marin3rv1alpha1.EnvoyConfigRevisionList) []marin
 
 	return revisionList
 }
+
+// generateProxiesStatus returns the summary of the proxies status from the published
+// revision. Per proxy details are only kept in the EnvoyConfigRevision status.
+func generateProxiesStatus(list *marin3rv1alpha1.EnvoyConfigRevisionList, publishedVersion string) *marin3rv1alpha1.ProxiesStatus {
+
+	for _, ecr := range list.Items {
+		if ecr.Spec.Version == publishedVersion && ecr.Status.IsPublished() && ecr.Status.Proxies != nil {
+			return &marin3rv1alpha1.ProxiesStatus{
+				Connected: ecr.Status.Proxies.Connected,
+				Synced:    ecr.Status.Proxies.Synced,
+				Failing:   ecr.Status.Proxies.Failing,
+			}
+		}
+	}
+
+	return nil
+}
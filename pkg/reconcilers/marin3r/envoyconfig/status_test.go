@@ -8,6 +8,7 @@ import (
 	"github.com/operator-framework/operator-lib/status"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/pointer"
 )
 
 func TestIsStatusReconciled(t *testing.T) {
@@ -183,6 +184,40 @@ func TestIsStatusReconciled(t *testing.T) {
 			},
 			want: false,
 		},
+		{
+			name: "Proxies needs update, return false",
+			args: args{
+				ec: &marin3rv1alpha1.EnvoyConfig{
+					Status: marin3rv1alpha1.EnvoyConfigStatus{
+						DesiredVersion:   "6ddbcdf795",
+						PublishedVersion: "6ddbcdf795",
+						CacheState:       marin3rv1alpha1.InSyncState,
+						ConfigRevisions: []marin3rv1alpha1.ConfigRevisionRef{
+							{Version: "6ddbcdf795", Ref: corev1.ObjectReference{Name: "ecr1", Namespace: "test"}},
+						},
+						Conditions: status.Conditions{
+							{Type: marin3rv1alpha1.CacheOutOfSyncCondition, Status: corev1.ConditionFalse},
+							{Type: marin3rv1alpha1.RollbackFailedCondition, Status: corev1.ConditionFalse},
+						},
+					},
+				},
+				cacheState:       marin3rv1alpha1.InSyncState,
+				publishedVersion: "6ddbcdf795",
+				list: &marin3rv1alpha1.EnvoyConfigRevisionList{
+					Items: []marin3rv1alpha1.EnvoyConfigRevision{
+						{
+							ObjectMeta: metav1.ObjectMeta{Name: "ecr1", Namespace: "test"},
+							Spec:       marin3rv1alpha1.EnvoyConfigRevisionSpec{Version: "6ddbcdf795"},
+							Status: marin3rv1alpha1.EnvoyConfigRevisionStatus{
+								Published: pointer.BoolPtr(true),
+								Proxies:   &marin3rv1alpha1.ProxiesStatus{Connected: 2, Synced: 1, Failing: 1},
+							},
+						},
+					},
+				},
+			},
+			want: false,
+		},
 		{
 			name: "Status empty, return false",
 			args: args{
@@ -264,3 +299,190 @@ func Test_generateRevisionList(t *testing.T) {
 		})
 	}
 }
+
+func Test_generateProxiesStatus(t *testing.T) {
+	type args struct {
+		list             *marin3rv1alpha1.EnvoyConfigRevisionList
+		publishedVersion string
+	}
+	tests := []struct {
+		name string
+		args args
+		want *marin3rv1alpha1.ProxiesStatus
+	}{
+		{
+			name: "Returns the counters of the published revision",
+			args: args{
+				list: &marin3rv1alpha1.EnvoyConfigRevisionList{
+					Items: []marin3rv1alpha1.EnvoyConfigRevision{
+						{
+							Spec: marin3rv1alpha1.EnvoyConfigRevisionSpec{Version: "1"},
+							Status: marin3rv1alpha1.EnvoyConfigRevisionStatus{
+								Published: pointer.BoolPtr(false),
+							},
+						},
+						{
+							Spec: marin3rv1alpha1.EnvoyConfigRevisionSpec{Version: "2"},
+							Status: marin3rv1alpha1.EnvoyConfigRevisionStatus{
+								Published: pointer.BoolPtr(true),
+								Proxies: &marin3rv1alpha1.ProxiesStatus{
+									Connected: 1, Synced: 1,
+									Details: []marin3rv1alpha1.ProxyStatus{{Address: "10.0.0.1:5000", Streams: 1, Synced: true}},
+								},
+							},
+						},
+					},
+				},
+				publishedVersion: "2",
+			},
+			want: &marin3rv1alpha1.ProxiesStatus{Connected: 1, Synced: 1},
+		},
+		{
+			name: "Returns nil if there is no published revision",
+			args: args{
+				list: &marin3rv1alpha1.EnvoyConfigRevisionList{
+					Items: []marin3rv1alpha1.EnvoyConfigRevision{
+						{Spec: marin3rv1alpha1.EnvoyConfigRevisionSpec{Version: "1"}},
+					},
+				},
+				publishedVersion: "1",
+			},
+			want: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := generateProxiesStatus(tt.args.list, tt.args.publishedVersion); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("generateProxiesStatus() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_getDanglingReferencesCondition(t *testing.T) {
+	cond := status.Condition{
+		Type:    marin3rv1alpha1.DanglingReferencesCondition,
+		Status:  corev1.ConditionTrue,
+		Reason:  "DanglingReferencesFound",
+		Message: "dangling references found",
+	}
+	type args struct {
+		list    *marin3rv1alpha1.EnvoyConfigRevisionList
+		version string
+	}
+	tests := []struct {
+		name string
+		args args
+		want *status.Condition
+	}{
+		{
+			name: "Returns the condition of the revision for the given version",
+			args: args{
+				list: &marin3rv1alpha1.EnvoyConfigRevisionList{
+					Items: []marin3rv1alpha1.EnvoyConfigRevision{
+						{Spec: marin3rv1alpha1.EnvoyConfigRevisionSpec{Version: "1"}},
+						{
+							Spec: marin3rv1alpha1.EnvoyConfigRevisionSpec{Version: "2"},
+							Status: marin3rv1alpha1.EnvoyConfigRevisionStatus{
+								Conditions: status.Conditions{cond},
+							},
+						},
+					},
+				},
+				version: "2",
+			},
+			want: &cond,
+		},
+		{
+			name: "Returns nil if the revision does not have the condition",
+			args: args{
+				list: &marin3rv1alpha1.EnvoyConfigRevisionList{
+					Items: []marin3rv1alpha1.EnvoyConfigRevision{
+						{Spec: marin3rv1alpha1.EnvoyConfigRevisionSpec{Version: "1"}},
+						{
+							Spec: marin3rv1alpha1.EnvoyConfigRevisionSpec{Version: "2"},
+							Status: marin3rv1alpha1.EnvoyConfigRevisionStatus{
+								Conditions: status.Conditions{cond},
+							},
+						},
+					},
+				},
+				version: "1",
+			},
+			want: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := getDanglingReferencesCondition(tt.args.list, tt.args.version); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("getDanglingReferencesCondition() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_calculateRevisionPinnedCondition(t *testing.T) {
+	type args struct {
+		ec               *marin3rv1alpha1.EnvoyConfig
+		cacheState       string
+		publishedVersion string
+		desiredVersion   string
+	}
+	tests := []struct {
+		name string
+		args args
+		want *status.Condition
+	}{
+		{
+			name: "Returns nil if no version is pinned",
+			args: args{
+				ec:               &marin3rv1alpha1.EnvoyConfig{},
+				cacheState:       marin3rv1alpha1.InSyncState,
+				publishedVersion: "xxxx",
+				desiredVersion:   "xxxx",
+			},
+			want: nil,
+		},
+		{
+			name: "Returns a True condition if the pinned version is published",
+			args: args{
+				ec: &marin3rv1alpha1.EnvoyConfig{
+					Spec: marin3rv1alpha1.EnvoyConfigSpec{PinnedVersion: pointer.StringPtr("aaaa")},
+				},
+				cacheState:       marin3rv1alpha1.PinnedState,
+				publishedVersion: "aaaa",
+				desiredVersion:   "xxxx",
+			},
+			want: &status.Condition{
+				Type:    marin3rv1alpha1.RevisionPinnedCondition,
+				Status:  corev1.ConditionTrue,
+				Reason:  "VersionPinned",
+				Message: "Pinned version \"aaaa\" is published instead of desired version \"xxxx\"",
+			},
+		},
+		{
+			name: "Returns a False condition if the pinned version cannot be published",
+			args: args{
+				ec: &marin3rv1alpha1.EnvoyConfig{
+					Spec: marin3rv1alpha1.EnvoyConfigSpec{PinnedVersion: pointer.StringPtr("aaaa")},
+				},
+				cacheState:       marin3rv1alpha1.InSyncState,
+				publishedVersion: "xxxx",
+				desiredVersion:   "xxxx",
+			},
+			want: &status.Condition{
+				Type:    marin3rv1alpha1.RevisionPinnedCondition,
+				Status:  corev1.ConditionFalse,
+				Reason:  "PinnedVersionUnavailable",
+				Message: "Pinned version \"aaaa\" cannot be published, the revision does not exist or is tainted",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := calculateRevisionPinnedCondition(tt.args.ec, tt.args.cacheState, tt.args.publishedVersion, tt.args.desiredVersion); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("calculateRevisionPinnedCondition() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -11,6 +11,7 @@ import (
 	envoy_resources "github.com/3scale/marin3r/pkg/envoy/resources"
 	envoy_serializer "github.com/3scale/marin3r/pkg/envoy/serializer"
 	"github.com/go-logr/logr"
+	"github.com/operator-framework/operator-lib/status"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime/schema"
@@ -23,8 +24,15 @@ import (
 const (
 	secretCertificate = "tls.crt"
 	secretPrivateKey  = "tls.key"
+
+	// DanglingReferencesReason is the reason used to taint revisions whose publication
+	// has been blocked because they hold references that cannot be resolved
+	DanglingReferencesReason status.ConditionReason = "DanglingReferences"
 )
 
+// resourceTypes is the list of resource types that a snapshot holds
+var resourceTypes = []envoy.Type{envoy.Endpoint, envoy.Cluster, envoy.Route, envoy.Listener, envoy.Secret, envoy.Runtime}
+
 type CacheReconciler struct {
 	ctx       context.Context
 	logger    logr.Logger
@@ -40,24 +48,29 @@ func NewCacheReconciler(ctx context.Context, logger logr.Logger, client client.C
 	return CacheReconciler{ctx, logger, client, xdsCache, decoder, generator}
 }
 
-func (r *CacheReconciler) Reconcile(req types.NamespacedName, resources *marin3rv1alpha1.EnvoyResources, nodeID, version string) (ctrl.Result, error) {
-
-	snap, err := r.GenerateSnapshot(req, resources, version)
+// Reconcile writes the given resources to the xDS cache. A xdss.DanglingReferencesError is returned
+// if blockOnDanglingReferences is true and the resources reference other resources that cannot be found.
+func (r *CacheReconciler) Reconcile(req types.NamespacedName, resources *marin3rv1alpha1.EnvoyResources,
+	nodeID, version string, blockOnDanglingReferences bool) (ctrl.Result, error) {
 
+	snap, err := r.generateConsistentSnapshot(req, resources, nodeID, version, blockOnDanglingReferences)
 	if err != nil {
 		return ctrl.Result{}, err
 	}
 
-	oldSnap, err := r.xdsCache.GetSnapshot(nodeID)
-	// Publish the generated snapshot when the version is different from the published one. We look specifically
-	// for the version of the "Secret" resources because secrets can change even when the spec hasn't changed.
+	// Snapshots are keyed by namespace and nodeID so proxies
+	// in other namespaces using the same nodeID are not affected
+	key := xdss.NodeKey(req.Namespace, nodeID)
+	oldSnap, err := r.xdsCache.GetSnapshot(key)
+	// Publish the generated snapshot when the version of any of the resource types is different from the
+	// published one. Secrets are included in the check because they can change even when the spec hasn't changed.
 	// Publish the snapshot when an error retrieving the published one occurs as it means that no snpshot has already
 	// been written to the cache for that specific nodeID.
-	if snap.GetVersion(envoy.Secret) != oldSnap.GetVersion(envoy.Secret) || err != nil {
+	if changed := changedTypes(oldSnap, snap); len(changed) > 0 || err != nil {
 
-		r.logger.Info("Writing new snapshot to xDS cache", "Version", version, "NodeID", nodeID, "Secrets Hash", snap.GetVersion(envoy.Secret))
+		r.logger.Info("Writing new snapshot to xDS cache", "Version", version, "NodeID", nodeID, "ChangedTypes", changed)
 
-		if err := r.xdsCache.SetSnapshot(nodeID, snap); err != nil {
+		if err := r.xdsCache.SetSnapshot(key, snap); err != nil {
 			return ctrl.Result{}, err
 		}
 
@@ -68,8 +81,33 @@ func (r *CacheReconciler) Reconcile(req types.NamespacedName, resources *marin3r
 	return ctrl.Result{}, nil
 }
 
-func (r *CacheReconciler) GenerateSnapshot(req types.NamespacedName, resources *marin3rv1alpha1.EnvoyResources, version string) (xdss.Snapshot, error) {
-	snap := r.xdsCache.NewSnapshot(version)
+// generateConsistentSnapshot returns a snapshot with the given resources. A xdss.DanglingReferencesError
+// is returned if blockOnDanglingReferences is true and the snapshot is not consistent.
+func (r *CacheReconciler) generateConsistentSnapshot(req types.NamespacedName, resources *marin3rv1alpha1.EnvoyResources,
+	nodeID, version string, blockOnDanglingReferences bool) (xdss.Snapshot, error) {
+
+	snap, err := r.GenerateSnapshot(req, resources)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := snap.Consistent(); err != nil {
+		if blockOnDanglingReferences {
+			return nil, err
+		}
+		r.logger.Info("Snapshot holds dangling references", "Version", version, "NodeID", nodeID, "Error", err.Error())
+	}
+
+	return snap, nil
+}
+
+// GenerateSnapshot returns a snapshot with the given resources. Each resource type in the snapshot
+// is versioned with the hash of the resources of that type.
+func (r *CacheReconciler) GenerateSnapshot(req types.NamespacedName, resources *marin3rv1alpha1.EnvoyResources) (xdss.Snapshot, error) {
+	snap := r.xdsCache.NewSnapshot("")
+	for _, rType := range resourceTypes {
+		snap.SetVersion(rType, resources.VersionFor(rType))
+	}
 
 	for idx, endpoint := range resources.Endpoints {
 		res := r.generator.New(envoy.Endpoint)
@@ -132,11 +170,24 @@ func (r *CacheReconciler) GenerateSnapshot(req types.NamespacedName, resources *
 	}
 
 	for idx, secret := range resources.Secrets {
-		s := &corev1.Secret{}
+		// Secrets are isolated per namespace, so EnvoyConfigs can
+		// only refer to the Secrets of their own namespace
 		key := types.NamespacedName{
 			Name:      secret.Ref.Name,
 			Namespace: secret.Ref.Namespace,
 		}
+		if key.Namespace == "" {
+			key.Namespace = req.Namespace
+		}
+		if key.Namespace != req.Namespace {
+			return nil,
+				resourceLoaderError(
+					req, secret.Ref, field.NewPath("spec", "resources").Child("secrets").Index(idx).Child("ref"),
+					"Secrets must be in the namespace of the EnvoyConfig",
+				)
+		}
+
+		s := &corev1.Secret{}
 		if err := r.client.Get(r.ctx, key, s); err != nil {
 			return nil, fmt.Errorf("%s", err.Error())
 		}
@@ -158,14 +209,25 @@ func (r *CacheReconciler) GenerateSnapshot(req types.NamespacedName, resources *
 	// Secrets are runtime calculated resourcesso its contents are not included in the spec. This means
 	// that changes in the content of secret resources wont be reflected in the hash of spec.envoyResources.
 	// To reflect changes to the content of secrets we append the hash of the runtime calculated secrets to
-	// the hash of sepc.envoyResources in the version of secret resources in the snapshot.
+	// the hash of the secrets in spec.envoyResources in the version of secret resources in the snapshot.
 	secretsHash := common.Hash(snap.GetResources(envoy.Secret))
-	snap.SetVersion(envoy.Secret, fmt.Sprintf("%s-%s", version, secretsHash))
+	snap.SetVersion(envoy.Secret, fmt.Sprintf("%s-%s", resources.VersionFor(envoy.Secret), secretsHash))
 
 	return snap, nil
 
 }
 
+// changedTypes returns the resource types whose version differs between both snapshots
+func changedTypes(old, new xdss.Snapshot) []envoy.Type {
+	changed := []envoy.Type{}
+	for _, rType := range resourceTypes {
+		if old.GetVersion(rType) != new.GetVersion(rType) {
+			changed = append(changed, rType)
+		}
+	}
+	return changed
+}
+
 func resourceLoaderError(req types.NamespacedName, value interface{}, resPath *field.Path, msg string) error {
 	return errors.NewInvalid(
 		schema.GroupKind{Group: "envoy", Kind: "EnvoyConfig"},
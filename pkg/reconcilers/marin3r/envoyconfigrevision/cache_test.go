@@ -45,16 +45,16 @@ func fakeCacheV2() xdss.Cache {
 	cache := xdss_v2.NewCache(cache_v2.NewSnapshotCache(true, cache_v2.IDHash{}, nil))
 	cache.SetSnapshot("node1", xdss_v2.NewSnapshot(&cache_v2.Snapshot{
 		Resources: [6]cache_v2.Resources{
-			{Version: "xxxx", Items: map[string]cache_types.Resource{
+			{Version: "fbb568774", Items: map[string]cache_types.Resource{
 				"endpoint1": &envoy_api_v2.ClusterLoadAssignment{ClusterName: "endpoint1"},
 			}},
-			{Version: "xxxx", Items: map[string]cache_types.Resource{
+			{Version: "fbb568774", Items: map[string]cache_types.Resource{
 				"cluster1": &envoy_api_v2.Cluster{Name: "cluster1"},
 			}},
-			{Version: "xxxx", Items: map[string]cache_types.Resource{}},
-			{Version: "xxxx", Items: map[string]cache_types.Resource{}},
-			{Version: "xxxx-557db659d4", Items: map[string]cache_types.Resource{}},
-			{Version: "xxxx", Items: map[string]cache_types.Resource{}},
+			{Version: "fbb568774", Items: map[string]cache_types.Resource{}},
+			{Version: "796b664c", Items: map[string]cache_types.Resource{}},
+			{Version: "bc6687b4b-557db659d4", Items: map[string]cache_types.Resource{}},
+			{Version: "fbb568774", Items: map[string]cache_types.Resource{}},
 		}}),
 	)
 	return cache
@@ -62,18 +62,18 @@ func fakeCacheV2() xdss.Cache {
 
 func fakeCacheV3() xdss.Cache {
 	cache := xdss_v3.NewCache(cache_v3.NewSnapshotCache(true, cache_v3.IDHash{}, nil))
-	cache.SetSnapshot("node1", xdss_v3.NewSnapshot(&cache_v3.Snapshot{
+	cache.SetSnapshot("xx/node1", xdss_v3.NewSnapshot(&cache_v3.Snapshot{
 		Resources: [6]cache_v3.Resources{
-			{Version: "xxxx", Items: map[string]cache_types.Resource{
+			{Version: "fbb568774", Items: map[string]cache_types.Resource{
 				"endpoint1": &envoy_config_endpoint_v3.ClusterLoadAssignment{ClusterName: "endpoint1"},
 			}},
-			{Version: "xxxx", Items: map[string]cache_types.Resource{
+			{Version: "fbb568774", Items: map[string]cache_types.Resource{
 				"cluster1": &envoy_config_cluster_v3.Cluster{Name: "cluster1"},
 			}},
-			{Version: "xxxx", Items: map[string]cache_types.Resource{}},
-			{Version: "xxxx", Items: map[string]cache_types.Resource{}},
-			{Version: "xxxx-557db659d4", Items: map[string]cache_types.Resource{}},
-			{Version: "xxxx", Items: map[string]cache_types.Resource{}},
+			{Version: "fbb568774", Items: map[string]cache_types.Resource{}},
+			{Version: "796b664c", Items: map[string]cache_types.Resource{}},
+			{Version: "bc6687b4b-557db659d4", Items: map[string]cache_types.Resource{}},
+			{Version: "fbb568774", Items: map[string]cache_types.Resource{}},
 		}}),
 	)
 	return cache
@@ -155,6 +155,7 @@ func TestCacheReconciler_Reconcile(t *testing.T) {
 		resources *marin3rv1alpha1.EnvoyResources
 		nodeID    string
 		version   string
+		block     bool
 	}
 	tests := []struct {
 		name        string
@@ -189,13 +190,13 @@ func TestCacheReconciler_Reconcile(t *testing.T) {
 			wantErr: false,
 			wantSnap: xdss_v2.NewSnapshot(&cache_v2.Snapshot{
 				Resources: [6]cache_v2.Resources{
-					{Version: "xxxx", Items: map[string]cache_types.Resource{
+					{Version: "5784cfcc87", Items: map[string]cache_types.Resource{
 						"endpoint": &envoy_api_v2.ClusterLoadAssignment{ClusterName: "endpoint"}}},
-					{Version: "xxxx", Items: map[string]cache_types.Resource{}},
-					{Version: "xxxx", Items: map[string]cache_types.Resource{}},
-					{Version: "xxxx", Items: map[string]cache_types.Resource{}},
-					{Version: "xxxx-557db659d4", Items: map[string]cache_types.Resource{}},
-					{Version: "xxxx", Items: map[string]cache_types.Resource{}},
+					{Version: "fbb568774", Items: map[string]cache_types.Resource{}},
+					{Version: "fbb568774", Items: map[string]cache_types.Resource{}},
+					{Version: "fbb568774", Items: map[string]cache_types.Resource{}},
+					{Version: "bc6687b4b-557db659d4", Items: map[string]cache_types.Resource{}},
+					{Version: "fbb568774", Items: map[string]cache_types.Resource{}},
 				}}),
 		},
 		{
@@ -222,17 +223,17 @@ func TestCacheReconciler_Reconcile(t *testing.T) {
 			wantErr: false,
 			wantSnap: xdss_v3.NewSnapshot(&cache_v3.Snapshot{
 				Resources: [6]cache_v3.Resources{
-					{Version: "xxxx", Items: map[string]cache_types.Resource{
+					{Version: "5784cfcc87", Items: map[string]cache_types.Resource{
 						"endpoint": &envoy_config_endpoint_v3.ClusterLoadAssignment{ClusterName: "endpoint"}}},
-					{Version: "xxxx", Items: map[string]cache_types.Resource{}},
-					{Version: "xxxx", Items: map[string]cache_types.Resource{}},
-					{Version: "xxxx", Items: map[string]cache_types.Resource{}},
-					{Version: "xxxx-557db659d4", Items: map[string]cache_types.Resource{}},
-					{Version: "xxxx", Items: map[string]cache_types.Resource{}},
+					{Version: "fbb568774", Items: map[string]cache_types.Resource{}},
+					{Version: "fbb568774", Items: map[string]cache_types.Resource{}},
+					{Version: "fbb568774", Items: map[string]cache_types.Resource{}},
+					{Version: "bc6687b4b-557db659d4", Items: map[string]cache_types.Resource{}},
+					{Version: "fbb568774", Items: map[string]cache_types.Resource{}},
 				}}),
 		},
 		{
-			name: "Does not write to cache if secret versions are equal",
+			name: "Does not write to cache if versions are equal",
 			fields: fields{
 				ctx:       context.TODO(),
 				logger:    ctrl.Log.WithName("test"),
@@ -255,16 +256,53 @@ func TestCacheReconciler_Reconcile(t *testing.T) {
 			wantErr: false,
 			wantSnap: xdss_v3.NewSnapshot(&cache_v3.Snapshot{
 				Resources: [6]cache_v3.Resources{
-					{Version: "xxxx", Items: map[string]cache_types.Resource{
+					{Version: "fbb568774", Items: map[string]cache_types.Resource{
 						"endpoint1": &envoy_config_endpoint_v3.ClusterLoadAssignment{ClusterName: "endpoint1"},
 					}},
-					{Version: "xxxx", Items: map[string]cache_types.Resource{
+					{Version: "fbb568774", Items: map[string]cache_types.Resource{
 						"cluster1": &envoy_config_cluster_v3.Cluster{Name: "cluster1"},
 					}},
-					{Version: "xxxx", Items: map[string]cache_types.Resource{}},
-					{Version: "xxxx", Items: map[string]cache_types.Resource{}},
-					{Version: "xxxx-557db659d4", Items: map[string]cache_types.Resource{}},
-					{Version: "xxxx", Items: map[string]cache_types.Resource{}},
+					{Version: "fbb568774", Items: map[string]cache_types.Resource{}},
+					{Version: "796b664c", Items: map[string]cache_types.Resource{}},
+					{Version: "bc6687b4b-557db659d4", Items: map[string]cache_types.Resource{}},
+					{Version: "fbb568774", Items: map[string]cache_types.Resource{}},
+				}}),
+		},
+		{
+			name: "Does not write to cache if there are dangling references and blocking is enabled",
+			fields: fields{
+				ctx:       context.TODO(),
+				logger:    ctrl.Log.WithName("test"),
+				client:    fake.NewFakeClient(),
+				xdsCache:  fakeCacheV3(),
+				decoder:   envoy_serializer.NewResourceUnmarshaller(envoy_serializer.JSON, envoy.APIv3),
+				generator: envoy_resources_v3.Generator{},
+			},
+			args: args{
+				req: types.NamespacedName{Name: "xx", Namespace: "xx"},
+				resources: &marin3rv1alpha1.EnvoyResources{
+					Routes: []marin3rv1alpha1.EnvoyResource{
+						{Name: "route", Value: "{\"name\": \"route\", \"virtual_hosts\": [{\"name\": \"vh\", \"domains\": [\"*\"], \"routes\": [{\"match\": {\"prefix\": \"/\"}, \"route\": {\"cluster\": \"missing\"}}]}]}"},
+					}},
+				version: "xxxx",
+				nodeID:  "node1",
+				block:   true,
+			},
+
+			want:    reconcile.Result{},
+			wantErr: true,
+			wantSnap: xdss_v3.NewSnapshot(&cache_v3.Snapshot{
+				Resources: [6]cache_v3.Resources{
+					{Version: "fbb568774", Items: map[string]cache_types.Resource{
+						"endpoint1": &envoy_config_endpoint_v3.ClusterLoadAssignment{ClusterName: "endpoint1"},
+					}},
+					{Version: "fbb568774", Items: map[string]cache_types.Resource{
+						"cluster1": &envoy_config_cluster_v3.Cluster{Name: "cluster1"},
+					}},
+					{Version: "fbb568774", Items: map[string]cache_types.Resource{}},
+					{Version: "796b664c", Items: map[string]cache_types.Resource{}},
+					{Version: "bc6687b4b-557db659d4", Items: map[string]cache_types.Resource{}},
+					{Version: "fbb568774", Items: map[string]cache_types.Resource{}},
 				}}),
 		},
 	}
@@ -278,7 +316,7 @@ func TestCacheReconciler_Reconcile(t *testing.T) {
 				decoder:   tt.fields.decoder,
 				generator: tt.fields.generator,
 			}
-			got, err := r.Reconcile(tt.args.req, tt.args.resources, tt.args.nodeID, tt.args.version)
+			got, err := r.Reconcile(tt.args.req, tt.args.resources, tt.args.nodeID, tt.args.version, tt.args.block)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("CacheReconciler.Reconcile() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -286,7 +324,7 @@ func TestCacheReconciler_Reconcile(t *testing.T) {
 			if !reflect.DeepEqual(got, tt.want) {
 				t.Errorf("CacheReconciler.Reconcile() = %v, want %v", got, tt.want)
 			}
-			gotSnap, _ := r.xdsCache.GetSnapshot(tt.args.nodeID)
+			gotSnap, _ := r.xdsCache.GetSnapshot(xdss.NodeKey(tt.args.req.Namespace, tt.args.nodeID))
 			if !testutil.SnapshotsAreEqual(gotSnap, tt.wantSnap) {
 				t.Errorf("CacheReconciler.GenerateSnapshot() Snapshot = %v, want %v", gotSnap, tt.wantSnap)
 			}
@@ -306,7 +344,6 @@ func TestCacheReconciler_GenerateSnapshot(t *testing.T) {
 	type args struct {
 		req       types.NamespacedName
 		resources *marin3rv1alpha1.EnvoyResources
-		version   string
 	}
 	tests := []struct {
 		name    string
@@ -343,24 +380,23 @@ func TestCacheReconciler_GenerateSnapshot(t *testing.T) {
 					Runtimes: []marin3rv1alpha1.EnvoyResource{
 						{Name: "runtime", Value: "{\"name\": \"runtime\"}"},
 					}},
-				version: "xxxx",
 			},
 			want: xdss_v2.NewSnapshot(&cache_v2.Snapshot{
 				Resources: [6]cache_v2.Resources{
-					{Version: "xxxx", Items: map[string]cache_types.Resource{
+					{Version: "5784cfcc87", Items: map[string]cache_types.Resource{
 						"endpoint": &envoy_api_v2.ClusterLoadAssignment{ClusterName: "endpoint"},
 					}},
-					{Version: "xxxx", Items: map[string]cache_types.Resource{
+					{Version: "6565db44", Items: map[string]cache_types.Resource{
 						"cluster": &envoy_api_v2.Cluster{Name: "cluster"},
 					}},
-					{Version: "xxxx", Items: map[string]cache_types.Resource{
+					{Version: "67f8f967d4", Items: map[string]cache_types.Resource{
 						"route": &envoy_api_v2.RouteConfiguration{Name: "route"},
 					}},
-					{Version: "xxxx", Items: map[string]cache_types.Resource{
+					{Version: "56ff98fd94", Items: map[string]cache_types.Resource{
 						"listener": &envoy_api_v2.Listener{Name: "listener"},
 					}},
-					{Version: "xxxx-557db659d4", Items: map[string]cache_types.Resource{}},
-					{Version: "xxxx", Items: map[string]cache_types.Resource{
+					{Version: "bc6687b4b-557db659d4", Items: map[string]cache_types.Resource{}},
+					{Version: "65ddcb4f76", Items: map[string]cache_types.Resource{
 						"runtime": &envoy_service_discovery_v2.Runtime{Name: "runtime"},
 					}},
 				},
@@ -395,24 +431,23 @@ func TestCacheReconciler_GenerateSnapshot(t *testing.T) {
 					Runtimes: []marin3rv1alpha1.EnvoyResource{
 						{Name: "runtime", Value: "{\"name\": \"runtime\"}"},
 					}},
-				version: "xxxx",
 			},
 			want: xdss_v3.NewSnapshot(&cache_v3.Snapshot{
 				Resources: [6]cache_v3.Resources{
-					{Version: "xxxx", Items: map[string]cache_types.Resource{
+					{Version: "5784cfcc87", Items: map[string]cache_types.Resource{
 						"endpoint": &envoy_config_endpoint_v3.ClusterLoadAssignment{ClusterName: "endpoint"},
 					}},
-					{Version: "xxxx", Items: map[string]cache_types.Resource{
+					{Version: "6565db44", Items: map[string]cache_types.Resource{
 						"cluster": &envoy_config_cluster_v3.Cluster{Name: "cluster"},
 					}},
-					{Version: "xxxx", Items: map[string]cache_types.Resource{
+					{Version: "67f8f967d4", Items: map[string]cache_types.Resource{
 						"route": &envoy_config_route_v3.RouteConfiguration{Name: "route"},
 					}},
-					{Version: "xxxx", Items: map[string]cache_types.Resource{
+					{Version: "56ff98fd94", Items: map[string]cache_types.Resource{
 						"listener": &envoy_config_listener_v3.Listener{Name: "listener"},
 					}},
-					{Version: "xxxx-557db659d4", Items: map[string]cache_types.Resource{}},
-					{Version: "xxxx", Items: map[string]cache_types.Resource{
+					{Version: "bc6687b4b-557db659d4", Items: map[string]cache_types.Resource{}},
+					{Version: "65ddcb4f76", Items: map[string]cache_types.Resource{
 						"runtime": &envoy_service_runtime_v3.Runtime{Name: "runtime"},
 					}},
 				},
@@ -435,7 +470,6 @@ func TestCacheReconciler_GenerateSnapshot(t *testing.T) {
 					Endpoints: []marin3rv1alpha1.EnvoyResource{
 						{Name: "endpoint", Value: "giberish"},
 					}},
-				version: "xxxx",
 			},
 			wantErr: true,
 			want:    xdss_v2.NewSnapshot(&cache_v2.Snapshot{}),
@@ -456,7 +490,6 @@ func TestCacheReconciler_GenerateSnapshot(t *testing.T) {
 					Clusters: []marin3rv1alpha1.EnvoyResource{
 						{Name: "cluster", Value: "giberish"},
 					}},
-				version: "xxxx",
 			},
 			wantErr: true,
 			want:    xdss_v2.NewSnapshot(&cache_v2.Snapshot{}),
@@ -477,7 +510,6 @@ func TestCacheReconciler_GenerateSnapshot(t *testing.T) {
 					Routes: []marin3rv1alpha1.EnvoyResource{
 						{Name: "route", Value: "giberish"},
 					}},
-				version: "xxxx",
 			},
 			wantErr: true,
 			want:    xdss_v2.NewSnapshot(&cache_v2.Snapshot{}),
@@ -498,7 +530,6 @@ func TestCacheReconciler_GenerateSnapshot(t *testing.T) {
 					Listeners: []marin3rv1alpha1.EnvoyResource{
 						{Name: "listener", Value: "giberish"},
 					}},
-				version: "xxxx",
 			},
 			wantErr: true,
 			want:    xdss_v2.NewSnapshot(&cache_v2.Snapshot{}),
@@ -519,7 +550,6 @@ func TestCacheReconciler_GenerateSnapshot(t *testing.T) {
 					Runtimes: []marin3rv1alpha1.EnvoyResource{
 						{Name: "runtime", Value: "giberish"},
 					}},
-				version: "xxxx",
 			},
 			wantErr: true,
 			want:    xdss_v2.NewSnapshot(&cache_v2.Snapshot{}),
@@ -539,7 +569,7 @@ func TestCacheReconciler_GenerateSnapshot(t *testing.T) {
 				generator: envoy_resources_v2.Generator{},
 			},
 			args: args{
-				req: types.NamespacedName{Name: "xx", Namespace: "xx"},
+				req: types.NamespacedName{Name: "xx", Namespace: "default"},
 				resources: &marin3rv1alpha1.EnvoyResources{
 					Secrets: []marin3rv1alpha1.EnvoySecretResource{
 						{Name: "secret", Ref: corev1.SecretReference{
@@ -547,16 +577,15 @@ func TestCacheReconciler_GenerateSnapshot(t *testing.T) {
 							Namespace: "default",
 						}},
 					}},
-				version: "xxxx",
 			},
 			wantErr: false,
 			want: xdss_v2.NewSnapshot(&cache_v2.Snapshot{
 				Resources: [6]cache_v2.Resources{
-					{Version: "xxxx", Items: map[string]cache_types.Resource{}},
-					{Version: "xxxx", Items: map[string]cache_types.Resource{}},
-					{Version: "xxxx", Items: map[string]cache_types.Resource{}},
-					{Version: "xxxx", Items: map[string]cache_types.Resource{}},
-					{Version: "xxxx-6c68d58f5f", Items: map[string]cache_types.Resource{
+					{Version: "fbb568774", Items: map[string]cache_types.Resource{}},
+					{Version: "fbb568774", Items: map[string]cache_types.Resource{}},
+					{Version: "fbb568774", Items: map[string]cache_types.Resource{}},
+					{Version: "fbb568774", Items: map[string]cache_types.Resource{}},
+					{Version: "57b78b4985-6c68d58f5f", Items: map[string]cache_types.Resource{
 						"secret": &envoy_api_v2_auth.Secret{
 							Name: "secret",
 							Type: &envoy_api_v2_auth.Secret_TlsCertificate{
@@ -567,7 +596,7 @@ func TestCacheReconciler_GenerateSnapshot(t *testing.T) {
 									CertificateChain: &envoy_api_v2_core.DataSource{
 										Specifier: &envoy_api_v2_core.DataSource_InlineBytes{InlineBytes: []byte("cert")},
 									}}}}}},
-					{Version: "xxxx", Items: map[string]cache_types.Resource{}},
+					{Version: "fbb568774", Items: map[string]cache_types.Resource{}},
 				},
 			}),
 		},
@@ -586,7 +615,7 @@ func TestCacheReconciler_GenerateSnapshot(t *testing.T) {
 				generator: envoy_resources_v3.Generator{},
 			},
 			args: args{
-				req: types.NamespacedName{Name: "xx", Namespace: "xx"},
+				req: types.NamespacedName{Name: "xx", Namespace: "default"},
 				resources: &marin3rv1alpha1.EnvoyResources{
 					Secrets: []marin3rv1alpha1.EnvoySecretResource{
 						{Name: "secret", Ref: corev1.SecretReference{
@@ -594,16 +623,15 @@ func TestCacheReconciler_GenerateSnapshot(t *testing.T) {
 							Namespace: "default",
 						}},
 					}},
-				version: "xxxx",
 			},
 			wantErr: false,
 			want: xdss_v3.NewSnapshot(&cache_v3.Snapshot{
 				Resources: [6]cache_v3.Resources{
-					{Version: "xxxx", Items: map[string]cache_types.Resource{}},
-					{Version: "xxxx", Items: map[string]cache_types.Resource{}},
-					{Version: "xxxx", Items: map[string]cache_types.Resource{}},
-					{Version: "xxxx", Items: map[string]cache_types.Resource{}},
-					{Version: "xxxx-77c9875d7b", Items: map[string]cache_types.Resource{
+					{Version: "fbb568774", Items: map[string]cache_types.Resource{}},
+					{Version: "fbb568774", Items: map[string]cache_types.Resource{}},
+					{Version: "fbb568774", Items: map[string]cache_types.Resource{}},
+					{Version: "fbb568774", Items: map[string]cache_types.Resource{}},
+					{Version: "57b78b4985-77c9875d7b", Items: map[string]cache_types.Resource{
 						"secret": &envoy_extensions_transport_sockets_tls_v3.Secret{
 							Name: "secret",
 							Type: &envoy_extensions_transport_sockets_tls_v3.Secret_TlsCertificate{
@@ -614,7 +642,7 @@ func TestCacheReconciler_GenerateSnapshot(t *testing.T) {
 									CertificateChain: &envoy_config_core_v3.DataSource{
 										Specifier: &envoy_config_core_v3.DataSource_InlineBytes{InlineBytes: []byte("cert")},
 									}}}}}},
-					{Version: "xxxx", Items: map[string]cache_types.Resource{}},
+					{Version: "fbb568774", Items: map[string]cache_types.Resource{}},
 				},
 			}),
 		},
@@ -633,7 +661,7 @@ func TestCacheReconciler_GenerateSnapshot(t *testing.T) {
 				generator: envoy_resources_v2.Generator{},
 			},
 			args: args{
-				req: types.NamespacedName{Name: "xx", Namespace: "xx"},
+				req: types.NamespacedName{Name: "xx", Namespace: "default"},
 				resources: &marin3rv1alpha1.EnvoyResources{
 					Secrets: []marin3rv1alpha1.EnvoySecretResource{
 						{Name: "secret", Ref: corev1.SecretReference{
@@ -641,7 +669,6 @@ func TestCacheReconciler_GenerateSnapshot(t *testing.T) {
 							Namespace: "default",
 						}},
 					}},
-				version: "xxxx",
 			},
 			wantErr: true,
 			want:    xdss_v2.NewSnapshot(&cache_v2.Snapshot{}),
@@ -657,7 +684,7 @@ func TestCacheReconciler_GenerateSnapshot(t *testing.T) {
 				generator: envoy_resources_v2.Generator{},
 			},
 			args: args{
-				req: types.NamespacedName{Name: "xx", Namespace: "xx"},
+				req: types.NamespacedName{Name: "xx", Namespace: "default"},
 				resources: &marin3rv1alpha1.EnvoyResources{
 					Secrets: []marin3rv1alpha1.EnvoySecretResource{
 						{Name: "secret", Ref: corev1.SecretReference{
@@ -665,7 +692,33 @@ func TestCacheReconciler_GenerateSnapshot(t *testing.T) {
 							Namespace: "default",
 						}},
 					}},
-				version: "xxxx",
+			},
+			wantErr: true,
+			want:    xdss_v2.NewSnapshot(&cache_v2.Snapshot{}),
+		},
+		{
+			name: "Fails with secrets of other namespaces",
+			fields: fields{
+				client: fake.NewFakeClient(&corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{Name: "secret", Namespace: "other"},
+					Type:       corev1.SecretTypeTLS,
+					Data:       map[string][]byte{"tls.crt": []byte("cert"), "tls.key": []byte("key")},
+				}),
+				ctx:       context.TODO(),
+				logger:    ctrl.Log.WithName("test"),
+				xdsCache:  xdss_v2.NewCache(cache_v2.NewSnapshotCache(true, cache_v2.IDHash{}, nil)),
+				decoder:   envoy_serializer.NewResourceUnmarshaller(envoy_serializer.JSON, envoy.APIv2),
+				generator: envoy_resources_v2.Generator{},
+			},
+			args: args{
+				req: types.NamespacedName{Name: "xx", Namespace: "default"},
+				resources: &marin3rv1alpha1.EnvoyResources{
+					Secrets: []marin3rv1alpha1.EnvoySecretResource{
+						{Name: "secret", Ref: corev1.SecretReference{
+							Name:      "secret",
+							Namespace: "other",
+						}},
+					}},
 			},
 			wantErr: true,
 			want:    xdss_v2.NewSnapshot(&cache_v2.Snapshot{}),
@@ -681,7 +734,7 @@ func TestCacheReconciler_GenerateSnapshot(t *testing.T) {
 				decoder:   tt.fields.decoder,
 				generator: tt.fields.generator,
 			}
-			got, err := r.GenerateSnapshot(tt.args.req, tt.args.resources, tt.args.version)
+			got, err := r.GenerateSnapshot(tt.args.req, tt.args.resources)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("CacheReconciler.GenerateSnapshot() error = %v, wantErr %v", err, tt.wantErr)
 				return
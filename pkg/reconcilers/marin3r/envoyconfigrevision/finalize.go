@@ -9,7 +9,7 @@ import (
 // CleanupLogic executes finalization code for EnvoyConfigRevision resources
 func CleanupLogic(ecr *marin3rv1alpha1.EnvoyConfigRevision, xdssCache xdss.Cache, log logr.Logger) {
 	if ecr.Status.Conditions.IsTrueFor(marin3rv1alpha1.RevisionPublishedCondition) {
-		xdssCache.ClearSnapshot(ecr.Spec.NodeID)
+		xdssCache.ClearSnapshot(xdss.NodeKey(ecr.GetNamespace(), ecr.Spec.NodeID))
 		log.Info("Successfully cleared xDS server cache", "XDSS", string(ecr.GetEnvoyAPIVersion()), "NodeID", ecr.Spec.NodeID)
 	}
 }
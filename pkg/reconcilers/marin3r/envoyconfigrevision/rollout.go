@@ -0,0 +1,187 @@
+package reconcilers
+
+import (
+	"time"
+
+	marin3rv1alpha1 "github.com/3scale/marin3r/apis/marin3r/v1alpha1"
+	xdss "github.com/3scale/marin3r/pkg/discoveryservice/xdss"
+	"github.com/3scale/marin3r/pkg/discoveryservice/xdss/stats"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+const (
+	// RolloutResyncPeriod is the period at which the
+	// canaries are checked while a rollout is in progress
+	RolloutResyncPeriod = 5 * time.Second
+)
+
+// ReconcileRollout writes the resources of a revision with a rollout policy to the xDS cache. The
+// resources are first written for the canaries, and for the rest of the proxies once all the canaries
+// have acknowledged them and the bake time has elapsed. The progress of the rollout is tracked in
+// status.rollout, which is modified in place and must be persisted by the caller.
+func (r *CacheReconciler) ReconcileRollout(req types.NamespacedName, ecr *marin3rv1alpha1.EnvoyConfigRevision,
+	xdsStats *stats.Stats, now time.Time) (ctrl.Result, error) {
+
+	nodeID := ecr.Spec.NodeID
+	key := xdss.NodeKey(ecr.GetNamespace(), nodeID)
+	if ecr.Status.Rollout != nil && ecr.Status.Rollout.Phase == marin3rv1alpha1.RolloutCompletedPhase {
+		return r.Reconcile(req, ecr.Spec.EnvoyResources, nodeID, ecr.Spec.Version, ecr.GetBlockOnDanglingReferences())
+	}
+
+	snap, err := r.generateConsistentSnapshot(req, ecr.Spec.EnvoyResources, nodeID, ecr.Spec.Version, ecr.GetBlockOnDanglingReferences())
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	// There is nothing to roll out progressively if no snapshot has been
+	// written for the nodeID yet or if it already holds the same resources
+	current, err := r.xdsCache.GetSnapshot(key)
+	if err != nil || len(changedTypes(current, snap)) == 0 {
+		return r.completeRollout(ecr, snap, now)
+	}
+
+	// Select the canaries and write the snapshot for them
+	if ecr.Status.Rollout == nil {
+		proxies := xdsStats.GetProxies(key)
+		n := ecr.Spec.Rollout.GetCanaries(len(proxies))
+		if n >= len(proxies) {
+			return r.completeRollout(ecr, snap, now)
+		}
+
+		canaries := []string{}
+		for _, proxy := range proxies {
+			if len(canaries) == n {
+				break
+			}
+			// The proxy might have disconnected since the list was retrieved
+			if err := r.xdsCache.SetProxySnapshot(key, proxy.Address, snap); err != nil {
+				r.logger.V(1).Info("Unable to select proxy as canary", "NodeID", nodeID, "Address", proxy.Address, "Error", err.Error())
+				continue
+			}
+			canaries = append(canaries, proxy.Address)
+		}
+		if len(canaries) == 0 {
+			return r.completeRollout(ecr, snap, now)
+		}
+
+		r.logger.Info("Writing new snapshot to xDS cache for canaries", "Version", ecr.Spec.Version, "NodeID", nodeID, "Canaries", canaries)
+		ecr.Status.Rollout = &marin3rv1alpha1.RolloutStatus{
+			Phase:     marin3rv1alpha1.RolloutCanaryPhase,
+			Canaries:  canaries,
+			StartedAt: &metav1.Time{Time: now},
+		}
+		return ctrl.Result{RequeueAfter: RolloutResyncPeriod}, nil
+	}
+
+	// Check the progress of the canaries. Canaries that reject the snapshot cause the revision
+	// to be tainted through the xDS server callbacks, so they never get to this point.
+	connected := map[string]stats.Proxy{}
+	for _, proxy := range xdsStats.GetProxies(key) {
+		connected[proxy.Address] = proxy
+	}
+	remaining, synced := 0, 0
+	for _, address := range ecr.Status.Rollout.Canaries {
+		proxy, ok := connected[address]
+		if !ok {
+			continue
+		}
+		// The snapshot is written again as the resources
+		// might have changed, like the contents of secrets
+		if err := r.xdsCache.SetProxySnapshot(key, address, snap); err != nil {
+			continue
+		}
+		remaining++
+		if isProxySynced(proxy, snap) {
+			synced++
+		}
+	}
+
+	if remaining == 0 {
+		// There is no evidence that the snapshot works if all
+		// the canaries are gone, so new canaries are selected
+		r.logger.Info("All canaries have disconnected, restarting rollout", "Version", ecr.Spec.Version, "NodeID", nodeID)
+		ecr.Status.Rollout = nil
+		return ctrl.Result{Requeue: true}, nil
+	}
+
+	if synced < remaining {
+		ecr.Status.Rollout.SyncedAt = nil
+		return ctrl.Result{RequeueAfter: RolloutResyncPeriod}, nil
+	}
+
+	if ecr.Status.Rollout.SyncedAt == nil {
+		ecr.Status.Rollout.SyncedAt = &metav1.Time{Time: now}
+	}
+	if wait := ecr.Spec.Rollout.GetBakeTime() - now.Sub(ecr.Status.Rollout.SyncedAt.Time); wait > 0 {
+		return ctrl.Result{RequeueAfter: wait}, nil
+	}
+
+	return r.completeRollout(ecr, snap, now)
+}
+
+// SkipRollout writes the resources of a revision with a rollout policy to all the proxies at once and
+// marks the rollout as completed in status.rollout, which must be persisted by the caller. It is used
+// when several discovery service replicas serve the proxies, as each replica only knows the proxies
+// connected to it and can neither select the canaries among all of them nor track their progress.
+func (r *CacheReconciler) SkipRollout(req types.NamespacedName, ecr *marin3rv1alpha1.EnvoyConfigRevision, now time.Time) (ctrl.Result, error) {
+
+	if ecr.Status.Rollout != nil && ecr.Status.Rollout.Phase == marin3rv1alpha1.RolloutCompletedPhase {
+		return r.Reconcile(req, ecr.Spec.EnvoyResources, ecr.Spec.NodeID, ecr.Spec.Version, ecr.GetBlockOnDanglingReferences())
+	}
+
+	snap, err := r.generateConsistentSnapshot(req, ecr.Spec.EnvoyResources, ecr.Spec.NodeID, ecr.Spec.Version, ecr.GetBlockOnDanglingReferences())
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	r.logger.Info("Progressive rollouts are not supported with several replicas", "Version", ecr.Spec.Version, "NodeID", ecr.Spec.NodeID)
+
+	return r.completeRollout(ecr, snap, now)
+}
+
+// AbortRollout writes the snapshot currently published for the nodeID back to the canaries of
+// a rollout that has not completed. This is used when the revision stops being published before
+// the rollout completes, for example when a canary rejects it and a rollback occurs.
+func (r *CacheReconciler) AbortRollout(ecr *marin3rv1alpha1.EnvoyConfigRevision) error {
+
+	if ecr.Status.Rollout == nil || ecr.Status.Rollout.Phase != marin3rv1alpha1.RolloutCanaryPhase {
+		return nil
+	}
+
+	key := xdss.NodeKey(ecr.GetNamespace(), ecr.Spec.NodeID)
+	current, err := r.xdsCache.GetSnapshot(key)
+	if err != nil {
+		// Nothing to write back
+		return nil
+	}
+
+	for _, address := range ecr.Status.Rollout.Canaries {
+		// Disconnected canaries get the published snapshot when they reconnect
+		if err := r.xdsCache.SetProxySnapshot(key, address, current); err != nil {
+			r.logger.V(1).Info("Unable to revert canary", "NodeID", ecr.Spec.NodeID, "Address", address, "Error", err.Error())
+		}
+	}
+	r.logger.Info("Rollout aborted, canaries reverted to the published snapshot", "Version", ecr.Spec.Version, "NodeID", ecr.Spec.NodeID)
+
+	return nil
+}
+
+// completeRollout writes the snapshot for all the proxies and marks the rollout as completed
+func (r *CacheReconciler) completeRollout(ecr *marin3rv1alpha1.EnvoyConfigRevision, snap xdss.Snapshot, now time.Time) (ctrl.Result, error) {
+
+	r.logger.Info("Writing new snapshot to xDS cache", "Version", ecr.Spec.Version, "NodeID", ecr.Spec.NodeID)
+	if err := r.xdsCache.SetSnapshot(xdss.NodeKey(ecr.GetNamespace(), ecr.Spec.NodeID), snap); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	rollout := &marin3rv1alpha1.RolloutStatus{}
+	if ecr.Status.Rollout != nil {
+		rollout = ecr.Status.Rollout
+	}
+	rollout.Phase = marin3rv1alpha1.RolloutCompletedPhase
+	rollout.CompletedAt = &metav1.Time{Time: now}
+	ecr.Status.Rollout = rollout
+
+	return ctrl.Result{}, nil
+}
@@ -0,0 +1,226 @@
+package reconcilers
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	marin3rv1alpha1 "github.com/3scale/marin3r/apis/marin3r/v1alpha1"
+	xdss "github.com/3scale/marin3r/pkg/discoveryservice/xdss"
+	"github.com/3scale/marin3r/pkg/discoveryservice/xdss/stats"
+	xdss_v3 "github.com/3scale/marin3r/pkg/discoveryservice/xdss/v3"
+	envoy "github.com/3scale/marin3r/pkg/envoy"
+	envoy_resources_v3 "github.com/3scale/marin3r/pkg/envoy/resources/v3"
+	envoy_serializer "github.com/3scale/marin3r/pkg/envoy/serializer"
+	envoy_config_core_v3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	cache_v3 "github.com/envoyproxy/go-control-plane/pkg/cache/v3"
+	resource_v3 "github.com/envoyproxy/go-control-plane/pkg/resource/v3"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+type rolloutTestEnv struct {
+	snapshotCache cache_v3.SnapshotCache
+	proxies       *xdss.Proxies
+	stats         *stats.Stats
+	reconciler    CacheReconciler
+}
+
+// newRolloutTestEnv returns a CacheReconciler with the given proxies
+// connected to the xDS server with nodeID "node1" in namespace "default"
+func newRolloutTestEnv(addresses ...string) *rolloutTestEnv {
+	env := &rolloutTestEnv{proxies: xdss.NewProxies(), stats: stats.New()}
+	env.snapshotCache = cache_v3.NewSnapshotCache(true, xdss_v3.ProxyHash{Proxies: env.proxies}, nil)
+	env.reconciler = NewCacheReconciler(context.TODO(), ctrl.Log.WithName("test"), fake.NewFakeClient(),
+		xdss_v3.NewCacheWithProxies(env.snapshotCache, env.proxies),
+		envoy_serializer.NewResourceUnmarshaller(envoy_serializer.JSON, envoy.APIv3), envoy_resources_v3.Generator{},
+	)
+	for idx, address := range addresses {
+		env.stats.OpenStream(int64(idx), address)
+		env.stats.ReportRequest(int64(idx), "default/node1", envoy.Cluster, "")
+		env.proxies.OpenStream(int64(idx), address)
+		env.proxies.Register(int64(idx), &envoy_config_core_v3.Node{Id: "node1"}, "default/node1", nil)
+	}
+	return env
+}
+
+// clusterVersion returns the version of the clusters in the snapshot
+// of the given proxy, or of the nodeID if address is empty
+func (env *rolloutTestEnv) clusterVersion(address string) string {
+	key := "default/node1"
+	if address != "" {
+		key, _ = env.proxies.Lookup("default/node1", address)
+	}
+	snap, _ := env.snapshotCache.GetSnapshot(key)
+	return snap.GetVersion(resource_v3.ClusterType)
+}
+
+func testRolloutRevision(clusters string, rollout *marin3rv1alpha1.RolloutPolicy) *marin3rv1alpha1.EnvoyConfigRevision {
+	return &marin3rv1alpha1.EnvoyConfigRevision{
+		ObjectMeta: metav1.ObjectMeta{Name: "ecr", Namespace: "default"},
+		Spec: marin3rv1alpha1.EnvoyConfigRevisionSpec{
+			NodeID:  "node1",
+			Version: clusters,
+			Rollout: rollout,
+			EnvoyResources: &marin3rv1alpha1.EnvoyResources{
+				Clusters: []marin3rv1alpha1.EnvoyResource{{Name: clusters, Value: "{\"name\": \"" + clusters + "\"}"}},
+			},
+		},
+	}
+}
+
+func TestCacheReconciler_ReconcileRollout(t *testing.T) {
+	req := types.NamespacedName{Name: "ecr", Namespace: "default"}
+	now := time.Now()
+	policy := &marin3rv1alpha1.RolloutPolicy{
+		Canaries: func() *intstr.IntOrString { i := intstr.FromString("50%"); return &i }(),
+		BakeTime: &metav1.Duration{Duration: time.Minute},
+	}
+
+	t.Run("Completes the rollout if no snapshot has been written for the nodeID", func(t *testing.T) {
+		env := newRolloutTestEnv("10.0.0.1:5000", "10.0.0.2:5000")
+		ecr := testRolloutRevision("new", policy)
+		if _, err := env.reconciler.ReconcileRollout(req, ecr, env.stats, now); err != nil {
+			t.Fatalf("CacheReconciler.ReconcileRollout() error = %v", err)
+		}
+		if ecr.Status.Rollout == nil || ecr.Status.Rollout.Phase != marin3rv1alpha1.RolloutCompletedPhase {
+			t.Errorf("CacheReconciler.ReconcileRollout() rollout = %v, want phase 'Completed'", ecr.Status.Rollout)
+		}
+	})
+
+	t.Run("Publishes to the canaries first and to the rest after the bake time", func(t *testing.T) {
+		env := newRolloutTestEnv("10.0.0.1:5000", "10.0.0.2:5000", "10.0.0.3:5000", "10.0.0.4:5000")
+		old := testRolloutRevision("old", nil)
+		env.reconciler.Reconcile(req, old.Spec.EnvoyResources, "node1", "old", false)
+		oldVersion := env.clusterVersion("")
+		ecr := testRolloutRevision("new", policy)
+
+		result, err := env.reconciler.ReconcileRollout(req, ecr, env.stats, now)
+		if err != nil {
+			t.Fatalf("CacheReconciler.ReconcileRollout() error = %v", err)
+		}
+		if want := []string{"10.0.0.1:5000", "10.0.0.2:5000"}; ecr.Status.Rollout == nil ||
+			ecr.Status.Rollout.Phase != marin3rv1alpha1.RolloutCanaryPhase || !reflect.DeepEqual(ecr.Status.Rollout.Canaries, want) {
+			t.Fatalf("CacheReconciler.ReconcileRollout() rollout = %v, want phase 'Canary' with canaries %v", ecr.Status.Rollout, want)
+		}
+		if result.RequeueAfter != RolloutResyncPeriod {
+			t.Errorf("CacheReconciler.ReconcileRollout() result = %v, want RequeueAfter %v", result, RolloutResyncPeriod)
+		}
+		newVersion := env.clusterVersion("10.0.0.1:5000")
+		if newVersion == oldVersion || env.clusterVersion("10.0.0.2:5000") != newVersion ||
+			env.clusterVersion("10.0.0.3:5000") != oldVersion || env.clusterVersion("") != oldVersion {
+			t.Fatalf("CacheReconciler.ReconcileRollout() the snapshot should only be written for the canaries")
+		}
+
+		// Canaries have not acknowledged the resources yet
+		if _, err := env.reconciler.ReconcileRollout(req, ecr, env.stats, now); err != nil || ecr.Status.Rollout.SyncedAt != nil {
+			t.Fatalf("CacheReconciler.ReconcileRollout() rollout = %v, want syncedAt unset", ecr.Status.Rollout)
+		}
+
+		env.stats.ReportRequest(0, "default/node1", envoy.Cluster, newVersion)
+		env.stats.ReportRequest(1, "default/node1", envoy.Cluster, newVersion)
+		result, err = env.reconciler.ReconcileRollout(req, ecr, env.stats, now)
+		if err != nil || ecr.Status.Rollout.SyncedAt == nil || result.RequeueAfter != time.Minute {
+			t.Fatalf("CacheReconciler.ReconcileRollout() rollout = %v, result = %v, want syncedAt set and RequeueAfter 1m", ecr.Status.Rollout, result)
+		}
+		if env.clusterVersion("10.0.0.3:5000") != oldVersion {
+			t.Fatalf("CacheReconciler.ReconcileRollout() the snapshot should not be written for all proxies before the bake time")
+		}
+
+		if _, err := env.reconciler.ReconcileRollout(req, ecr, env.stats, now.Add(time.Minute)); err != nil {
+			t.Fatalf("CacheReconciler.ReconcileRollout() error = %v", err)
+		}
+		if ecr.Status.Rollout.Phase != marin3rv1alpha1.RolloutCompletedPhase || ecr.Status.Rollout.CompletedAt == nil {
+			t.Errorf("CacheReconciler.ReconcileRollout() rollout = %v, want phase 'Completed'", ecr.Status.Rollout)
+		}
+		for _, address := range []string{"", "10.0.0.3:5000", "10.0.0.4:5000"} {
+			if got := env.clusterVersion(address); got != newVersion {
+				t.Errorf("CacheReconciler.ReconcileRollout() got version %q for %q, want %q", got, address, newVersion)
+			}
+		}
+	})
+
+	t.Run("Selects new canaries if all of them disconnect", func(t *testing.T) {
+		env := newRolloutTestEnv("10.0.0.1:5000", "10.0.0.2:5000")
+		env.reconciler.Reconcile(req, testRolloutRevision("old", nil).Spec.EnvoyResources, "node1", "old", false)
+		ecr := testRolloutRevision("new", policy)
+		ecr.Status.Rollout = &marin3rv1alpha1.RolloutStatus{
+			Phase:    marin3rv1alpha1.RolloutCanaryPhase,
+			Canaries: []string{"10.0.0.9:5000"},
+		}
+		result, err := env.reconciler.ReconcileRollout(req, ecr, env.stats, now)
+		if err != nil || ecr.Status.Rollout != nil || !result.Requeue {
+			t.Errorf("CacheReconciler.ReconcileRollout() rollout = %v, result = %v, want rollout reset and requeue", ecr.Status.Rollout, result)
+		}
+	})
+}
+
+func TestCacheReconciler_AbortRollout(t *testing.T) {
+	req := types.NamespacedName{Name: "ecr", Namespace: "default"}
+	env := newRolloutTestEnv("10.0.0.1:5000", "10.0.0.2:5000")
+	env.reconciler.Reconcile(req, testRolloutRevision("old", nil).Spec.EnvoyResources, "node1", "old", false)
+	oldVersion := env.clusterVersion("")
+
+	ecr := testRolloutRevision("new", &marin3rv1alpha1.RolloutPolicy{})
+	if _, err := env.reconciler.ReconcileRollout(req, ecr, env.stats, time.Now()); err != nil {
+		t.Fatalf("CacheReconciler.ReconcileRollout() error = %v", err)
+	}
+	if env.clusterVersion("10.0.0.1:5000") == oldVersion {
+		t.Fatalf("CacheReconciler.ReconcileRollout() the snapshot should be written for the canary")
+	}
+
+	if err := env.reconciler.AbortRollout(ecr); err != nil {
+		t.Fatalf("CacheReconciler.AbortRollout() error = %v", err)
+	}
+	if got := env.clusterVersion("10.0.0.1:5000"); got != oldVersion {
+		t.Errorf("CacheReconciler.AbortRollout() got version %q for canary, want %q", got, oldVersion)
+	}
+}
+
+func TestCacheReconciler_SkipRollout(t *testing.T) {
+	req := types.NamespacedName{Name: "ecr", Namespace: "default"}
+
+	t.Run("Writes the snapshot for all the proxies and completes the rollout", func(t *testing.T) {
+		env := newRolloutTestEnv("10.0.0.1:5000", "10.0.0.2:5000")
+		env.reconciler.Reconcile(req, testRolloutRevision("old", nil).Spec.EnvoyResources, "node1", "old", false)
+		oldVersion := env.clusterVersion("")
+
+		ecr := testRolloutRevision("new", &marin3rv1alpha1.RolloutPolicy{})
+		if _, err := env.reconciler.SkipRollout(req, ecr, time.Now()); err != nil {
+			t.Fatalf("CacheReconciler.SkipRollout() error = %v", err)
+		}
+		for _, address := range []string{"", "10.0.0.1:5000", "10.0.0.2:5000"} {
+			if env.clusterVersion(address) == oldVersion {
+				t.Errorf("CacheReconciler.SkipRollout() the snapshot should be written for proxy %q", address)
+			}
+		}
+		if ecr.Status.Rollout == nil || ecr.Status.Rollout.Phase != marin3rv1alpha1.RolloutCompletedPhase || len(ecr.Status.Rollout.Canaries) != 0 {
+			t.Errorf("CacheReconciler.SkipRollout() rollout = %v, want completed without canaries", ecr.Status.Rollout)
+		}
+	})
+
+	t.Run("Completes a rollout in progress", func(t *testing.T) {
+		env := newRolloutTestEnv("10.0.0.1:5000", "10.0.0.2:5000")
+		env.reconciler.Reconcile(req, testRolloutRevision("old", nil).Spec.EnvoyResources, "node1", "old", false)
+		oldVersion := env.clusterVersion("")
+
+		ecr := testRolloutRevision("new", &marin3rv1alpha1.RolloutPolicy{})
+		ecr.Status.Rollout = &marin3rv1alpha1.RolloutStatus{
+			Phase:    marin3rv1alpha1.RolloutCanaryPhase,
+			Canaries: []string{"10.0.0.1:5000"},
+		}
+		if _, err := env.reconciler.SkipRollout(req, ecr, time.Now()); err != nil {
+			t.Fatalf("CacheReconciler.SkipRollout() error = %v", err)
+		}
+		if env.clusterVersion("10.0.0.2:5000") == oldVersion {
+			t.Errorf("CacheReconciler.SkipRollout() the snapshot should be written for all the proxies")
+		}
+		if ecr.Status.Rollout.Phase != marin3rv1alpha1.RolloutCompletedPhase {
+			t.Errorf("CacheReconciler.SkipRollout() rollout phase = %v, want %v", ecr.Status.Rollout.Phase, marin3rv1alpha1.RolloutCompletedPhase)
+		}
+	})
+}
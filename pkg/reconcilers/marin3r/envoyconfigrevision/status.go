@@ -5,7 +5,7 @@ import (
 
 	marin3rv1alpha1 "github.com/3scale/marin3r/apis/marin3r/v1alpha1"
 	xdss "github.com/3scale/marin3r/pkg/discoveryservice/xdss"
-	"github.com/3scale/marin3r/pkg/envoy"
+	"github.com/3scale/marin3r/pkg/discoveryservice/xdss/stats"
 	"github.com/operator-framework/operator-lib/status"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/equality"
@@ -13,8 +13,9 @@ import (
 	"k8s.io/utils/pointer"
 )
 
-// IsStatusReconciled calculates the status of the resource
-func IsStatusReconciled(ecr *marin3rv1alpha1.EnvoyConfigRevision, xdssCache xdss.Cache) bool {
+// IsStatusReconciled calculates the status of the resource. The status of the
+// connected proxies is only calculated if xdssStats is not nil.
+func IsStatusReconciled(ecr *marin3rv1alpha1.EnvoyConfigRevision, xdssCache xdss.Cache, xdssStats *stats.Stats) bool {
 
 	ok := true
 
@@ -32,6 +33,15 @@ func IsStatusReconciled(ecr *marin3rv1alpha1.EnvoyConfigRevision, xdssCache xdss
 		}
 	}
 
+	// The DanglingReferencesCondition is left untouched when it cannot be calculated
+	if cond := calculateDanglingReferencesCondition(ecr, xdssCache); cond != nil {
+		current := ecr.Status.Conditions.GetCondition(marin3rv1alpha1.DanglingReferencesCondition)
+		if current == nil || current.Status != cond.Status || current.Reason != cond.Reason || current.Message != cond.Message {
+			ecr.Status.Conditions.SetCondition(*cond)
+			ok = false
+		}
+	}
+
 	// Set status.published and status.lastPublishedAt fields
 	if ecr.Status.Conditions.IsTrueFor(marin3rv1alpha1.RevisionPublishedCondition) && !ecr.Status.IsPublished() {
 		ecr.Status.Published = pointer.BoolPtr(true)
@@ -42,6 +52,12 @@ func IsStatusReconciled(ecr *marin3rv1alpha1.EnvoyConfigRevision, xdssCache xdss
 		ok = false
 	}
 
+	// status.rollout is only kept while the revision is published
+	if !ecr.Status.Conditions.IsTrueFor(marin3rv1alpha1.RevisionPublishedCondition) && ecr.Status.Rollout != nil {
+		ecr.Status.Rollout = nil
+		ok = false
+	}
+
 	// Set status.tainted field
 	if ecr.Status.Conditions.IsTrueFor(marin3rv1alpha1.RevisionTaintedCondition) && !ecr.Status.IsTainted() {
 		ecr.Status.Tainted = pointer.BoolPtr(true)
@@ -51,14 +67,33 @@ func IsStatusReconciled(ecr *marin3rv1alpha1.EnvoyConfigRevision, xdssCache xdss
 		ok = false
 	}
 
+	// Set status.proxies field
+	if xdssStats != nil {
+		proxies := calculateProxiesStatus(ecr, xdssCache, xdssStats)
+		if !equality.Semantic.DeepEqual(ecr.Status.Proxies, proxies) {
+			ecr.Status.Proxies = proxies
+			ok = false
+		}
+	}
+
 	return ok
 }
 
 func calculateResourcesInSyncCondition(ecr *marin3rv1alpha1.EnvoyConfigRevision, xdssCache xdss.Cache) *status.Condition {
 
 	if ecr.Status.Conditions.IsTrueFor(marin3rv1alpha1.RevisionPublishedCondition) {
+		// Resources are only written for the canaries while a rollout is in progress
+		if ecr.Status.Rollout != nil && ecr.Status.Rollout.Phase == marin3rv1alpha1.RolloutCanaryPhase {
+			return &status.Condition{
+				Type:    marin3rv1alpha1.ResourcesInSyncCondition,
+				Reason:  "RolloutInProgress",
+				Status:  corev1.ConditionFalse,
+				Message: fmt.Sprintf("EnvoyConfigRevision resources synced with xDS server cache for canaries %v", ecr.Status.Rollout.Canaries),
+			}
+		}
+
 		// Check what is currently written in the xds server cache
-		snap, err := xdssCache.GetSnapshot(ecr.Spec.NodeID)
+		snap, err := xdssCache.GetSnapshot(xdss.NodeKey(ecr.GetNamespace(), ecr.Spec.NodeID))
 		// OutOfSync if NodeID not found or resources version different that expected
 		if err != nil {
 			return &status.Condition{
@@ -69,12 +104,14 @@ func calculateResourcesInSyncCondition(ecr *marin3rv1alpha1.EnvoyConfigRevision,
 			}
 		}
 
-		if snap.GetVersion(envoy.Cluster) != ecr.Spec.Version {
-			return &status.Condition{
-				Type:    marin3rv1alpha1.ResourcesInSyncCondition,
-				Reason:  "SnapshotVersionDiffers",
-				Status:  corev1.ConditionFalse,
-				Message: fmt.Sprintf("The snapshot for nodeID %q holds resources version %q", ecr.Spec.NodeID, snap.GetVersion(envoy.Cluster)),
+		for _, rType := range resourceTypes {
+			if !ecr.Spec.EnvoyResources.MatchesVersion(rType, snap.GetVersion(rType)) {
+				return &status.Condition{
+					Type:    marin3rv1alpha1.ResourcesInSyncCondition,
+					Reason:  "SnapshotVersionDiffers",
+					Status:  corev1.ConditionFalse,
+					Message: fmt.Sprintf("The snapshot for nodeID %q holds %s resources version %q", ecr.Spec.NodeID, rType, snap.GetVersion(rType)),
+				}
 			}
 		}
 
@@ -88,3 +125,98 @@ func calculateResourcesInSyncCondition(ecr *marin3rv1alpha1.EnvoyConfigRevision,
 
 	return nil
 }
+
+// calculateDanglingReferencesCondition checks the references between the resources of the revision.
+// This can only be done when the publication of the revision has been blocked or when the revision
+// resources are in the xDS server cache, otherwise nil is returned.
+func calculateDanglingReferencesCondition(ecr *marin3rv1alpha1.EnvoyConfigRevision, xdssCache xdss.Cache) *status.Condition {
+
+	if taint := ecr.Status.Conditions.GetCondition(marin3rv1alpha1.RevisionTaintedCondition); taint != nil &&
+		taint.IsTrue() && taint.Reason == DanglingReferencesReason {
+		return &status.Condition{
+			Type:    marin3rv1alpha1.DanglingReferencesCondition,
+			Reason:  "PublicationBlocked",
+			Status:  corev1.ConditionTrue,
+			Message: taint.Message,
+		}
+	}
+
+	if !ecr.Status.Conditions.IsTrueFor(marin3rv1alpha1.ResourcesInSyncCondition) {
+		return nil
+	}
+
+	snap, err := xdssCache.GetSnapshot(xdss.NodeKey(ecr.GetNamespace(), ecr.Spec.NodeID))
+	if err != nil {
+		return nil
+	}
+
+	if err := snap.Consistent(); err != nil {
+		return &status.Condition{
+			Type:    marin3rv1alpha1.DanglingReferencesCondition,
+			Reason:  "DanglingReferencesFound",
+			Status:  corev1.ConditionTrue,
+			Message: err.Error(),
+		}
+	}
+
+	return &status.Condition{
+		Type:    marin3rv1alpha1.DanglingReferencesCondition,
+		Reason:  "AllReferencesResolved",
+		Status:  corev1.ConditionFalse,
+		Message: "All the references between resources have been resolved",
+	}
+}
+
+func calculateProxiesStatus(ecr *marin3rv1alpha1.EnvoyConfigRevision, xdssCache xdss.Cache, xdssStats *stats.Stats) *marin3rv1alpha1.ProxiesStatus {
+
+	if !ecr.Status.Conditions.IsTrueFor(marin3rv1alpha1.RevisionPublishedCondition) {
+		return nil
+	}
+
+	snap, err := xdssCache.GetSnapshot(xdss.NodeKey(ecr.GetNamespace(), ecr.Spec.NodeID))
+	if err != nil {
+		snap = nil
+	}
+
+	ps := &marin3rv1alpha1.ProxiesStatus{}
+	for _, proxy := range xdssStats.GetProxies(xdss.NodeKey(ecr.GetNamespace(), ecr.Spec.NodeID)) {
+		detail := marin3rv1alpha1.ProxyStatus{
+			Address: proxy.Address,
+			Streams: int32(proxy.Streams),
+			Synced:  isProxySynced(proxy, snap),
+		}
+		if len(proxy.AckedVersions) > 0 {
+			detail.AckedVersions = make(map[string]string, len(proxy.AckedVersions))
+			for rType, version := range proxy.AckedVersions {
+				detail.AckedVersions[string(rType)] = version
+			}
+		}
+		if proxy.LastNack != nil {
+			detail.LastNackedVersion = proxy.LastNack.Version
+			detail.LastNackMessage = proxy.LastNack.Message
+			ps.Failing++
+		}
+		if detail.Synced {
+			ps.Synced++
+		}
+		ps.Connected++
+		ps.Details = append(ps.Details, detail)
+	}
+
+	return ps
+}
+
+// isProxySynced returns true if the proxy has acknowledged the versions currently
+// held in the snapshot for all the resource types it has requested so far and has
+// no pending rejections.
+func isProxySynced(proxy stats.Proxy, snap xdss.Snapshot) bool {
+	if snap == nil || proxy.LastNack != nil || len(proxy.AckedVersions) == 0 {
+		return false
+	}
+	for rType, version := range proxy.AckedVersions {
+		if snap.GetVersion(rType) != version {
+			return false
+		}
+	}
+	return true
+}
@@ -1,15 +1,19 @@
 package reconcilers
 
 import (
+	"fmt"
 	"testing"
 
 	marin3rv1alpha1 "github.com/3scale/marin3r/apis/marin3r/v1alpha1"
 	xdss "github.com/3scale/marin3r/pkg/discoveryservice/xdss"
+	"github.com/3scale/marin3r/pkg/discoveryservice/xdss/stats"
 	xdss_v3 "github.com/3scale/marin3r/pkg/discoveryservice/xdss/v3"
+	"github.com/3scale/marin3r/pkg/envoy"
 	cache_types "github.com/envoyproxy/go-control-plane/pkg/cache/types"
 	cache_v3 "github.com/envoyproxy/go-control-plane/pkg/cache/v3"
 	"github.com/operator-framework/operator-lib/status"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/utils/pointer"
 )
@@ -30,10 +34,39 @@ func testCacheGenerator(nodeID, version string) func() xdss.Cache {
 	}
 }
 
+// testCacheGeneratorForResources returns a cache holding a snapshot
+// generated from the given resources
+func testCacheGeneratorForResources(nodeID string, resources *marin3rv1alpha1.EnvoyResources) func() xdss.Cache {
+	return func() xdss.Cache {
+		cache := xdss_v3.NewCache(cache_v3.NewSnapshotCache(true, cache_v3.IDHash{}, nil))
+		snap := cache.NewSnapshot("")
+		for _, rType := range resourceTypes {
+			snap.SetVersion(rType, resources.VersionFor(rType))
+		}
+		snap.SetVersion(envoy.Secret, resources.VersionFor(envoy.Secret)+"-xxxx")
+		cache.SetSnapshot(nodeID, snap)
+		return cache
+	}
+}
+
+func testStatsGenerator(nodeID string, versions ...string) func() *stats.Stats {
+	return func() *stats.Stats {
+		s := stats.New()
+		for i, version := range versions {
+			s.OpenStream(int64(i), fmt.Sprintf("10.0.0.%d:5000", i))
+			s.ReportRequest(int64(i), nodeID, envoy.Cluster, version)
+		}
+		return s
+	}
+}
+
 func TestIsStatusReconciled(t *testing.T) {
+	emptyVersion := (&marin3rv1alpha1.EnvoyResources{}).VersionFor(envoy.Cluster)
+
 	type args struct {
 		envoyConfigRevisionFactory func() *marin3rv1alpha1.EnvoyConfigRevision
 		xdssCacheFactory           func() xdss.Cache
+		xdssStatsFactory           func() *stats.Stats
 	}
 	tests := []struct {
 		name string
@@ -83,11 +116,17 @@ func TestIsStatusReconciled(t *testing.T) {
 									Reason:  "EnvoyConficRevisionResourcesSynced",
 									Message: "EnvoyConfigRevision resources successfully synced with xDS server cache",
 								},
+								{
+									Type:    marin3rv1alpha1.DanglingReferencesCondition,
+									Status:  corev1.ConditionFalse,
+									Reason:  "AllReferencesResolved",
+									Message: "All the references between resources have been resolved",
+								},
 							},
 						},
 					}
 				},
-				xdssCacheFactory: testCacheGenerator("test", "xxxx"),
+				xdssCacheFactory: testCacheGeneratorForResources("test", &marin3rv1alpha1.EnvoyResources{}),
 			},
 			want: true,
 		},
@@ -177,11 +216,95 @@ func TestIsStatusReconciled(t *testing.T) {
 			},
 			want: true,
 		},
+		{
+			name: "Revision published, proxies status needs update",
+			args: args{
+				envoyConfigRevisionFactory: func() *marin3rv1alpha1.EnvoyConfigRevision {
+					return &marin3rv1alpha1.EnvoyConfigRevision{
+						Spec: marin3rv1alpha1.EnvoyConfigRevisionSpec{
+							Version: "xxxx",
+							NodeID:  "test",
+						},
+						Status: marin3rv1alpha1.EnvoyConfigRevisionStatus{
+							Published:       pointer.BoolPtr(true),
+							LastPublishedAt: func(t metav1.Time) *metav1.Time { return &t }(metav1.Now()),
+							Conditions: status.Conditions{
+								{
+									Type:   marin3rv1alpha1.RevisionPublishedCondition,
+									Status: corev1.ConditionTrue,
+								},
+								{
+									Type:    marin3rv1alpha1.ResourcesInSyncCondition,
+									Status:  corev1.ConditionTrue,
+									Reason:  "EnvoyConficRevisionResourcesSynced",
+									Message: "EnvoyConfigRevision resources successfully synced with xDS server cache",
+								},
+							},
+						},
+					}
+				},
+				xdssCacheFactory: testCacheGenerator("test", "xxxx"),
+				xdssStatsFactory: testStatsGenerator("test", "xxxx"),
+			},
+			want: false,
+		},
+		{
+			name: "Revision published, proxies status already up to date",
+			args: args{
+				envoyConfigRevisionFactory: func() *marin3rv1alpha1.EnvoyConfigRevision {
+					return &marin3rv1alpha1.EnvoyConfigRevision{
+						Spec: marin3rv1alpha1.EnvoyConfigRevisionSpec{
+							Version: "xxxx",
+							NodeID:  "test",
+						},
+						Status: marin3rv1alpha1.EnvoyConfigRevisionStatus{
+							Published:       pointer.BoolPtr(true),
+							LastPublishedAt: func(t metav1.Time) *metav1.Time { return &t }(metav1.Now()),
+							Proxies: &marin3rv1alpha1.ProxiesStatus{
+								Connected: 1,
+								Synced:    1,
+								Details: []marin3rv1alpha1.ProxyStatus{{
+									Address:       "10.0.0.0:5000",
+									Streams:       1,
+									Synced:        true,
+									AckedVersions: map[string]string{"Cluster": emptyVersion},
+								}},
+							},
+							Conditions: status.Conditions{
+								{
+									Type:   marin3rv1alpha1.RevisionPublishedCondition,
+									Status: corev1.ConditionTrue,
+								},
+								{
+									Type:    marin3rv1alpha1.ResourcesInSyncCondition,
+									Status:  corev1.ConditionTrue,
+									Reason:  "EnvoyConficRevisionResourcesSynced",
+									Message: "EnvoyConfigRevision resources successfully synced with xDS server cache",
+								},
+								{
+									Type:    marin3rv1alpha1.DanglingReferencesCondition,
+									Status:  corev1.ConditionFalse,
+									Reason:  "AllReferencesResolved",
+									Message: "All the references between resources have been resolved",
+								},
+							},
+						},
+					}
+				},
+				xdssCacheFactory: testCacheGeneratorForResources("test", &marin3rv1alpha1.EnvoyResources{}),
+				xdssStatsFactory: testStatsGenerator("test", emptyVersion),
+			},
+			want: true,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			ecr := tt.args.envoyConfigRevisionFactory()
-			if got := IsStatusReconciled(ecr, tt.args.xdssCacheFactory()); got != tt.want {
+			var xdssStats *stats.Stats
+			if tt.args.xdssStatsFactory != nil {
+				xdssStats = tt.args.xdssStatsFactory()
+			}
+			if got := IsStatusReconciled(ecr, tt.args.xdssCacheFactory(), xdssStats); got != tt.want {
 				t.Errorf("IsStatusReconciled() = %v, want %v", got, tt.want)
 			}
 		})
@@ -214,7 +337,7 @@ func Test_calculateResourcesInSyncCondition(t *testing.T) {
 						},
 					}
 				},
-				xdssCacheFactory: testCacheGenerator("test", "xxxx"),
+				xdssCacheFactory: testCacheGeneratorForResources("test", &marin3rv1alpha1.EnvoyResources{}),
 			},
 			want: corev1.ConditionTrue,
 		},
@@ -257,7 +380,9 @@ func Test_calculateResourcesInSyncCondition(t *testing.T) {
 						},
 					}
 				},
-				xdssCacheFactory: testCacheGenerator("test", "zzzz"),
+				xdssCacheFactory: testCacheGeneratorForResources("test", &marin3rv1alpha1.EnvoyResources{
+					Clusters: []marin3rv1alpha1.EnvoyResource{{Name: "cluster", Value: "{\"name\": \"cluster\"}"}},
+				}),
 			},
 			want: corev1.ConditionFalse,
 		},
@@ -270,3 +395,175 @@ func Test_calculateResourcesInSyncCondition(t *testing.T) {
 		})
 	}
 }
+
+func Test_calculateProxiesStatus(t *testing.T) {
+	published := func() *marin3rv1alpha1.EnvoyConfigRevision {
+		return &marin3rv1alpha1.EnvoyConfigRevision{
+			Spec: marin3rv1alpha1.EnvoyConfigRevisionSpec{Version: "xxxx", NodeID: "test"},
+			Status: marin3rv1alpha1.EnvoyConfigRevisionStatus{
+				Conditions: status.Conditions{
+					{Type: marin3rv1alpha1.RevisionPublishedCondition, Status: corev1.ConditionTrue},
+				},
+			},
+		}
+	}
+	type args struct {
+		envoyConfigRevisionFactory func() *marin3rv1alpha1.EnvoyConfigRevision
+		xdssCacheFactory           func() xdss.Cache
+		xdssStatsFactory           func() *stats.Stats
+	}
+	tests := []struct {
+		name string
+		args args
+		want *marin3rv1alpha1.ProxiesStatus
+	}{
+		{
+			name: "Returns nil if the revision is not published",
+			args: args{
+				envoyConfigRevisionFactory: func() *marin3rv1alpha1.EnvoyConfigRevision {
+					return &marin3rv1alpha1.EnvoyConfigRevision{
+						Spec: marin3rv1alpha1.EnvoyConfigRevisionSpec{Version: "xxxx", NodeID: "test"},
+					}
+				},
+				xdssCacheFactory: testCacheGenerator("test", "xxxx"),
+				xdssStatsFactory: testStatsGenerator("test", "xxxx"),
+			},
+			want: nil,
+		},
+		{
+			name: "Counts synced and out of sync proxies",
+			args: args{
+				envoyConfigRevisionFactory: published,
+				xdssCacheFactory:           testCacheGenerator("test", "xxxx"),
+				xdssStatsFactory:           testStatsGenerator("test", "xxxx", "zzzz"),
+			},
+			want: &marin3rv1alpha1.ProxiesStatus{
+				Connected: 2,
+				Synced:    1,
+				Details: []marin3rv1alpha1.ProxyStatus{
+					{Address: "10.0.0.0:5000", Streams: 1, Synced: true, AckedVersions: map[string]string{"Cluster": "xxxx"}},
+					{Address: "10.0.0.1:5000", Streams: 1, Synced: false, AckedVersions: map[string]string{"Cluster": "zzzz"}},
+				},
+			},
+		},
+		{
+			name: "Counts failing proxies",
+			args: args{
+				envoyConfigRevisionFactory: published,
+				xdssCacheFactory:           testCacheGenerator("test", "xxxx"),
+				xdssStatsFactory: func() *stats.Stats {
+					s := testStatsGenerator("test", "zzzz")()
+					s.ReportNACK(0, "test", envoy.Cluster, "xxxx", "error")
+					return s
+				},
+			},
+			want: &marin3rv1alpha1.ProxiesStatus{
+				Connected: 1,
+				Failing:   1,
+				Details: []marin3rv1alpha1.ProxyStatus{
+					{
+						Address:           "10.0.0.0:5000",
+						Streams:           1,
+						Synced:            false,
+						AckedVersions:     map[string]string{"Cluster": "zzzz"},
+						LastNackedVersion: "xxxx",
+						LastNackMessage:   "error",
+					},
+				},
+			},
+		},
+		{
+			name: "Ignores proxies of other nodeIDs",
+			args: args{
+				envoyConfigRevisionFactory: published,
+				xdssCacheFactory:           testCacheGenerator("test", "xxxx"),
+				xdssStatsFactory:           testStatsGenerator("other", "xxxx"),
+			},
+			want: &marin3rv1alpha1.ProxiesStatus{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := calculateProxiesStatus(tt.args.envoyConfigRevisionFactory(), tt.args.xdssCacheFactory(), tt.args.xdssStatsFactory())
+			if !equality.Semantic.DeepEqual(got, tt.want) {
+				t.Errorf("calculateProxiesStatus() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_calculateDanglingReferencesCondition(t *testing.T) {
+	tests := []struct {
+		name                       string
+		envoyConfigRevisionFactory func() *marin3rv1alpha1.EnvoyConfigRevision
+		xdssCacheFactory           func() xdss.Cache
+		want                       *status.Condition
+	}{
+		{
+			name: "Returns a True condition if the publication has been blocked",
+			envoyConfigRevisionFactory: func() *marin3rv1alpha1.EnvoyConfigRevision {
+				return &marin3rv1alpha1.EnvoyConfigRevision{
+					Spec: marin3rv1alpha1.EnvoyConfigRevisionSpec{NodeID: "test"},
+					Status: marin3rv1alpha1.EnvoyConfigRevisionStatus{
+						Conditions: status.Conditions{{
+							Type:    marin3rv1alpha1.RevisionTaintedCondition,
+							Status:  corev1.ConditionTrue,
+							Reason:  DanglingReferencesReason,
+							Message: "dangling references found",
+						}},
+					},
+				}
+			},
+			xdssCacheFactory: testCacheGenerator("test", "xxxx"),
+			want: &status.Condition{
+				Type:    marin3rv1alpha1.DanglingReferencesCondition,
+				Status:  corev1.ConditionTrue,
+				Reason:  "PublicationBlocked",
+				Message: "dangling references found",
+			},
+		},
+		{
+			name: "Returns a False condition if the resources in the cache have no dangling references",
+			envoyConfigRevisionFactory: func() *marin3rv1alpha1.EnvoyConfigRevision {
+				return &marin3rv1alpha1.EnvoyConfigRevision{
+					Spec: marin3rv1alpha1.EnvoyConfigRevisionSpec{NodeID: "test"},
+					Status: marin3rv1alpha1.EnvoyConfigRevisionStatus{
+						Conditions: status.Conditions{
+							{Type: marin3rv1alpha1.RevisionPublishedCondition, Status: corev1.ConditionTrue},
+							{Type: marin3rv1alpha1.ResourcesInSyncCondition, Status: corev1.ConditionTrue},
+						},
+					},
+				}
+			},
+			xdssCacheFactory: testCacheGenerator("test", "xxxx"),
+			want: &status.Condition{
+				Type:    marin3rv1alpha1.DanglingReferencesCondition,
+				Status:  corev1.ConditionFalse,
+				Reason:  "AllReferencesResolved",
+				Message: "All the references between resources have been resolved",
+			},
+		},
+		{
+			name: "Returns nil if the resources are not in the cache",
+			envoyConfigRevisionFactory: func() *marin3rv1alpha1.EnvoyConfigRevision {
+				return &marin3rv1alpha1.EnvoyConfigRevision{
+					Spec: marin3rv1alpha1.EnvoyConfigRevisionSpec{NodeID: "test"},
+					Status: marin3rv1alpha1.EnvoyConfigRevisionStatus{
+						Conditions: status.Conditions{
+							{Type: marin3rv1alpha1.RevisionPublishedCondition, Status: corev1.ConditionFalse},
+						},
+					},
+				}
+			},
+			xdssCacheFactory: testCacheGenerator("test", "xxxx"),
+			want:             nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := calculateDanglingReferencesCondition(tt.envoyConfigRevisionFactory(), tt.xdssCacheFactory()); !equality.Semantic.DeepEqual(got, tt.want) {
+				t.Errorf("calculateDanglingReferencesCondition() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
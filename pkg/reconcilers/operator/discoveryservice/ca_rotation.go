@@ -0,0 +1,408 @@
+package reconcilers
+
+import (
+	"bytes"
+	"context"
+	"crypto/x509"
+	"fmt"
+	"math"
+	"time"
+
+	operatorv1alpha1 "github.com/3scale/marin3r/apis/operator/v1alpha1"
+	"github.com/3scale/marin3r/pkg/util/pki"
+	"github.com/go-logr/logr"
+	"github.com/operator-framework/operator-lib/status"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+const (
+	// TrustBundlePropagationPeriod is the time both the current and the new root CA are
+	// trusted before the new one is put in use, so the updated trust bundle reaches the
+	// discovery service and the envoy bootstrap configs mounted in the Pods
+	TrustBundlePropagationPeriod time.Duration = 3 * time.Minute
+	// reissuanceCheckPeriod is the period at which the reissuance of the
+	// certificates signed by the previous root CA is checked
+	reissuanceCheckPeriod time.Duration = 30 * time.Second
+)
+
+// Clock knows how to get the current time.
+// It can be used to fake out timing for testing.
+type Clock interface {
+	Now() time.Time
+}
+
+type realClock struct{}
+
+// Now returns the current time
+func (realClock) Now() time.Time { return time.Now() }
+
+// CARotationReconciler is a struct with methods to rotate the
+// root CA of a DiscoveryService without downtime
+type CARotationReconciler struct {
+	ctx    context.Context
+	logger logr.Logger
+	client client.Client
+	scheme *runtime.Scheme
+	ds     *operatorv1alpha1.DiscoveryService
+	clock  Clock
+}
+
+// NewCARotationReconciler returns a new CARotationReconciler
+func NewCARotationReconciler(ctx context.Context, logger logr.Logger, client client.Client,
+	s *runtime.Scheme, ds *operatorv1alpha1.DiscoveryService) CARotationReconciler {
+
+	return CARotationReconciler{ctx, logger, client, s, ds, realClock{}}
+}
+
+// StagedCAName returns the name of the DiscoveryServiceCertificate, and its Secret,
+// that holds the new root CA while it is being rotated
+func StagedCAName(caName string) string {
+	return fmt.Sprintf("%s-next", caName)
+}
+
+// Reconcile keeps the trust bundle of the root CA, held by the DiscoveryServiceCertificate
+// with the given key, and progresses its rotation. A rotation goes through the following phases:
+//   - Staging: a new root CA is issued.
+//   - Distributing: the trust bundle holds both root CAs until it reaches all its consumers.
+//   - Reissuing: the new root CA replaces the current one, which makes all the certificates
+//     it signed to be reissued. The previous root CA is trusted until they all are.
+//   - Completed: the trust bundle holds only the new root CA.
+func (r *CARotationReconciler) Reconcile(ca types.NamespacedName) (ctrl.Result, error) {
+
+	caSecret, caCert, err := r.getCertificate(ca)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			// The root CA has not been issued yet
+			return ctrl.Result{}, nil
+		}
+		return ctrl.Result{}, err
+	}
+
+	var phase operatorv1alpha1.CARotationPhase
+	if r.ds.Status.CARotation != nil {
+		phase = r.ds.Status.CARotation.Phase
+	}
+
+	switch phase {
+
+	case operatorv1alpha1.CARotationStagingPhase:
+		stagedSecret, _, err := r.getCertificate(types.NamespacedName{Name: StagedCAName(ca.Name), Namespace: ca.Namespace})
+		if err != nil {
+			if errors.IsNotFound(err) {
+				// Wait until the new root CA is issued
+				return ctrl.Result{}, r.reconcileStagedCA(ca)
+			}
+			return ctrl.Result{}, err
+		}
+		if err := r.reconcileTrustBundle(ca, caSecret.Data[corev1.TLSCertKey], stagedSecret.Data[corev1.TLSCertKey]); err != nil {
+			return ctrl.Result{}, err
+		}
+		if err := r.setPhase(operatorv1alpha1.CARotationDistributingPhase); err != nil {
+			return ctrl.Result{}, err
+		}
+		return ctrl.Result{RequeueAfter: TrustBundlePropagationPeriod}, nil
+
+	case operatorv1alpha1.CARotationDistributingPhase:
+		stagedSecret, stagedCert, err := r.getCertificate(types.NamespacedName{Name: StagedCAName(ca.Name), Namespace: ca.Namespace})
+		if err != nil {
+			if errors.IsNotFound(err) {
+				// The new root CA is gone, issue it again
+				return ctrl.Result{}, r.setPhase(operatorv1alpha1.CARotationStagingPhase)
+			}
+			return ctrl.Result{}, err
+		}
+
+		// Skip to the next phase if the new root CA was already put in use
+		if !bytes.Equal(caSecret.Data[corev1.TLSCertKey], stagedSecret.Data[corev1.TLSCertKey]) {
+			if err := r.reconcileTrustBundle(ca, caSecret.Data[corev1.TLSCertKey], stagedSecret.Data[corev1.TLSCertKey]); err != nil {
+				return ctrl.Result{}, err
+			}
+			if wait := r.ds.Status.CARotation.LastTransitionTime.Add(TrustBundlePropagationPeriod).Sub(r.clock.Now()); wait > 0 {
+				return ctrl.Result{RequeueAfter: wait}, nil
+			}
+			if err := r.promote(ca, caSecret, caCert, stagedSecret, stagedCert); err != nil {
+				return ctrl.Result{}, err
+			}
+		}
+
+		if err := r.setPhase(operatorv1alpha1.CARotationReissuingPhase); err != nil {
+			return ctrl.Result{}, err
+		}
+		return ctrl.Result{RequeueAfter: reissuanceCheckPeriod}, nil
+
+	case operatorv1alpha1.CARotationReissuingPhase:
+		if err := r.deleteStagedCA(ca); err != nil {
+			return ctrl.Result{}, err
+		}
+		ok, err := r.isReissuanceCompleted(ca, caCert)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		if !ok {
+			return ctrl.Result{RequeueAfter: reissuanceCheckPeriod}, nil
+		}
+		// Stop trusting the previous root CA
+		if err := r.reconcileTrustBundle(ca, caSecret.Data[corev1.TLSCertKey]); err != nil {
+			return ctrl.Result{}, err
+		}
+		return ctrl.Result{}, r.setPhase(operatorv1alpha1.CARotationCompletedPhase)
+
+	default:
+		if err := r.reconcileTrustBundle(ca, caSecret.Data[corev1.TLSCertKey]); err != nil {
+			return ctrl.Result{}, err
+		}
+		if !r.isRotationDue(caCert) {
+			return ctrl.Result{}, nil
+		}
+		if err := r.reconcileStagedCA(ca); err != nil {
+			return ctrl.Result{}, err
+		}
+		return ctrl.Result{}, r.setPhase(operatorv1alpha1.CARotationStagingPhase)
+	}
+}
+
+// isRotationDue returns true if the rotation of the root CA has been requested
+// after it was issued or if 20% or less of its duration is left
+func (r *CARotationReconciler) isRotationDue(caCert *x509.Certificate) bool {
+
+	if value, ok := r.ds.GetAnnotations()[operatorv1alpha1.CARotationRequestedAtAnnotation]; ok {
+		requestedAt, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			r.logger.Error(err, "invalid annotation", "Annotation", operatorv1alpha1.CARotationRequestedAtAnnotation)
+		} else if requestedAt.After(caCert.NotBefore) {
+			r.logger.Info("root CA rotation requested", "RequestedAt", value)
+			return true
+		}
+	}
+
+	duration := caCert.NotAfter.Sub(caCert.NotBefore)
+	renewBefore := time.Duration(int64(math.Floor(float64(duration) * 0.20)))
+	if caCert.NotAfter.Sub(r.clock.Now()) < renewBefore {
+		r.logger.Info("root CA is close to expire", "NotAfter", caCert.NotAfter.String())
+		return true
+	}
+
+	return false
+}
+
+// reconcileStagedCA creates the DiscoveryServiceCertificate that issues the new
+// root CA, with the same spec as the DiscoveryServiceCertificate of the current one
+func (r *CARotationReconciler) reconcileStagedCA(ca types.NamespacedName) error {
+
+	staged := &operatorv1alpha1.DiscoveryServiceCertificate{}
+	key := types.NamespacedName{Name: StagedCAName(ca.Name), Namespace: ca.Namespace}
+	if err := r.client.Get(r.ctx, key, staged); err == nil || !errors.IsNotFound(err) {
+		return err
+	}
+
+	current := &operatorv1alpha1.DiscoveryServiceCertificate{}
+	if err := r.client.Get(r.ctx, ca, current); err != nil {
+		return err
+	}
+
+	staged = &operatorv1alpha1.DiscoveryServiceCertificate{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      key.Name,
+			Namespace: key.Namespace,
+			Labels:    current.GetLabels(),
+		},
+		Spec: *current.Spec.DeepCopy(),
+	}
+	staged.Spec.SecretRef = corev1.SecretReference{Name: key.Name, Namespace: key.Namespace}
+	if err := controllerutil.SetControllerReference(r.ds, staged, r.scheme); err != nil {
+		return err
+	}
+	if err := r.client.Create(r.ctx, staged); err != nil {
+		return err
+	}
+	r.logger.Info("created new root CA certificate", "Name", key.Name)
+	return nil
+}
+
+// deleteStagedCA deletes the DiscoveryServiceCertificate of the new
+// root CA once it has replaced the current one
+func (r *CARotationReconciler) deleteStagedCA(ca types.NamespacedName) error {
+
+	staged := &operatorv1alpha1.DiscoveryServiceCertificate{}
+	key := types.NamespacedName{Name: StagedCAName(ca.Name), Namespace: ca.Namespace}
+	if err := r.client.Get(r.ctx, key, staged); err != nil {
+		if errors.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+	if err := r.client.Delete(r.ctx, staged); err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+	return nil
+}
+
+// promote replaces the current root CA with the new one. The revocation list of
+// the current root CA is signed again with the new one so the certificates it
+// revoked are still rejected while the previous root CA is trusted.
+func (r *CARotationReconciler) promote(ca types.NamespacedName, caSecret *corev1.Secret, caCert *x509.Certificate,
+	stagedSecret *corev1.Secret, stagedCert *x509.Certificate) error {
+
+	stagedKey, err := pki.DecodePrivateKeyBytes(stagedSecret.Data[corev1.TLSPrivateKeyKey])
+	if err != nil {
+		return err
+	}
+
+	crlSecret := &corev1.Secret{}
+	crlKey := types.NamespacedName{Name: operatorv1alpha1.RevocationListSecretName(ca.Name), Namespace: ca.Namespace}
+	if err := r.client.Get(r.ctx, crlKey, crlSecret); err != nil {
+		if !errors.IsNotFound(err) {
+			return err
+		}
+	} else if list, err := pki.LoadRevocationList(crlSecret.Data[operatorv1alpha1.RevocationListSecretKey], caCert); err == nil {
+		crl, err := pki.GenerateRevocationList(stagedCert, stagedKey, list.TBSCertList.RevokedCertificates, time.Until(stagedCert.NotAfter))
+		if err != nil {
+			return err
+		}
+		crlSecret.Data = map[string][]byte{operatorv1alpha1.RevocationListSecretKey: crl}
+		if err := r.client.Update(r.ctx, crlSecret); err != nil {
+			return err
+		}
+	}
+
+	caSecret.Data[corev1.TLSCertKey] = stagedSecret.Data[corev1.TLSCertKey]
+	caSecret.Data[corev1.TLSPrivateKeyKey] = stagedSecret.Data[corev1.TLSPrivateKeyKey]
+	if err := r.client.Update(r.ctx, caSecret); err != nil {
+		return err
+	}
+
+	r.logger.Info("new root CA in use", "SerialNumber", stagedCert.SerialNumber.String())
+	return nil
+}
+
+// isReissuanceCompleted returns true if all the DiscoveryServiceCertificates
+// signed by the root CA have been reissued by the current root CA, and all
+// per-pod certificates issued by the previous one have expired
+func (r *CARotationReconciler) isReissuanceCompleted(ca types.NamespacedName, caCert *x509.Certificate) (bool, error) {
+
+	// The pod certificate signer picks up the new root CA when the kubelet updates the
+	// mounted Secret, so give it some margin before waiting for the per-pod certificates to expire
+	if r.ds.PodCertificatesEnabled() {
+		wait := r.ds.GetPodCertificateDuration() + TrustBundlePropagationPeriod
+		if r.clock.Now().Before(r.ds.Status.CARotation.LastTransitionTime.Add(wait)) {
+			return false, nil
+		}
+	}
+
+	list := &operatorv1alpha1.DiscoveryServiceCertificateList{}
+	if err := r.client.List(r.ctx, list); err != nil {
+		return false, err
+	}
+
+	for _, dsc := range list.Items {
+		if dsc.Spec.Signer.CASigned == nil {
+			continue
+		}
+		issuer := dsc.Spec.Signer.CASigned.SecretRef
+		if issuer.Name != ca.Name || issuer.Namespace != ca.Namespace {
+			continue
+		}
+		if !dsc.GetCertificateRenewalConfig().Enabled {
+			// These are never reissued, so they stop being trusted with the previous root CA
+			r.logger.Info("certificate renewal disabled, certificate won't be reissued", "Name", dsc.GetName(), "Namespace", dsc.GetNamespace())
+			continue
+		}
+
+		_, cert, err := r.getCertificate(types.NamespacedName{Name: dsc.Spec.SecretRef.Name, Namespace: dsc.GetNamespace()})
+		if err != nil {
+			if errors.IsNotFound(err) {
+				// Not issued yet, so it will be issued by the current root CA
+				continue
+			}
+			return false, err
+		}
+		if err := pki.Verify(cert, caCert); err != nil {
+			r.logger.V(1).Info("certificate not reissued yet", "Name", dsc.GetName(), "Namespace", dsc.GetNamespace())
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
+
+// reconcileTrustBundle keeps the Secret with the bundle
+// of trusted CA certificates in sync with the given certificates
+func (r *CARotationReconciler) reconcileTrustBundle(ca types.NamespacedName, certificates ...[]byte) error {
+
+	bundle := []byte{}
+	for _, cert := range certificates {
+		if bytes.Contains(bundle, cert) {
+			continue
+		}
+		bundle = append(bundle, cert...)
+	}
+
+	secret := &corev1.Secret{}
+	key := types.NamespacedName{Name: operatorv1alpha1.TrustBundleSecretName(ca.Name), Namespace: ca.Namespace}
+	if err := r.client.Get(r.ctx, key, secret); err != nil {
+		if !errors.IsNotFound(err) {
+			return err
+		}
+		secret = &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: key.Name, Namespace: key.Namespace},
+			Type:       corev1.SecretTypeOpaque,
+			Data:       map[string][]byte{operatorv1alpha1.TrustBundleSecretKey: bundle},
+		}
+		if err := controllerutil.SetControllerReference(r.ds, secret, r.scheme); err != nil {
+			return err
+		}
+		if err := r.client.Create(r.ctx, secret); err != nil {
+			return err
+		}
+		r.logger.Info("created trust bundle", "Name", key.Name)
+		return nil
+	}
+
+	if bytes.Equal(secret.Data[operatorv1alpha1.TrustBundleSecretKey], bundle) {
+		return nil
+	}
+	secret.Data = map[string][]byte{operatorv1alpha1.TrustBundleSecretKey: bundle}
+	if err := r.client.Update(r.ctx, secret); err != nil {
+		return err
+	}
+	r.logger.Info("updated trust bundle", "Name", key.Name, "Certificates", len(certificates))
+	return nil
+}
+
+// setPhase updates the phase of the rotation in the status of the DiscoveryService
+func (r *CARotationReconciler) setPhase(phase operatorv1alpha1.CARotationPhase) error {
+
+	r.ds.Status.CARotation = &operatorv1alpha1.CARotationStatus{
+		Phase:              phase,
+		LastTransitionTime: metav1.NewTime(r.clock.Now()),
+	}
+	if r.ds.Status.Conditions == nil {
+		r.ds.Status.Conditions = status.NewConditions()
+	}
+	if err := r.client.Status().Update(r.ctx, r.ds); err != nil {
+		return err
+	}
+	r.logger.Info("root CA rotation phase changed", "Phase", phase)
+	return nil
+}
+
+// getCertificate returns the Secret with the given key and the certificate it holds
+func (r *CARotationReconciler) getCertificate(key types.NamespacedName) (*corev1.Secret, *x509.Certificate, error) {
+
+	secret := &corev1.Secret{}
+	if err := r.client.Get(r.ctx, key, secret); err != nil {
+		return nil, nil, err
+	}
+	cert, err := pki.LoadX509Certificate(secret.Data[corev1.TLSCertKey])
+	if err != nil {
+		return nil, nil, err
+	}
+	return secret, cert, nil
+}
@@ -0,0 +1,274 @@
+package reconcilers
+
+import (
+	"bytes"
+	"context"
+	"crypto/x509/pkix"
+	"testing"
+	"time"
+
+	operatorv1alpha1 "github.com/3scale/marin3r/apis/operator/v1alpha1"
+	"github.com/3scale/marin3r/pkg/util/pki"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/utils/pointer"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+var s *runtime.Scheme = scheme.Scheme
+
+func init() {
+	s.AddKnownTypes(operatorv1alpha1.GroupVersion,
+		&operatorv1alpha1.DiscoveryService{},
+		&operatorv1alpha1.DiscoveryServiceCertificate{},
+		&operatorv1alpha1.DiscoveryServiceCertificateList{},
+	)
+}
+
+type testClock struct {
+	now time.Time
+}
+
+// Now returns the the current time
+func (tc *testClock) Now() time.Time { return tc.now }
+
+func testCertificateSecret(name string, crt, key []byte) *corev1.Secret {
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+		Type:       corev1.SecretTypeTLS,
+		Data:       map[string][]byte{corev1.TLSCertKey: crt, corev1.TLSPrivateKeyKey: key},
+	}
+}
+
+func TestCARotationReconciler_Reconcile(t *testing.T) {
+	clock := &testClock{now: time.Now()}
+
+	caPEM, caKeyPEM, err := pki.GenerateCertificate(nil, nil, "ca", 24*time.Hour, false, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ca, _ := pki.LoadX509Certificate(caPEM)
+	caKey, _ := pki.DecodePrivateKeyBytes(caKeyPEM)
+	serverPEM, serverKeyPEM, err := pki.GenerateCertificate(ca, caKey, "server", time.Hour, true, false, "localhost")
+	if err != nil {
+		t.Fatal(err)
+	}
+	revokedPEM, _, err := pki.GenerateCertificate(ca, caKey, "revoked", time.Hour, false, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	revoked, _ := pki.LoadX509Certificate(revokedPEM)
+	crl, err := pki.GenerateRevocationList(ca, caKey,
+		[]pkix.RevokedCertificate{{SerialNumber: revoked.SerialNumber, RevocationTime: clock.now}}, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ds := &operatorv1alpha1.DiscoveryService{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "ds",
+			Namespace:   "default",
+			Annotations: map[string]string{operatorv1alpha1.CARotationRequestedAtAnnotation: clock.now.Add(time.Minute).Format(time.RFC3339)},
+		},
+	}
+	caDSC := &operatorv1alpha1.DiscoveryServiceCertificate{
+		ObjectMeta: metav1.ObjectMeta{Name: "ca", Namespace: "default"},
+		Spec: operatorv1alpha1.DiscoveryServiceCertificateSpec{
+			CommonName: "ca",
+			IsCA:       pointer.BoolPtr(true),
+			ValidFor:   86400,
+			Signer:     operatorv1alpha1.DiscoveryServiceCertificateSigner{SelfSigned: &operatorv1alpha1.SelfSignedConfig{}},
+			SecretRef:  corev1.SecretReference{Name: "ca", Namespace: "default"},
+		},
+	}
+	serverDSC := &operatorv1alpha1.DiscoveryServiceCertificate{
+		ObjectMeta: metav1.ObjectMeta{Name: "server", Namespace: "default"},
+		Spec: operatorv1alpha1.DiscoveryServiceCertificateSpec{
+			CommonName: "server",
+			ValidFor:   3600,
+			Signer: operatorv1alpha1.DiscoveryServiceCertificateSigner{
+				CASigned: &operatorv1alpha1.CASignedConfig{SecretRef: corev1.SecretReference{Name: "ca", Namespace: "default"}},
+			},
+			SecretRef:                corev1.SecretReference{Name: "server", Namespace: "default"},
+			CertificateRenewalConfig: &operatorv1alpha1.CertificateRenewalConfig{Enabled: true},
+		},
+	}
+
+	cl := fake.NewFakeClientWithScheme(s, ds, caDSC, serverDSC,
+		testCertificateSecret("ca", caPEM, caKeyPEM),
+		testCertificateSecret("server", serverPEM, serverKeyPEM),
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "ca-crl", Namespace: "default"},
+			Data:       map[string][]byte{operatorv1alpha1.RevocationListSecretKey: crl},
+		},
+	)
+	caKeyName := types.NamespacedName{Name: "ca", Namespace: "default"}
+
+	reconcile := func(wantPhase operatorv1alpha1.CARotationPhase) ctrl.Result {
+		t.Helper()
+		ds := &operatorv1alpha1.DiscoveryService{}
+		if err := cl.Get(context.TODO(), types.NamespacedName{Name: "ds", Namespace: "default"}, ds); err != nil {
+			t.Fatal(err)
+		}
+		r := NewCARotationReconciler(context.TODO(), ctrl.Log.WithName("test"), cl, s, ds)
+		r.clock = clock
+		result, err := r.Reconcile(caKeyName)
+		if err != nil {
+			t.Fatalf("CARotationReconciler.Reconcile() error = %v", err)
+		}
+		if ds.Status.CARotation == nil || ds.Status.CARotation.Phase != wantPhase {
+			t.Fatalf("CARotationReconciler.Reconcile() status = %v, want phase %v", ds.Status.CARotation, wantPhase)
+		}
+		return result
+	}
+	getSecret := func(name string) *corev1.Secret {
+		t.Helper()
+		secret := &corev1.Secret{}
+		if err := cl.Get(context.TODO(), types.NamespacedName{Name: name, Namespace: "default"}, secret); err != nil {
+			t.Fatal(err)
+		}
+		return secret
+	}
+	wantBundle := func(certs ...[]byte) {
+		t.Helper()
+		if got := getSecret("ca-bundle").Data[operatorv1alpha1.TrustBundleSecretKey]; !bytes.Equal(got, bytes.Join(certs, nil)) {
+			t.Errorf("CARotationReconciler.Reconcile() trust bundle = %s, want %s", got, bytes.Join(certs, nil))
+		}
+	}
+
+	// A rotation is requested, so the new root CA is staged
+	reconcile(operatorv1alpha1.CARotationStagingPhase)
+	wantBundle(caPEM)
+	staged := &operatorv1alpha1.DiscoveryServiceCertificate{}
+	if err := cl.Get(context.TODO(), types.NamespacedName{Name: "ca-next", Namespace: "default"}, staged); err != nil {
+		t.Fatalf("CARotationReconciler.Reconcile() new root CA not staged: %v", err)
+	}
+	if staged.Spec.SecretRef.Name != "ca-next" || staged.Spec.CommonName != "ca" {
+		t.Errorf("CARotationReconciler.Reconcile() staged root CA spec = %v", staged.Spec)
+	}
+
+	// The new root CA is issued and added to the trust bundle
+	nextPEM, nextKeyPEM, err := pki.GenerateCertificate(nil, nil, "ca", 24*time.Hour, false, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	next, _ := pki.LoadX509Certificate(nextPEM)
+	nextKey, _ := pki.DecodePrivateKeyBytes(nextKeyPEM)
+	if err := cl.Create(context.TODO(), testCertificateSecret("ca-next", nextPEM, nextKeyPEM)); err != nil {
+		t.Fatal(err)
+	}
+	if result := reconcile(operatorv1alpha1.CARotationDistributingPhase); result.RequeueAfter != TrustBundlePropagationPeriod {
+		t.Errorf("CARotationReconciler.Reconcile() = %v, want to requeue after %v", result, TrustBundlePropagationPeriod)
+	}
+	wantBundle(caPEM, nextPEM)
+
+	// The new root CA is not used until the trust bundle propagates
+	clock.now = clock.now.Add(time.Minute)
+	if result := reconcile(operatorv1alpha1.CARotationDistributingPhase); result.RequeueAfter <= 0 {
+		t.Errorf("CARotationReconciler.Reconcile() = %v, want to requeue", result)
+	}
+	if !bytes.Equal(getSecret("ca").Data[corev1.TLSCertKey], caPEM) {
+		t.Errorf("CARotationReconciler.Reconcile() new root CA in use before the trust bundle propagates")
+	}
+
+	// The new root CA replaces the current one
+	clock.now = clock.now.Add(TrustBundlePropagationPeriod)
+	reconcile(operatorv1alpha1.CARotationReissuingPhase)
+	caSecret := getSecret("ca")
+	if !bytes.Equal(caSecret.Data[corev1.TLSCertKey], nextPEM) || !bytes.Equal(caSecret.Data[corev1.TLSPrivateKeyKey], nextKeyPEM) {
+		t.Errorf("CARotationReconciler.Reconcile() new root CA not in use")
+	}
+	list, err := pki.LoadRevocationList(getSecret("ca-crl").Data[operatorv1alpha1.RevocationListSecretKey], next)
+	if err != nil {
+		t.Fatalf("CARotationReconciler.Reconcile() revocation list not signed by the new root CA: %v", err)
+	}
+	if !pki.IsRevoked(list, revoked) {
+		t.Errorf("CARotationReconciler.Reconcile() revocation list lost the revoked certificates")
+	}
+	wantBundle(caPEM, nextPEM)
+
+	// The previous root CA is trusted until the server certificate is reissued
+	if result := reconcile(operatorv1alpha1.CARotationReissuingPhase); result.RequeueAfter != reissuanceCheckPeriod {
+		t.Errorf("CARotationReconciler.Reconcile() = %v, want to requeue after %v", result, reissuanceCheckPeriod)
+	}
+	if err := cl.Get(context.TODO(), types.NamespacedName{Name: "ca-next", Namespace: "default"}, staged); !errors.IsNotFound(err) {
+		t.Errorf("CARotationReconciler.Reconcile() staged root CA not deleted: %v", err)
+	}
+	wantBundle(caPEM, nextPEM)
+
+	serverPEM, serverKeyPEM, err = pki.GenerateCertificate(next, nextKey, "server", time.Hour, true, false, "localhost")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := getSecret("server")
+	server.Data = map[string][]byte{corev1.TLSCertKey: serverPEM, corev1.TLSPrivateKeyKey: serverKeyPEM}
+	if err := cl.Update(context.TODO(), server); err != nil {
+		t.Fatal(err)
+	}
+	reconcile(operatorv1alpha1.CARotationCompletedPhase)
+	wantBundle(nextPEM)
+}
+
+func TestCARotationReconciler_isRotationDue(t *testing.T) {
+	now := time.Now()
+	caPEM, _, err := pki.GenerateCertificate(nil, nil, "ca", 10*time.Hour, false, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ca, _ := pki.LoadX509Certificate(caPEM)
+
+	tests := []struct {
+		name        string
+		annotations map[string]string
+		now         time.Time
+		want        bool
+	}{
+		{
+			name:        "Not due",
+			annotations: nil,
+			now:         now,
+			want:        false,
+		},
+		{
+			name:        "Due when requested after the root CA was issued",
+			annotations: map[string]string{operatorv1alpha1.CARotationRequestedAtAnnotation: now.Add(time.Minute).Format(time.RFC3339)},
+			now:         now,
+			want:        true,
+		},
+		{
+			name:        "Not due when requested before the root CA was issued",
+			annotations: map[string]string{operatorv1alpha1.CARotationRequestedAtAnnotation: now.Add(-time.Minute).Format(time.RFC3339)},
+			now:         now,
+			want:        false,
+		},
+		{
+			name:        "Not due with an invalid annotation",
+			annotations: map[string]string{operatorv1alpha1.CARotationRequestedAtAnnotation: "xxxx"},
+			now:         now,
+			want:        false,
+		},
+		{
+			name:        "Due when the root CA is close to expire",
+			annotations: nil,
+			now:         now.Add(9 * time.Hour),
+			want:        true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &CARotationReconciler{
+				logger: ctrl.Log.WithName("test"),
+				ds:     &operatorv1alpha1.DiscoveryService{ObjectMeta: metav1.ObjectMeta{Annotations: tt.annotations}},
+				clock:  &testClock{now: tt.now},
+			}
+			if got := r.isRotationDue(ca); got != tt.want {
+				t.Errorf("CARotationReconciler.isRotationDue() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
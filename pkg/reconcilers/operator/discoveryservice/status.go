@@ -0,0 +1,125 @@
+package reconcilers
+
+import (
+	"fmt"
+	"reflect"
+
+	operatorv1alpha1 "github.com/3scale/marin3r/apis/operator/v1alpha1"
+	"github.com/operator-framework/operator-lib/status"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/utils/pointer"
+)
+
+// IsStatusReconciled calculates the status of the DiscoveryService from the objects it owns. Any
+// of them can be nil if it does not exist yet. Returns false if the status has been modified.
+func IsStatusReconciled(ds *operatorv1alpha1.DiscoveryService, caDSC, serverDSC *operatorv1alpha1.DiscoveryServiceCertificate,
+	dep *appsv1.Deployment, svc *corev1.Service, connectedProxies int32) bool {
+
+	ok := true
+
+	if ds.Status.Conditions == nil {
+		ds.Status.Conditions = status.NewConditions()
+		ok = false
+	}
+
+	caReady := caDSC != nil && caDSC.Status.IsReady()
+	if setCondition(ds, operatorv1alpha1.CACertificateReadyCondition, caReady,
+		"CertificateReady", "CertificateNotReady", "root CA certificate") {
+		ok = false
+	}
+
+	serverReady := serverDSC != nil && serverDSC.Status.IsReady()
+	if setCondition(ds, operatorv1alpha1.ServerCertificateReadyCondition, serverReady,
+		"CertificateReady", "CertificateNotReady", "server certificate") {
+		ok = false
+	}
+
+	var replicas, availableReplicas int32
+	if dep != nil {
+		replicas = dep.Status.Replicas
+		availableReplicas = dep.Status.AvailableReplicas
+	}
+	if ds.Status.Replicas != replicas {
+		ds.Status.Replicas = replicas
+		ok = false
+	}
+	if ds.Status.AvailableReplicas != availableReplicas {
+		ds.Status.AvailableReplicas = availableReplicas
+		ok = false
+	}
+
+	deploymentAvailable := isDeploymentAvailable(dep)
+	if setCondition(ds, operatorv1alpha1.DeploymentAvailableCondition, deploymentAvailable,
+		"MinimumReplicasAvailable", "MinimumReplicasUnavailable", "Deployment") {
+		ok = false
+	}
+
+	endpoint := xdsEndpoint(ds, svc)
+	if !reflect.DeepEqual(ds.Status.XdsEndpoint, endpoint) {
+		ds.Status.XdsEndpoint = endpoint
+		ok = false
+	}
+
+	if ds.Status.ConnectedProxies != connectedProxies {
+		ds.Status.ConnectedProxies = connectedProxies
+		ok = false
+	}
+
+	ready := caReady && serverReady && deploymentAvailable && endpoint != nil
+	if setCondition(ds, operatorv1alpha1.DiscoveryServiceReadyCondition, ready,
+		"DiscoveryServiceReady", "DiscoveryServiceNotReady", "discovery service") {
+		ok = false
+	}
+	if ds.Status.Ready == nil || *ds.Status.Ready != ready {
+		ds.Status.Ready = pointer.BoolPtr(ready)
+		ok = false
+	}
+
+	return ok
+}
+
+// setCondition sets the condition of the given type to true or false, with
+// the matching reason. Returns true if the condition has been modified.
+func setCondition(ds *operatorv1alpha1.DiscoveryService, t status.ConditionType, value bool,
+	trueReason, falseReason status.ConditionReason, subject string) bool {
+
+	if value {
+		return ds.Status.Conditions.SetCondition(status.Condition{
+			Type:    t,
+			Status:  corev1.ConditionTrue,
+			Reason:  trueReason,
+			Message: fmt.Sprintf("The %s is ready", subject),
+		})
+	}
+	return ds.Status.Conditions.SetCondition(status.Condition{
+		Type:    t,
+		Status:  corev1.ConditionFalse,
+		Reason:  falseReason,
+		Message: fmt.Sprintf("The %s is not ready", subject),
+	})
+}
+
+// isDeploymentAvailable returns true if the Deployment
+// has its minimum number of replicas available
+func isDeploymentAvailable(dep *appsv1.Deployment) bool {
+	if dep == nil {
+		return false
+	}
+	for _, c := range dep.Status.Conditions {
+		if c.Type == appsv1.DeploymentAvailable {
+			return c.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
+
+// xdsEndpoint returns the address and port envoy proxies use
+// to connect to the xDS server, or nil if its Service does not exist
+func xdsEndpoint(ds *operatorv1alpha1.DiscoveryService, svc *corev1.Service) *operatorv1alpha1.XdsEndpointStatus {
+	if svc == nil {
+		return nil
+	}
+	host, port := ds.GetXdsEndpoint()
+	return &operatorv1alpha1.XdsEndpointStatus{Address: host, Port: port}
+}
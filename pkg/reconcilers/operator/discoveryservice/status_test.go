@@ -0,0 +1,222 @@
+package reconcilers
+
+import (
+	"reflect"
+	"testing"
+
+	operatorv1alpha1 "github.com/3scale/marin3r/apis/operator/v1alpha1"
+	"github.com/operator-framework/operator-lib/status"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/pointer"
+)
+
+func testObjectMeta() metav1.ObjectMeta {
+	return metav1.ObjectMeta{Name: "instance", Namespace: "test"}
+}
+
+func testReadyDSC() *operatorv1alpha1.DiscoveryServiceCertificate {
+	return &operatorv1alpha1.DiscoveryServiceCertificate{
+		Status: operatorv1alpha1.DiscoveryServiceCertificateStatus{Ready: pointer.BoolPtr(true)},
+	}
+}
+
+func testAvailableDeployment() *appsv1.Deployment {
+	return &appsv1.Deployment{
+		Status: appsv1.DeploymentStatus{
+			Replicas:          2,
+			AvailableReplicas: 2,
+			Conditions: []appsv1.DeploymentCondition{
+				{Type: appsv1.DeploymentAvailable, Status: corev1.ConditionTrue},
+			},
+		},
+	}
+}
+
+func testService() *corev1.Service {
+	return &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: "marin3r-instance", Namespace: "test"},
+		Spec: corev1.ServiceSpec{
+			Ports: []corev1.ServicePort{
+				{Name: "discovery", Port: 18000},
+				{Name: "webhook", Port: 443},
+			},
+		},
+	}
+}
+
+func testReadyStatus() operatorv1alpha1.DiscoveryServiceStatus {
+	return operatorv1alpha1.DiscoveryServiceStatus{
+		Conditions: status.Conditions{
+			{Type: operatorv1alpha1.CACertificateReadyCondition, Status: corev1.ConditionTrue,
+				Reason: "CertificateReady", Message: "The root CA certificate is ready"},
+			{Type: operatorv1alpha1.ServerCertificateReadyCondition, Status: corev1.ConditionTrue,
+				Reason: "CertificateReady", Message: "The server certificate is ready"},
+			{Type: operatorv1alpha1.DeploymentAvailableCondition, Status: corev1.ConditionTrue,
+				Reason: "MinimumReplicasAvailable", Message: "The Deployment is ready"},
+			{Type: operatorv1alpha1.DiscoveryServiceReadyCondition, Status: corev1.ConditionTrue,
+				Reason: "DiscoveryServiceReady", Message: "The discovery service is ready"},
+		},
+		Ready:             pointer.BoolPtr(true),
+		XdsEndpoint:       &operatorv1alpha1.XdsEndpointStatus{Address: "marin3r-instance.test.svc", Port: 18000},
+		Replicas:          2,
+		AvailableReplicas: 2,
+		ConnectedProxies:  3,
+	}
+}
+
+func TestIsStatusReconciled(t *testing.T) {
+	type args struct {
+		ds               *operatorv1alpha1.DiscoveryService
+		caDSC            *operatorv1alpha1.DiscoveryServiceCertificate
+		serverDSC        *operatorv1alpha1.DiscoveryServiceCertificate
+		dep              *appsv1.Deployment
+		svc              *corev1.Service
+		connectedProxies int32
+	}
+	tests := []struct {
+		name       string
+		args       args
+		want       bool
+		wantStatus func() operatorv1alpha1.DiscoveryServiceStatus
+	}{
+		{
+			name: "Status already up to date, returns true",
+			args: args{
+				ds:               &operatorv1alpha1.DiscoveryService{ObjectMeta: testObjectMeta(), Status: testReadyStatus()},
+				caDSC:            testReadyDSC(),
+				serverDSC:        testReadyDSC(),
+				dep:              testAvailableDeployment(),
+				svc:              testService(),
+				connectedProxies: 3,
+			},
+			want:       true,
+			wantStatus: testReadyStatus,
+		},
+		{
+			name: "Empty status is populated, returns false",
+			args: args{
+				ds:               &operatorv1alpha1.DiscoveryService{ObjectMeta: testObjectMeta()},
+				caDSC:            testReadyDSC(),
+				serverDSC:        testReadyDSC(),
+				dep:              testAvailableDeployment(),
+				svc:              testService(),
+				connectedProxies: 3,
+			},
+			want:       false,
+			wantStatus: testReadyStatus,
+		},
+		{
+			name: "Connected proxies changed, returns false",
+			args: args{
+				ds:               &operatorv1alpha1.DiscoveryService{ObjectMeta: testObjectMeta(), Status: testReadyStatus()},
+				caDSC:            testReadyDSC(),
+				serverDSC:        testReadyDSC(),
+				dep:              testAvailableDeployment(),
+				svc:              testService(),
+				connectedProxies: 5,
+			},
+			want: false,
+			wantStatus: func() operatorv1alpha1.DiscoveryServiceStatus {
+				s := testReadyStatus()
+				s.ConnectedProxies = 5
+				return s
+			},
+		},
+		{
+			name: "External address is reported as the xDS endpoint",
+			args: args{
+				ds: &operatorv1alpha1.DiscoveryService{
+					ObjectMeta: testObjectMeta(),
+					Spec: operatorv1alpha1.DiscoveryServiceSpec{
+						ServiceConfig: &operatorv1alpha1.ServiceConfig{
+							Name:            "marin3r-instance",
+							Type:            operatorv1alpha1.NodePortType,
+							NodePort:        pointer.Int32Ptr(30000),
+							ExternalAddress: "xds.example.com",
+						},
+					},
+					Status: testReadyStatus(),
+				},
+				caDSC:            testReadyDSC(),
+				serverDSC:        testReadyDSC(),
+				dep:              testAvailableDeployment(),
+				svc:              testService(),
+				connectedProxies: 3,
+			},
+			want: false,
+			wantStatus: func() operatorv1alpha1.DiscoveryServiceStatus {
+				s := testReadyStatus()
+				s.XdsEndpoint = &operatorv1alpha1.XdsEndpointStatus{Address: "xds.example.com", Port: 30000}
+				return s
+			},
+		},
+		{
+			name: "Nothing created yet, not ready",
+			args: args{
+				ds: &operatorv1alpha1.DiscoveryService{ObjectMeta: testObjectMeta()},
+			},
+			want: false,
+			wantStatus: func() operatorv1alpha1.DiscoveryServiceStatus {
+				return operatorv1alpha1.DiscoveryServiceStatus{
+					Conditions: status.Conditions{
+						{Type: operatorv1alpha1.CACertificateReadyCondition, Status: corev1.ConditionFalse,
+							Reason: "CertificateNotReady", Message: "The root CA certificate is not ready"},
+						{Type: operatorv1alpha1.ServerCertificateReadyCondition, Status: corev1.ConditionFalse,
+							Reason: "CertificateNotReady", Message: "The server certificate is not ready"},
+						{Type: operatorv1alpha1.DeploymentAvailableCondition, Status: corev1.ConditionFalse,
+							Reason: "MinimumReplicasUnavailable", Message: "The Deployment is not ready"},
+						{Type: operatorv1alpha1.DiscoveryServiceReadyCondition, Status: corev1.ConditionFalse,
+							Reason: "DiscoveryServiceNotReady", Message: "The discovery service is not ready"},
+					},
+					Ready: pointer.BoolPtr(false),
+				}
+			},
+		},
+		{
+			name: "Deployment unavailable, not ready",
+			args: args{
+				ds:        &operatorv1alpha1.DiscoveryService{ObjectMeta: testObjectMeta(), Status: testReadyStatus()},
+				caDSC:     testReadyDSC(),
+				serverDSC: testReadyDSC(),
+				dep: &appsv1.Deployment{
+					Status: appsv1.DeploymentStatus{
+						Replicas:          2,
+						AvailableReplicas: 0,
+						Conditions: []appsv1.DeploymentCondition{
+							{Type: appsv1.DeploymentAvailable, Status: corev1.ConditionFalse},
+						},
+					},
+				},
+				svc:              testService(),
+				connectedProxies: 3,
+			},
+			want: false,
+			wantStatus: func() operatorv1alpha1.DiscoveryServiceStatus {
+				s := testReadyStatus()
+				s.Conditions[2] = status.Condition{Type: operatorv1alpha1.DeploymentAvailableCondition, Status: corev1.ConditionFalse,
+					Reason: "MinimumReplicasUnavailable", Message: "The Deployment is not ready"}
+				s.Conditions[3] = status.Condition{Type: operatorv1alpha1.DiscoveryServiceReadyCondition, Status: corev1.ConditionFalse,
+					Reason: "DiscoveryServiceNotReady", Message: "The discovery service is not ready"}
+				s.Ready = pointer.BoolPtr(false)
+				s.AvailableReplicas = 0
+				return s
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsStatusReconciled(tt.args.ds, tt.args.caDSC, tt.args.serverDSC, tt.args.dep, tt.args.svc, tt.args.connectedProxies); got != tt.want {
+				t.Errorf("IsStatusReconciled() = %v, want %v", got, tt.want)
+			}
+			// LastTransitionTime is not relevant for the comparison
+			for i := range tt.args.ds.Status.Conditions {
+				tt.args.ds.Status.Conditions[i].LastTransitionTime = metav1.Time{}
+			}
+			if wantStatus := tt.wantStatus(); !reflect.DeepEqual(tt.args.ds.Status, wantStatus) {
+				t.Errorf("IsStatusReconciled() status = %v, want %v", tt.args.ds.Status, wantStatus)
+			}
+		})
+	}
+}
@@ -3,6 +3,7 @@ package reconcilers
 import (
 	operatorv1alpha1 "github.com/3scale/marin3r/apis/operator/v1alpha1"
 	"k8s.io/utils/pointer"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 )
 
 // IsInitialized checks whether the EnvoyConfigRevision object is initialized
@@ -28,6 +29,10 @@ func IsInitialized(dsc *operatorv1alpha1.DiscoveryServiceCertificate) bool {
 		dsc.Spec.CertificateRenewalConfig = &crc
 		ok = false
 	}
+	if dsc.IsRevocable() && !controllerutil.ContainsFinalizer(dsc, operatorv1alpha1.DiscoveryServiceCertificateFinalizer) {
+		controllerutil.AddFinalizer(dsc, operatorv1alpha1.DiscoveryServiceCertificateFinalizer)
+		ok = false
+	}
 
 	return ok
 }
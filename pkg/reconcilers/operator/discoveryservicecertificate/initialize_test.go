@@ -30,6 +30,21 @@ func TestIsInitialized(t *testing.T) {
 			},
 			want: true,
 		},
+		{
+			name: "Returns false, CA signed certificates require a finalizer",
+			args: args{
+				dsc: &operatorv1alpha1.DiscoveryServiceCertificate{
+					Spec: operatorv1alpha1.DiscoveryServiceCertificateSpec{
+						IsServerCertificate:      pointer.BoolPtr(false),
+						IsCA:                     pointer.BoolPtr(false),
+						Hosts:                    []string{"host"},
+						CertificateRenewalConfig: &operatorv1alpha1.CertificateRenewalConfig{Enabled: true},
+						Signer:                   operatorv1alpha1.DiscoveryServiceCertificateSigner{CASigned: &operatorv1alpha1.CASignedConfig{}},
+					},
+				},
+			},
+			want: false,
+		},
 		{
 			name: "Returns false, IsServerCertificate requires init",
 			args: args{
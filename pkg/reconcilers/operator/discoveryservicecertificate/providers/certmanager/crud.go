@@ -0,0 +1,362 @@
+package providers
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"reflect"
+	"time"
+
+	operatorv1alpha1 "github.com/3scale/marin3r/apis/operator/v1alpha1"
+	"github.com/3scale/marin3r/pkg/reconcilers/operator/discoveryservicecertificate/providers"
+	"github.com/3scale/marin3r/pkg/util/pki"
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+const (
+	tlsCertificateKey = "tls.crt"
+	tlsPrivateKeyKey  = "tls.key"
+)
+
+// optionalSpecFields are the fields of the cert-manager
+// Certificate spec that are only set when not empty
+var optionalSpecFields = []string{"dnsNames", "ipAddresses", "uris", "subject"}
+
+// CertificateGVK is the GroupVersionKind of cert-manager Certificates
+var CertificateGVK = schema.GroupVersionKind{Group: "cert-manager.io", Version: "v1", Kind: "Certificate"}
+
+// CertificateProvider is a certificate provider that delegates the
+// issuance of certificates to cert-manager. It manages a cert-manager
+// Certificate for the DiscoveryServiceCertificate, which makes cert-manager
+// store the certificate in the Secret referred in the DiscoveryServiceCertificate.
+// Certificates are issued asynchronously, so CreateCertificate and
+// UpdateCertificate return a PendingError when the Certificate is in place.
+type CertificateProvider struct {
+	ctx    context.Context
+	logger logr.Logger
+	client client.Client
+	scheme *runtime.Scheme
+	dsc    *operatorv1alpha1.DiscoveryServiceCertificate
+}
+
+// NewCertificateProvider returns a CertificateProvider struct for the given parameters
+func NewCertificateProvider(ctx context.Context, logger logr.Logger, client client.Client,
+	scheme *runtime.Scheme, dsc *operatorv1alpha1.DiscoveryServiceCertificate) *CertificateProvider {
+
+	return &CertificateProvider{
+		ctx:    ctx,
+		logger: logger,
+		client: client,
+		scheme: scheme,
+		dsc:    dsc,
+	}
+}
+
+// CreateCertificate creates the cert-manager Certificate for the
+// DiscoveryServiceCertificate, or updates it if it already exists
+func (cp *CertificateProvider) CreateCertificate() ([]byte, []byte, error) {
+	logger := cp.logger.WithValues("method", "CreateCertificate")
+
+	if err := cp.reconcileCertificate(); err != nil {
+		logger.Error(err, "unable to reconcile cert-manager Certificate")
+		return nil, nil, err
+	}
+
+	return nil, nil, providers.NewPendingError("waiting for cert-manager to issue the certificate")
+}
+
+// GetCertificate loads a certificate form the Secret referred in the
+// DiscoveryServiceCertificate resource
+func (cp *CertificateProvider) GetCertificate() ([]byte, []byte, error) {
+	logger := cp.logger.WithValues("method", "GetCertificate")
+
+	secret := &corev1.Secret{}
+	key := types.NamespacedName{
+		Name:      cp.dsc.Spec.SecretRef.Name,
+		Namespace: cp.dsc.GetNamespace(),
+	}
+	if err := cp.client.Get(cp.ctx, key, secret); err != nil {
+		if !errors.IsNotFound(err) {
+			logger.Error(err, "unable to get Secret")
+		}
+		return nil, nil, err
+	}
+
+	return secret.Data[tlsCertificateKey], secret.Data[tlsPrivateKeyKey], nil
+}
+
+// UpdateCertificate updates the cert-manager Certificate to match the spec of
+// the DiscoveryServiceCertificate. cert-manager reissues the certificate when
+// its spec changes and renews it before it expires.
+func (cp *CertificateProvider) UpdateCertificate() ([]byte, []byte, error) {
+	logger := cp.logger.WithValues("method", "UpdateCertificate")
+
+	if err := cp.reconcileCertificate(); err != nil {
+		logger.Error(err, "unable to reconcile cert-manager Certificate")
+		return nil, nil, err
+	}
+
+	return nil, nil, providers.NewPendingError("waiting for cert-manager to reissue the certificate")
+}
+
+// VerifyCertificate verifies that the cert-manager Certificate matches the
+// spec of the DiscoveryServiceCertificate and is ready, and that the certificate
+// in the Secret has not expired. Returns 'nil' if verification is correct, an error otherwise.
+func (cp *CertificateProvider) VerifyCertificate() error {
+	logger := cp.logger.WithValues("method", "VerifyCertificate")
+
+	certificate := &unstructured.Unstructured{}
+	certificate.SetGroupVersionKind(CertificateGVK)
+	if err := cp.client.Get(cp.ctx, types.NamespacedName{Name: cp.dsc.GetName(), Namespace: cp.dsc.GetNamespace()}, certificate); err != nil {
+		if errors.IsNotFound(err) {
+			return pki.NewVerifyError("cert-manager Certificate not found")
+		}
+		logger.Error(err, "unable to get cert-manager Certificate")
+		return err
+	}
+
+	desired, err := certificateSpec(cp.dsc)
+	if err != nil {
+		return err
+	}
+	if !specMatches(certificate, desired) {
+		return pki.NewVerifyError("cert-manager Certificate does not match the spec")
+	}
+
+	if ok, msg := isReady(certificate); !ok {
+		return pki.NewVerifyError(fmt.Sprintf("cert-manager Certificate is not ready: %s", msg))
+	}
+
+	certBytes, _, err := cp.GetCertificate()
+	if err != nil {
+		return err
+	}
+	cert, err := pki.LoadX509Certificate(certBytes)
+	if err != nil {
+		logger.Error(err, "unable to load certificate from Secret")
+		return err
+	}
+	if now := time.Now(); now.Before(cert.NotBefore) || now.After(cert.NotAfter) {
+		return pki.NewVerifyError("certificate is not within its validity period")
+	}
+
+	return nil
+}
+
+// reconcileCertificate creates or updates the cert-manager Certificate
+func (cp *CertificateProvider) reconcileCertificate() error {
+
+	desired, err := certificateSpec(cp.dsc)
+	if err != nil {
+		return err
+	}
+
+	certificate := &unstructured.Unstructured{}
+	certificate.SetGroupVersionKind(CertificateGVK)
+	err = cp.client.Get(cp.ctx, types.NamespacedName{Name: cp.dsc.GetName(), Namespace: cp.dsc.GetNamespace()}, certificate)
+	if err != nil {
+		if !errors.IsNotFound(err) {
+			return err
+		}
+		certificate.SetName(cp.dsc.GetName())
+		certificate.SetNamespace(cp.dsc.GetNamespace())
+		certificate.Object["spec"] = desired
+		if err := controllerutil.SetControllerReference(cp.dsc, certificate, cp.scheme); err != nil {
+			return err
+		}
+		if err := cp.client.Create(cp.ctx, certificate); err != nil {
+			return err
+		}
+		cp.logger.V(1).Info("created cert-manager Certificate")
+		return nil
+	}
+
+	if specMatches(certificate, desired) {
+		return nil
+	}
+	spec, _, _ := unstructured.NestedMap(certificate.Object, "spec")
+	if spec == nil {
+		spec = map[string]interface{}{}
+	}
+	for k, v := range desired {
+		spec[k] = v
+	}
+	// Remove the optional fields that are no longer set
+	for _, k := range optionalSpecFields {
+		if _, ok := desired[k]; !ok {
+			delete(spec, k)
+		}
+	}
+	certificate.Object["spec"] = spec
+	if err := cp.client.Update(cp.ctx, certificate); err != nil {
+		return err
+	}
+	cp.logger.V(1).Info("updated cert-manager Certificate")
+	return nil
+}
+
+// certificateSpec returns the spec of the cert-manager Certificate
+// for a DiscoveryServiceCertificate
+func certificateSpec(dsc *operatorv1alpha1.DiscoveryServiceCertificate) (map[string]interface{}, error) {
+
+	if dsc.Spec.Signer.CertManager == nil {
+		return nil, fmt.Errorf("DiscoveryServiceCertificate has no cert-manager signer")
+	}
+	issuerRef := dsc.Spec.Signer.CertManager.IssuerRef
+	validFor := time.Duration(dsc.Spec.ValidFor) * time.Second
+
+	algorithm := string(pki.DefaultKeyAlgorithm)
+	size := 0
+	if pk := dsc.Spec.PrivateKey; pk != nil {
+		algorithm = string(pk.Algorithm)
+		size = pk.Size
+	}
+	if size == 0 {
+		size = pki.DefaultECDSAKeySize
+		if algorithm == string(pki.RSAKeyAlgorithm) {
+			size = pki.DefaultRSAKeySize
+		}
+	}
+
+	spec := map[string]interface{}{
+		"secretName": dsc.Spec.SecretRef.Name,
+		"commonName": dsc.Spec.CommonName,
+		"duration":   validFor.String(),
+		// Renew when 20% of the duration is left, like the internal provider does
+		"renewBefore": (validFor / 5).String(),
+		"isCA":        dsc.IsCA(),
+		"privateKey": map[string]interface{}{
+			"algorithm": algorithm,
+			"size":      int64(size),
+		},
+		"usages": stringList(certificateUsages(dsc, algorithm)),
+		"issuerRef": map[string]interface{}{
+			"name":  issuerRef.Name,
+			"kind":  issuerRef.GetKind(),
+			"group": issuerRef.GetGroup(),
+		},
+	}
+
+	dnsNames := []string{}
+	ipAddresses := []string{}
+	for _, host := range dsc.GetHosts() {
+		if net.ParseIP(host) != nil {
+			ipAddresses = append(ipAddresses, host)
+		} else {
+			dnsNames = append(dnsNames, host)
+		}
+	}
+	if len(dnsNames) > 0 {
+		spec["dnsNames"] = stringList(dnsNames)
+	}
+	if len(ipAddresses) > 0 {
+		spec["ipAddresses"] = stringList(ipAddresses)
+	}
+	if len(dsc.Spec.URIs) > 0 {
+		spec["uris"] = stringList(dsc.Spec.URIs)
+	}
+
+	if s := dsc.Spec.Subject; s != nil {
+		subject := map[string]interface{}{}
+		for k, v := range map[string][]string{
+			"organizations":       s.Organizations,
+			"organizationalUnits": s.OrganizationalUnits,
+			"countries":           s.Countries,
+			"provinces":           s.Provinces,
+			"localities":          s.Localities,
+		} {
+			if len(v) > 0 {
+				subject[k] = stringList(v)
+			}
+		}
+		if len(subject) > 0 {
+			spec["subject"] = subject
+		}
+	}
+
+	return spec, nil
+}
+
+// certificateUsages returns the usages of the certificate, which
+// default to the ones of the certificates issued by the internal provider
+func certificateUsages(dsc *operatorv1alpha1.DiscoveryServiceCertificate, algorithm string) []string {
+	usages := []string{}
+	for _, usage := range dsc.Spec.Usages {
+		usages = append(usages, string(usage))
+	}
+
+	if len(usages) == 0 {
+		if dsc.IsCA() {
+			usages = append(usages, string(operatorv1alpha1.UsageCertSign), string(operatorv1alpha1.UsageCRLSign))
+		} else {
+			usages = append(usages, string(operatorv1alpha1.UsageDigitalSignature))
+			if algorithm == string(pki.RSAKeyAlgorithm) {
+				usages = append(usages, string(operatorv1alpha1.UsageKeyEncipherment))
+			}
+		}
+	}
+
+	if dsc.IsServerCertificate() {
+		for _, usage := range usages {
+			if usage == string(operatorv1alpha1.UsageServerAuth) {
+				return usages
+			}
+		}
+		usages = append(usages, string(operatorv1alpha1.UsageServerAuth))
+	}
+
+	return usages
+}
+
+// specMatches returns true if the fields of the desired spec
+// are equal in the spec of the cert-manager Certificate
+func specMatches(certificate *unstructured.Unstructured, desired map[string]interface{}) bool {
+	spec, _, _ := unstructured.NestedMap(certificate.Object, "spec")
+	if spec == nil {
+		return false
+	}
+	for k, v := range desired {
+		if !reflect.DeepEqual(spec[k], v) {
+			return false
+		}
+	}
+	for _, k := range optionalSpecFields {
+		if _, ok := desired[k]; !ok {
+			if _, ok := spec[k]; ok {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// isReady returns true if the cert-manager Certificate has the
+// Ready condition set to True, and the message of the condition otherwise
+func isReady(certificate *unstructured.Unstructured) (bool, string) {
+	conditions, _, _ := unstructured.NestedSlice(certificate.Object, "status", "conditions")
+	for _, c := range conditions {
+		condition, ok := c.(map[string]interface{})
+		if !ok || condition["type"] != "Ready" {
+			continue
+		}
+		message, _ := condition["message"].(string)
+		return condition["status"] == string(corev1.ConditionTrue), message
+	}
+	return false, "no Ready condition"
+}
+
+func stringList(in []string) []interface{} {
+	out := make([]interface{}, 0, len(in))
+	for _, s := range in {
+		out = append(out, s)
+	}
+	return out
+}
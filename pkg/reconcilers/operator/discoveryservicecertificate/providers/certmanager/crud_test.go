@@ -0,0 +1,241 @@
+package providers
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	operatorv1alpha1 "github.com/3scale/marin3r/apis/operator/v1alpha1"
+	"github.com/3scale/marin3r/pkg/reconcilers/operator/discoveryservicecertificate/providers"
+	"github.com/3scale/marin3r/pkg/util/pki"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/utils/pointer"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+var s *runtime.Scheme = scheme.Scheme
+
+func init() {
+	s.AddKnownTypes(operatorv1alpha1.GroupVersion,
+		&operatorv1alpha1.DiscoveryServiceCertificate{},
+	)
+}
+
+func testDiscoveryServiceCertificate() *operatorv1alpha1.DiscoveryServiceCertificate {
+	return &operatorv1alpha1.DiscoveryServiceCertificate{
+		ObjectMeta: metav1.ObjectMeta{Name: "dsc", Namespace: "test"},
+		Spec: operatorv1alpha1.DiscoveryServiceCertificateSpec{
+			CommonName:          "test",
+			IsServerCertificate: pointer.BoolPtr(true),
+			ValidFor:            3600,
+			Hosts:               []string{"example.com", "127.0.0.1"},
+			Signer: operatorv1alpha1.DiscoveryServiceCertificateSigner{
+				CertManager: &operatorv1alpha1.CertManagerConfig{
+					IssuerRef: operatorv1alpha1.CertManagerIssuerReference{Name: "issuer"},
+				},
+			},
+			SecretRef: corev1.SecretReference{Name: "secret"},
+		},
+	}
+}
+
+func testCertificate(t *testing.T, dsc *operatorv1alpha1.DiscoveryServiceCertificate, ready string) *unstructured.Unstructured {
+	spec, err := certificateSpec(dsc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certificate := &unstructured.Unstructured{Object: map[string]interface{}{
+		"spec": spec,
+		"status": map[string]interface{}{
+			"conditions": []interface{}{
+				map[string]interface{}{"type": "Ready", "status": ready, "message": "test"},
+			},
+		},
+	}}
+	certificate.SetGroupVersionKind(CertificateGVK)
+	certificate.SetName(dsc.GetName())
+	certificate.SetNamespace(dsc.GetNamespace())
+	return certificate
+}
+
+func TestCertificateProvider_CreateCertificate(t *testing.T) {
+	dsc := testDiscoveryServiceCertificate()
+	cp := NewCertificateProvider(context.TODO(), ctrl.Log.WithName("test"), fake.NewFakeClientWithScheme(s, dsc), s, dsc)
+
+	if _, _, err := cp.CreateCertificate(); !providers.IsPendingError(err) {
+		t.Fatalf("CertificateProvider.CreateCertificate() error = %v, want a PendingError", err)
+	}
+
+	certificate := &unstructured.Unstructured{}
+	certificate.SetGroupVersionKind(CertificateGVK)
+	if err := cp.client.Get(context.TODO(), types.NamespacedName{Name: "dsc", Namespace: "test"}, certificate); err != nil {
+		t.Fatalf("CertificateProvider.CreateCertificate() cert-manager Certificate not created: %v", err)
+	}
+	want, _ := certificateSpec(dsc)
+	if !specMatches(certificate, want) {
+		t.Errorf("CertificateProvider.CreateCertificate() spec = %v, want %v", certificate.Object["spec"], want)
+	}
+	if refs := certificate.GetOwnerReferences(); len(refs) != 1 || refs[0].Name != "dsc" {
+		t.Errorf("CertificateProvider.CreateCertificate() cert-manager Certificate not owned by the DiscoveryServiceCertificate: %v", refs)
+	}
+
+	t.Run("Updates the cert-manager Certificate when the spec changes", func(t *testing.T) {
+		cp.dsc.Spec.Hosts = []string{"example.com"}
+		if _, _, err := cp.UpdateCertificate(); !providers.IsPendingError(err) {
+			t.Fatalf("CertificateProvider.UpdateCertificate() error = %v, want a PendingError", err)
+		}
+		if err := cp.client.Get(context.TODO(), types.NamespacedName{Name: "dsc", Namespace: "test"}, certificate); err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := certificate.Object["spec"].(map[string]interface{})["ipAddresses"]; ok {
+			t.Errorf("CertificateProvider.UpdateCertificate() ipAddresses not removed from the spec")
+		}
+		want, _ := certificateSpec(cp.dsc)
+		if !specMatches(certificate, want) {
+			t.Errorf("CertificateProvider.UpdateCertificate() spec = %v, want %v", certificate.Object["spec"], want)
+		}
+	})
+}
+
+func TestCertificateProvider_VerifyCertificate(t *testing.T) {
+	crt, key, err := pki.GenerateCertificate(nil, nil, "test", time.Hour, true, false, "example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "secret", Namespace: "test"},
+		Data:       map[string][]byte{tlsCertificateKey: crt, tlsPrivateKeyKey: key},
+	}
+	outdated := testDiscoveryServiceCertificate()
+	outdated.Spec.ValidFor = 7200
+
+	tests := []struct {
+		name            string
+		objects         []runtime.Object
+		wantErr         bool
+		wantVerifyError bool
+	}{
+		{
+			name:            "Verify returns error=nil",
+			objects:         []runtime.Object{secret, testCertificate(t, testDiscoveryServiceCertificate(), "True")},
+			wantErr:         false,
+			wantVerifyError: false,
+		},
+		{
+			name:            "Verify returns a VerifyError if the cert-manager Certificate does not exist",
+			objects:         []runtime.Object{secret},
+			wantErr:         true,
+			wantVerifyError: true,
+		},
+		{
+			name:            "Verify returns a VerifyError if the cert-manager Certificate does not match the spec",
+			objects:         []runtime.Object{secret, testCertificate(t, outdated, "True")},
+			wantErr:         true,
+			wantVerifyError: true,
+		},
+		{
+			name:            "Verify returns a VerifyError if the cert-manager Certificate is not ready",
+			objects:         []runtime.Object{secret, testCertificate(t, testDiscoveryServiceCertificate(), "False")},
+			wantErr:         true,
+			wantVerifyError: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cp := NewCertificateProvider(context.TODO(), ctrl.Log.WithName("test"),
+				fake.NewFakeClientWithScheme(s, tt.objects...), s, testDiscoveryServiceCertificate())
+			err := cp.VerifyCertificate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("CertificateProvider.VerifyCertificate() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if err != nil && pki.IsVerifyError(err) != tt.wantVerifyError {
+				t.Errorf("CertificateProvider.VerifyCertificate() error = %v, wantVerifyError %v", err, tt.wantVerifyError)
+			}
+		})
+	}
+}
+
+func Test_certificateSpec(t *testing.T) {
+	tests := []struct {
+		name    string
+		dsc     func() *operatorv1alpha1.DiscoveryServiceCertificate
+		want    map[string]interface{}
+		wantErr bool
+	}{
+		{
+			name: "Returns the spec of a server certificate",
+			dsc:  testDiscoveryServiceCertificate,
+			want: map[string]interface{}{
+				"secretName":  "secret",
+				"commonName":  "test",
+				"duration":    "1h0m0s",
+				"renewBefore": "12m0s",
+				"isCA":        false,
+				"privateKey":  map[string]interface{}{"algorithm": "ECDSA", "size": int64(256)},
+				"usages":      []interface{}{"digital signature", "server auth"},
+				"issuerRef":   map[string]interface{}{"name": "issuer", "kind": "Issuer", "group": "cert-manager.io"},
+				"dnsNames":    []interface{}{"example.com"},
+				"ipAddresses": []interface{}{"127.0.0.1"},
+			},
+			wantErr: false,
+		},
+		{
+			name: "Returns the spec with the key, subject, URIs and usages",
+			dsc: func() *operatorv1alpha1.DiscoveryServiceCertificate {
+				dsc := testDiscoveryServiceCertificate()
+				dsc.Spec.IsServerCertificate = pointer.BoolPtr(false)
+				dsc.Spec.Hosts = []string{"example.com"}
+				dsc.Spec.Signer.CertManager.IssuerRef = operatorv1alpha1.CertManagerIssuerReference{Name: "issuer", Kind: "ClusterIssuer"}
+				dsc.Spec.PrivateKey = &operatorv1alpha1.PrivateKeyConfig{Algorithm: operatorv1alpha1.RSAPrivateKeyAlgorithm}
+				dsc.Spec.Subject = &operatorv1alpha1.X509Subject{Organizations: []string{"org"}}
+				dsc.Spec.URIs = []string{"spiffe://marin3r.3scale.net/test"}
+				dsc.Spec.Usages = []operatorv1alpha1.KeyUsage{operatorv1alpha1.UsageDigitalSignature, operatorv1alpha1.UsageClientAuth}
+				return dsc
+			},
+			want: map[string]interface{}{
+				"secretName":  "secret",
+				"commonName":  "test",
+				"duration":    "1h0m0s",
+				"renewBefore": "12m0s",
+				"isCA":        false,
+				"privateKey":  map[string]interface{}{"algorithm": "RSA", "size": int64(3072)},
+				"usages":      []interface{}{"digital signature", "client auth"},
+				"issuerRef":   map[string]interface{}{"name": "issuer", "kind": "ClusterIssuer", "group": "cert-manager.io"},
+				"dnsNames":    []interface{}{"example.com"},
+				"uris":        []interface{}{"spiffe://marin3r.3scale.net/test"},
+				"subject":     map[string]interface{}{"organizations": []interface{}{"org"}},
+			},
+			wantErr: false,
+		},
+		{
+			name: "Fails without a cert-manager signer",
+			dsc: func() *operatorv1alpha1.DiscoveryServiceCertificate {
+				dsc := testDiscoveryServiceCertificate()
+				dsc.Spec.Signer = operatorv1alpha1.DiscoveryServiceCertificateSigner{SelfSigned: &operatorv1alpha1.SelfSignedConfig{}}
+				return dsc
+			},
+			want:    nil,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := certificateSpec(tt.dsc())
+			if (err != nil) != tt.wantErr {
+				t.Errorf("certificateSpec() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("certificateSpec() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
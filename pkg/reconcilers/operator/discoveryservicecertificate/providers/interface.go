@@ -7,3 +7,29 @@ type CertificateProvider interface {
 	UpdateCertificate() ([]byte, []byte, error)
 	VerifyCertificate() error
 }
+
+// PendingError is an error type returned by providers that issue
+// certificates asynchronously when the certificate has been requested
+// but not issued yet
+type PendingError struct {
+	msg string
+}
+
+func (pe PendingError) Error() string {
+	return pe.msg
+}
+
+// NewPendingError returns a PendingError
+func NewPendingError(msg string) PendingError {
+	return PendingError{msg: msg}
+}
+
+// IsPendingError returns true if the error
+// has type PendingError
+func IsPendingError(err error) bool {
+	switch err.(type) {
+	case PendingError:
+		return true
+	}
+	return false
+}
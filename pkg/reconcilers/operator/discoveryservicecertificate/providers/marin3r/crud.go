@@ -3,7 +3,9 @@ package providers
 import (
 	"context"
 	"crypto/x509"
+	"crypto/x509/pkix"
 	"fmt"
+	"net/url"
 	"time"
 
 	operatorv1alpha1 "github.com/3scale/marin3r/apis/operator/v1alpha1"
@@ -188,7 +190,25 @@ func (cp *CertificateProvider) VerifyCertificate() error {
 		root = cert
 	}
 
-	return pki.Verify(cert, root)
+	if err := pki.Verify(cert, root); err != nil {
+		return err
+	}
+
+	// Certificates are reissued when the private key configuration changes
+	if pk := cp.dsc.Spec.PrivateKey; pk != nil && !pki.KeyMatches(cert.PublicKey, pki.KeyAlgorithm(pk.Algorithm), pk.Size) {
+		return pki.NewVerifyError(fmt.Sprintf("certificate key is not a %s %d key", pk.Algorithm, pk.Size))
+	}
+
+	// Server certificates are reissued when the hosts change
+	if cp.dsc.IsServerCertificate() {
+		for _, host := range cp.dsc.GetHosts() {
+			if err := cert.VerifyHostname(host); err != nil {
+				return pki.NewVerifyError(fmt.Sprintf("certificate is not valid for host %s", host))
+			}
+		}
+	}
+
+	return nil
 }
 
 // getIssuerCertificate returns the issuer certificate for a DiscoveryServiceCertificate resource
@@ -223,15 +243,11 @@ func (cp *CertificateProvider) getIssuerCertificate() (*x509.Certificate, interf
 
 func (cp *CertificateProvider) genSecret(issuerCert *x509.Certificate, issuerKey interface{}) (*corev1.Secret, error) {
 
-	crt, key, err := pki.GenerateCertificate(
-		issuerCert,
-		issuerKey,
-		cp.dsc.Spec.CommonName,
-		time.Duration(cp.dsc.Spec.ValidFor)*time.Second,
-		cp.dsc.IsServerCertificate(),
-		cp.dsc.IsCA(),
-		cp.dsc.GetHosts()...,
-	)
+	opts, err := certificateOptions(cp.dsc)
+	if err != nil {
+		return nil, err
+	}
+	crt, key, err := pki.GenerateCertificateWithOptions(issuerCert, issuerKey, opts)
 	if err != nil {
 		return nil, err
 	}
@@ -246,3 +262,60 @@ func (cp *CertificateProvider) genSecret(issuerCert *x509.Certificate, issuerKey
 
 	return secret, err
 }
+
+// certificateOptions returns the options to issue the
+// certificate of a DiscoveryServiceCertificate
+func certificateOptions(dsc *operatorv1alpha1.DiscoveryServiceCertificate) (pki.CertificateOptions, error) {
+
+	opts := pki.CertificateOptions{
+		CommonName: dsc.Spec.CommonName,
+		ValidFor:   time.Duration(dsc.Spec.ValidFor) * time.Second,
+		IsServer:   dsc.IsServerCertificate(),
+		IsCA:       dsc.IsCA(),
+		Hosts:      dsc.GetHosts(),
+	}
+
+	if pk := dsc.Spec.PrivateKey; pk != nil {
+		opts.KeyAlgorithm = pki.KeyAlgorithm(pk.Algorithm)
+		opts.KeySize = pk.Size
+	}
+
+	if subject := dsc.Spec.Subject; subject != nil {
+		opts.Subject = &pkix.Name{
+			Organization:       subject.Organizations,
+			OrganizationalUnit: subject.OrganizationalUnits,
+			Country:            subject.Countries,
+			Province:           subject.Provinces,
+			Locality:           subject.Localities,
+		}
+	}
+
+	for _, u := range dsc.Spec.URIs {
+		uri, err := url.Parse(u)
+		if err != nil {
+			return pki.CertificateOptions{}, fmt.Errorf("invalid URI SAN %q: %w", u, err)
+		}
+		opts.URIs = append(opts.URIs, uri)
+	}
+
+	for _, usage := range dsc.Spec.Usages {
+		switch usage {
+		case operatorv1alpha1.UsageDigitalSignature:
+			opts.KeyUsage |= x509.KeyUsageDigitalSignature
+		case operatorv1alpha1.UsageKeyEncipherment:
+			opts.KeyUsage |= x509.KeyUsageKeyEncipherment
+		case operatorv1alpha1.UsageCertSign:
+			opts.KeyUsage |= x509.KeyUsageCertSign
+		case operatorv1alpha1.UsageCRLSign:
+			opts.KeyUsage |= x509.KeyUsageCRLSign
+		case operatorv1alpha1.UsageServerAuth:
+			opts.ExtKeyUsage = append(opts.ExtKeyUsage, x509.ExtKeyUsageServerAuth)
+		case operatorv1alpha1.UsageClientAuth:
+			opts.ExtKeyUsage = append(opts.ExtKeyUsage, x509.ExtKeyUsageClientAuth)
+		default:
+			return pki.CertificateOptions{}, fmt.Errorf("unsupported key usage %q", usage)
+		}
+	}
+
+	return opts, nil
+}
@@ -3,8 +3,11 @@ package providers
 import (
 	"context"
 	"crypto/x509"
+	"crypto/x509/pkix"
+	"net/url"
 	"reflect"
 	"testing"
+	"time"
 
 	operatorv1alpha1 "github.com/3scale/marin3r/apis/operator/v1alpha1"
 	"github.com/3scale/marin3r/pkg/util/pki"
@@ -628,3 +631,118 @@ func TestCertificateProvider_genSecret(t *testing.T) {
 		})
 	}
 }
+
+func TestCertificateProvider_VerifyCertificate_PrivateKey(t *testing.T) {
+	crt, key, err := pki.GenerateCertificateWithOptions(nil, nil, pki.CertificateOptions{
+		CommonName: "test", ValidFor: time.Hour, KeyAlgorithm: pki.ECDSAKeyAlgorithm,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		privateKey *operatorv1alpha1.PrivateKeyConfig
+		wantErr    bool
+	}{
+		{"Valid with the default private key", nil, false},
+		{"Valid with a matching private key", &operatorv1alpha1.PrivateKeyConfig{Algorithm: operatorv1alpha1.ECDSAPrivateKeyAlgorithm, Size: 256}, false},
+		{"Invalid with a different key size", &operatorv1alpha1.PrivateKeyConfig{Algorithm: operatorv1alpha1.ECDSAPrivateKeyAlgorithm, Size: 384}, true},
+		{"Invalid with a different key algorithm", &operatorv1alpha1.PrivateKeyConfig{Algorithm: operatorv1alpha1.RSAPrivateKeyAlgorithm}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cp := &CertificateProvider{
+				ctx:    context.TODO(),
+				logger: ctrl.Log.WithName("test"),
+				client: fake.NewFakeClientWithScheme(s,
+					&corev1.Secret{
+						ObjectMeta: metav1.ObjectMeta{Name: "secret", Namespace: "test"},
+						Data:       map[string][]byte{tlsCertificateKey: crt, tlsPrivateKeyKey: key},
+					},
+				),
+				scheme: s,
+				dsc: &operatorv1alpha1.DiscoveryServiceCertificate{
+					ObjectMeta: metav1.ObjectMeta{Name: "dsc", Namespace: "test"},
+					Spec: operatorv1alpha1.DiscoveryServiceCertificateSpec{
+						Signer:     operatorv1alpha1.DiscoveryServiceCertificateSigner{SelfSigned: &operatorv1alpha1.SelfSignedConfig{}},
+						SecretRef:  corev1.SecretReference{Name: "secret"},
+						PrivateKey: tt.privateKey,
+					},
+				},
+			}
+			err := cp.VerifyCertificate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("CertificateProvider.VerifyCertificate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !pki.IsVerifyError(err) {
+				t.Errorf("CertificateProvider.VerifyCertificate() error = %v, want a VerifyError", err)
+			}
+		})
+	}
+}
+
+func Test_certificateOptions(t *testing.T) {
+	tests := []struct {
+		name    string
+		dsc     *operatorv1alpha1.DiscoveryServiceCertificate
+		want    pki.CertificateOptions
+		wantErr bool
+	}{
+		{
+			name: "Returns the default options",
+			dsc: &operatorv1alpha1.DiscoveryServiceCertificate{
+				Spec: operatorv1alpha1.DiscoveryServiceCertificateSpec{CommonName: "test", ValidFor: 3600},
+			},
+			want:    pki.CertificateOptions{CommonName: "test", ValidFor: time.Hour, Hosts: []string{"test"}},
+			wantErr: false,
+		},
+		{
+			name: "Returns the key, subject, URIs and usages",
+			dsc: &operatorv1alpha1.DiscoveryServiceCertificate{
+				Spec: operatorv1alpha1.DiscoveryServiceCertificateSpec{
+					CommonName: "test",
+					ValidFor:   3600,
+					PrivateKey: &operatorv1alpha1.PrivateKeyConfig{Algorithm: operatorv1alpha1.RSAPrivateKeyAlgorithm, Size: 4096},
+					Subject:    &operatorv1alpha1.X509Subject{Organizations: []string{"org"}, Countries: []string{"ES"}},
+					URIs:       []string{"spiffe://marin3r.3scale.net/test"},
+					Usages: []operatorv1alpha1.KeyUsage{
+						operatorv1alpha1.UsageDigitalSignature, operatorv1alpha1.UsageKeyEncipherment, operatorv1alpha1.UsageClientAuth,
+					},
+				},
+			},
+			want: pki.CertificateOptions{
+				CommonName:   "test",
+				ValidFor:     time.Hour,
+				Hosts:        []string{"test"},
+				KeyAlgorithm: pki.RSAKeyAlgorithm,
+				KeySize:      4096,
+				Subject:      &pkix.Name{Organization: []string{"org"}, Country: []string{"ES"}},
+				URIs:         []*url.URL{{Scheme: "spiffe", Host: "marin3r.3scale.net", Path: "/test"}},
+				KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+				ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+			},
+			wantErr: false,
+		},
+		{
+			name: "Fails for unsupported usages",
+			dsc: &operatorv1alpha1.DiscoveryServiceCertificate{
+				Spec: operatorv1alpha1.DiscoveryServiceCertificateSpec{Usages: []operatorv1alpha1.KeyUsage{"code signing"}},
+			},
+			want:    pki.CertificateOptions{},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := certificateOptions(tt.dsc)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("certificateOptions() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("certificateOptions() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -8,6 +8,7 @@ import (
 	operatorv1alpha1 "github.com/3scale/marin3r/apis/operator/v1alpha1"
 	"github.com/3scale/marin3r/pkg/common"
 	"github.com/3scale/marin3r/pkg/reconcilers/operator/discoveryservicecertificate/providers"
+	certmanager_provider "github.com/3scale/marin3r/pkg/reconcilers/operator/discoveryservicecertificate/providers/certmanager"
 	internal_provider "github.com/3scale/marin3r/pkg/reconcilers/operator/discoveryservicecertificate/providers/marin3r"
 	"github.com/3scale/marin3r/pkg/util/pki"
 	"github.com/go-logr/logr"
@@ -46,8 +47,9 @@ type CertificateReconciler struct {
 	schedule  *time.Duration
 }
 
-// Ensure the provider implements the CertificateProvider interface
+// Ensure the providers implement the CertificateProvider interface
 var _ providers.CertificateProvider = &internal_provider.CertificateProvider{}
+var _ providers.CertificateProvider = &certmanager_provider.CertificateProvider{}
 
 // NewCertificateReconciler returns a new RevisionReconciler
 func NewCertificateReconciler(ctx context.Context, logger logr.Logger, client client.Client,
@@ -101,6 +103,11 @@ func (r *CertificateReconciler) Reconcile() (ctrl.Result, error) {
 		if errors.IsNotFound(err) {
 			_, _, err = r.provider.CreateCertificate()
 			if err != nil {
+				if providers.IsPendingError(err) {
+					// The provider notifies when the certificate is issued
+					r.logger.Info("certificate issuance pending", "reason", err.Error())
+					return ctrl.Result{}, nil
+				}
 				return ctrl.Result{}, err
 			}
 			return ctrl.Result{Requeue: true}, nil
@@ -139,18 +146,25 @@ func (r *CertificateReconciler) Reconcile() (ctrl.Result, error) {
 
 		// If certificate is not valid or is within the renewal window, reissue it
 		if r.ready == false || timeToExpire < renewBefore {
-			certBytes, _, err = r.provider.UpdateCertificate()
-			if err != nil {
+			_, _, err = r.provider.UpdateCertificate()
+			if err == nil {
+				r.logger.Info("reissued certificate")
+				return ctrl.Result{Requeue: true}, nil
+			}
+			if !providers.IsPendingError(err) {
 				return ctrl.Result{}, err
 			}
-			r.logger.Info("reissued certificate")
-			return ctrl.Result{Requeue: true}, nil
-		}
+			// The provider notifies when the certificate is reissued, meanwhile
+			// the status reports the current certificate
+			r.logger.Info("certificate reissuance pending", "reason", err.Error())
+			r.schedule = nil
 
-		// schedule next reconcile
-		schedule := timeToExpire - renewBefore
-		r.schedule = &schedule
-		r.logger.Info("scheduled certificate renewal", "time", r.clock.Now().Add(schedule).String())
+		} else {
+			// schedule next reconcile
+			schedule := timeToExpire - renewBefore
+			r.schedule = &schedule
+			r.logger.Info("scheduled certificate renewal", "time", r.clock.Now().Add(schedule).String())
+		}
 
 	} else {
 		// schedule nextReconcile when certificate expires to update Ready = false in the status
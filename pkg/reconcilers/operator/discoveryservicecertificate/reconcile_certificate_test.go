@@ -45,14 +45,19 @@ func (tc testClock) Now() time.Time { return tc.now }
 // testCertificateProvider fakes a certificate provider by returning the hardcoded certificates the provider
 // is created with. The certificate in index 0 is returned when CreateCertificate() is invoked and its used as the
 // "active" certificate for GetCertificate() and VerifyCertificate() operations. Each time UpdateCertificate()
-// is called, the next certificates in the slice is used as the "active" one for all methods.
+// is called, the next certificates in the slice is used as the "active" one for all methods. If pending
+// is true, CreateCertificate() and UpdateCertificate() return a PendingError instead.
 type testCertificateProvider struct {
 	index        int
 	certificates [][]byte
 	currentTime  time.Time
+	pending      bool
 }
 
 func (tcp *testCertificateProvider) CreateCertificate() ([]byte, []byte, error) {
+	if tcp.pending {
+		return nil, nil, providers.NewPendingError("pending")
+	}
 	tcp.index = 0
 	cert := tcp.certificates[tcp.index]
 	return cert, []byte("key"), nil
@@ -65,6 +70,9 @@ func (tcp *testCertificateProvider) GetCertificate() ([]byte, []byte, error) {
 	return cert, []byte("key"), nil
 }
 func (tcp *testCertificateProvider) UpdateCertificate() ([]byte, []byte, error) {
+	if tcp.pending {
+		return nil, nil, providers.NewPendingError("pending")
+	}
 	tcp.index = tcp.index + 1
 	cert := tcp.certificates[tcp.index]
 	return cert, []byte("key"), nil
@@ -470,6 +478,79 @@ func TestCertificateReconciler_Reconcile(t *testing.T) {
 			wantNotAfter:  &time.Time{},
 			wantSchedule:  nil,
 		},
+		{
+			name: "Waits for a pending certificate issuance",
+			r: &CertificateReconciler{
+				ctx:    context.TODO(),
+				logger: ctrl.Log.WithName("test"),
+				client: fake.NewFakeClientWithScheme(s),
+				scheme: s,
+				dsc: &operatorv1alpha1.DiscoveryServiceCertificate{
+					ObjectMeta: metav1.ObjectMeta{Name: "dsc", Namespace: "test"},
+					Spec:       operatorv1alpha1.DiscoveryServiceCertificateSpec{}},
+				provider: &testCertificateProvider{
+					index:   -1,
+					pending: true,
+					// go run hack/gen_cert.go --not-before=2021-01-01T00:00:00Z --not-after=2021-01-01T00:01:40Z --key-size 512
+					certificates: [][]byte{
+						[]byte(heredoc.Doc(`
+						-----BEGIN CERTIFICATE-----
+						MIIBdjCCASCgAwIBAgIQFS94k33VgPtanU/j0OvC8DANBgkqhkiG9w0BAQsFADAr
+						MRUwEwYDVQQKEwxtYXJpbjNyLnRlc3QxEjAQBgNVBAMTCWxvY2FsaG9zdDAeFw0y
+						MTAxMDEwMDAwMDBaFw0yMTAxMDEwMDAxNDBaMCsxFTATBgNVBAoTDG1hcmluM3Iu
+						dGVzdDESMBAGA1UEAxMJbG9jYWxob3N0MFwwDQYJKoZIhvcNAQEBBQADSwAwSAJB
+						AK1ShFw1t1r8vrn5cVJj98ei4UYAwIy7hymr7oCXom1TcWCLURZsMfKG2A8YKUBC
+						iKQWT/zAknqKOrV8qn9bSUkCAwEAAaMgMB4wDgYDVR0PAQH/BAQDAgWgMAwGA1Ud
+						EwEB/wQCMAAwDQYJKoZIhvcNAQELBQADQQBVv03X7BjjcTqpkcCCiejTyJYTc1pN
+						kfwbx8mNF+Zx5V763W74/+fr2Z5+Q0l7O1k3gcsnaWSoGfV9PST7iNpQ
+						-----END CERTIFICATE-----
+						`)),
+					},
+				},
+				clock: realClock{},
+			},
+			want:         ctrl.Result{},
+			wantErr:      false,
+			wantIsReady:  false,
+			wantSchedule: nil,
+		},
+		{
+			name: "Returns not ready while the certificate reissuance is pending (renewal enabled)",
+			r: &CertificateReconciler{
+				ctx:    context.TODO(),
+				logger: ctrl.Log.WithName("test"),
+				client: fake.NewFakeClientWithScheme(s),
+				scheme: s,
+				dsc: &operatorv1alpha1.DiscoveryServiceCertificate{
+					ObjectMeta: metav1.ObjectMeta{Name: "dsc", Namespace: "test"},
+					Spec:       operatorv1alpha1.DiscoveryServiceCertificateSpec{},
+				},
+				provider: &testCertificateProvider{
+					index:   0,
+					pending: true,
+					// go run hack/gen_cert.go --not-before=2021-01-01T00:00:00Z --not-after=2021-01-01T00:01:40Z --key-size 512
+					certificates: [][]byte{
+						[]byte(heredoc.Doc(`
+						-----BEGIN CERTIFICATE-----
+						MIIBdjCCASCgAwIBAgIQFS94k33VgPtanU/j0OvC8DANBgkqhkiG9w0BAQsFADAr
+						MRUwEwYDVQQKEwxtYXJpbjNyLnRlc3QxEjAQBgNVBAMTCWxvY2FsaG9zdDAeFw0y
+						MTAxMDEwMDAwMDBaFw0yMTAxMDEwMDAxNDBaMCsxFTATBgNVBAoTDG1hcmluM3Iu
+						dGVzdDESMBAGA1UEAxMJbG9jYWxob3N0MFwwDQYJKoZIhvcNAQEBBQADSwAwSAJB
+						AK1ShFw1t1r8vrn5cVJj98ei4UYAwIy7hymr7oCXom1TcWCLURZsMfKG2A8YKUBC
+						iKQWT/zAknqKOrV8qn9bSUkCAwEAAaMgMB4wDgYDVR0PAQH/BAQDAgWgMAwGA1Ud
+						EwEB/wQCMAAwDQYJKoZIhvcNAQELBQADQQBVv03X7BjjcTqpkcCCiejTyJYTc1pN
+						kfwbx8mNF+Zx5V763W74/+fr2Z5+Q0l7O1k3gcsnaWSoGfV9PST7iNpQ
+						-----END CERTIFICATE-----
+						`)),
+					},
+					currentTime: func() time.Time { t, _ := time.Parse(time.RFC3339, "2021-01-01T00:02:00Z"); return t }(),
+				},
+				clock: testClock{now: func() time.Time { t, _ := time.Parse(time.RFC3339, "2021-01-01T00:02:00Z"); return t }()},
+			},
+			want:        ctrl.Result{},
+			wantErr:     false,
+			wantIsReady: false,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -0,0 +1,139 @@
+package reconcilers
+
+import (
+	"context"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"time"
+
+	operatorv1alpha1 "github.com/3scale/marin3r/apis/operator/v1alpha1"
+	"github.com/3scale/marin3r/pkg/util/pki"
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+// RevocationReconciler is a struct with methods to revoke the
+// certificates of deleted DiscoveryServiceCertificates
+type RevocationReconciler struct {
+	ctx    context.Context
+	logger logr.Logger
+	client client.Client
+	scheme *runtime.Scheme
+	dsc    *operatorv1alpha1.DiscoveryServiceCertificate
+}
+
+// NewRevocationReconciler returns a new RevocationReconciler
+func NewRevocationReconciler(ctx context.Context, logger logr.Logger, client client.Client,
+	s *runtime.Scheme, dsc *operatorv1alpha1.DiscoveryServiceCertificate) RevocationReconciler {
+
+	return RevocationReconciler{ctx, logger, client, s, dsc}
+}
+
+// Revoke adds the certificate of the DiscoveryServiceCertificate to the revocation list
+// of its issuer. The revocation list is stored in a Secret next to the issuer's Secret
+// and is signed by the issuer, so it is discarded when the issuer changes.
+func (r *RevocationReconciler) Revoke() error {
+
+	if !r.dsc.IsRevocable() {
+		return nil
+	}
+
+	secret := &corev1.Secret{}
+	if err := r.client.Get(r.ctx, types.NamespacedName{Name: r.dsc.Spec.SecretRef.Name, Namespace: r.dsc.GetNamespace()}, secret); err != nil {
+		if errors.IsNotFound(err) {
+			// Nothing to revoke if the certificate was never issued
+			return nil
+		}
+		return err
+	}
+	cert, err := pki.LoadX509Certificate(secret.Data[corev1.TLSCertKey])
+	if err != nil {
+		return err
+	}
+
+	issuerSecret := &corev1.Secret{}
+	issuerKey := types.NamespacedName{
+		Name:      r.dsc.Spec.Signer.CASigned.SecretRef.Name,
+		Namespace: r.dsc.Spec.Signer.CASigned.SecretRef.Namespace,
+	}
+	if err := r.client.Get(r.ctx, issuerKey, issuerSecret); err != nil {
+		if errors.IsNotFound(err) {
+			// Certificates of a deleted issuer are not trusted anymore
+			return nil
+		}
+		return err
+	}
+	issuerCert, err := pki.LoadX509Certificate(issuerSecret.Data[corev1.TLSCertKey])
+	if err != nil {
+		return err
+	}
+	issuerPrivateKey, err := pki.DecodePrivateKeyBytes(issuerSecret.Data[corev1.TLSPrivateKeyKey])
+	if err != nil {
+		return err
+	}
+
+	crlSecret := &corev1.Secret{}
+	crlKey := types.NamespacedName{Name: operatorv1alpha1.RevocationListSecretName(issuerKey.Name), Namespace: issuerKey.Namespace}
+	exists := true
+	if err := r.client.Get(r.ctx, crlKey, crlSecret); err != nil {
+		if !errors.IsNotFound(err) {
+			return err
+		}
+		exists = false
+	}
+
+	revoked := revokedCertificates(crlSecret.Data[operatorv1alpha1.RevocationListSecretKey], issuerCert)
+	if !isSerialListed(revoked, cert) {
+		revoked = append(revoked, pkix.RevokedCertificate{SerialNumber: cert.SerialNumber, RevocationTime: time.Now()})
+	}
+
+	crl, err := pki.GenerateRevocationList(issuerCert, issuerPrivateKey, revoked, time.Until(issuerCert.NotAfter))
+	if err != nil {
+		return err
+	}
+	crlSecret.Data = map[string][]byte{operatorv1alpha1.RevocationListSecretKey: crl}
+
+	if !exists {
+		crlSecret.ObjectMeta = metav1.ObjectMeta{Name: crlKey.Name, Namespace: crlKey.Namespace}
+		// The revocation list is garbage collected along with the issuer
+		if err := controllerutil.SetOwnerReference(issuerSecret, crlSecret, r.scheme); err != nil {
+			return err
+		}
+		if err := r.client.Create(r.ctx, crlSecret); err != nil {
+			return err
+		}
+	} else if err := r.client.Update(r.ctx, crlSecret); err != nil {
+		return err
+	}
+
+	r.logger.Info("revoked certificate", "SerialNumber", cert.SerialNumber.String(), "RevocationList", crlKey.Name)
+	return nil
+}
+
+// revokedCertificates returns the entries of the given revocation list, or
+// none if the list is missing, invalid or was signed by a different issuer
+func revokedCertificates(crl []byte, issuerCert *x509.Certificate) []pkix.RevokedCertificate {
+	if len(crl) == 0 {
+		return []pkix.RevokedCertificate{}
+	}
+	list, err := pki.LoadRevocationList(crl, issuerCert)
+	if err != nil {
+		return []pkix.RevokedCertificate{}
+	}
+	return list.TBSCertList.RevokedCertificates
+}
+
+func isSerialListed(revoked []pkix.RevokedCertificate, cert *x509.Certificate) bool {
+	for _, entry := range revoked {
+		if entry.SerialNumber.Cmp(cert.SerialNumber) == 0 {
+			return true
+		}
+	}
+	return false
+}
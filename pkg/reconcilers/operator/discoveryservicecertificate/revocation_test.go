@@ -0,0 +1,91 @@
+package reconcilers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	operatorv1alpha1 "github.com/3scale/marin3r/apis/operator/v1alpha1"
+	"github.com/3scale/marin3r/pkg/util/pki"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestRevocationReconciler_Revoke(t *testing.T) {
+	caPEM, caKeyPEM, err := pki.GenerateCertificate(nil, nil, "ca", time.Hour, false, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ca, _ := pki.LoadX509Certificate(caPEM)
+	caKey, _ := pki.DecodePrivateKeyBytes(caKeyPEM)
+
+	issue := func(cn string) *corev1.Secret {
+		crt, key, err := pki.GenerateCertificate(ca, caKey, cn, time.Hour, false, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: cn, Namespace: "default"},
+			Data:       map[string][]byte{tlsCertificateKey: crt, tlsPrivateKeyKey: key},
+		}
+	}
+	dsc := func(secret string) *operatorv1alpha1.DiscoveryServiceCertificate {
+		return &operatorv1alpha1.DiscoveryServiceCertificate{
+			ObjectMeta: metav1.ObjectMeta{Name: secret, Namespace: "default"},
+			Spec: operatorv1alpha1.DiscoveryServiceCertificateSpec{
+				SecretRef: corev1.SecretReference{Name: secret},
+				Signer: operatorv1alpha1.DiscoveryServiceCertificateSigner{
+					CASigned: &operatorv1alpha1.CASignedConfig{
+						SecretRef: corev1.SecretReference{Name: "ca", Namespace: "default"},
+					},
+				},
+			},
+		}
+	}
+
+	caSecret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "ca", Namespace: "default"},
+		Data:       map[string][]byte{tlsCertificateKey: caPEM, tlsPrivateKeyKey: caKeyPEM},
+	}
+	first := issue("first")
+	second := issue("second")
+	cl := fake.NewFakeClientWithScheme(s, caSecret, first, second)
+
+	for _, secret := range []*corev1.Secret{first, second, first} {
+		r := NewRevocationReconciler(context.TODO(), ctrl.Log.WithName("test"), cl, s, dsc(secret.GetName()))
+		if err := r.Revoke(); err != nil {
+			t.Fatalf("RevocationReconciler.Revoke() error = %v", err)
+		}
+	}
+
+	crlSecret := &corev1.Secret{}
+	if err := cl.Get(context.TODO(), types.NamespacedName{Name: "ca-crl", Namespace: "default"}, crlSecret); err != nil {
+		t.Fatalf("RevocationReconciler.Revoke() revocation list not created: %v", err)
+	}
+	list, err := pki.LoadRevocationList(crlSecret.Data[operatorv1alpha1.RevocationListSecretKey], ca)
+	if err != nil {
+		t.Fatalf("RevocationReconciler.Revoke() invalid revocation list: %v", err)
+	}
+	if got := len(list.TBSCertList.RevokedCertificates); got != 2 {
+		t.Errorf("RevocationReconciler.Revoke() got %v revoked certificates, want 2", got)
+	}
+	for _, secret := range []*corev1.Secret{first, second} {
+		cert, _ := pki.LoadX509Certificate(secret.Data[tlsCertificateKey])
+		if !pki.IsRevoked(list, cert) {
+			t.Errorf("RevocationReconciler.Revoke() certificate %s not revoked", secret.GetName())
+		}
+	}
+	if len(crlSecret.GetOwnerReferences()) != 1 || crlSecret.GetOwnerReferences()[0].Name != "ca" {
+		t.Errorf("RevocationReconciler.Revoke() revocation list not owned by the CA Secret: %v", crlSecret.GetOwnerReferences())
+	}
+
+	t.Run("Does nothing for certificates that were never issued", func(t *testing.T) {
+		r := NewRevocationReconciler(context.TODO(), ctrl.Log.WithName("test"), cl, s, dsc("missing"))
+		if err := r.Revoke(); err != nil {
+			t.Errorf("RevocationReconciler.Revoke() error = %v", err)
+		}
+	})
+}
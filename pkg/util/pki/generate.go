@@ -1,36 +1,144 @@
 package pki
 
 import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
 	"crypto/rand"
 	"crypto/rsa"
 	"crypto/x509"
 	"crypto/x509/pkix"
 	"encoding/pem"
+	"fmt"
 	"math/big"
 	"net"
+	"net/url"
 	"time"
 )
 
-// GeneratePrivateKey generates a new RSA private key
-func GeneratePrivateKey() (*rsa.PrivateKey, error) {
-	priv, err := rsa.GenerateKey(rand.Reader, 2048)
-	if err != nil {
-		return nil, err
+// KeyAlgorithm is the public key algorithm of a private key
+type KeyAlgorithm string
+
+const (
+	// RSAKeyAlgorithm generates RSA keys of 3072 or 4096 bits
+	RSAKeyAlgorithm KeyAlgorithm = "RSA"
+	// ECDSAKeyAlgorithm generates ECDSA keys over the P-256 or P-384 curves
+	ECDSAKeyAlgorithm KeyAlgorithm = "ECDSA"
+
+	// DefaultKeyAlgorithm is the algorithm of the keys generated when none is specified
+	DefaultKeyAlgorithm KeyAlgorithm = ECDSAKeyAlgorithm
+	// DefaultRSAKeySize is the size of the RSA keys generated when none is specified
+	DefaultRSAKeySize int = 3072
+	// DefaultECDSAKeySize is the size of the ECDSA keys generated when none is specified
+	DefaultECDSAKeySize int = 256
+)
+
+// CertificateOptions holds the options of the certificates
+// issued by GenerateCertificateWithOptions
+type CertificateOptions struct {
+	CommonName string
+	// Subject holds additional fields of the subject of the certificate, its CommonName
+	// is ignored. Defaults to a subject with the "marin3r.3scale.net" Organization.
+	Subject  *pkix.Name
+	ValidFor time.Duration
+	// IsServer adds the server auth extended key usage
+	IsServer bool
+	IsCA     bool
+	// Hosts are added to the certificate as DNS or IP SANs
+	Hosts []string
+	URIs  []*url.URL
+	// KeyUsage overrides the default key usages of the certificate if not zero
+	KeyUsage x509.KeyUsage
+	// ExtKeyUsage are the extended key usages of the certificate
+	ExtKeyUsage []x509.ExtKeyUsage
+	// KeyAlgorithm and KeySize are the algorithm and size of the private key.
+	// The defaults for the algorithm are used if unset.
+	KeyAlgorithm KeyAlgorithm
+	KeySize      int
+}
+
+// GeneratePrivateKey generates a new private key with the default algorithm and size
+func GeneratePrivateKey() (crypto.Signer, error) {
+	return GenerateKey(DefaultKeyAlgorithm, 0)
+}
+
+// GenerateKey generates a new private key with the given algorithm and size. Supported
+// sizes are 3072 and 4096 bits for RSA and 256 and 384 bits for ECDSA. The default size
+// for the algorithm is used if size is 0.
+func GenerateKey(algorithm KeyAlgorithm, size int) (crypto.Signer, error) {
+	switch algorithm {
+	case RSAKeyAlgorithm:
+		if size == 0 {
+			size = DefaultRSAKeySize
+		}
+		if size != 3072 && size != 4096 {
+			return nil, fmt.Errorf("unsupported RSA key size %d, must be 3072 or 4096", size)
+		}
+		return rsa.GenerateKey(rand.Reader, size)
+
+	case ECDSAKeyAlgorithm:
+		var curve elliptic.Curve
+		switch size {
+		case 0, 256:
+			curve = elliptic.P256()
+		case 384:
+			curve = elliptic.P384()
+		default:
+			return nil, fmt.Errorf("unsupported ECDSA key size %d, must be 256 or 384", size)
+		}
+		return ecdsa.GenerateKey(curve, rand.Reader)
+
+	default:
+		return nil, fmt.Errorf("unsupported key algorithm %q", algorithm)
+	}
+}
+
+// KeyMatches returns true if the public key is of the given algorithm and
+// size. The default size for the algorithm is used if size is 0.
+func KeyMatches(pub crypto.PublicKey, algorithm KeyAlgorithm, size int) bool {
+	switch key := pub.(type) {
+	case *rsa.PublicKey:
+		if size == 0 {
+			size = DefaultRSAKeySize
+		}
+		return algorithm == RSAKeyAlgorithm && key.N.BitLen() == size
+	case *ecdsa.PublicKey:
+		if size == 0 {
+			size = DefaultECDSAKeySize
+		}
+		return algorithm == ECDSAKeyAlgorithm && key.Curve.Params().BitSize == size
+	default:
+		return false
 	}
-	return priv, nil
 }
 
 // GenerateCertificate issues a new certificate with the passed options and signed by the parent certificate if one is given. A self-signed
 // is issued otherwise.
 func GenerateCertificate(issuerCert *x509.Certificate, signerKey interface{}, commonName string, validFor time.Duration, isServer, isCA bool, host ...string) ([]byte, []byte, error) {
+	return GenerateCertificateWithOptions(issuerCert, signerKey, CertificateOptions{
+		CommonName: commonName,
+		ValidFor:   validFor,
+		IsServer:   isServer,
+		IsCA:       isCA,
+		Hosts:      host,
+	})
+}
 
-	priv, err := GeneratePrivateKey()
+// GenerateCertificateWithOptions issues a new certificate with the passed options and signed by the
+// parent certificate if one is given. A self-signed is issued otherwise.
+func GenerateCertificateWithOptions(issuerCert *x509.Certificate, signerKey interface{}, opts CertificateOptions) ([]byte, []byte, error) {
+
+	algorithm := opts.KeyAlgorithm
+	if algorithm == "" {
+		algorithm = DefaultKeyAlgorithm
+	}
+	priv, err := GenerateKey(algorithm, opts.KeySize)
 	if err != nil {
 		return nil, nil, err
 	}
 
 	notBefore := time.Now()
-	notAfter := notBefore.Add(validFor)
+	notAfter := notBefore.Add(opts.ValidFor)
 
 	serialNumberLimit := new(big.Int).Lsh(big.NewInt(1), 128)
 	serialNumber, err := rand.Int(rand.Reader, serialNumberLimit)
@@ -38,20 +146,29 @@ func GenerateCertificate(issuerCert *x509.Certificate, signerKey interface{}, co
 		return nil, nil, err
 	}
 
+	subject := pkix.Name{Organization: []string{"marin3r.3scale.net"}}
+	if opts.Subject != nil {
+		subject = *opts.Subject
+	}
+	subject.CommonName = opts.CommonName
+
 	template := x509.Certificate{
 		SerialNumber: serialNumber,
-		Subject: pkix.Name{
-			Organization: []string{"marin3r.3scale.net"},
-			CommonName:   commonName,
-		},
-		NotBefore: notBefore,
-		NotAfter:  notAfter,
-
-		KeyUsage:              x509.KeyUsageKeyEncipherment | x509.KeyUsageDigitalSignature,
+		Subject:      subject,
+		NotBefore:    notBefore,
+		NotAfter:     notAfter,
+
+		KeyUsage:              x509.KeyUsageDigitalSignature,
 		BasicConstraintsValid: true,
+		URIs:                  opts.URIs,
 	}
 
-	for _, h := range host {
+	// Key encipherment is only meaningful for RSA keys
+	if algorithm == RSAKeyAlgorithm {
+		template.KeyUsage |= x509.KeyUsageKeyEncipherment
+	}
+
+	for _, h := range opts.Hosts {
 		if ip := net.ParseIP(h); ip != nil {
 			template.IPAddresses = append(template.IPAddresses, ip)
 		} else {
@@ -59,27 +176,32 @@ func GenerateCertificate(issuerCert *x509.Certificate, signerKey interface{}, co
 		}
 	}
 
-	if isServer {
-		template.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth}
+	template.ExtKeyUsage = append(template.ExtKeyUsage, opts.ExtKeyUsage...)
+	if opts.IsServer && !hasExtKeyUsage(template.ExtKeyUsage, x509.ExtKeyUsageServerAuth) {
+		template.ExtKeyUsage = append([]x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth}, template.ExtKeyUsage...)
 	}
 
-	if isCA {
+	if opts.IsCA {
 		template.IsCA = true
-		template.KeyUsage = x509.KeyUsageCertSign
+		template.KeyUsage = x509.KeyUsageCertSign | x509.KeyUsageCRLSign
+	}
+
+	if opts.KeyUsage != 0 {
+		template.KeyUsage = opts.KeyUsage
 	}
 
 	var derBytes []byte
 
 	if issuerCert == nil {
 		// Self-signed
-		derBytes, err = x509.CreateCertificate(rand.Reader, &template, &template, &priv.PublicKey, priv)
+		derBytes, err = x509.CreateCertificate(rand.Reader, &template, &template, priv.Public(), priv)
 		if err != nil {
 			return nil, nil, err
 		}
 
 	} else {
 		// CA signed
-		derBytes, err = x509.CreateCertificate(rand.Reader, &template, issuerCert, &priv.PublicKey, signerKey)
+		derBytes, err = x509.CreateCertificate(rand.Reader, &template, issuerCert, priv.Public(), signerKey)
 		if err != nil {
 			return nil, nil, err
 		}
@@ -95,3 +217,12 @@ func GenerateCertificate(issuerCert *x509.Certificate, signerKey interface{}, co
 
 	return crtPEM, privPEM, nil
 }
+
+func hasExtKeyUsage(usages []x509.ExtKeyUsage, usage x509.ExtKeyUsage) bool {
+	for _, u := range usages {
+		if u == usage {
+			return true
+		}
+	}
+	return false
+}
@@ -1,7 +1,11 @@
 package pki
 
 import (
+	"crypto/tls"
 	"crypto/x509"
+	"crypto/x509/pkix"
+	"fmt"
+	"net/url"
 	"reflect"
 	"testing"
 	"time"
@@ -151,6 +155,119 @@ func TestGenerateCertificate(t *testing.T) {
 	}
 }
 
+func TestGenerateCertificateWithOptions(t *testing.T) {
+	uri, _ := url.Parse("spiffe://marin3r.3scale.net/ns/test")
+	tests := []struct {
+		name    string
+		opts    CertificateOptions
+		check   func(*x509.Certificate) bool
+		wantErr bool
+	}{
+		{
+			name:    "Generates an ECDSA P-256 key by default",
+			opts:    CertificateOptions{CommonName: "test", ValidFor: time.Hour},
+			check:   func(c *x509.Certificate) bool { return KeyMatches(c.PublicKey, ECDSAKeyAlgorithm, 256) },
+			wantErr: false,
+		},
+		{
+			name:    "Generates an ECDSA P-384 key",
+			opts:    CertificateOptions{CommonName: "test", ValidFor: time.Hour, KeyAlgorithm: ECDSAKeyAlgorithm, KeySize: 384},
+			check:   func(c *x509.Certificate) bool { return KeyMatches(c.PublicKey, ECDSAKeyAlgorithm, 384) },
+			wantErr: false,
+		},
+		{
+			name: "Generates an RSA 3072 key",
+			opts: CertificateOptions{CommonName: "test", ValidFor: time.Hour, KeyAlgorithm: RSAKeyAlgorithm},
+			check: func(c *x509.Certificate) bool {
+				return KeyMatches(c.PublicKey, RSAKeyAlgorithm, 3072) && c.KeyUsage&x509.KeyUsageKeyEncipherment != 0
+			},
+			wantErr: false,
+		},
+		{
+			name:    "Fails for RSA 2048 keys",
+			opts:    CertificateOptions{CommonName: "test", ValidFor: time.Hour, KeyAlgorithm: RSAKeyAlgorithm, KeySize: 2048},
+			wantErr: true,
+		},
+		{
+			name: "Sets the subject, URIs and usages",
+			opts: CertificateOptions{
+				CommonName:  "test",
+				Subject:     &pkix.Name{Organization: []string{"org"}, OrganizationalUnit: []string{"unit"}},
+				ValidFor:    time.Hour,
+				IsServer:    true,
+				URIs:        []*url.URL{uri},
+				ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+			},
+			check: func(c *x509.Certificate) bool {
+				return c.Subject.CommonName == "test" &&
+					reflect.DeepEqual(c.Subject.Organization, []string{"org"}) &&
+					reflect.DeepEqual(c.Subject.OrganizationalUnit, []string{"unit"}) &&
+					len(c.URIs) == 1 && c.URIs[0].String() == uri.String() &&
+					reflect.DeepEqual(c.ExtKeyUsage, []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth})
+			},
+			wantErr: false,
+		},
+		{
+			name: "Generates CAs that can sign revocation lists",
+			opts: CertificateOptions{CommonName: "test", ValidFor: time.Hour, IsCA: true},
+			check: func(c *x509.Certificate) bool {
+				return c.IsCA && c.KeyUsage == x509.KeyUsageCertSign|x509.KeyUsageCRLSign
+			},
+			wantErr: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, key, err := GenerateCertificateWithOptions(nil, nil, tt.opts)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("GenerateCertificateWithOptions() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr {
+				return
+			}
+			if _, err := tls.X509KeyPair(got, key); err != nil {
+				t.Errorf("GenerateCertificateWithOptions() key does not match the certificate: %v", err)
+			}
+			cert, err := LoadX509Certificate(got)
+			if err != nil {
+				t.Fatalf("GenerateCertificateWithOptions() error trying to load certificate = %v", err)
+			}
+			if !tt.check(cert) {
+				t.Errorf("GenerateCertificateWithOptions() unexpected certificate %+v", cert)
+			}
+		})
+	}
+}
+
+func TestGenerateKey(t *testing.T) {
+	tests := []struct {
+		algorithm KeyAlgorithm
+		size      int
+		wantErr   bool
+	}{
+		{RSAKeyAlgorithm, 0, false},
+		{RSAKeyAlgorithm, 4096, false},
+		{RSAKeyAlgorithm, 2048, true},
+		{ECDSAKeyAlgorithm, 0, false},
+		{ECDSAKeyAlgorithm, 384, false},
+		{ECDSAKeyAlgorithm, 521, true},
+		{"DSA", 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%s-%d", tt.algorithm, tt.size), func(t *testing.T) {
+			key, err := GenerateKey(tt.algorithm, tt.size)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("GenerateKey() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !tt.wantErr && !KeyMatches(key.Public(), tt.algorithm, tt.size) {
+				t.Errorf("GenerateKey() key does not match %s %d", tt.algorithm, tt.size)
+			}
+		})
+	}
+}
+
 func TestGeneratePrivateKey(t *testing.T) {
 	tests := []struct {
 		name    string
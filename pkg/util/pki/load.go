@@ -19,8 +19,35 @@ func LoadX509Certificate(cert []byte) (*x509.Certificate, error) {
 	return crt, nil
 }
 
+// LoadX509Certificates loads all the x509.Certificate objects from the
+// given PEM bytes, like a bundle of CA certificates
+func LoadX509Certificates(certs []byte) ([]*x509.Certificate, error) {
+
+	crts := []*x509.Certificate{}
+	for {
+		var block *pem.Block
+		block, certs = pem.Decode(certs)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		crt, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		crts = append(crts, crt)
+	}
+
+	if len(crts) == 0 {
+		return nil, fmt.Errorf("no certificates found")
+	}
+	return crts, nil
+}
+
 // DecodePrivateKeyBytes will decode a PEM encoded private key into a crypto.Signer.
-// It supports RSA private keys only. All other types will return err.
+// It supports PKCS#8 encoded keys, PKCS#1 RSA keys and SEC 1 EC keys. All other types will return err.
 func DecodePrivateKeyBytes(keyBytes []byte) (crypto.Signer, error) {
 	// decode the private key pem
 	block, _ := pem.Decode(keyBytes)
@@ -52,6 +79,13 @@ func DecodePrivateKeyBytes(keyBytes []byte) (crypto.Signer, error) {
 		}
 		return key, nil
 
+	case "EC PRIVATE KEY":
+		key, err := x509.ParseECPrivateKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing ec private key: %s", err.Error())
+		}
+		return key, nil
+
 	default:
 		return nil, fmt.Errorf("unknown private key type: %s", block.Type)
 	}
@@ -1,6 +1,11 @@
 package pki
 
 import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
 	"testing"
 )
 
@@ -90,6 +95,49 @@ func TestLoadX509Certificate(t *testing.T) {
 	}
 }
 
+func TestLoadX509Certificates(t *testing.T) {
+	type args struct {
+		certs []byte
+	}
+	tests := []struct {
+		name    string
+		args    args
+		want    int
+		wantErr bool
+	}{
+		{
+			name:    "Loads a single certificate",
+			args:    args{certs: testCertificate()},
+			want:    1,
+			wantErr: false,
+		},
+		{
+			name:    "Loads a bundle of certificates",
+			args:    args{certs: append(append(testCertificate(), testPrivateKey()...), testCertificate()...)},
+			want:    2,
+			wantErr: false,
+		},
+		{
+			name:    "Returns error",
+			args:    args{certs: testPrivateKey()},
+			want:    0,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := LoadX509Certificates(tt.args.certs)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("LoadX509Certificates() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if len(got) != tt.want {
+				t.Errorf("LoadX509Certificates() got %v certificates, want %v", len(got), tt.want)
+			}
+		})
+	}
+}
+
 func TestDecodePrivateKeyBytes(t *testing.T) {
 	type args struct {
 		keyBytes []byte
@@ -104,6 +152,15 @@ func TestDecodePrivateKeyBytes(t *testing.T) {
 			args:    args{keyBytes: testPrivateKey()},
 			wantErr: false,
 		},
+		{
+			name: "Loads an EC private key",
+			args: args{keyBytes: func() []byte {
+				key, _ := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+				der, _ := x509.MarshalECPrivateKey(key)
+				return pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der})
+			}()},
+			wantErr: false,
+		},
 		{
 			name:    "Returns an error",
 			args:    args{keyBytes: testCertificate()},
@@ -0,0 +1,54 @@
+package pki
+
+import (
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"time"
+)
+
+// GenerateRevocationList returns a PEM encoded certificate revocation
+// list with the given entries, signed by the issuer
+func GenerateRevocationList(issuerCert *x509.Certificate, signerKey interface{},
+	revoked []pkix.RevokedCertificate, validFor time.Duration) ([]byte, error) {
+
+	now := time.Now()
+	derBytes, err := issuerCert.CreateCRL(rand.Reader, signerKey, revoked, now, now.Add(validFor))
+	if err != nil {
+		return nil, err
+	}
+
+	return pem.EncodeToMemory(&pem.Block{Type: "X509 CRL", Bytes: derBytes}), nil
+}
+
+// LoadRevocationList loads a pkix.CertificateList object from the given PEM
+// encoded bytes and checks that it is signed by the given issuer
+func LoadRevocationList(crl []byte, issuerCert *x509.Certificate) (*pkix.CertificateList, error) {
+
+	block, _ := pem.Decode(crl)
+	if block == nil || block.Type != "X509 CRL" {
+		return nil, fmt.Errorf("error decoding revocation list PEM block")
+	}
+	list, err := x509.ParseDERCRL(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	if err := issuerCert.CheckCRLSignature(list); err != nil {
+		return nil, err
+	}
+
+	return list, nil
+}
+
+// IsRevoked returns true if the serial number of the
+// certificate is listed in the revocation list
+func IsRevoked(list *pkix.CertificateList, cert *x509.Certificate) bool {
+	for _, entry := range list.TBSCertList.RevokedCertificates {
+		if entry.SerialNumber.Cmp(cert.SerialNumber) == 0 {
+			return true
+		}
+	}
+	return false
+}
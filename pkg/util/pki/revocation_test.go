@@ -0,0 +1,65 @@
+package pki
+
+import (
+	"crypto/x509/pkix"
+	"testing"
+	"time"
+)
+
+func TestRevocationList(t *testing.T) {
+	caPEM, caKeyPEM, err := GenerateCertificate(nil, nil, "ca", time.Hour, false, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ca, _ := LoadX509Certificate(caPEM)
+	caKey, _ := DecodePrivateKeyBytes(caKeyPEM)
+
+	otherPEM, otherKeyPEM, err := GenerateCertificate(nil, nil, "other", time.Hour, false, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, _ := LoadX509Certificate(otherPEM)
+	otherKey, _ := DecodePrivateKeyBytes(otherKeyPEM)
+
+	revokedPEM, _, err := GenerateCertificate(ca, caKey, "revoked", time.Hour, false, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	revoked, _ := LoadX509Certificate(revokedPEM)
+	validPEM, _, err := GenerateCertificate(ca, caKey, "valid", time.Hour, false, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	valid, _ := LoadX509Certificate(validPEM)
+
+	crl, err := GenerateRevocationList(ca, caKey,
+		[]pkix.RevokedCertificate{{SerialNumber: revoked.SerialNumber, RevocationTime: time.Now()}}, time.Hour)
+	if err != nil {
+		t.Fatalf("GenerateRevocationList() error = %v", err)
+	}
+
+	list, err := LoadRevocationList(crl, ca)
+	if err != nil {
+		t.Fatalf("LoadRevocationList() error = %v", err)
+	}
+	if !IsRevoked(list, revoked) {
+		t.Errorf("IsRevoked() = false for a revoked certificate")
+	}
+	if IsRevoked(list, valid) {
+		t.Errorf("IsRevoked() = true for a valid certificate")
+	}
+
+	if _, err := LoadRevocationList(crl, other); err == nil {
+		t.Errorf("LoadRevocationList() expected error for a list signed by another issuer")
+	}
+	otherCRL, err := GenerateRevocationList(other, otherKey, nil, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadRevocationList(otherCRL, ca); err == nil {
+		t.Errorf("LoadRevocationList() expected error for a list signed by another issuer")
+	}
+	if _, err := LoadRevocationList([]byte("xxxx"), ca); err == nil {
+		t.Errorf("LoadRevocationList() expected error for invalid PEM")
+	}
+}
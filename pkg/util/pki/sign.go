@@ -0,0 +1,87 @@
+package pki
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net/url"
+	"time"
+)
+
+// GenerateCertificateRequest returns a PEM encoded certificate
+// signing request for the given key and common name
+func GenerateCertificateRequest(key crypto.Signer, commonName string) ([]byte, error) {
+	template := x509.CertificateRequest{
+		Subject: pkix.Name{
+			Organization: []string{"marin3r.3scale.net"},
+			CommonName:   commonName,
+		},
+	}
+
+	derBytes, err := x509.CreateCertificateRequest(rand.Reader, &template, key)
+	if err != nil {
+		return nil, err
+	}
+
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: derBytes}), nil
+}
+
+// LoadCertificateRequest loads a x509.CertificateRequest object from the given
+// PEM encoded bytes and checks that it is signed by the key it holds
+func LoadCertificateRequest(csr []byte) (*x509.CertificateRequest, error) {
+
+	block, _ := pem.Decode(csr)
+	if block == nil || block.Type != "CERTIFICATE REQUEST" {
+		return nil, fmt.Errorf("error decoding certificate request PEM block")
+	}
+
+	req, err := x509.ParseCertificateRequest(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	if err := req.CheckSignature(); err != nil {
+		return nil, err
+	}
+
+	return req, nil
+}
+
+// SignCertificateRequest issues a client certificate for the public key of the given certificate
+// request, signed by the issuer certificate. The subject of the request is ignored, the certificate
+// is issued for the given common name and URIs instead.
+func SignCertificateRequest(issuerCert *x509.Certificate, signerKey interface{}, csr *x509.CertificateRequest,
+	commonName string, validFor time.Duration, uris ...*url.URL) ([]byte, error) {
+
+	serialNumberLimit := new(big.Int).Lsh(big.NewInt(1), 128)
+	serialNumber, err := rand.Int(rand.Reader, serialNumberLimit)
+	if err != nil {
+		return nil, err
+	}
+
+	notBefore := time.Now()
+	template := x509.Certificate{
+		SerialNumber: serialNumber,
+		Subject: pkix.Name{
+			Organization: []string{"marin3r.3scale.net"},
+			CommonName:   commonName,
+		},
+		NotBefore: notBefore,
+		NotAfter:  notBefore.Add(validFor),
+		URIs:      uris,
+
+		KeyUsage:              x509.KeyUsageKeyEncipherment | x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+	}
+
+	derBytes, err := x509.CreateCertificate(rand.Reader, &template, issuerCert, csr.PublicKey, signerKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: derBytes}), nil
+}
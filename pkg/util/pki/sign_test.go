@@ -0,0 +1,55 @@
+package pki
+
+import (
+	"crypto/x509"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestSignCertificateRequest(t *testing.T) {
+	caPEM, caKeyPEM, err := GenerateCertificate(nil, nil, "ca", time.Hour, false, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ca, _ := LoadX509Certificate(caPEM)
+	caKey, _ := DecodePrivateKeyBytes(caKeyPEM)
+
+	key, err := GeneratePrivateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	csrPEM, err := GenerateCertificateRequest(key, "ignored")
+	if err != nil {
+		t.Fatal(err)
+	}
+	csr, err := LoadCertificateRequest(csrPEM)
+	if err != nil {
+		t.Fatalf("LoadCertificateRequest() error = %v", err)
+	}
+
+	uri, _ := url.Parse("spiffe://marin3r.3scale.net/ns/test/sa/default/pod/pod-1")
+	certPEM, err := SignCertificateRequest(ca, caKey, csr, "pod-1", time.Hour, uri)
+	if err != nil {
+		t.Fatalf("SignCertificateRequest() error = %v", err)
+	}
+
+	cert, err := LoadX509Certificate(certPEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	roots := x509.NewCertPool()
+	roots.AddCert(ca)
+	if _, err := cert.Verify(x509.VerifyOptions{Roots: roots, KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}}); err != nil {
+		t.Errorf("SignCertificateRequest() certificate not signed by the CA: %v", err)
+	}
+	if cert.Subject.CommonName != "pod-1" || len(cert.URIs) != 1 || cert.URIs[0].String() != uri.String() {
+		t.Errorf("SignCertificateRequest() = %v %v, want pod-1 %v", cert.Subject.CommonName, cert.URIs, uri)
+	}
+}
+
+func TestLoadCertificateRequest(t *testing.T) {
+	if _, err := LoadCertificateRequest([]byte("xxxx")); err == nil {
+		t.Errorf("LoadCertificateRequest() expected error for invalid PEM")
+	}
+}
@@ -29,14 +29,16 @@ func IsVerifyError(err error) bool {
 	return false
 }
 
-// Verify validates that the given certificate is valid and signed by the given root
+// Verify validates that the given certificate is valid and signed by the given
+// root. Certificates are valid regardless of their extended key usages.
 func Verify(certificate, root *x509.Certificate) error {
 
 	roots := x509.NewCertPool()
 	roots.AddCert(root)
 
 	opts := x509.VerifyOptions{
-		Roots: roots,
+		Roots:     roots,
+		KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
 	}
 
 	_, err := certificate.Verify(opts)
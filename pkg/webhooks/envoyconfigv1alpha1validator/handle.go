@@ -0,0 +1,115 @@
+package envoyconfigv1alpha1validator
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	marin3rv1alpha1 "github.com/3scale/marin3r/apis/marin3r/v1alpha1"
+	"github.com/3scale/marin3r/pkg/envoy"
+	envoy_serializer "github.com/3scale/marin3r/pkg/envoy/serializer"
+	admissionv1beta1 "k8s.io/api/admission/v1beta1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+const (
+	// ValidateEnvoyConfigPath is the path where the webhook server
+	// listens for EnvoyConfig admission requests
+	ValidateEnvoyConfigPath string = "/envoyconfig-v1alpha1-validate"
+	// ValidateEnvoyConfigRevisionPath is the path where the webhook server
+	// listens for EnvoyConfigRevision admission requests
+	ValidateEnvoyConfigRevisionPath string = "/envoyconfigrevision-v1alpha1-validate"
+)
+
+// validatable is implemented by the resources this
+// webhook validates
+type validatable interface {
+	runtime.Object
+	metav1.Object
+	Validate() error
+	GetEnvoyAPIVersion() envoy.APIVersion
+	GetSerialization() envoy_serializer.Serialization
+}
+
+// newValidatable returns an empty object of the given kind,
+// or nil if the kind is not validated by this webhook
+func newValidatable(kind string) validatable {
+	switch kind {
+	case "EnvoyConfig":
+		return &marin3rv1alpha1.EnvoyConfig{}
+	case "EnvoyConfigRevision":
+		return &marin3rv1alpha1.EnvoyConfigRevision{}
+	}
+	return nil
+}
+
+// envoyResources returns the envoy resources of the object
+func envoyResources(obj validatable) *marin3rv1alpha1.EnvoyResources {
+	switch o := obj.(type) {
+	case *marin3rv1alpha1.EnvoyConfig:
+		return o.Spec.EnvoyResources
+	case *marin3rv1alpha1.EnvoyConfigRevision:
+		return o.Spec.EnvoyResources
+	}
+	return nil
+}
+
+// EnvoyConfigValidator validates the envoy resources of EnvoyConfig
+// and EnvoyConfigRevision objects
+type EnvoyConfigValidator struct {
+	decoder *admission.Decoder
+}
+
+// Handle rejects EnvoyConfig and EnvoyConfigRevision objects that hold
+// envoy resources that cannot be loaded by the discovery service
+func (a *EnvoyConfigValidator) Handle(ctx context.Context, req admission.Request) admission.Response {
+
+	obj := newValidatable(req.Kind.Kind)
+	if obj == nil {
+		return admission.Errored(http.StatusBadRequest, fmt.Errorf("Unsupported kind '%s'", req.Kind.Kind))
+	}
+
+	if err := a.decoder.Decode(req, obj); err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+
+	// Objects being deleted are not validated, so finalizers can always be removed
+	if obj.GetDeletionTimestamp() != nil {
+		return admission.Allowed("")
+	}
+
+	// Updates that don't change the envoy resources are not validated, so the metadata
+	// and status of objects already stored with invalid resources can be updated
+	if req.Operation == admissionv1beta1.Update {
+		old := newValidatable(req.Kind.Kind)
+		if err := a.decoder.DecodeRaw(req.OldObject, old); err != nil {
+			return admission.Errored(http.StatusBadRequest, err)
+		}
+		if old.GetEnvoyAPIVersion() == obj.GetEnvoyAPIVersion() && old.GetSerialization() == obj.GetSerialization() &&
+			equality.Semantic.DeepEqual(envoyResources(old), envoyResources(obj)) {
+			return admission.Allowed("")
+		}
+	}
+
+	if err := obj.Validate(); err != nil {
+		if statusErr, ok := err.(*errors.StatusError); ok {
+			return admission.Response{AdmissionResponse: admissionv1beta1.AdmissionResponse{
+				Allowed: false,
+				Result:  &statusErr.ErrStatus,
+			}}
+		}
+		return admission.Denied(err.Error())
+	}
+
+	return admission.Allowed("")
+}
+
+// InjectDecoder injects the decoder.
+func (a *EnvoyConfigValidator) InjectDecoder(d *admission.Decoder) error {
+	a.decoder = d
+	return nil
+}
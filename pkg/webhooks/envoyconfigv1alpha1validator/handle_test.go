@@ -0,0 +1,153 @@
+package envoyconfigv1alpha1validator
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	marin3rv1alpha1 "github.com/3scale/marin3r/apis/marin3r/v1alpha1"
+	admissionv1beta1 "k8s.io/api/admission/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+func init() {
+	marin3rv1alpha1.AddToScheme(scheme.Scheme)
+}
+
+func testRequest(kind, raw string) admission.Request {
+	return admission.Request{
+		AdmissionRequest: admissionv1beta1.AdmissionRequest{
+			UID:       "xxxx",
+			Kind:      metav1.GroupVersionKind{Group: marin3rv1alpha1.GroupVersion.Group, Version: "v1alpha1", Kind: kind},
+			Namespace: "default",
+			Operation: admissionv1beta1.Create,
+			Object:    runtime.RawExtension{Raw: []byte(raw)},
+		},
+	}
+}
+
+func testUpdateRequest(kind, raw, oldRaw string) admission.Request {
+	req := testRequest(kind, raw)
+	req.Operation = admissionv1beta1.Update
+	req.OldObject = runtime.RawExtension{Raw: []byte(oldRaw)}
+	return req
+}
+
+// invalidRevision is an EnvoyConfigRevision with a listener that does not pass validation
+const invalidRevision string = `{
+	"apiVersion": "marin3r.3scale.net/v1alpha1",
+	"kind": "EnvoyConfigRevision",
+	"metadata": {"name": "test", "namespace": "default"%s},
+	"spec": {
+		"nodeID": "test",
+		"version": "xxxx",
+		"envoyAPI": "v3",
+		"envoyResources": {"listeners": [{"name": "listener", "value": "{\"name\": \"listener\", \"per_connection_buffer_limit_bytes\": 0, \"address\": {}}"}]}
+	}
+}`
+
+func TestEnvoyConfigValidator_Handle(t *testing.T) {
+	tests := []struct {
+		name        string
+		req         admission.Request
+		wantAllowed bool
+		wantCauses  []string
+	}{
+		{
+			name: "Allows a valid EnvoyConfig",
+			req: testRequest("EnvoyConfig", `{
+				"apiVersion": "marin3r.3scale.net/v1alpha1",
+				"kind": "EnvoyConfig",
+				"metadata": {"name": "test", "namespace": "default"},
+				"spec": {
+					"nodeID": "test",
+					"envoyAPI": "v3",
+					"envoyResources": {"clusters": [{"name": "cluster", "value": "{\"name\": \"cluster\"}"}]}
+				}
+			}`),
+			wantAllowed: true,
+		},
+		{
+			name: "Rejects an EnvoyConfig with a resource that cannot be unmarshalled",
+			req: testRequest("EnvoyConfig", `{
+				"apiVersion": "marin3r.3scale.net/v1alpha1",
+				"kind": "EnvoyConfig",
+				"metadata": {"name": "test", "namespace": "default"},
+				"spec": {
+					"nodeID": "test",
+					"envoyResources": {"clusters": [
+						{"name": "cluster", "value": "{\"name\": \"cluster\"}"},
+						{"name": "cluster", "value": "{\"unknown\": \"cluster\"}"}
+					]}
+				}
+			}`),
+			wantAllowed: false,
+			wantCauses:  []string{"spec.envoyResources.clusters[1].value"},
+		},
+		{
+			name: "Rejects an EnvoyConfigRevision with a resource that does not pass validation",
+			req: testRequest("EnvoyConfigRevision", `{
+				"apiVersion": "marin3r.3scale.net/v1alpha1",
+				"kind": "EnvoyConfigRevision",
+				"metadata": {"name": "test", "namespace": "default"},
+				"spec": {
+					"nodeID": "test",
+					"version": "xxxx",
+					"envoyAPI": "v3",
+					"envoyResources": {"listeners": [{"name": "listener", "value": "{\"name\": \"listener\", \"per_connection_buffer_limit_bytes\": 0, \"address\": {}}"}]}
+				}
+			}`),
+			wantAllowed: false,
+			wantCauses:  []string{"spec.envoyResources.listeners[0].value"},
+		},
+		{
+			name: "Allows removing the finalizer of an invalid EnvoyConfigRevision being deleted",
+			req: testUpdateRequest("EnvoyConfigRevision",
+				fmt.Sprintf(invalidRevision, `, "deletionTimestamp": "2021-01-01T00:00:00Z"`),
+				fmt.Sprintf(invalidRevision, `, "deletionTimestamp": "2021-01-01T00:00:00Z", "finalizers": ["finalizer"]`),
+			),
+			wantAllowed: true,
+		},
+		{
+			name: "Allows updates of an invalid EnvoyConfigRevision that don't change its resources",
+			req: testUpdateRequest("EnvoyConfigRevision",
+				fmt.Sprintf(invalidRevision, `, "annotations": {"key": "value"}`),
+				fmt.Sprintf(invalidRevision, ""),
+			),
+			wantAllowed: true,
+		},
+		{
+			name: "Rejects updates that change the resources to invalid ones",
+			req: testUpdateRequest("EnvoyConfigRevision",
+				fmt.Sprintf(invalidRevision, ""),
+				`{
+					"apiVersion": "marin3r.3scale.net/v1alpha1",
+					"kind": "EnvoyConfigRevision",
+					"metadata": {"name": "test", "namespace": "default"},
+					"spec": {"nodeID": "test", "version": "xxxx", "envoyAPI": "v3", "envoyResources": {}}
+				}`,
+			),
+			wantAllowed: false,
+			wantCauses:  []string{"spec.envoyResources.listeners[0].value"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			decoder, _ := admission.NewDecoder(scheme.Scheme)
+			a := &EnvoyConfigValidator{decoder: decoder}
+			got := a.Handle(context.TODO(), tt.req)
+			if got.Allowed != tt.wantAllowed {
+				t.Fatalf("EnvoyConfigValidator.Handle() allowed = %v, want %v (%v)", got.Allowed, tt.wantAllowed, got.Result)
+			}
+			for idx, cause := range tt.wantCauses {
+				if got.Result == nil || got.Result.Details == nil || len(got.Result.Details.Causes) <= idx ||
+					got.Result.Details.Causes[idx].Field != cause {
+					t.Errorf("EnvoyConfigValidator.Handle() causes = %v, want %v", got.Result, tt.wantCauses)
+				}
+			}
+		})
+	}
+}
@@ -46,7 +46,9 @@ func (a *PodMutator) Handle(ctx context.Context, req admission.Request) admissio
 		return admission.Errored(http.StatusBadRequest, fmt.Errorf("Error trying to load envoy container config from annotations: '%s'", err))
 	}
 
+	pod.Spec.InitContainers = append(pod.Spec.InitContainers, config.initContainers()...)
 	pod.Spec.Containers = append(pod.Spec.Containers, config.container())
+	pod.Spec.Containers = append(pod.Spec.Containers, config.sidecarContainers()...)
 	pod.Spec.Volumes = append(pod.Spec.Volumes, config.volumes()...)
 
 	marshaledPod, err := json.Marshal(pod)
@@ -19,10 +19,13 @@ import (
 	"strconv"
 	"strings"
 
+	operatorv1alpha1 "github.com/3scale/marin3r/apis/operator/v1alpha1"
 	"github.com/3scale/marin3r/pkg/envoy"
+	"github.com/3scale/marin3r/pkg/version"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
 	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/utils/pointer"
 )
 
 type parameter struct {
@@ -46,6 +49,8 @@ const (
 	paramClientCertificate  = "client-certificate"
 	paramEnvoyExtraArgs     = "envoy-extra-args"
 	paramEnvoyAPIVersion    = "envoy-api-version"
+	paramXdsAuthentication  = "xds-authentication"
+	paramMarin3rImage       = "marin3r-image"
 
 	// Annotations to allow definition of container resource requests
 	// and limits for CPU and Memory. For this annitations, a non-existing
@@ -70,6 +75,20 @@ const (
 	DefaultEnvoyConfigFileName  = "config.json"
 	DefaultEnvoyTLSBasePath     = "/etc/envoy/tls/client"
 	DefaultEnvoyAPIVersion      = string(envoy.APIv2)
+	DefaultXdsAuthentication    = XdsAuthenticationClientCertificate
+	DefaultEnvoyTokenBasePath   = "/var/run/secrets/marin3r.3scale.net"
+	DefaultTokenExpiration      = 3600
+
+	// XdsAuthenticationClientCertificate authenticates the envoy sidecar with the
+	// discovery service using the client certificate Secret
+	XdsAuthenticationClientCertificate = "client-certificate"
+	// XdsAuthenticationServiceAccountToken authenticates the envoy sidecar with the
+	// discovery service using a projected token of the Pod's ServiceAccount
+	XdsAuthenticationServiceAccountToken = "service-account-token"
+	// XdsAuthenticationPodCertificate authenticates the envoy sidecar with the discovery
+	// service using a client certificate issued for the Pod, that is requested by an init
+	// container and renewed by a sidecar container
+	XdsAuthenticationPodCertificate = "pod-certificate"
 
 	TlsCertificateSdsSecretFileName = "tls_certificate_sds_secret.yaml"
 
@@ -89,6 +108,13 @@ type envoySidecarConfig struct {
 	extraArgs          string
 	resources          corev1.ResourceRequirements
 	envoyAPI           envoy.APIVersion
+	// serviceAccountToken is true when the sidecar authenticates
+	// with a projected ServiceAccount token
+	serviceAccountToken bool
+	// podCertificate is true when the sidecar authenticates
+	// with a client certificate issued for the Pod
+	podCertificate bool
+	marin3rImage   string
 }
 
 func lookupMarin3rAnnotation(key string, annotations map[string]string) (string, bool) {
@@ -106,6 +132,8 @@ func getStringParam(key string, annotations map[string]string) string {
 		paramClientCertificate: DefaultClientCertificate,
 		paramEnvoyExtraArgs:    DefaultEnvoyExtraArgs,
 		paramEnvoyAPIVersion:   DefaultEnvoyAPIVersion,
+		paramXdsAuthentication: DefaultXdsAuthentication,
+		paramMarin3rImage:      fmt.Sprintf("%s:%s", operatorv1alpha1.DefaultImageRegistry, version.Current()),
 	}
 
 	// return the value specified in the corresponding annotation, if any
@@ -149,6 +177,23 @@ func (esc *envoySidecarConfig) PopulateFromAnnotations(annotations map[string]st
 	esc.clientCertSecret = getStringParam(paramClientCertificate, annotations)
 	esc.extraArgs = getStringParam(paramEnvoyExtraArgs, annotations)
 
+	switch auth := getStringParam(paramXdsAuthentication, annotations); auth {
+	case XdsAuthenticationClientCertificate:
+		esc.serviceAccountToken = false
+	case XdsAuthenticationServiceAccountToken:
+		esc.serviceAccountToken = true
+	case XdsAuthenticationPodCertificate:
+		esc.podCertificate = true
+		esc.marin3rImage = getStringParam(paramMarin3rImage, annotations)
+		envoyAPI, err := envoy.ParseAPIVersion(getStringParam(paramEnvoyAPIVersion, annotations))
+		if err != nil {
+			return err
+		}
+		esc.envoyAPI = envoyAPI
+	default:
+		return fmt.Errorf("Unsupported xDS authentication method '%s'", auth)
+	}
+
 	resources, err := getContainerResourceRequirements(annotations)
 	if err != nil {
 		return err
@@ -376,6 +421,10 @@ func (esc *envoySidecarConfig) container() corev1.Container {
 		},
 	}
 
+	if esc.serviceAccountToken {
+		container.VolumeMounts[0].MountPath = DefaultEnvoyTokenBasePath
+	}
+
 	if esc.extraArgs != "" {
 		for _, arg := range strings.Split(esc.extraArgs, " ") {
 			container.Args = append(container.Args, arg)
@@ -408,5 +457,92 @@ func (esc *envoySidecarConfig) volumes() []corev1.Volume {
 		},
 	}
 
+	// The token is rotated by the kubelet before it expires
+	if esc.serviceAccountToken {
+		volumes[0].VolumeSource = serviceAccountTokenVolumeSource()
+	}
+
+	// The pod certificate is written by the marin3r containers to an
+	// emptyDir, and they authenticate with the ServiceAccount token
+	if esc.podCertificate {
+		volumes[0].VolumeSource = corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}}
+		volumes = append(volumes, corev1.Volume{
+			Name:         esc.tokenVolume(),
+			VolumeSource: serviceAccountTokenVolumeSource(),
+		})
+	}
+
 	return volumes
 }
+
+// initContainers returns the containers that need to run before the envoy sidecar starts
+func (esc *envoySidecarConfig) initContainers() []corev1.Container {
+	if !esc.podCertificate {
+		return []corev1.Container{}
+	}
+	return []corev1.Container{esc.podCertificateContainer(fmt.Sprintf("%s-init-cert", esc.name), false)}
+}
+
+// sidecarContainers returns the containers that run alongside the envoy sidecar
+func (esc *envoySidecarConfig) sidecarContainers() []corev1.Container {
+	if !esc.podCertificate {
+		return []corev1.Container{}
+	}
+	return []corev1.Container{esc.podCertificateContainer(fmt.Sprintf("%s-renew-cert", esc.name), true)}
+}
+
+// podCertificateContainer returns a container that requests a client certificate for
+// the Pod from the discovery service. If renew is true the container keeps running and
+// renews the certificate before it expires.
+func (esc *envoySidecarConfig) podCertificateContainer(name string, renew bool) corev1.Container {
+	container := corev1.Container{
+		Name:  name,
+		Image: esc.marin3rImage,
+		Args: []string{
+			"pod-certificate",
+			fmt.Sprintf("--envoy-api-version=%s", esc.envoyAPI),
+		},
+		VolumeMounts: []corev1.VolumeMount{
+			{
+				Name:      esc.tlsVolume,
+				MountPath: DefaultEnvoyTLSBasePath,
+			},
+			{
+				Name:      esc.configVolume,
+				ReadOnly:  true,
+				MountPath: DefaultEnvoyConfigBasePath,
+			},
+			{
+				Name:      esc.tokenVolume(),
+				ReadOnly:  true,
+				MountPath: DefaultEnvoyTokenBasePath,
+			},
+		},
+	}
+
+	if renew {
+		container.Args = append(container.Args, "--renew")
+	}
+
+	return container
+}
+
+// tokenVolume returns the name of the volume that holds the ServiceAccount
+// token when the tls volume is used to store the pod certificate
+func (esc *envoySidecarConfig) tokenVolume() string {
+	return fmt.Sprintf("%s-token", esc.tlsVolume)
+}
+
+func serviceAccountTokenVolumeSource() corev1.VolumeSource {
+	return corev1.VolumeSource{
+		Projected: &corev1.ProjectedVolumeSource{
+			Sources: []corev1.VolumeProjection{{
+				ServiceAccountToken: &corev1.ServiceAccountTokenProjection{
+					Audience:          operatorv1alpha1.DiscoveryServiceTokenAudience,
+					ExpirationSeconds: pointer.Int64Ptr(DefaultTokenExpiration),
+					Path:              corev1.ServiceAccountTokenKey,
+				},
+			}},
+		},
+	}
+}
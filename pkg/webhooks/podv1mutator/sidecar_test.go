@@ -9,6 +9,7 @@ import (
 	"k8s.io/apimachinery/pkg/api/equality"
 	"k8s.io/apimachinery/pkg/api/resource"
 	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/utils/pointer"
 )
 
 func Test_envoySidecarConfig_PopulateFromAnnotations(t *testing.T) {
@@ -85,6 +86,30 @@ func Test_envoySidecarConfig_PopulateFromAnnotations(t *testing.T) {
 				clientCertSecret:   DefaultClientCertificate,
 			},
 			false,
+		}, {
+			"Populate '*envoySidecarConfig' from annotations, with pod certificates",
+			&envoySidecarConfig{},
+			args{map[string]string{
+				"marin3r.3scale.net/node-id":            "node-id",
+				"marin3r.3scale.net/envoy-api-version":  "v3",
+				"marin3r.3scale.net/xds-authentication": "pod-certificate",
+				"marin3r.3scale.net/marin3r-image":      "marin3r:test",
+			}},
+			&envoySidecarConfig{
+				name:               DefaultContainerName,
+				image:              DefaultImage,
+				ports:              []corev1.ContainerPort{},
+				bootstrapConfigMap: DefaultBootstrapConfigMapV3,
+				nodeID:             "node-id",
+				clusterID:          "node-id",
+				tlsVolume:          DefaultTLSVolume,
+				configVolume:       DefaultConfigVolume,
+				clientCertSecret:   DefaultClientCertificate,
+				envoyAPI:           "v3",
+				podCertificate:     true,
+				marin3rImage:       "marin3r:test",
+			},
+			false,
 		},
 	}
 	for _, tt := range tests {
@@ -745,6 +770,82 @@ func Test_envoySidecarConfig_volumes(t *testing.T) {
 				},
 			},
 		},
+		{
+			"Returns a projected ServiceAccount token volume",
+			&envoySidecarConfig{
+				bootstrapConfigMap:  "ads-configmap",
+				tlsVolume:           "tls-volume",
+				configVolume:        "config-volume",
+				clientCertSecret:    "secret",
+				serviceAccountToken: true,
+			},
+			[]corev1.Volume{
+				{
+					Name: "tls-volume",
+					VolumeSource: corev1.VolumeSource{
+						Projected: &corev1.ProjectedVolumeSource{
+							Sources: []corev1.VolumeProjection{{
+								ServiceAccountToken: &corev1.ServiceAccountTokenProjection{
+									Audience:          "marin3r.3scale.net",
+									ExpirationSeconds: pointer.Int64Ptr(3600),
+									Path:              "token",
+								},
+							}},
+						},
+					},
+				},
+				{
+					Name: "config-volume",
+					VolumeSource: corev1.VolumeSource{
+						ConfigMap: &corev1.ConfigMapVolumeSource{
+							LocalObjectReference: corev1.LocalObjectReference{
+								Name: "ads-configmap",
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			"Returns an emptyDir and a ServiceAccount token volume for pod certificates",
+			&envoySidecarConfig{
+				bootstrapConfigMap: "ads-configmap",
+				tlsVolume:          "tls-volume",
+				configVolume:       "config-volume",
+				clientCertSecret:   "secret",
+				podCertificate:     true,
+			},
+			[]corev1.Volume{
+				{
+					Name:         "tls-volume",
+					VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}},
+				},
+				{
+					Name: "config-volume",
+					VolumeSource: corev1.VolumeSource{
+						ConfigMap: &corev1.ConfigMapVolumeSource{
+							LocalObjectReference: corev1.LocalObjectReference{
+								Name: "ads-configmap",
+							},
+						},
+					},
+				},
+				{
+					Name: "tls-volume-token",
+					VolumeSource: corev1.VolumeSource{
+						Projected: &corev1.ProjectedVolumeSource{
+							Sources: []corev1.VolumeProjection{{
+								ServiceAccountToken: &corev1.ServiceAccountTokenProjection{
+									Audience:          "marin3r.3scale.net",
+									ExpirationSeconds: pointer.Int64Ptr(3600),
+									Path:              "token",
+								},
+							}},
+						},
+					},
+				},
+			},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -755,6 +856,61 @@ func Test_envoySidecarConfig_volumes(t *testing.T) {
 	}
 }
 
+func Test_envoySidecarConfig_podCertificateContainers(t *testing.T) {
+	esc := &envoySidecarConfig{
+		name:           "envoy-sidecar",
+		tlsVolume:      "tls-volume",
+		configVolume:   "config-volume",
+		envoyAPI:       "v3",
+		marin3rImage:   "marin3r:test",
+		podCertificate: true,
+	}
+	mounts := []corev1.VolumeMount{
+		{Name: "tls-volume", MountPath: "/etc/envoy/tls/client"},
+		{Name: "config-volume", ReadOnly: true, MountPath: "/etc/envoy/bootstrap"},
+		{Name: "tls-volume-token", ReadOnly: true, MountPath: "/var/run/secrets/marin3r.3scale.net"},
+	}
+
+	tests := []struct {
+		name string
+		got  []corev1.Container
+		want []corev1.Container
+	}{
+		{
+			"Returns the init container that requests the certificate",
+			esc.initContainers(),
+			[]corev1.Container{{
+				Name:         "envoy-sidecar-init-cert",
+				Image:        "marin3r:test",
+				Args:         []string{"pod-certificate", "--envoy-api-version=v3"},
+				VolumeMounts: mounts,
+			}},
+		},
+		{
+			"Returns the sidecar container that renews the certificate",
+			esc.sidecarContainers(),
+			[]corev1.Container{{
+				Name:         "envoy-sidecar-renew-cert",
+				Image:        "marin3r:test",
+				Args:         []string{"pod-certificate", "--envoy-api-version=v3", "--renew"},
+				VolumeMounts: mounts,
+			}},
+		},
+		{
+			"Returns no init containers without pod certificates",
+			(&envoySidecarConfig{}).initContainers(),
+			[]corev1.Container{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if !equality.Semantic.DeepEqual(tt.got, tt.want) {
+				t.Errorf("got %v, want %v", tt.got, tt.want)
+			}
+		})
+	}
+}
+
 func Test_lookupMarin3rAnnotation(t *testing.T) {
 	type args struct {
 		annotations map[string]string